@@ -0,0 +1,95 @@
+package cconf
+
+import (
+	"sort"
+	"strings"
+)
+
+// Alias declares oldKey a deprecated name of newKey, like a renamed key during a transition
+// period: the value at oldKey, loaded or set, is moved to newKey, so that Get, Lookup and
+// Populate for newKey see it. If both keys have values, the value at newKey wins; if both
+// are maps, they are merged with the keys of newKey winning. Set at oldKey, or below it,
+// sets newKey instead. Aliases apply to the current store immediately and to every load
+// and reload; the first time a value at oldKey is found, the OnDeprecated functions are called.
+func (c *Conf) Alias(oldKey, newKey string) {
+	c.mu.Lock()
+	defer c.unlock()
+	if c.frozen {
+		return
+	}
+	aliases := make(map[string]string, len(c.aliases)+1)
+	for k, v := range c.aliases {
+		aliases[k] = v
+	}
+	aliases[oldKey] = newKey
+	c.aliases = aliases
+	c.reset(c.applyTypesLenient(c.effective(c.base)))
+}
+
+// OnDeprecated registers a function that is called once for every key declared with Alias
+// that is found in the configuration, with the deprecated and the new key, to log it.
+// Like OnEvent functions, it is called after the change has been applied and outside
+// the locks of the Conf.
+func (c *Conf) OnDeprecated(fn func(oldKey, newKey string)) {
+	c.mu.Lock()
+	defer c.unlock()
+	c.deprecatedHandlers = append(c.deprecatedHandlers[:len(c.deprecatedHandlers):len(c.deprecatedHandlers)], fn)
+}
+
+// resolveAlias returns the key with a deprecated prefix replaced by its new key, see Alias.
+// The caller must hold c.mu.
+func (c *Conf) resolveAlias(key string) string {
+	for old, key2 := range c.aliases {
+		if key == old {
+			return key2
+		}
+		if strings.HasPrefix(key, old+c.Separator) {
+			return key2 + key[len(old):]
+		}
+	}
+	return key
+}
+
+// applyAliases returns the store with the values at deprecated keys moved to their new keys,
+// see Alias. The caller must hold c.mu.
+func (c *Conf) applyAliases(store interface{}) interface{} {
+	if len(c.aliases) == 0 {
+		return store
+	}
+	olds := make([]string, 0, len(c.aliases))
+	for old := range c.aliases {
+		olds = append(olds, old)
+	}
+	sort.Strings(olds)
+	for _, old := range olds {
+		v, ok := walk(store, old, c.Separator)
+		if !ok {
+			continue
+		}
+		key := c.aliases[old]
+		if c.deprecated == nil {
+			c.deprecated = make(map[string]bool)
+		}
+		if !c.deprecated[old] {
+			c.deprecated[old] = true
+			c.deprecations = append(c.deprecations, [2]string{old, key})
+		}
+		store, _ = remove(store, strings.Split(old, c.Separator))
+		if nv, ok := walk(store, key, c.Separator); ok {
+			v = merge(v, nv)
+		}
+		if s, err := c.set(store, strings.Split(key, c.Separator), 0, v); err == nil {
+			store = s
+		}
+	}
+	return store
+}
+
+// notifyDeprecated calls the OnDeprecated functions for the deprecated keys.
+func (c *Conf) notifyDeprecated(deprecations [][2]string, handlers []func(oldKey, newKey string)) {
+	for _, d := range deprecations {
+		for _, fn := range handlers {
+			c.protect(d[0], "OnDeprecated function", func() { fn(d[0], d[1]) })
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package cconf
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAlias(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "app.json")
+	if err := os.WriteFile(file, []byte(`{"redis": {"addr": "localhost:6379", "db": 1}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	c := New()
+	c.Alias("redis.addr", "cache.redis.address")
+	var calls [][2]string
+	c.OnDeprecated(func(old, new string) {
+		calls = append(calls, [2]string{old, new})
+	})
+	if err := c.Load(file); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, "localhost:6379", c.GetString("cache.redis.address"))
+	v, ok := c.Lookup("cache.redis.address")
+	equal(t, true, ok)
+	equal(t, "localhost:6379", v)
+	var cache struct {
+		Redis struct {
+			Address string `cconf:"address"`
+		} `cconf:"redis"`
+	}
+	if err := c.Populate(&cache, "cache"); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, "localhost:6379", cache.Redis.Address)
+	// the old key is moved.
+	equal(t, map[string]interface{}{"db": float64(1)}, c.Get("redis"))
+
+	// Set on the alias sets the new key.
+	if err := c.Set("redis.addr", "redis:6379"); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, "redis:6379", c.GetString("cache.redis.address"))
+
+	// the alias applies across reloads, and the callback fires once.
+	if err := c.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, "redis:6379", c.GetString("cache.redis.address"))
+	equal(t, [][2]string{{"redis.addr", "cache.redis.address"}}, calls)
+
+	// the new key wins.
+	if err := os.WriteFile(file, []byte(`{"redis": {"addr": "old:6379"}, "cache": {"redis": {"address": "new:6379"}}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	c.ClearOverride("cache.redis.address")
+	if err := c.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, "new:6379", c.GetString("cache.redis.address"))
+	equal(t, nil, c.Get("redis"))
+	equal(t, 1, len(calls))
+}
+
+func TestAliasMerge(t *testing.T) {
+	c := New()
+	c.SetStore(map[string]interface{}{
+		"db":       map[string]interface{}{"host": "old", "port": 5432},
+		"database": map[string]interface{}{"host": "new"},
+	})
+	c.Alias("db", "database")
+	equal(t, map[string]interface{}{"host": "new", "port": 5432}, c.Get("database"))
+	equal(t, nil, c.Get("db"))
+}
@@ -0,0 +1,57 @@
+package cconf
+
+import (
+	"reflect"
+)
+
+// BindStruct populates the struct pointed to by v like Populate, and populates it again
+// whenever a change of the store, like a Set or a reload, changes the values below the key.
+// Every population starts from a copy of the struct as it was passed to BindStruct, so that
+// its fields serve as defaults, and the result is assigned to the struct only if it succeeds;
+// failures are reported by an EventPopulate (see OnEvent). The returned function unbinds
+// the struct.
+//
+// The struct is written by the goroutine changing the store, so reads of the struct must be
+// synchronized by the caller. A simple pattern is to bind a private struct and publish
+// copies of it through an atomic.Pointer, from an OnChange function registered afterwards:
+//
+//	var bound Settings
+//	var settings atomic.Pointer[Settings]
+//	publish := func(_, _ interface{}) {
+//		s := bound
+//		settings.Store(&s)
+//	}
+//	unbind, err := c.BindStruct(&bound, "app")
+//	...
+//	publish(nil, nil)
+//	c.OnChange("app", publish)
+func (c *Conf) BindStruct(v interface{}, key ...string) (func(), error) {
+	val := reflect.ValueOf(v)
+	if val.Kind() != reflect.Ptr || val.IsNil() {
+		return nil, &ConfigTargetError{val}
+	}
+	defaults := reflect.New(val.Type().Elem()).Elem()
+	defaults.Set(val.Elem())
+	populate := func() error {
+		p := reflect.New(defaults.Type())
+		p.Elem().Set(defaults)
+		if err := c.Populate(p.Interface(), key...); err != nil {
+			return err
+		}
+		val.Elem().Set(p.Elem())
+		return nil
+	}
+	if err := populate(); err != nil {
+		return nil, err
+	}
+
+	var k string
+	if len(key) > 0 {
+		k = key[0]
+	}
+	return c.onChange(k, func(_, _ interface{}) {
+		if err := populate(); err != nil {
+			c.fire([]Event{{Type: EventPopulate, Key: k, Err: err}})
+		}
+	}), nil
+}
@@ -0,0 +1,74 @@
+package cconf
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBindStruct(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "server.json")
+	write := func(data string) {
+		if err := os.WriteFile(file, []byte(data), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(`{"server": {"Host": "localhost", "Port": 8080}, "name": "app"}`)
+	c := New()
+	if err := c.Load(file); err != nil {
+		t.Fatal(err)
+	}
+	type server struct {
+		Host  string
+		Port  int
+		Debug bool
+	}
+	s := server{Debug: true}
+	unbind, err := c.BindStruct(&s, "server")
+	if err != nil {
+		t.Fatal(err)
+	}
+	equal(t, server{"localhost", 8080, true}, s)
+
+	var events []Event
+	c.OnEvent(func(e Event) {
+		if e.Type == EventPopulate {
+			events = append(events, e)
+		}
+	})
+
+	write(`{"server": {"Host": "example.com"}, "name": "app"}`)
+	if err := c.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	// removed keys fall back to the initial values.
+	equal(t, server{"example.com", 0, true}, s)
+
+	// a failed population leaves the struct unchanged.
+	if err := c.Set("server.Port", "invalid"); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, server{"example.com", 0, true}, s)
+	equal(t, 1, len(events))
+	equal(t, "server", events[0].Key)
+
+	if err := c.Set("server.Port", 9090); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, server{"example.com", 9090, true}, s)
+
+	unbind()
+	unbind()
+	if err := c.Set("server.Port", 9091); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, 9090, s.Port)
+
+	if _, err := c.BindStruct(s); err == nil {
+		t.Fatal("expected an error")
+	}
+	var n struct{ Name int }
+	if _, err := c.BindStruct(&n); err == nil {
+		t.Fatal("expected an error")
+	}
+}
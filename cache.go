@@ -0,0 +1,90 @@
+package cconf
+
+import (
+	"sync/atomic"
+)
+
+// CacheStats describes the activity of the Get cache.
+type CacheStats struct {
+	Hits      uint64 // lookups served by the cache or the index
+	Misses    uint64 // lookups that had to walk the store
+	Evictions uint64 // entries dropped because the cache was full
+	Entries   int    // entries currently in the cache
+}
+
+// cacheStats counts the cache activity of a Conf across snapshots.
+type cacheStats struct {
+	hits, misses, evictions atomic.Uint64
+}
+
+// SetCacheSize limits the number of entries of the Get cache, including cached misses.
+// When the cache is full, an arbitrary entry is evicted to make room for a new one.
+// A size of 0, the default, means unlimited.
+func (c *Conf) SetCacheSize(size int) {
+	c.mu.Lock()
+	defer c.unlock()
+	c.cacheSize = size
+	c.republish()
+}
+
+// DisableCache turns off the Get cache: every Get walks the store and no cache entries are
+// allocated. This suits programs that read a few keys once, or that change the store so often
+// that cached values would rarely be reused, at the cost of slower repeated reads.
+func (c *Conf) DisableCache() {
+	c.mu.Lock()
+	defer c.unlock()
+	c.nocache = true
+	c.republish()
+}
+
+// CacheStats returns the statistics of the Get cache.
+func (c *Conf) CacheStats() CacheStats {
+	s := c.snap.Load()
+	return CacheStats{
+		Hits:      c.stats.hits.Load(),
+		Misses:    c.stats.misses.Load(),
+		Evictions: c.stats.evictions.Load(),
+		Entries:   int(s.entries.Load()),
+	}
+}
+
+// newSnapshot returns an unpublished snapshot of the store with an empty cache.
+// The caller must hold c.mu.
+func (c *Conf) newSnapshot(store interface{}) *snapshot {
+	return &snapshot{store: store, stats: &c.stats, size: c.cacheSize, nocache: c.nocache, env: c.env,
+		fileSuffix: c.fileSuffix, envKeys: c.envKeys, lazy: c.lazy,
+		parent: c.parent, fire: c.fire}
+}
+
+// republish publishes the current store with an empty cache, to apply changed settings.
+// The caller must hold c.mu.
+func (c *Conf) republish() {
+	old := c.snap.Load()
+	s := c.newSnapshot(old.store)
+	s.index = old.index
+	c.publish(s)
+	c.queue(Event{Type: EventCacheReset})
+}
+
+// cacheStore caches the value of the key, evicting another entry if the cache is full.
+func (s *snapshot) cacheStore(key string, v interface{}) {
+	if s.nocache {
+		return
+	}
+	if _, loaded := s.cache.LoadOrStore(key, v); loaded {
+		return
+	}
+	if s.entries.Add(1) <= int64(s.size) || s.size <= 0 {
+		return
+	}
+	s.cache.Range(func(k, _ interface{}) bool {
+		if k.(string) == key {
+			return true
+		}
+		if _, loaded := s.cache.LoadAndDelete(k); loaded {
+			s.entries.Add(-1)
+			s.stats.evictions.Add(1)
+		}
+		return false
+	})
+}
@@ -0,0 +1,46 @@
+package cconf
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestCacheEviction(t *testing.T) {
+	c := New()
+	data := make(map[string]interface{})
+	for i := 0; i < 10; i++ {
+		data[fmt.Sprintf("key%d", i)] = i
+	}
+	c.SetStore(data)
+	c.SetCacheSize(3)
+
+	for round := 0; round < 2; round++ {
+		for i := 0; i < 10; i++ {
+			equal(t, i, c.GetInt(fmt.Sprintf("key%d", i)))
+			equal(t, "none", c.GetString(fmt.Sprintf("missing%d", i), "none"))
+		}
+	}
+	stats := c.CacheStats()
+	if stats.Entries > 3 {
+		t.Errorf("Expected at most 3 cache entries - Got %v", stats.Entries)
+	}
+	equal(t, uint64(40), stats.Hits+stats.Misses)
+	equal(t, stats.Misses-uint64(stats.Entries), stats.Evictions)
+	if stats.Evictions < 30 {
+		t.Errorf("Expected at least 30 evictions - Got %v", stats.Evictions)
+	}
+}
+
+func TestCacheUnlimited(t *testing.T) {
+	c := New()
+	c.SetStore(map[string]interface{}{"a": 1})
+	for i := 0; i < 100; i++ {
+		c.Get(fmt.Sprintf("missing%d", i))
+	}
+	c.Get("a")
+	c.Get("a")
+	stats := c.CacheStats()
+	equal(t, 101, stats.Entries)
+	equal(t, uint64(0), stats.Evictions)
+	equal(t, uint64(1), stats.Hits)
+}
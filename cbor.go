@@ -0,0 +1,243 @@
+package cconf
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"time"
+)
+
+// loadCBOR reads and parses a CBOR file, see decodeCBOR.
+func loadCBOR(file string, data interface{}) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return decodeCBOR(f, data)
+}
+
+// decodeCBOR parses a single CBOR data item from the reader into data, which must be a
+// *interface{}. Integers are decoded as int64, floats as float64, byte strings as []byte,
+// undefined as nil and date-times (tags 0 and 1) as time.Time; other tags are dropped, keeping
+// their content. Map keys are converted to strings like those of MessagePack, see scalarKey.
+func decodeCBOR(r io.Reader, data interface{}) error {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	d := &cborDecoder{binaryReader{format: "cbor", b: b}}
+	v, err := d.value()
+	if err != nil {
+		return err
+	}
+	if d.off < len(b) {
+		return fmt.Errorf("cbor: unexpected data after the top-level item at offset %d", d.off)
+	}
+	*data.(*interface{}) = v
+	return nil
+}
+
+// cborDecoder decodes CBOR data items from a buffer.
+type cborDecoder struct {
+	binaryReader
+}
+
+// cborIndefinite is the argument of the head of an item of indefinite length.
+const cborIndefinite = math.MaxUint64
+
+// head returns the major type and the argument of the next item.
+func (d *cborDecoder) head() (major byte, arg uint64, err error) {
+	start := d.off
+	b, err := d.next(1)
+	if err != nil {
+		return 0, 0, err
+	}
+	major, info := b[0]>>5, b[0]&0x1f
+	switch {
+	case info < 24:
+		return major, uint64(info), nil
+	case info <= 27:
+		arg, err := d.uint(1 << (info - 24))
+		return major, arg, err
+	case info == 31 && major >= 2 && major != 6:
+		return major, cborIndefinite, nil
+	}
+	return 0, 0, fmt.Errorf("cbor: invalid byte 0x%02x at offset %d", b[0], start)
+}
+
+// elements calls fn for each element of a string, an array or a map with the argument, which
+// is the number of elements or indefinite, in which case the elements end with a break.
+func (d *cborDecoder) elements(arg uint64, fn func() error) error {
+	if arg == cborIndefinite {
+		for {
+			if d.off == len(d.b) {
+				return d.eof()
+			}
+			if d.b[d.off] == 0xff {
+				d.off++
+				return nil
+			}
+			if err := fn(); err != nil {
+				return err
+			}
+		}
+	}
+	n, err := d.length(arg)
+	if err != nil {
+		return err
+	}
+	for i := 0; i < n; i++ {
+		if err := fn(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// value decodes the next data item.
+func (d *cborDecoder) value() (interface{}, error) {
+	start := d.off
+	major, arg, err := d.head()
+	if err != nil {
+		return nil, err
+	}
+	switch major {
+	case 0:
+		if arg > math.MaxInt64 {
+			return nil, fmt.Errorf("cbor: integer %d at offset %d overflows int64", arg, start)
+		}
+		return int64(arg), nil
+	case 1:
+		if arg > math.MaxInt64 {
+			return nil, fmt.Errorf("cbor: integer -1-%d at offset %d overflows int64", arg, start)
+		}
+		return -1 - int64(arg), nil
+	case 2:
+		return d.str(major, arg)
+	case 3:
+		b, err := d.str(major, arg)
+		return string(b), err
+	case 4:
+		s := make([]interface{}, 0)
+		err := d.elements(arg, func() error {
+			v, err := d.value()
+			s = append(s, v)
+			return err
+		})
+		return s, err
+	case 5:
+		m := make(map[string]interface{})
+		err := d.elements(arg, func() error {
+			start := d.off
+			k, err := d.value()
+			if err != nil {
+				return err
+			}
+			key, ok := scalarKey(k)
+			if !ok {
+				return fmt.Errorf("cbor: invalid map key at offset %d", start)
+			}
+			m[key], err = d.value()
+			return err
+		})
+		return m, err
+	case 6:
+		v, err := d.value()
+		if err != nil {
+			return nil, err
+		}
+		return d.tag(start, arg, v)
+	}
+
+	switch arg {
+	case 20, 21:
+		return arg == 21, nil
+	case 22, 23:
+		return nil, nil
+	case cborIndefinite:
+		return nil, fmt.Errorf("cbor: unexpected break at offset %d", start)
+	}
+	switch d.off - start {
+	case 3:
+		return halfFloat(uint16(arg)), nil
+	case 5:
+		return float64(math.Float32frombits(uint32(arg))), nil
+	case 9:
+		return math.Float64frombits(arg), nil
+	}
+	return nil, fmt.Errorf("cbor: unsupported simple value %d at offset %d", arg, start)
+}
+
+// str decodes the content of a byte or text string of the major type.
+func (d *cborDecoder) str(major byte, arg uint64) ([]byte, error) {
+	if arg != cborIndefinite {
+		n, err := d.length(arg)
+		if err != nil {
+			return nil, err
+		}
+		b, err := d.next(n)
+		return append([]byte(nil), b...), err
+	}
+	b := make([]byte, 0)
+	err := d.elements(arg, func() error {
+		// the chunks are strings of the same major type and definite length.
+		start := d.off
+		m, arg, err := d.head()
+		if err != nil {
+			return err
+		}
+		if m != major || arg == cborIndefinite {
+			return fmt.Errorf("cbor: invalid chunk at offset %d", start)
+		}
+		chunk, err := d.str(m, arg)
+		b = append(b, chunk...)
+		return err
+	})
+	return b, err
+}
+
+// tag returns the content of the tag number, converting date-times.
+func (d *cborDecoder) tag(start int, num uint64, v interface{}) (interface{}, error) {
+	switch num {
+	case 0:
+		if s, ok := v.(string); ok {
+			if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+				return t, nil
+			}
+		}
+	case 1:
+		switch v := v.(type) {
+		case int64:
+			return time.Unix(v, 0).UTC(), nil
+		case float64:
+			sec, frac := math.Modf(v)
+			return time.Unix(int64(sec), int64(frac*1e9)).UTC(), nil
+		}
+	default:
+		return v, nil
+	}
+	return nil, fmt.Errorf("cbor: invalid date-time at offset %d", start)
+}
+
+// halfFloat returns the IEEE 754 half-precision float of the bits as a float64.
+func halfFloat(h uint16) float64 {
+	exp, frac := int(h>>10&0x1f), float64(h&0x3ff)
+	var f float64
+	switch exp {
+	case 0:
+		f = math.Ldexp(frac, -24)
+	case 31:
+		f = math.Inf(1)
+		if frac != 0 {
+			f = math.NaN()
+		}
+	default:
+		f = math.Ldexp(frac+1024, exp-25)
+	}
+	if h&0x8000 != 0 {
+		f = -f
+	}
+	return f
+}
@@ -0,0 +1,103 @@
+package cconf
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadCBOR(t *testing.T) {
+	c := New()
+	if err := c.Load("./testdata/app.json", "./testdata/app.cbor"); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, "cconf-cbor", c.GetString("name"))
+	equal(t, 1.5, c.Get("ratio"))
+	equal(t, "syyong.x", c.GetString("ext.author"))
+	equal(t, "cbor@example.com", c.GetString("ext.email"))
+	equal(t, "dev-1", c.GetString("devices.0.id"))
+	equal(t, int64(443), c.Get("devices.0.ports.1"))
+	equal(t, "b", c.GetString("devices.1.labels.zone"))
+	equal(t, []byte{1, 2}, c.Get("devices.0.serial"))
+	equal(t, int64(-10), c.Get("devices.1.offset"))
+	equal(t, "second", c.GetString("slots.2"))
+	equal(t, "byte-string key", c.GetString("raw"))
+	equal(t, time.Unix(1700000000, 0).UTC(), c.Get("provisioned"))
+
+	var s struct {
+		Devices []struct {
+			ID      string            `cconf:"id"`
+			Ports   []int             `cconf:"ports"`
+			Labels  map[string]string `cconf:"labels"`
+			Serial  []byte            `cconf:"serial"`
+			Offset  int               `cconf:"offset"`
+			Enabled bool              `cconf:"enabled"`
+		} `cconf:"devices"`
+		Slots       map[string]string `cconf:"slots"`
+		Provisioned time.Time         `cconf:"provisioned"`
+	}
+	for key, v := range map[string]interface{}{"devices": &s.Devices, "slots": &s.Slots, "provisioned": &s.Provisioned} {
+		if err := c.Populate(v, key); err != nil {
+			t.Fatal(err)
+		}
+	}
+	equal(t, 2, len(s.Devices))
+	equal(t, []int{80, 443}, s.Devices[0].Ports)
+	equal(t, []int{8080}, s.Devices[1].Ports)
+	equal(t, map[string]string{"zone": "a", "rack": "r1"}, s.Devices[0].Labels)
+	equal(t, []byte{1, 2}, s.Devices[0].Serial)
+	equal(t, -10, s.Devices[1].Offset)
+	equal(t, true, s.Devices[0].Enabled)
+	equal(t, map[string]string{"1": "first", "2": "second"}, s.Slots)
+	equal(t, 2023, s.Provisioned.Year())
+
+	if err := c.LoadReader("cbor", strings.NewReader("\xa1\x64name\x61x")); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, "x", c.GetString("name"))
+}
+
+func TestDecodeCBOR(t *testing.T) {
+	for doc, want := range map[string]interface{}{
+		"\x3b\x7f\xff\xff\xff\xff\xff\xff\xff":     int64(math.MinInt64),
+		"\x5f\x42\x01\x02\x41\x03\xff":             []byte{1, 2, 3},
+		"\x7f\x62ab\x61c\xff":                      "abc",
+		"\xbf\x01\x80\xf4\xf7\xff":                 map[string]interface{}{"1": []interface{}{}, "false": nil},
+		"\xfa\x3f\xc0\x00\x00":                     1.5,
+		"\xf9\xfc\x00":                             math.Inf(-1),
+		"\xc0\x74" + "2013-03-21T20:04:00Z":        time.Date(2013, 3, 21, 20, 4, 0, 0, time.UTC),
+		"\xc1\xfb\x41\xd4\x52\xd9\xec\x20\x00\x00": time.Unix(1363896240, 5e8).UTC(),
+		"\xc2\x41\x01":                             []byte{1},
+	} {
+		var v interface{}
+		if err := decodeCBOR(strings.NewReader(doc), &v); err != nil {
+			t.Errorf("%q: %v", doc, err)
+			continue
+		}
+		equal(t, want, v)
+	}
+
+	for doc, want := range map[string]string{
+		"":                     "cbor: unexpected EOF at offset 0",
+		"\x9f\x01":             "cbor: unexpected EOF at offset 2",
+		"\x7a\xff\xff\xff\xff": "cbor: unexpected EOF at offset 5",
+		"\x01\x02":             "cbor: unexpected data after the top-level item at offset 1",
+		"\x1c":                 "cbor: invalid byte 0x1c at offset 0",
+		"\xff":                 "cbor: unexpected break at offset 0",
+		"\xf0":                 "cbor: unsupported simple value 16 at offset 0",
+		"\x1b" + string(bytes.Repeat([]byte{0xff}, 8)): "cbor: integer 18446744073709551615 at offset 0 overflows int64",
+		"\xa1\x80\x01":  "cbor: invalid map key at offset 1",
+		"\x5f\x61a\xff": "cbor: invalid chunk at offset 1",
+		"\xc1\x61a":     "cbor: invalid date-time at offset 0",
+	} {
+		var v interface{}
+		err := decodeCBOR(strings.NewReader(doc), &v)
+		if err == nil {
+			t.Errorf("%q: expected an error", doc)
+			continue
+		}
+		equal(t, want, err.Error())
+	}
+}
@@ -0,0 +1,112 @@
+package cconf
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// changeHandler is a function registered by OnChange.
+type changeHandler struct {
+	key string
+	fn  func(old, new interface{})
+}
+
+// OnChange registers a function that is called with the old and the new value of the key
+// whenever a change of the store, like a Set or a reload, changes that value, as compared by
+// reflect.DeepEqual. A missing value is nil. A key ending with the separator, like "db.",
+// subscribes to everything below it: the function is called with the old and the new value
+// of "db" when anything underneath changes.
+//
+// The functions are called in registration order, after the change has been applied and
+// outside the locks of the Conf, so they may call any method of the Conf. A panic of a
+// function is recovered and reported by an EventPanic (see OnEvent).
+func (c *Conf) OnChange(key string, fn func(old, new interface{})) {
+	c.onChange(key, fn)
+}
+
+// onChange registers the OnChange function and returns a function removing it.
+func (c *Conf) onChange(key string, fn func(old, new interface{})) func() {
+	c.mu.Lock()
+	defer c.unlock()
+	h := &changeHandler{key: strings.TrimSuffix(key, c.Separator), fn: fn}
+	var handlers []*changeHandler
+	if p := c.changeHandlers.Load(); p != nil {
+		handlers = append(handlers, *p...)
+	}
+	handlers = append(handlers, h)
+	c.changeHandlers.Store(&handlers)
+
+	return func() {
+		c.mu.Lock()
+		defer c.unlock()
+		var handlers []*changeHandler
+		if p := c.changeHandlers.Load(); p != nil {
+			for _, o := range *p {
+				if o != h {
+					handlers = append(handlers, o)
+				}
+			}
+		}
+		if len(handlers) == 0 {
+			c.changeHandlers.Store(nil)
+			return
+		}
+		c.changeHandlers.Store(&handlers)
+	}
+}
+
+// OnReload registers a function that is called with the differences of the store after
+// every reload that changed it (see Watch). The functions are called in registration order,
+// after the new store has been swapped in and before the OnChange functions, outside the
+// locks of the Conf. A panic of a function is recovered and reported by an EventPanic.
+func (c *Conf) OnReload(fn func(Diff)) {
+	c.mu.Lock()
+	defer c.unlock()
+	var handlers []func(Diff)
+	if p := c.reloadHandlers.Load(); p != nil {
+		handlers = append(handlers, *p...)
+	}
+	handlers = append(handlers, fn)
+	c.reloadHandlers.Store(&handlers)
+}
+
+// notifyReload calls the OnReload functions with the differences between the stores.
+func (c *Conf) notifyReload(old, new interface{}) {
+	d := diff(old, new, c.Separator)
+	if d.Empty() {
+		return
+	}
+	for _, fn := range *c.reloadHandlers.Load() {
+		c.protect("", "OnReload function", func() { fn(d) })
+	}
+}
+
+// notifyChanges calls the OnChange functions whose values differ between the stores.
+func (c *Conf) notifyChanges(old, new interface{}) {
+	p := c.changeHandlers.Load()
+	if p == nil {
+		return
+	}
+	for _, h := range *p {
+		ov, nv := old, new
+		if h.key != "" {
+			ov, _ = walk(old, h.key, c.Separator)
+			nv, _ = walk(new, h.key, c.Separator)
+		}
+		if !reflect.DeepEqual(ov, nv) {
+			fn := h.fn
+			c.protect(h.key, fmt.Sprintf("OnChange function of %q", h.key), func() { fn(ov, nv) })
+		}
+	}
+}
+
+// protect calls fn, reporting a panic as an EventPanic of the key.
+func (c *Conf) protect(key, name string, fn func()) {
+	defer func() {
+		if r := recover(); r != nil {
+			c.fire([]Event{{Type: EventPanic, Key: key, Err: fmt.Errorf("%s panicked: %v", name, r)}})
+		}
+	}()
+	fn()
+}
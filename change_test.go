@@ -0,0 +1,126 @@
+package cconf
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestOnChange(t *testing.T) {
+	c := New()
+	if err := c.Load("./testdata/app.json"); err != nil {
+		t.Fatal(err)
+	}
+	type change struct{ old, new interface{} }
+	var level, db []change
+	c.OnChange("log.level", func(old, new interface{}) {
+		// functions may use the Conf.
+		c.Get("log.level")
+		level = append(level, change{old, new})
+	})
+	c.OnChange("db.", func(old, new interface{}) {
+		db = append(db, change{old, new})
+	})
+
+	// changed
+	if err := c.Set("log.level", "debug"); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, []change{{nil, "debug"}}, level)
+	equal(t, 0, len(db))
+
+	// unchanged
+	if err := c.Set("log.level", "debug"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Set("name", "other"); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, 1, len(level))
+
+	// prefix
+	if err := c.Set("db.pool.size", 10); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, 1, len(db))
+	equal(t, nil, db[0].old)
+	equal(t, map[string]interface{}{"pool": map[string]interface{}{"size": 10}}, db[0].new)
+	if err := c.Set("db.pool.size", 20); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, 2, len(db))
+	equal(t, db[0].new, db[1].old)
+
+	// reloads and loads
+	c.SetStore(nil)
+	equal(t, []change{{nil, "debug"}, {"debug", nil}}, level)
+	equal(t, 3, len(db))
+}
+
+func TestOnChangePanic(t *testing.T) {
+	c := New()
+	var events []Event
+	c.OnEvent(func(e Event) {
+		if e.Type == EventPanic {
+			events = append(events, e)
+		}
+	})
+	called := false
+	c.OnChange("name", func(old, new interface{}) {
+		panic("boom")
+	})
+	c.OnChange("name", func(old, new interface{}) {
+		called = true
+	})
+	if err := c.Set("name", "app"); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, true, called)
+	equal(t, 1, len(events))
+	equal(t, "name", events[0].Key)
+	equal(t, `OnChange function of "name" panicked: boom`, events[0].Err.Error())
+	equal(t, "app", c.GetString("name"))
+}
+
+func TestOnReload(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "app.json")
+	write := func(data string) {
+		if err := os.WriteFile(file, []byte(data), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(`{"name": "app", "db": {"host": "localhost", "port": 5432}}`)
+	c := New()
+	if err := c.Load(file); err != nil {
+		t.Fatal(err)
+	}
+	var order []string
+	var diffs []Diff
+	c.OnChange("db.host", func(old, new interface{}) {
+		order = append(order, "change")
+	})
+	c.OnReload(func(d Diff) {
+		order = append(order, "reload")
+		diffs = append(diffs, d)
+	})
+	c.OnReload(func(d Diff) {
+		order = append(order, "reload 2")
+	})
+
+	write(`{"name": "app", "db": {"host": "db", "user": "root"}}`)
+	c.Reload()
+	equal(t, []string{"reload", "reload 2", "change"}, order)
+	equal(t, 1, len(diffs))
+	equal(t, []KeyChange{{Key: "db.user", New: "root"}}, diffs[0].Added)
+	equal(t, []KeyChange{{Key: "db.port", Old: 5432.0}}, diffs[0].Removed)
+	equal(t, []KeyChange{{Key: "db.host", Old: "localhost", New: "db"}}, diffs[0].Changed)
+
+	// unchanged or failed reloads and other changes are not reported.
+	c.Reload()
+	write(`{"name": `)
+	c.Reload()
+	if err := c.Set("name", "other"); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, 1, len(diffs))
+}
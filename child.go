@@ -0,0 +1,27 @@
+package cconf
+
+// NewChild returns a Conf layered over c, like the configuration of a tenant over a shared
+// base, holding the optional data like SetStore. Get, Lookup, the typed getters and Populate
+// of the child look up the values of the child first and fall back to the live values of c,
+// so that changes and reloads of c are visible through the child immediately; a map of the
+// child is merged over the map of c at the same key. Set, Load and the other writers of the
+// child change only the child. Children may have children in turn.
+//
+// The child starts with the Separator and the types registered with Register of c, and must
+// keep the Separator of c. GetStore, Walk and the other functions working on the whole store
+// see only the values of the child.
+func (c *Conf) NewChild(data ...interface{}) *Conf {
+	n := New()
+	n.Separator = c.Separator
+	c.typesMu.RLock()
+	for name, v := range c.types {
+		n.types[name] = v
+	}
+	c.typesMu.RUnlock()
+	n.parent = c
+	n.snap.Store(n.newSnapshot(nil))
+	if len(data) > 0 {
+		n.SetStore(data...)
+	}
+	return n
+}
@@ -0,0 +1,70 @@
+package cconf
+
+import (
+	"testing"
+)
+
+func TestNewChild(t *testing.T) {
+	base := NewFromMap(map[string]interface{}{
+		"name": "base",
+		"db":   map[string]interface{}{"host": "db.local", "port": 5432},
+		"http": map[string]interface{}{"port": 80},
+	})
+	tenant := base.NewChild(map[string]interface{}{
+		"name": "acme",
+		"db":   map[string]interface{}{"host": "acme.db.local"},
+	})
+
+	// shadowing and fallback.
+	equal(t, "acme", tenant.GetString("name"))
+	equal(t, "acme.db.local", tenant.GetString("db.host"))
+	equal(t, 5432, tenant.GetInt("db.port"))
+	equal(t, 80, tenant.GetInt("http.port"))
+	equal(t, map[string]interface{}{"host": "acme.db.local", "port": 5432}, tenant.Get("db"))
+	_, ok := tenant.Lookup("missing")
+	equal(t, false, ok)
+
+	var s struct {
+		Name string `cconf:"name"`
+		DB   struct {
+			Host string `cconf:"host"`
+			Port int    `cconf:"port"`
+		} `cconf:"db"`
+		HTTP map[string]int `cconf:"http"`
+	}
+	if err := tenant.Populate(&s); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, "acme", s.Name)
+	equal(t, "acme.db.local", s.DB.Host)
+	equal(t, 5432, s.DB.Port)
+	equal(t, map[string]int{"port": 80}, s.HTTP)
+	if err := tenant.Populate(&s.DB, "db"); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, 5432, s.DB.Port)
+
+	// Set writes only to the child.
+	if err := tenant.Set("http.port", 8080); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, 8080, tenant.GetInt("http.port"))
+	equal(t, 80, base.GetInt("http.port"))
+
+	// changes of the parent are visible immediately, also after cached lookups.
+	equal(t, 5432, tenant.GetInt("db.port"))
+	base.Set("db.port", 6432)
+	base.Set("timeout", 5)
+	equal(t, 6432, tenant.GetInt("db.port"))
+	equal(t, 5, tenant.GetInt("timeout"))
+
+	// a chain of three levels.
+	user := tenant.NewChild(map[string]interface{}{"db": map[string]interface{}{"user": "bob"}})
+	equal(t, "bob", user.GetString("db.user"))
+	equal(t, "acme.db.local", user.GetString("db.host"))
+	equal(t, 6432, user.GetInt("db.port"))
+	equal(t, "acme", user.GetString("name"))
+	base.Set("name", "shared")
+	tenant.Set("name", nil)
+	equal(t, "shared", user.GetString("name"))
+}
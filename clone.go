@@ -0,0 +1,91 @@
+package cconf
+
+import (
+	"reflect"
+)
+
+// Clone returns a copy of the Conf with the same settings, store, overrides, defaults and
+// secrets, which changes independently of c. The functions registered with OnEvent,
+// OnChange, OnReload, BindStruct and RegisterLazy and the background work like Watch are
+// not copied. The sources are copied with the data they loaded last, so that reloading
+// the clone does not load them again.
+func (c *Conf) Clone() *Conf {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n := New()
+	n.Separator = c.Separator
+	n.ExpandEnv = c.ExpandEnv
+	n.UnsetEnv = c.UnsetEnv
+	n.Interpolate = c.Interpolate
+	n.ExecTemplates = c.ExecTemplates
+	n.ProfileTemplate = c.ProfileTemplate
+	n.WatchInterval = c.WatchInterval
+	n.WatchDebounce = c.WatchDebounce
+	n.DockerSecretsPrefix = c.DockerSecretsPrefix
+	n.ValidationMode = c.ValidationMode
+	n.VersionKey = c.VersionKey
+	n.SniffUnknown = c.SniffUnknown
+	for typ, fn := range c.LoadFuncs {
+		n.LoadFuncs[typ] = fn
+	}
+	for typ, fn := range c.DecodeFuncs {
+		n.DecodeFuncs[typ] = fn
+	}
+	for typ, fn := range c.DumpFuncs {
+		n.DumpFuncs[typ] = fn
+	}
+	for typ, fn := range c.EncodeFuncs {
+		n.EncodeFuncs[typ] = fn
+	}
+	for name, fn := range c.templateFuncs {
+		n.templateFuncs[name] = fn
+	}
+	c.typesMu.RLock()
+	for name, v := range c.types {
+		n.types[name] = v
+	}
+	if c.structValidators != nil {
+		n.structValidators = make(map[reflect.Type][]func(v interface{}) error, len(c.structValidators))
+		for t, fns := range c.structValidators {
+			n.structValidators[t] = fns
+		}
+	}
+	c.typesMu.RUnlock()
+	if c.declared != nil {
+		n.declared = make(map[string]reflect.Type, len(c.declared))
+		for k, t := range c.declared {
+			n.declared[k] = t
+		}
+	}
+
+	// the stores and the published settings are never modified, so they are shared.
+	n.indexed, n.cacheSize, n.nocache = c.indexed, c.cacheSize, c.nocache
+	n.env, n.fileSuffix, n.secrets = c.env, c.fileSuffix, c.secrets
+	n.base, n.overrides = c.base, c.overrides
+	n.setOrigins(append([]origin(nil), c.origins...), append([]origin(nil), c.overrideOrigins...))
+	n.expected = c.expected
+	n.aliases = c.aliases
+	n.reloadValidator = c.reloadValidator
+	n.validators = c.validators
+	n.allowedKeys = c.allowedKeys
+	n.migrations = c.migrations
+	n.parent = c.parent
+	n.mergeConflict = c.mergeConflict
+	n.sources = make([]source, len(c.sources))
+	for i, src := range c.sources {
+		if src.fetch != nil {
+			src.data = src.cached
+			src.fetch, src.file, src.remote = nil, "", nil
+		}
+		if src.templates != nil {
+			templates := make(map[string]string, len(src.templates))
+			for k, v := range src.templates {
+				templates[k] = v
+			}
+			src.templates = templates
+		}
+		n.sources[i] = src
+	}
+	n.publish(n.newSnapshot(c.snap.Load().store))
+	return n
+}
@@ -0,0 +1,48 @@
+package cconf
+
+import (
+	"testing"
+)
+
+func TestClone(t *testing.T) {
+	c := New()
+	if err := c.SetDefaults(map[string]interface{}{"debug": false}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Load("./testdata/app.json"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.SetOverride("name", "override"); err != nil {
+		t.Fatal(err)
+	}
+	c.MarkSecret("ext.email")
+	c.EnableIndex()
+
+	clone := c.Clone()
+	equal(t, c.GetStore(), clone.GetStore())
+	equal(t, c.GetStoreCopy(true), clone.GetStoreCopy(true))
+	equal(t, "./testdata/app.json", clone.Source("ext.author"))
+	equal(t, "SetOverride()", clone.Source("name"))
+
+	// the clones change independently.
+	if err := clone.Set("ext.author", "clone"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Set("version", 2); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, "syyong.x", c.GetString("ext.author"))
+	equal(t, "clone", clone.GetString("ext.author"))
+	equal(t, 0.1, clone.GetFloat("version"))
+	equal(t, "override", clone.GetString("name"))
+
+	// reloading the clone keeps the data loaded by c.
+	if err := clone.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, "clone", clone.GetString("ext.author"))
+	equal(t, false, clone.GetBool("debug"))
+	clone.ClearOverrides()
+	equal(t, "cconf", clone.GetString("name"))
+	equal(t, "override", c.GetString("name"))
+}
@@ -0,0 +1,58 @@
+package cconf
+
+import (
+	"context"
+	"errors"
+)
+
+// errClosed is returned when background activity is started on a closed Conf.
+var errClosed = errors.New("the Conf is closed")
+
+// Close stops all background activity of the Conf, like Watch, Poll, WatchRemote and the
+// Subscribe channels, which are closed, and waits for it to stop. Lazy fetches are canceled
+// (see RegisterLazy). Afterwards, Get, Populate and the other readers keep working on the
+// last store, but background activity cannot be started anymore. Closing a closed Conf
+// does nothing.
+func (c *Conf) Close() error {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil
+	}
+	c.closed = true
+	c.cancel()
+	c.mu.Unlock()
+	c.wg.Wait()
+	return nil
+}
+
+// start runs fn in a goroutine with a context that is done when ctx is done or the Conf
+// is closed, and that Close waits for. The caller must hold c.mu.
+func (c *Conf) start(ctx context.Context, fn func(ctx context.Context)) error {
+	ctx, done, err := c.background(ctx)
+	if err != nil {
+		return err
+	}
+	go func() {
+		defer done()
+		fn(ctx)
+	}()
+	return nil
+}
+
+// background returns a context for background activity that is done when ctx is done or
+// the Conf is closed, and a function to call when the activity stopped, which Close waits for.
+// The caller must hold c.mu.
+func (c *Conf) background(ctx context.Context) (context.Context, func(), error) {
+	if c.closed {
+		return nil, nil, errClosed
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	stop := context.AfterFunc(c.ctx, cancel)
+	c.wg.Add(1)
+	return ctx, func() {
+		stop()
+		cancel()
+		c.wg.Done()
+	}, nil
+}
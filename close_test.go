@@ -0,0 +1,57 @@
+package cconf
+
+import (
+	"context"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestClose(t *testing.T) {
+	files := copyTestdata(t, "app.json")
+	before := runtime.NumGoroutine()
+
+	c := New()
+	c.WatchInterval = time.Millisecond
+	if err := c.Load(files...); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Watch(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Watch(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	events, _ := c.Subscribe("")
+	if runtime.NumGoroutine() <= before {
+		t.Fatal("expected background goroutines")
+	}
+
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := <-events; ok {
+		t.Fatal("expected a closed channel")
+	}
+	// the goroutines of the standard library may take a moment to exit.
+	for i := 0; runtime.NumGoroutine() > before; i++ {
+		if i == 100 {
+			t.Fatalf("leaked goroutines: %d before, %d after", before, runtime.NumGoroutine())
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// readers keep working, background activity cannot be started.
+	equal(t, "cconf", c.GetString("name"))
+	if err := c.Watch(context.Background()); err == nil {
+		t.Fatal("expected an error")
+	}
+	events, unsubscribe := c.Subscribe("")
+	if _, ok := <-events; ok {
+		t.Fatal("expected a closed channel")
+	}
+	unsubscribe()
+}
@@ -1,68 +1,374 @@
 package cconf
 
 import (
+	"context"
 	"errors"
-	"fmt"
+	"io"
 	"path/filepath"
 	"reflect"
-	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"text/template"
+	"time"
 )
 
 // load file function
 type loadFunc func(string, interface{}) error
 
+// decode reader function
+type decodeFunc func(io.Reader, interface{}) error
+
+// dump file function
+type dumpFunc func(string, interface{}) error
+
+// encode writer function
+type encodeFunc func(io.Writer, interface{}) error
+
 // DefaultSeparator default separator.
 var DefaultSeparator = "."
 
 // DefaultLoadFuncs default load functions.
-var DefaultLoadFuncs = map[string]loadFunc{"json": loadJSON}
+// New copies them, so changes only affect the Confs created afterwards.
+var DefaultLoadFuncs = map[string]loadFunc{
+	"json":    loadJSON,
+	"jsonc":   loadJSONC,
+	"json5":   loadJSONC,
+	"yaml":    loadYAML,
+	"yml":     loadYAML,
+	"toml":    loadTOML,
+	"ini":     loadINI,
+	"env":     loadEnvFile,
+	"hcl":     loadHCL,
+	"xml":     loadXML,
+	"msgpack": loadMsgpack,
+	"mp":      loadMsgpack,
+	"cbor":    loadCBOR,
+}
+
+// DefaultDecodeFuncs default decode functions used by LoadReader.
+// New copies them, so changes only affect the Confs created afterwards.
+var DefaultDecodeFuncs = map[string]decodeFunc{
+	"json":    decodeJSON,
+	"jsonc":   decodeJSONC,
+	"json5":   decodeJSONC,
+	"yaml":    decodeYAML,
+	"yml":     decodeYAML,
+	"toml":    decodeTOML,
+	"ini":     decodeINI,
+	"env":     decodeEnvFile,
+	"hcl":     decodeHCL,
+	"xml":     decodeXML,
+	"msgpack": decodeMsgpack,
+	"mp":      decodeMsgpack,
+	"cbor":    decodeCBOR,
+}
+
+// DefaultDumpFuncs default dump functions used by Save.
+// New copies them, so changes only affect the Confs created afterwards.
+var DefaultDumpFuncs = map[string]dumpFunc{
+	"json": dumpJSON,
+	"yaml": dumpYAML,
+	"yml":  dumpYAML,
+	"toml": dumpTOML,
+}
+
+// DefaultEncodeFuncs default encode functions used by WriteTo, and by Save for types without
+// a dump function.
+// New copies them, so changes only affect the Confs created afterwards.
+var DefaultEncodeFuncs = map[string]encodeFunc{
+	"json":       encodeJSON,
+	"yaml":       encodeYAML,
+	"yml":        encodeYAML,
+	"toml":       encodeTOML,
+	"properties": encodeProperties,
+	"env":        encodeEnv,
+}
 
 // Conf conf
 type Conf struct {
-	Separator string
-	LoadFuncs map[string]loadFunc
-	types     map[string]reflect.Value
-	store     reflect.Value
-	cache     map[string]interface{}
+	Separator   string
+	LoadFuncs   map[string]loadFunc
+	DecodeFuncs map[string]decodeFunc
+	DumpFuncs   map[string]dumpFunc
+	EncodeFuncs map[string]encodeFunc
+	// ExpandEnv enables the expansion of ${VAR} and $VAR references to environment variables
+	// in the string values of loaded data; $$ stands for a literal $.
+	// ${VAR:-default} expands to the default if VAR is unset or empty, like in the shell.
+	ExpandEnv bool
+	// UnsetEnv tells how references to unset variables are expanded.
+	UnsetEnv UnsetPolicy
+	// Interpolate enables the resolution of ${key} references to other configuration values
+	// in the string values of loaded data. References are resolved after the loaded data is
+	// merged, so that a key names the value of the last file setting it, or else of the store;
+	// later loads and reloads resolve them again. Values passed to Set are kept as is.
+	// References are resolved transitively and reference cycles fail the load.
+	// As with ExpandEnv, $$ escapes a $, so $${key} is loaded as the literal ${key}.
+	Interpolate bool
+	// ExecTemplates enables the execution of string values of loaded data containing "{{"
+	// as text/template templates, like "{{hostname}}" or "{{now \"2006-01-02\"}}", after
+	// references are resolved. See RegisterTemplateFunc for the available functions.
+	ExecTemplates bool
+	// ProfileTemplate is the naming scheme of the profile overlays of LoadProfile.
+	ProfileTemplate string
+	// WatchInterval is the interval at which Watch polls the loaded files.
+	WatchInterval time.Duration
+	// WatchDebounce is the time Watch waits for a burst of changes to settle before reloading.
+	WatchDebounce time.Duration
+	// DockerSecretsPrefix is the key under which LoadDockerSecrets loads the secrets.
+	DockerSecretsPrefix string
+	// ValidationMode tells whether the validations, like Validate and Populate, stop at the
+	// first problem (FailFast, the default) or report every one (CollectAll).
+	ValidationMode ValidationMode
+	// VersionKey is the key of the version of configuration files, see RegisterMigration.
+	VersionKey string
+	// SniffUnknown enables loading files of an unregistered type, like "app.conf", by
+	// detecting their type from their contents: the registered load functions of the
+	// candidate types are tried in order, failing with the errors of all of them.
+	SniffUnknown       bool
+	types              map[string]reflect.Value
+	typesMu            sync.RWMutex                                 // guards types and structValidators
+	structValidators   map[reflect.Type][]func(v interface{}) error // see AddStructValidator
+	mu                 sync.Mutex                                   // serializes writers
+	snap               atomic.Pointer[snapshot]
+	indexed            bool // see EnableIndex
+	cacheSize          int  // see SetCacheSize
+	nocache            bool // see DisableCache
+	stats              cacheStats
+	loads              []LoadStat
+	handlers           atomic.Pointer[[]func(Event)]
+	pending            []Event // events to fire when mu is released
+	changeHandlers     atomic.Pointer[[]*changeHandler]
+	reloadHandlers     atomic.Pointer[[]func(Diff)]
+	prev               interface{} // the store before the changes made while mu is held
+	changed            bool        // whether prev is set
+	reloaded           bool        // whether the sources were reloaded while mu is held
+	flight             flightGroup
+	declared           map[string]reflect.Type // see DeclareTypes
+	expected           map[string]*typeExpr    // see ExpectTypes
+	env                *envConfig
+	base               interface{}                   // the store without the override layer
+	sources            []source                      // see reload
+	origins            []origin                      // the origins of the values of base, see Sources
+	overrideOrigins    []origin                      // the origins of the overrides
+	envKeys            map[string]bool               // see setOrigins, never modified
+	reloadValidator    func(*Conf) error             // see SetReloadValidator
+	aliases            map[string]string             // the new keys by deprecated key, see Alias
+	deprecated         map[string]bool               // the deprecated keys found, see OnDeprecated
+	deprecations       [][2]string                   // the deprecated keys to notify when mu is released
+	deprecatedHandlers []func(oldKey, newKey string) // see OnDeprecated
+	validators         []keyValidator                // see AddValidator
+	allowedKeys        []string                      // see SetAllowedKeys
+	mergeConflict      mergeFunc                     // see SetMergeConflictFunc
+	parent             *Conf                         // see NewChild
+	migrations         map[int]migration             // see RegisterMigration
+	lazy               []*lazyProvider               // see RegisterLazy
+	ctx                context.Context               // canceled by Close
+	cancel             context.CancelFunc
+	wg                 sync.WaitGroup // background goroutines, see start
+	closed             bool
+	frozen             bool              // see Freeze
+	digests            map[string]string // see Stats
+	overrides          interface{}       // see setOverrides
+	fileSuffix         string            // see SetFileSuffix
+	secrets            []string          // see MarkSecret
+	templateFuncs      template.FuncMap  // see RegisterTemplateFunc
 }
 
 // New returns an instance of the Conf.
 func New() *Conf {
-	return &Conf{
-		Separator: DefaultSeparator,
-		LoadFuncs: DefaultLoadFuncs,
-		types:     make(map[string]reflect.Value),
-		cache:     make(map[string]interface{}),
+	loadFuncs := make(map[string]loadFunc, len(DefaultLoadFuncs))
+	for typ, fn := range DefaultLoadFuncs {
+		loadFuncs[typ] = fn
+	}
+	decodeFuncs := make(map[string]decodeFunc, len(DefaultDecodeFuncs))
+	for typ, fn := range DefaultDecodeFuncs {
+		decodeFuncs[typ] = fn
+	}
+	dumpFuncs := make(map[string]dumpFunc, len(DefaultDumpFuncs))
+	for typ, fn := range DefaultDumpFuncs {
+		dumpFuncs[typ] = fn
+	}
+	encodeFuncs := make(map[string]encodeFunc, len(DefaultEncodeFuncs))
+	for typ, fn := range DefaultEncodeFuncs {
+		encodeFuncs[typ] = fn
 	}
+	templateFuncs := make(template.FuncMap, len(DefaultTemplateFuncs))
+	for name, fn := range DefaultTemplateFuncs {
+		templateFuncs[name] = fn
+	}
+	c := &Conf{
+		Separator:           DefaultSeparator,
+		LoadFuncs:           loadFuncs,
+		DecodeFuncs:         decodeFuncs,
+		DumpFuncs:           dumpFuncs,
+		EncodeFuncs:         encodeFuncs,
+		types:               make(map[string]reflect.Value),
+		fileSuffix:          DefaultFileSuffix,
+		templateFuncs:       templateFuncs,
+		ProfileTemplate:     DefaultProfileTemplate,
+		DockerSecretsPrefix: DefaultDockerSecretsPrefix,
+		VersionKey:          DefaultVersionKey,
+		WatchInterval:       DefaultWatchInterval,
+		WatchDebounce:       DefaultWatchDebounce,
+	}
+	c.ctx, c.cancel = context.WithCancel(context.Background())
+	c.snap.Store(c.newSnapshot(nil))
+	return c
 }
 
 // RegisterLoadFunc register load function.
 // like:
-// RegisterLoadFunc("toml", loadTOML)
-// RegisterLoadFunc("yaml", loadYAML)
+// RegisterLoadFunc("cue", loadCUE)
 func (c *Conf) RegisterLoadFunc(typ string, fn loadFunc) {
+	c.mu.Lock()
+	defer c.unlock()
 	c.LoadFuncs[typ] = fn
 }
 
+// RegisterDecodeFunc register the function decoding data of the type from a reader.
+func (c *Conf) RegisterDecodeFunc(typ string, fn decodeFunc) {
+	c.mu.Lock()
+	defer c.unlock()
+	c.DecodeFuncs[typ] = fn
+}
+
 // Load loads configuration data from one or multiple files.
+// The files are merged into the store in order. Load is atomic: if any file fails to load,
+// the store and the cache are left unchanged.
+// The type of a file is its extension, unless the name ends with "#" and a type, like
+// "config.rendered#json", which is parsed as JSON.
 func (c *Conf) Load(files ...string) error {
-	defer func() {
-		// Reset cache.
-		c.cache = make(map[string]interface{})
-	}()
+	c.mu.Lock()
+	defer c.unlock()
+	return c.loadFiles(context.Background(), "", "", files)
+}
+
+// LoadAs loads configuration data from one or multiple files like Load, but parses them as
+// the type (e.g. "json") whatever their names.
+func (c *Conf) LoadAs(typ string, files ...string) error {
+	c.mu.Lock()
+	defer c.unlock()
+	return c.loadFiles(context.Background(), "", typ, files)
+}
+
+// LoadContext is like Load, but stops when ctx is done, between files, returning its error
+// and leaving the store unchanged.
+func (c *Conf) LoadContext(ctx context.Context, files ...string) error {
+	c.mu.Lock()
+	defer c.unlock()
+	return c.loadFiles(ctx, "", "", files)
+}
+
+// LoadInto loads configuration data from one or multiple files like Load, but merges it
+// below the key instead of the root of the store, so that the file "db.json" holding
+// {"host": "localhost"} loaded into the key "db" sets "db.host".
+func (c *Conf) LoadInto(key string, files ...string) error {
+	c.mu.Lock()
+	defer c.unlock()
+	return c.loadFiles(context.Background(), key, "", files)
+}
+
+// loadFiles loads the files of the type below the key, or into the root for an empty key.
+// Without a type, the type of each file is detected by fileType. The caller must hold c.mu.
+func (c *Conf) loadFiles(ctx context.Context, key, typ string, files []string) error {
+	srcs := make([]source, 0, len(files))
 	for _, file := range files {
-		typ := strings.TrimLeft(filepath.Ext(file), ".")
-		if fn, ok := c.LoadFuncs[typ]; ok {
+		file, typ := file, typ
+		explicit := typ != ""
+		if !explicit {
+			file, typ, explicit = fileType(file)
+		}
+		fn, ok := c.LoadFuncs[typ]
+		if !ok && !explicit && c.SniffUnknown {
+			fn, ok = c.sniffLoader(), true
+		}
+		if !ok {
+			return &wrapError{"please register " + typ + " type loading function to load " + file, ErrUnknownType}
+		}
+		srcs = append(srcs, source{name: file, file: file, key: key, fetch: func() (interface{}, error) {
 			var data interface{}
-			if err := fn(file, &data); err != nil {
-				return err
-			}
-			c.store = merge(c.store, reflect.ValueOf(data))
-		} else {
-			return errors.New("please register " + typ + " type loading function")
+			err := fn(file, &data)
+			return data, err
+		}})
+	}
+	return c.loadSourcesContext(ctx, srcs...)
+}
+
+// fileType splits the type override off the file, like "config.rendered#json", or returns
+// the extension of the file as its type otherwise, and reports whether it was overridden.
+func fileType(file string) (name, typ string, explicit bool) {
+	if i := strings.LastIndexByte(file, '#'); i >= 0 && i < len(file)-1 && !strings.ContainsAny(file[i+1:], `./\`) {
+		return file[:i], file[i+1:], true
+	}
+	return file, strings.TrimLeft(filepath.Ext(file), "."), false
+}
+
+// LoadReader loads configuration data of the type (e.g. "json") from the reader
+// and merges it into the store.
+func (c *Conf) LoadReader(typ string, r io.Reader) error {
+	return c.LoadReaderContext(context.Background(), typ, r)
+}
+
+// LoadReaderContext is like LoadReader, but stops reading when ctx is done, returning its
+// error and leaving the store unchanged.
+func (c *Conf) LoadReaderContext(ctx context.Context, typ string, r io.Reader) error {
+	c.mu.Lock()
+	defer c.unlock()
+
+	fn, ok := c.DecodeFuncs[typ]
+	if !ok {
+		return &wrapError{"please register " + typ + " type decoding function", ErrUnknownType}
+	}
+	var data interface{}
+	if err := fn(&contextReader{ctx, r}, &data); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
 		}
+		return err
+	}
+	data, err := c.migrate("reader:"+typ, data)
+	if err != nil {
+		return err
+	}
+	// the reader cannot be read again: reloads resolve the decoded data again.
+	return c.loadSourcesContext(ctx, source{raw: normalize(data), origin: named("reader:" + typ)})
+}
+
+// contextReader is a reader that fails with the error of its context once it is done.
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r *contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
+}
+
+// effective returns the store of the base layer with the override layer merged above it
+// and the aliases applied, see Alias.
+func (c *Conf) effective(base interface{}) interface{} {
+	if c.overrides != nil {
+		base = merge(base, c.overrides)
+	}
+	return c.applyAliases(base)
+}
+
+// commit sets the base layer, merges the override layer into the new store, converts the
+// declared types and publishes it. The caller must hold c.mu.
+func (c *Conf) commit(base interface{}) error {
+	if err := c.errFrozen(); err != nil {
+		return err
+	}
+	store, err := c.applyTypes(c.effective(base), "")
+	if err != nil {
+		return err
 	}
+	c.base = base
+	c.reset(store)
 	return nil
 }
 
@@ -79,142 +385,202 @@ func (c *Conf) LoadWithPattern(pattern string) error {
 }
 
 // Set sets the configuration value at the specified path.
+// Overridden keys (see SetOverride) keep their override values. The value is set again
+// whenever the sources are reloaded (see Reload), until it is dropped by ClearOverride.
 func (c *Conf) Set(key string, val interface{}) error {
-	if !c.store.IsValid() {
-		c.store = reflect.ValueOf(make(map[string]interface{}))
-		c.cache = make(map[string]interface{})
-	}
-	defer delete(c.cache, key)
+	c.mu.Lock()
+	defer c.unlock()
+	return c.setValue(key, val)
+}
 
-	store := c.store
+// setValue sets the value at the key, see Set. The caller must hold c.mu.
+func (c *Conf) setValue(key string, val interface{}) error {
+	key = c.resolveAlias(key)
+	if c.frozen {
+		return &ConfigKeyError{Key: key, Message: "the configuration is frozen", Err: ErrFrozen}
+	}
 	segs := strings.Split(key, c.Separator)
-	length := len(segs)
-	for i := 0; i < length; i++ {
-		switch store.Kind() {
-		case reflect.Map, reflect.Slice, reflect.Array:
-		default:
-			return &ConfigKeyError{strings.Join(segs[:i+1], c.Separator), fmt.Sprintf("got %v instead of a map, array, or slice", store.Kind())}
-		}
-
-		if i == length-1 {
-			if err := setElement(store, segs[i], val); err != nil {
-				return &ConfigKeyError{key, err.Error()}
-			}
-			return nil
+	val = normalize(val)
+	if c.overrides != nil || len(c.aliases) > 0 {
+		// rebuild the store, so that the override layer stays on top and the aliases apply.
+		base := c.base
+		if base == nil {
+			base = make(map[string]interface{})
 		}
-
-		e := getElement(store, segs[i])
-		if e.IsValid() {
-			store = e
-			continue
+		base, err := c.set(base, segs, 0, val)
+		if err != nil {
+			return err
 		}
-
-		newMap := make(map[string]interface{})
-		if err := setElement(store, segs[i], newMap); err != nil {
-			return &ConfigKeyError{strings.Join(segs[:i+1], c.Separator), err.Error()}
+		// track the value first, so that the new snapshot knows it is set, see lookupLayers.
+		srcs, origins := c.sources, c.origins
+		c.trackSet(key, val)
+		if err := c.commit(base); err != nil {
+			c.sources = srcs
+			c.setOrigins(origins, c.overrideOrigins)
+			return err
 		}
-
-		store = reflect.ValueOf(newMap)
+		c.queue(Event{Type: EventSet, Key: key})
+		return nil
 	}
 
+	old := c.snap.Load()
+	store := old.store
+	if store == nil {
+		store = make(map[string]interface{})
+	}
+	store, err := c.set(store, segs, 0, val)
+	if err != nil {
+		return err
+	}
+	if store, err = c.applyTypes(store, key); err != nil {
+		return err
+	}
+	c.base = store
+	c.trackSet(key, val)
+	s := c.newSnapshot(store)
+	s.inherit(old, key, c.Separator)
+	if old.index != nil {
+		s.reindex(old, key, c.Separator)
+	}
+	c.publish(s)
+	c.queue(Event{Type: EventSet, Key: key})
 	return nil
 }
 
-// Get config
-func (c *Conf) Get(key string, def ...interface{}) interface{} {
-	var v interface{}
-	if len(def) > 0 {
-		v = def[0]
-	}
-	// take priority from the cache.
-	if cv, ok := c.cache[key]; ok {
-		if cv == nil {
-			return v
+// set returns a copy of data with the value at the path segs[i:] set to val.
+// Missing containers on the path are created as maps.
+func (c *Conf) set(data interface{}, segs []string, i int, val interface{}) (interface{}, error) {
+	if i == len(segs)-1 {
+		d, err := setElement(data, segs[i], val)
+		if err != nil {
+			return nil, &ConfigKeyError{Key: strings.Join(segs, c.Separator), Message: err.Error(), Err: errors.Unwrap(err), Segment: i}
 		}
-		return cv
+		return d, nil
 	}
-	store := c.store
-	segs := strings.Split(key, c.Separator)
-	length := len(segs)
-	for i := 0; i < length-1; i++ {
-		if store = getElement(store, segs[i]); !store.IsValid() {
-			c.cache[key] = nil
-			return v
-		}
+
+	e, ok := getElement(data, segs[i])
+	if !ok {
+		e = make(map[string]interface{})
 	}
-	val := getElement(store, segs[length-1])
-	if !val.IsValid() {
-		c.cache[key] = nil
-		return v
+	e, err := c.set(e, segs, i+1, val)
+	if err != nil {
+		return nil, err
+	}
+	d, err := setElement(data, segs[i], e)
+	if err != nil {
+		return nil, &ConfigKeyError{Key: strings.Join(segs[:i+1], c.Separator), Message: err.Error(), Err: errors.Unwrap(err), Segment: i}
+	}
+	return d, nil
+}
+
+// Get config
+func (c *Conf) Get(key string, def ...interface{}) interface{} {
+	if len(def) == 0 {
+		val, _ := c.snap.Load().lookup(key, c.Separator)
+		return val
 	}
+	return c.snap.Load().get(key, c.Separator, def[0])
+}
+
+// Lookup returns the value at the key like Get, and whether the key has a value.
+// Like everywhere in the store, an explicit null counts as no value.
+func (c *Conf) Lookup(key string) (interface{}, bool) {
+	return c.snap.Load().lookup(key, c.Separator)
+}
 
-	// convert the value to the same type as the default value.
-	if tv := reflect.ValueOf(v); tv.IsValid() {
-		if val.Type().ConvertibleTo(tv.Type()) {
-			c.cache[key] = val.Convert(tv.Type()).Interface()
-			return c.cache[key]
+// GetMany returns the values at the specified paths, all resolved against the same version
+// of the store. Missing keys have no entry in the result.
+func (c *Conf) GetMany(keys []string) map[string]interface{} {
+	s := c.snap.Load()
+	vals := make(map[string]interface{}, len(keys))
+	for _, key := range keys {
+		if v, ok := s.lookup(key, c.Separator); ok {
+			vals[key] = v
 		}
-		// unable to convert: return the default value.
-		c.cache[key] = nil
-		return v
 	}
+	return vals
+}
 
-	c.cache[key] = val.Interface()
-	return c.cache[key]
+// GetManyWithDefaults is like GetMany, but takes the keys with their default values:
+// every value is converted to the type of its default as with Get, and missing keys get their default.
+func (c *Conf) GetManyWithDefaults(defs map[string]interface{}) map[string]interface{} {
+	s := c.snap.Load()
+	vals := make(map[string]interface{}, len(defs))
+	for key, def := range defs {
+		vals[key] = s.get(key, c.Separator, def)
+	}
+	return vals
 }
 
 // GetString returns a string.
 func (c *Conf) GetString(key string, def ...string) string {
-	var v string
+	if val, ok := c.snap.Load().lookup(key, c.Separator); ok {
+		if v, ok := toString(val); ok {
+			return v
+		}
+	}
 	if len(def) > 0 {
-		v = def[0]
+		return def[0]
 	}
-	return c.Get(key, v).(string)
+	return ""
 }
 
 // GetInt returns an int
 func (c *Conf) GetInt(key string, def ...int) int {
-	var v int
+	if val, ok := c.snap.Load().lookupAs(key, c.Separator, intType); ok {
+		if v, ok := toInt(val); ok {
+			return v
+		}
+	}
 	if len(def) > 0 {
-		v = def[0]
+		return def[0]
 	}
-	return c.Get(key, v).(int)
+	return 0
 }
 
 // GetInt64 returns an int64
 func (c *Conf) GetInt64(key string, def ...int64) int64 {
-	var v int64
+	if val, ok := c.snap.Load().lookupAs(key, c.Separator, int64Type); ok {
+		if v, ok := toInt64(val); ok {
+			return v
+		}
+	}
 	if len(def) > 0 {
-		v = def[0]
+		return def[0]
 	}
-	return c.Get(key, v).(int64)
+	return 0
 }
 
 // GetFloat returns an float
 func (c *Conf) GetFloat(key string, def ...float64) float64 {
-	var v float64
+	if val, ok := c.snap.Load().lookupAs(key, c.Separator, float64Type); ok {
+		if v, ok := toFloat(val); ok {
+			return v
+		}
+	}
 	if len(def) > 0 {
-		v = def[0]
+		return def[0]
 	}
-	return c.Get(key, v).(float64)
+	return 0
 }
 
 // GetBool returns a bool
 func (c *Conf) GetBool(key string, def ...bool) bool {
-	var v bool
+	if val, ok := c.snap.Load().lookupAs(key, c.Separator, boolType); ok {
+		if v, ok := toBool(val); ok {
+			return v
+		}
+	}
 	if len(def) > 0 {
-		v = def[0]
+		return def[0]
 	}
-	return c.Get(key, v).(bool)
+	return false
 }
 
 // GetStore returns the complete configuration store.
 // Nil will be returned if the configuration has never been loaded before.
 func (c *Conf) GetStore() interface{} {
-	if c.store.IsValid() {
-		return c.store.Interface()
-	}
-	return nil
+	return c.snap.Load().store
 }
 
 // SetStore sets the configuration data.
@@ -225,85 +591,34 @@ func (c *Conf) GetStore() interface{} {
 // B). Otherwise, add all key-value pairs of C2 to C1; If a key of C2 is also found in C1,
 // merge the corresponding values in C1 and C2 recursively.
 //
-// Note that this method will clear any existing configuration data.
+// Note that this method will clear any existing configuration data, except for command-line overrides
+// and the defaults layer (see SetDefaults).
+// Values that cannot be converted to their declared types (see DeclareTypes) are kept as is.
 func (c *Conf) SetStore(data ...interface{}) {
-	c.store = reflect.Value{}
-	for _, d := range data {
-		c.store = merge(c.store, reflect.ValueOf(d))
-	}
-	c.cache = make(map[string]interface{})
-}
-
-// mapIndex
-func mapIndex(data reflect.Value, index reflect.Value) reflect.Value {
-	v := data.MapIndex(index)
-	if v.Kind() == reflect.Interface {
-		v = v.Elem()
-	}
-	return v
-}
-
-// merge
-func merge(v1, v2 reflect.Value) reflect.Value {
-	if v1.Kind() != reflect.Map || v2.Kind() != reflect.Map || !v1.IsValid() {
-		return v2
+	c.mu.Lock()
+	defer c.unlock()
+	if err := c.errFrozen(); err != nil {
+		c.queue(Event{Type: EventLoad, Source: "SetStore()", Err: err})
+		return
 	}
-
-	for _, key := range v2.MapKeys() {
-		e1 := mapIndex(v1, key)
-		e2 := mapIndex(v2, key)
-		if e1.Kind() == reflect.Map && e2.Kind() == reflect.Map {
-			e2 = merge(e1, e2)
-		}
-		v1.SetMapIndex(key, e2)
-	}
-
-	return v1
-}
-
-// getElement returns the element value of a map, array, or slice at the specified index.
-func getElement(v reflect.Value, seg string) reflect.Value {
-	switch v.Kind() {
-	case reflect.Map:
-		return mapIndex(v, reflect.ValueOf(seg))
-	case reflect.Slice, reflect.Array:
-		if i, err := strconv.Atoi(seg); err == nil {
-			if i >= 0 && i < v.Len() {
-				v = v.Index(i)
-				for v.Kind() == reflect.Interface {
-					v = v.Elem()
-				}
-				return v
-			}
+	var stored interface{}
+	for _, d := range data {
+		var err error
+		if stored, err = c.mergeLoaded("", stored, normalize(d)); err != nil {
+			// the store is kept, see SetMergeConflictFunc.
+			c.queue(Event{Type: EventLoad, Source: "SetStore()", Err: err})
+			return
 		}
 	}
-
-	return reflect.Value{}
-}
-
-// setElement ses the element value of a map, array, or slice at the specified index.
-func setElement(data reflect.Value, seg string, v interface{}) error {
-	val := reflect.ValueOf(v)
-
-	switch data.Kind() {
-	case reflect.Map:
-		key := reflect.ValueOf(seg)
-		data.SetMapIndex(key, val)
-	case reflect.Slice, reflect.Array:
-		i, err := strconv.Atoi(seg)
-		if err != nil || i < 0 {
-			return fmt.Errorf("%v is not a valid array or slice index", seg)
-		}
-		if data.Kind() == reflect.Slice {
-			if i >= data.Cap() {
-				return fmt.Errorf("%v is out of the slice index bound", seg)
-			}
-			data.SetLen(i + 1)
-		} else if i >= data.Cap() {
-			return fmt.Errorf("%v is out of the array index bound", seg)
-		}
-		data.Index(i).Set(val)
+	var base interface{}
+	var srcs []source
+	var origins []origin
+	if d := c.defaults(); d != nil {
+		base, srcs, origins = d.data, []source{*d}, c.originsOf("", d.data, d.origin)
 	}
-
-	return nil
+	base = merge(base, stored)
+	c.base = base
+	c.sources = append(srcs, source{data: stored, origin: named("SetStore()")})
+	c.setOrigins(append(origins, c.originsOf("", stored, named("SetStore()"))...), c.overrideOrigins)
+	c.reset(c.applyTypesLenient(c.effective(base)))
 }
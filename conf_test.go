@@ -1,7 +1,13 @@
 package cconf
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
 	"reflect"
+	"sync"
 	"testing"
 )
 
@@ -23,9 +29,634 @@ func TestPopulate(t *testing.T) {
 
 }
 
+type ptrInner struct {
+	Name string
+	Port int
+}
+
+type ptrOuter struct {
+	Inner  **ptrInner
+	Map    map[string]*ptrInner
+	Slice  []*ptrInner
+	Nested map[string]**ptrInner
+	Any    interface{}
+}
+
+func TestPopulatePointers(t *testing.T) {
+	c := New()
+	c.SetStore(map[string]interface{}{
+		"Inner":  map[string]interface{}{"Name": "inner", "Port": 80},
+		"Map":    map[string]interface{}{"a": map[string]interface{}{"Name": "map"}},
+		"Slice":  []interface{}{map[string]interface{}{"Name": "slice"}},
+		"Nested": map[string]interface{}{"b": map[string]interface{}{"Port": 8080}},
+		"Any":    map[string]interface{}{"Name": "any"},
+	})
+
+	var o **ptrOuter
+	if err := c.Populate(&o); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, ptrInner{"inner", 80}, **(*o).Inner)
+	equal(t, ptrInner{"map", 0}, *(*o).Map["a"])
+	equal(t, ptrInner{"slice", 0}, *(*o).Slice[0])
+	equal(t, ptrInner{"", 8080}, **(*o).Nested["b"])
+
+	// a typed nil pointer held by an interface is allocated.
+	p := ptrOuter{Any: (*ptrInner)(nil)}
+	if err := c.Populate(&p); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, &ptrInner{"any", 0}, p.Any)
+}
+
 // Expected to be equal.
 func equal(t *testing.T, expected, actual interface{}) {
 	if !reflect.DeepEqual(expected, actual) {
 		t.Errorf("Expected %v (type %v) - Got %v (type %v)", expected, reflect.TypeOf(expected), actual, reflect.TypeOf(actual))
 	}
 }
+
+type plugin interface {
+	Start() string
+}
+
+type echoPlugin struct {
+	Message string
+}
+
+func (p *echoPlugin) Start() string { return p.Message }
+
+func TestPopulateConcurrentRegister(t *testing.T) {
+	c := New()
+	c.Register("echo", func() *echoPlugin { return &echoPlugin{} })
+	c.SetStore(map[string]interface{}{
+		"Plugin": map[string]interface{}{"type": "echo", "Message": "hello"},
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				var v struct{ Plugin plugin }
+				if err := c.Populate(&v); err != nil {
+					t.Error(err)
+					return
+				}
+				if v.Plugin.Start() != "hello" {
+					t.Errorf("unexpected plugin message %q", v.Plugin.Start())
+					return
+				}
+			}
+		}()
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				c.Register(fmt.Sprintf("late%d-%d", i, j), func() *echoPlugin { return &echoPlugin{} })
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+type serverConfig struct {
+	Host    string
+	Port    int
+	Timeout int
+	Limits  struct {
+		Conns int
+	}
+}
+
+func TestPopulateReport(t *testing.T) {
+	c := New()
+	if err := c.Load("./testdata/server.json"); err != nil {
+		t.Fatal(err)
+	}
+
+	var s serverConfig
+	report, err := c.PopulateReport(&s, "server")
+	if err != nil {
+		t.Fatal(err)
+	}
+	equal(t, []string{"server.Host", "server.Limits", "server.Limits.Conns", "server.Port"}, report.MatchedKeys)
+	equal(t, []string{"server.Debug"}, report.UnusedConfigKeys)
+	equal(t, []string{"server.Timeout"}, report.UnsetFields)
+	// the target is left untouched.
+	equal(t, serverConfig{}, s)
+
+	// Populate still rejects the unused key.
+	if err := c.Populate(&s, "server"); err == nil {
+		t.Error("Expected an error for the unused key")
+	}
+}
+
+func TestConcurrentGetSet(t *testing.T) {
+	c := New()
+	if err := c.Load("./testdata/app.json"); err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				if name := c.GetString("name"); name != "cconf" {
+					t.Errorf("Expected cconf - Got %v", name)
+					return
+				}
+				c.GetString("ext.email")
+				c.Get("dynamic.key")
+			}
+		}()
+	}
+	for i := 0; i < 200; i++ {
+		if err := c.Set(fmt.Sprintf("dynamic.key%d", i), i); err != nil {
+			t.Fatal(err)
+		}
+		if i%20 == 0 {
+			c.Set("dynamic.key", i)
+		}
+	}
+	close(done)
+	wg.Wait()
+	equal(t, 199, c.GetInt("dynamic.key199"))
+}
+
+func BenchmarkGet(b *testing.B) {
+	c := New()
+	if err := c.Load("./testdata/app.json"); err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c.GetString("ext.email")
+	}
+}
+
+func TestRegisterLoadFuncIsolation(t *testing.T) {
+	c1, c2 := New(), New()
+	c1.RegisterLoadFunc("cue", func(file string, data interface{}) error {
+		*data.(*interface{}) = map[string]interface{}{"loader": "c1"}
+		return nil
+	})
+	c2.RegisterLoadFunc("cue", func(file string, data interface{}) error {
+		*data.(*interface{}) = map[string]interface{}{"loader": "c2"}
+		return nil
+	})
+
+	if err := c1.Load("app.cue"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c2.Load("app.cue"); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, "c1", c1.GetString("loader"))
+	equal(t, "c2", c2.GetString("loader"))
+
+	if _, ok := DefaultLoadFuncs["cue"]; ok {
+		t.Error("RegisterLoadFunc must not modify DefaultLoadFuncs")
+	}
+	if err := New().Load("app.cue"); err == nil {
+		t.Error("Expected an error for an unregistered type")
+	}
+}
+
+func TestSetPublishesNewStore(t *testing.T) {
+	c := New()
+	if err := c.Load("./testdata/app.json"); err != nil {
+		t.Fatal(err)
+	}
+	ext := c.Get("ext").(map[string]interface{})
+	if err := c.Set("ext.email", "new@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	// values obtained before Set are never modified.
+	equal(t, "syyong.x@gmail.com", ext["email"])
+	equal(t, "new@example.com", c.GetString("ext.email"))
+}
+
+func BenchmarkGetParallel(b *testing.B) {
+	c := New()
+	if err := c.Load("./testdata/app.json"); err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			c.GetString("ext.email")
+		}
+	})
+}
+
+// BenchmarkGetParallelMutex guards every Get with a RWMutex, for comparison with the lock-free Get.
+func BenchmarkGetParallelMutex(b *testing.B) {
+	c := New()
+	if err := c.Load("./testdata/app.json"); err != nil {
+		b.Fatal(err)
+	}
+	var mu sync.RWMutex
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			mu.RLock()
+			c.GetString("ext.email")
+			mu.RUnlock()
+		}
+	})
+}
+
+func TestGetCache(t *testing.T) {
+	c := New()
+	if err := c.Load("./testdata/app.json"); err != nil {
+		t.Fatal(err)
+	}
+
+	// misses are cached without remembering the default value.
+	equal(t, "d1", c.Get("missing", "d1"))
+	equal(t, "d2", c.Get("missing", "d2"))
+	equal(t, nil, c.Get("missing"))
+
+	// conversions are not cached.
+	equal(t, 0.1, c.GetFloat("version"))
+	equal(t, 0, c.GetInt("version"))
+	equal(t, 0.1, c.Get("version"))
+
+	// Set only invalidates the affected keys.
+	equal(t, "syyong.x@gmail.com", c.GetString("ext.email"))
+	equal(t, "cconf", c.GetString("name"))
+	if err := c.Set("ext", map[string]interface{}{"email": "new@example.com"}); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, "new@example.com", c.GetString("ext.email"))
+	equal(t, "", c.GetString("ext.author"))
+	if _, ok := c.snap.Load().cache.Load("name"); !ok {
+		t.Error("Expected the cache entry of an unaffected key to survive Set")
+	}
+	if err := c.Set("missing", "set"); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, "set", c.Get("missing"))
+
+	// Load and SetStore reset the cache.
+	c.SetStore(map[string]interface{}{"name": "other"})
+	equal(t, "other", c.GetString("name"))
+	equal(t, nil, c.Get("missing"))
+}
+
+func TestGetAllocs(t *testing.T) {
+	c := New()
+	c.SetStore(map[string]interface{}{
+		"limits": map[string]interface{}{"conns": 100, "name": "limits", "ratio": 0.5, "on": true},
+	})
+	// warm up the cache.
+	c.Get("limits.conns")
+	c.Get("limits.name")
+	c.Get("limits.ratio")
+	c.Get("limits.on")
+
+	allocs := testing.AllocsPerRun(100, func() {
+		c.Get("limits.conns")
+		c.GetInt("limits.conns")
+		c.GetInt("limits.conns", 10)
+		c.GetString("limits.name", "default")
+		c.GetFloat("limits.ratio", 1.5)
+		c.GetBool("limits.on")
+	})
+	equal(t, float64(0), allocs)
+}
+
+func TestTypedGetterConversions(t *testing.T) {
+	values := []interface{}{
+		"str", json.Number("12.5"), 7, int64(-3), 2.75, true, uint8(4), float32(1.5),
+		[]interface{}{1}, map[string]interface{}{"a": 1},
+	}
+	for _, val := range values {
+		c := New()
+		c.SetStore(map[string]interface{}{"v": val})
+
+		// the typed getters behave like a conversion by reflection.
+		expect := func(def interface{}) interface{} {
+			if v, ok := convertTo(val, reflect.TypeOf(def)); ok {
+				return v
+			}
+			return def
+		}
+		equal(t, expect("def"), c.GetString("v", "def"))
+		equal(t, expect(-1), c.GetInt("v", -1))
+		equal(t, expect(int64(-1)), c.GetInt64("v", -1))
+		equal(t, expect(-1.0), c.GetFloat("v", -1))
+		equal(t, expect(false), c.GetBool("v"))
+	}
+}
+
+func BenchmarkTypedGetters(b *testing.B) {
+	c := New()
+	if err := c.Load("./testdata/app.json"); err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c.GetString("name")
+		c.GetFloat("version")
+		c.GetInt("version")
+	}
+}
+
+func TestLoadIsAtomic(t *testing.T) {
+	c := New()
+	if err := c.Load("./testdata/app.json"); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, "cconf", c.GetString("name"))
+	before := c.GetStore()
+
+	if err := c.Load("./testdata/server.json", "./testdata/broken.json"); err == nil {
+		t.Fatal("Expected an error for the malformed file")
+	}
+	equal(t, before, c.GetStore())
+	equal(t, nil, c.Get("server"))
+	equal(t, "cconf", c.GetString("name"))
+
+	if err := c.LoadWithPattern("./testdata/*.json"); err == nil {
+		t.Fatal("Expected an error for the malformed file")
+	}
+	equal(t, before, c.GetStore())
+}
+
+func TestLoadAs(t *testing.T) {
+	c := New()
+	// the files are merged in order, whether their types are overridden or not.
+	err := c.Load("./testdata/app.json", "./testdata/app.rendered#json", "./testdata/server.json", "./testdata/app.tpl#json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	equal(t, "cconf-rendered", c.GetString("name"))
+	equal(t, "syyong.x", c.GetString("ext.author"))
+	equal(t, "tpl@example.com", c.GetString("ext.email"))
+	equal(t, 9090.0, c.Get("server.port"))
+	equal(t, "./testdata/app.tpl", c.Source("ext.email"))
+
+	c = New()
+	if err := c.LoadAs("json", "./testdata/app.tpl", "./testdata/app.rendered"); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, 8080.0, c.Get("server.port"))
+	equal(t, "tpl@example.com", c.GetString("ext.email"))
+	if err := c.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, 8080.0, c.Get("server.port"))
+
+	err = c.Load("./testdata/app.rendered#cue")
+	if !errors.Is(err, ErrUnknownType) {
+		t.Fatalf("Expected ErrUnknownType, got %v", err)
+	}
+	equal(t, "please register cue type loading function to load ./testdata/app.rendered", err.Error())
+	err = c.LoadAs("cue", "./testdata/app.json")
+	equal(t, "please register cue type loading function to load ./testdata/app.json", err.Error())
+	c.SniffUnknown = true
+	if err := c.LoadAs("cue", "./testdata/app.json"); !errors.Is(err, ErrUnknownType) {
+		t.Errorf("Expected ErrUnknownType for an explicit type, got %v", err)
+	}
+}
+
+func TestFileType(t *testing.T) {
+	for file, want := range map[string][2]string{
+		"app.json":              {"app.json", "json"},
+		"config.rendered#json":  {"config.rendered", "json"},
+		"dir#1/app.yaml":        {"dir#1/app.yaml", "yaml"},
+		"notes#v1.2":            {"notes#v1.2", "2"},
+		"trailing#":             {"trailing#", ""},
+		"/etc/app#json.d/x.ini": {"/etc/app#json.d/x.ini", "ini"},
+	} {
+		name, typ, _ := fileType(file)
+		equal(t, want, [2]string{name, typ})
+	}
+}
+
+func TestGetters(t *testing.T) {
+	for _, disabled := range []bool{false, true} {
+		name := "cached"
+		if disabled {
+			name = "uncached"
+		}
+		t.Run(name, func(t *testing.T) {
+			c := New()
+			if disabled {
+				c.DisableCache()
+			}
+			if err := c.Load("./testdata/app.json"); err != nil {
+				t.Fatal(err)
+			}
+			// run twice, so that the second round may be served by the cache.
+			for i := 0; i < 2; i++ {
+				equal(t, "cconf", c.GetString("name"))
+				equal(t, map[string]interface{}{"email": "syyong.x@gmail.com", "author": "syyong.x"}, c.Get("ext"))
+				equal(t, "syyong.x@gmail.com", c.GetString("ext.email"))
+				equal(t, 0.1, c.GetFloat("version", 2.0))
+				equal(t, 0, c.GetInt("version", 5))
+				equal(t, int64(0), c.GetInt64("version"))
+				equal(t, false, c.GetBool("missing"))
+				equal(t, true, c.GetBool("missing", true))
+				equal(t, "default", c.GetString("ext.missing", "default"))
+				equal(t, nil, c.Get("name.missing"))
+			}
+
+			if err := c.Set("ext", map[string]interface{}{"email": "new@example.com"}); err != nil {
+				t.Fatal(err)
+			}
+			equal(t, "new@example.com", c.GetString("ext.email"))
+			equal(t, "", c.GetString("ext.author"))
+			c.SetStore(map[string]interface{}{"name": "other"})
+			equal(t, "other", c.GetString("name"))
+			equal(t, nil, c.Get("ext"))
+
+			if disabled {
+				equal(t, 0, c.CacheStats().Entries)
+			}
+		})
+	}
+}
+
+func TestGetMany(t *testing.T) {
+	c := New()
+	if err := c.Load("./testdata/app.json"); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, map[string]interface{}{"name": "cconf", "ext.author": "syyong.x"},
+		c.GetMany([]string{"name", "ext.author", "missing"}))
+	equal(t, map[string]interface{}{"name": "cconf", "version": 0, "missing": "def"},
+		c.GetManyWithDefaults(map[string]interface{}{"name": "", "version": 1, "missing": "def"}))
+
+	// all values come from the same version of the store.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 500; i++ {
+			c.SetStore(map[string]interface{}{"a": i, "b": i})
+		}
+	}()
+	for {
+		select {
+		case <-done:
+			return
+		default:
+		}
+		vals := c.GetMany([]string{"a", "b"})
+		if vals["a"] != vals["b"] {
+			t.Fatalf("Inconsistent values %v", vals)
+		}
+	}
+}
+
+type taggedConfig struct {
+	Port  int      `cconf:"port,required"`
+	Level string   `cconf:"level,default=info"`
+	Hosts []string `cconf:"hosts,default=a,b"`
+	Name  string
+}
+
+func TestPopulateTags(t *testing.T) {
+	c := New()
+	c.SetStore(map[string]interface{}{"port": 8080, "Name": "app"})
+	var s taggedConfig
+	if err := c.Populate(&s); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, taggedConfig{8080, "info", []string{"a", "b"}, "app"}, s)
+
+	// a configured value wins over the default.
+	c.Set("level", "debug")
+	if err := c.Populate(&s); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, "debug", s.Level)
+
+	// the field name does not match a renamed field.
+	c.Set("Port", 80)
+	if err := c.Populate(&s); err == nil {
+		t.Error("Expected an error for the field name of a renamed field")
+	}
+
+	m := New()
+	m.SetStore(map[string]interface{}{"level": "warn"})
+	err := m.Populate(&s)
+	var ve *ConfigValueError
+	if !errors.As(err, &ve) || ve.Key != ".port" {
+		t.Errorf("Expected an error for the missing required port, got %v", err)
+	}
+}
+
+func TestErrorsIs(t *testing.T) {
+	c := New()
+	c.SetStore(map[string]interface{}{
+		"app":     map[string]interface{}{"name": "app"},
+		"http":    map[string]interface{}{"port": "8080"},
+		"plugins": map[string]interface{}{"main": map[string]interface{}{"type": "missing"}},
+	})
+	var ke *ConfigKeyError
+	var ve *ConfigValueError
+
+	tests := []struct {
+		name string
+		err  error
+		is   error
+		as   interface{}
+	}{
+		{"load", c.Load("app.cue"), ErrUnknownType, nil},
+		{"set", c.Set("app.name.first", "a"), ErrTypeMismatch, &ke},
+		{"populate missing key", c.Populate(&struct{}{}, "db"), ErrKeyNotFound, &ke},
+		{"populate mismatch", c.Populate(&struct {
+			Port int `cconf:"port"`
+		}{}, "http"), ErrTypeMismatch, nil},
+		{"populate required", c.Populate(&struct {
+			Type string `cconf:"type"`
+			Host string `cconf:"host,required"`
+		}{}, "plugins.main"), ErrKeyNotFound, &ve},
+		{"populate unknown type", c.Populate(&struct {
+			Main plugin `cconf:"main"`
+		}{}, "plugins"), ErrUnknownType, &ve},
+		{"validate struct", c.ValidateStruct(struct {
+			Port int `cconf:"port"`
+		}{}, "http"), ErrTypeMismatch, &ve},
+		{"require", c.RequireKeys("db.host"), ErrKeyNotFound, &ke},
+		{"save key", c.SaveKey("db", "db.json"), ErrKeyNotFound, &ke},
+		{"expect types", c.ExpectTypes(map[string]string{"http.port": "int"}), ErrTypeMismatch, &ve},
+		{"declare types", c.DeclareTypes(map[string]interface{}{"app.name": 0}), ErrTypeMismatch, &ve},
+	}
+	for _, test := range tests {
+		if !errors.Is(test.err, test.is) {
+			t.Errorf("%s: expected an error wrapping %v, got %v", test.name, test.is, test.err)
+		}
+		if test.as != nil && !errors.As(test.err, test.as) {
+			t.Errorf("%s: expected a %T, got %v", test.name, test.as, test.err)
+		}
+	}
+	if err := c.Populate(&struct {
+		Name int `cconf:"name"`
+	}{}, "app"); err == nil || errors.Is(err, ErrKeyNotFound) || errors.Is(err, ErrUnknownType) {
+		t.Errorf("Expected only a type mismatch, got %v", err)
+	}
+}
+
+func TestErrorFields(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "app.json")
+	if err := os.WriteFile(file, []byte(`{"http": {"port": "80a", "hosts": ["a", 1]}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	c := New()
+	c.ValidationMode = CollectAll
+	if err := c.Load(file); err != nil {
+		t.Fatal(err)
+	}
+	var s struct {
+		Port  int      `cconf:"port"`
+		Hosts []string `cconf:"hosts"`
+	}
+	err := c.Populate(&s, "http")
+	var me *MultiError
+	if !errors.As(err, &me) || len(me.Errors) != 2 {
+		t.Fatalf("Expected two errors, got %v", err)
+	}
+	want := map[string]*ConfigValueError{
+		"http.port":    {Key: "http.port", Message: "string cannot be used to configure int", Err: ErrTypeMismatch, Expected: "int", Actual: "80a", Source: file},
+		"http.hosts.1": {Key: "http.hosts.1", Message: "float64 cannot be used to configure string", Err: ErrTypeMismatch, Expected: "string", Actual: 1.0, Source: file},
+	}
+	for _, err := range me.Errors {
+		var ve *ConfigValueError
+		if !errors.As(err, &ve) {
+			t.Fatalf("Expected a *ConfigValueError, got %v", err)
+		}
+		equal(t, want[ve.Key], ve)
+	}
+	// the message is unchanged.
+	equal(t, `"http.port" points to an inappropriate configuration value: string cannot be used to configure int`, want["http.port"].Error())
+
+	err = c.Populate(&s, "http.tls.cert")
+	var ke *ConfigKeyError
+	if !errors.As(err, &ke) {
+		t.Fatalf("Expected a *ConfigKeyError, got %v", err)
+	}
+	equal(t, 1, ke.Segment)
+
+	err = c.Set("http.port.number", 80)
+	if !errors.As(err, &ke) {
+		t.Fatalf("Expected a *ConfigKeyError, got %v", err)
+	}
+	equal(t, "http.port.number", ke.Key)
+	equal(t, 2, ke.Segment)
+}
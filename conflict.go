@@ -0,0 +1,69 @@
+package cconf
+
+import (
+	"sort"
+)
+
+// mergeFunc merges conflicting values, see SetMergeConflictFunc.
+type mergeFunc func(key string, existing, incoming interface{}) (interface{}, error)
+
+// SetMergeConflictFunc sets a function that decides the merges of loaded data where both the
+// existing and the incoming data have a value at the same key, and not both are maps, like a
+// string of a file overridden by a later file. It returns the merged value, like the existing
+// or the incoming value or a combination of both, or an error aborting the merge, which fails
+// with a *ConfigValueError naming the key and wrapping the error. Without function, or when
+// one of the values is a map of the other, the incoming value replaces the existing one.
+//
+// The function applies to Load, LoadReader and the other loads, to their reloads and to the
+// data passed to SetStore, but not to Set, the overrides and the defaults layer. Since SetStore
+// cannot fail, it keeps the store when the function fails and reports the error by an
+// EventLoad (see OnEvent). Nil restores the default rules.
+func (c *Conf) SetMergeConflictFunc(fn func(key string, existing, incoming interface{}) (interface{}, error)) {
+	c.mu.Lock()
+	defer c.unlock()
+	c.mergeConflict = fn
+}
+
+// mergeLoaded merges the incoming data into the existing data at the key like merge, calling
+// the merge conflict function for conflicting values. The caller must hold c.mu.
+func (c *Conf) mergeLoaded(key string, existing, incoming interface{}) (interface{}, error) {
+	if c.mergeConflict == nil {
+		return merge(existing, incoming), nil
+	}
+	m1, ok1 := existing.(map[string]interface{})
+	m2, ok2 := incoming.(map[string]interface{})
+	switch {
+	case existing == nil || incoming == nil:
+		return incoming, nil
+	case !ok1 || !ok2:
+		v, err := c.mergeConflict(key, existing, incoming)
+		if err != nil {
+			return nil, &ConfigValueError{Key: key, Message: "conflicting values: " + err.Error(), Err: err, Actual: incoming}
+		}
+		return normalize(v), nil
+	}
+
+	m := make(map[string]interface{}, len(m1)+len(m2))
+	for k, e := range m1 {
+		m[k] = e
+	}
+	// the keys are merged in order, so that the function is called in order.
+	keys := make([]string, 0, len(m2))
+	for k := range m2 {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		e2 := m2[k]
+		if e2 == nil {
+			delete(m, k)
+			continue
+		}
+		e, err := c.mergeLoaded(c.join(key, k), m[k], e2)
+		if err != nil {
+			return nil, err
+		}
+		m[k] = e
+	}
+	return m, nil
+}
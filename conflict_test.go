@@ -0,0 +1,99 @@
+package cconf
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSetMergeConflictFunc(t *testing.T) {
+	dir := t.TempDir()
+	base := filepath.Join(dir, "base.json")
+	local := filepath.Join(dir, "local.json")
+	if err := os.WriteFile(base, []byte(`{"tags": "a", "db": {"host": "x", "port": 1}, "name": "app"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(local, []byte(`{"tags": "b", "db": {"port": 2}, "name": {"first": "app"}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	c := New()
+	var conflicts []string
+	c.SetMergeConflictFunc(func(key string, existing, incoming interface{}) (interface{}, error) {
+		conflicts = append(conflicts, key)
+		if s, ok := existing.(string); ok && key == "tags" {
+			return s + "," + incoming.(string), nil
+		}
+		return incoming, nil
+	})
+	if err := c.Load(base, local); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, []string{"db.port", "name", "tags"}, conflicts)
+	equal(t, "a,b", c.GetString("tags"))
+	equal(t, 2, c.GetInt("db.port"))
+	equal(t, "x", c.GetString("db.host"))
+	equal(t, "app", c.GetString("name.first"))
+
+	// reloads merge the same way.
+	if err := c.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, "a,b", c.GetString("tags"))
+
+	// SetStore merges its data with the function.
+	c.SetStore(map[string]interface{}{"tags": "c"}, map[string]interface{}{"tags": "d"})
+	equal(t, "c,d", c.GetString("tags"))
+
+	// nil restores the default rules.
+	c.SetMergeConflictFunc(nil)
+	c.SetStore(map[string]interface{}{"tags": "c"}, map[string]interface{}{"tags": "d"})
+	equal(t, "d", c.GetString("tags"))
+}
+
+func TestMergeConflictAbort(t *testing.T) {
+	dir := t.TempDir()
+	base := filepath.Join(dir, "base.json")
+	local := filepath.Join(dir, "local.json")
+	if err := os.WriteFile(base, []byte(`{"db": {"host": "x"}, "debug": false}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(local, []byte(`{"db": {"host": "y"}, "debug": true}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	errLocked := errors.New("the key is locked")
+	c := New()
+	c.SetMergeConflictFunc(func(key string, existing, incoming interface{}) (interface{}, error) {
+		if key == "db.host" {
+			return nil, errLocked
+		}
+		return incoming, nil
+	})
+	var events []Event
+	c.OnEvent(func(e Event) {
+		events = append(events, e)
+	})
+	if err := c.Load(base); err != nil {
+		t.Fatal(err)
+	}
+	err := c.Load(local)
+	var ve *ConfigValueError
+	if !errors.As(err, &ve) || !errors.Is(err, errLocked) {
+		t.Fatalf("Expected a *ConfigValueError wrapping the error, got %v", err)
+	}
+	equal(t, "db.host", ve.Key)
+	equal(t, `"db.host" points to an inappropriate configuration value: conflicting values: the key is locked`, err.Error())
+	// the load is atomic.
+	equal(t, "x", c.GetString("db.host"))
+	equal(t, false, c.GetBool("debug"))
+
+	c.SetStore(map[string]interface{}{"db": map[string]interface{}{"host": "a"}}, map[string]interface{}{"db": map[string]interface{}{"host": "b"}})
+	equal(t, "x", c.GetString("db.host"))
+	last := events[len(events)-1]
+	equal(t, "SetStore()", last.Source)
+	if !errors.Is(last.Err, errLocked) {
+		t.Errorf("Expected an event with the error, got %v", fmt.Sprint(last))
+	}
+}
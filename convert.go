@@ -0,0 +1,246 @@
+package cconf
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"time"
+)
+
+// types of the typed getters.
+var (
+	stringType  = reflect.TypeOf("")
+	intType     = reflect.TypeOf(0)
+	int64Type   = reflect.TypeOf(int64(0))
+	float64Type = reflect.TypeOf(float64(0))
+	boolType    = reflect.TypeOf(false)
+)
+
+// convert converts the value to the type of the default value v.
+// The value is returned as is if v is nil, and v is returned if the value cannot be converted.
+func convert(val, v interface{}) interface{} {
+	if v == nil {
+		return val
+	}
+	if cv, ok := convertTo(val, reflect.TypeOf(v)); ok {
+		return cv
+	}
+	return v
+}
+
+// convertTo converts the value to type t.
+func convertTo(val interface{}, t reflect.Type) (interface{}, bool) {
+	rv := reflect.ValueOf(val)
+	if rv.Type() == t {
+		return val, true
+	}
+	if rv.Type().ConvertibleTo(t) {
+		return rv.Convert(t).Interface(), true
+	}
+	return nil, false
+}
+
+// The toXXX functions convert a value for the typed getters. They behave like convertTo,
+// with fast paths that avoid reflection for the common types.
+
+// toString converts the value to a string.
+func toString(val interface{}) (string, bool) {
+	switch v := val.(type) {
+	case string:
+		return v, true
+	case json.Number:
+		return string(v), true
+	case time.Time:
+		return v.Format(time.RFC3339Nano), true
+	}
+	if v, ok := convertTo(val, stringType); ok {
+		return v.(string), true
+	}
+	return "", false
+}
+
+// toInt converts the value to an int.
+func toInt(val interface{}) (int, bool) {
+	switch v := val.(type) {
+	case int:
+		return v, true
+	case int64:
+		return int(v), true
+	case float64:
+		return int(v), true
+	}
+	if v, ok := convertTo(val, intType); ok {
+		return v.(int), true
+	}
+	return 0, false
+}
+
+// toInt64 converts the value to an int64.
+func toInt64(val interface{}) (int64, bool) {
+	switch v := val.(type) {
+	case int64:
+		return v, true
+	case int:
+		return int64(v), true
+	case float64:
+		return int64(v), true
+	}
+	if v, ok := convertTo(val, int64Type); ok {
+		return v.(int64), true
+	}
+	return 0, false
+}
+
+// toFloat converts the value to a float64.
+func toFloat(val interface{}) (float64, bool) {
+	switch v := val.(type) {
+	case float64:
+		return v, true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	}
+	if v, ok := convertTo(val, float64Type); ok {
+		return v.(float64), true
+	}
+	return 0, false
+}
+
+// toBool converts the value to a bool.
+func toBool(val interface{}) (bool, bool) {
+	if v, ok := val.(bool); ok {
+		return v, true
+	}
+	if v, ok := convertTo(val, boolType); ok {
+		return v.(bool), true
+	}
+	return false, false
+}
+
+// durationType is the type of time.Duration.
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// convertValue strictly converts a value of the store to type t: numbers only convert to
+// numbers (and integers only from whole numbers in range), strings to strings, bools to bools,
+// and durations are parsed from strings such as "1m30s" or taken from integer nanoseconds.
+// Slices and maps are converted element-wise.
+func convertValue(val interface{}, t reflect.Type) (interface{}, error) {
+	v, err := convertReflect(reflect.ValueOf(val), t)
+	if err != nil {
+		return nil, err
+	}
+	return v.Interface(), nil
+}
+
+// convertReflect converts v to type t, see convertValue.
+func convertReflect(v reflect.Value, t reflect.Type) (reflect.Value, error) {
+	for v.Kind() == reflect.Interface {
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		return reflect.Zero(t), nil
+	}
+	if v.Type() == t {
+		return v, nil
+	}
+	fail := func() (reflect.Value, error) {
+		return reflect.Value{}, fmt.Errorf("%v cannot be converted to %v", v.Type(), t)
+	}
+
+	if t == durationType {
+		switch v.Kind() {
+		case reflect.String:
+			d, err := time.ParseDuration(v.String())
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			return reflect.ValueOf(d), nil
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Float32, reflect.Float64:
+			return convertReflect(v, int64Type)
+		}
+		return fail()
+	}
+
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		switch v.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			if r := reflect.New(t).Elem(); !r.OverflowInt(v.Int()) {
+				return v.Convert(t), nil
+			}
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			if r := reflect.New(t).Elem(); v.Uint() <= math.MaxInt64 && !r.OverflowInt(int64(v.Uint())) {
+				return v.Convert(t), nil
+			}
+		case reflect.Float32, reflect.Float64:
+			f := v.Float()
+			if f == math.Trunc(f) && f >= math.MinInt64 && f < math.MaxInt64 && !reflect.New(t).Elem().OverflowInt(int64(f)) {
+				return v.Convert(t), nil
+			}
+		}
+		return reflect.Value{}, fmt.Errorf("%v is not a valid %v", v.Interface(), t)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		switch v.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			if i := v.Int(); i >= 0 && !reflect.New(t).Elem().OverflowUint(uint64(i)) {
+				return v.Convert(t), nil
+			}
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			if !reflect.New(t).Elem().OverflowUint(v.Uint()) {
+				return v.Convert(t), nil
+			}
+		case reflect.Float32, reflect.Float64:
+			f := v.Float()
+			if f == math.Trunc(f) && f >= 0 && f < math.MaxUint64 && !reflect.New(t).Elem().OverflowUint(uint64(f)) {
+				return v.Convert(t), nil
+			}
+		}
+		return reflect.Value{}, fmt.Errorf("%v is not a valid %v", v.Interface(), t)
+	case reflect.Float32, reflect.Float64:
+		switch v.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+			reflect.Float32, reflect.Float64:
+			return v.Convert(t), nil
+		}
+		return fail()
+	case reflect.String, reflect.Bool:
+		if v.Kind() == t.Kind() {
+			return v.Convert(t), nil
+		}
+		return fail()
+	case reflect.Slice:
+		if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+			return fail()
+		}
+		s := reflect.MakeSlice(t, v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			e, err := convertReflect(v.Index(i), t.Elem())
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("element %d: %v", i, err)
+			}
+			s.Index(i).Set(e)
+		}
+		return s, nil
+	case reflect.Map:
+		if v.Kind() != reflect.Map || t.Key().Kind() != reflect.String {
+			return fail()
+		}
+		m := reflect.MakeMapWithSize(t, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			e, err := convertReflect(iter.Value(), t.Elem())
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("element %v: %v", iter.Key(), err)
+			}
+			m.SetMapIndex(reflect.ValueOf(fmt.Sprint(iter.Key().Interface())).Convert(t.Key()), e)
+		}
+		return m, nil
+	}
+	if v.Type().ConvertibleTo(t) {
+		return v.Convert(t), nil
+	}
+	return fail()
+}
@@ -0,0 +1,67 @@
+package cconf
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// defaultConf is the Conf of the package-level functions, created by Default on first use.
+var (
+	defaultConf atomic.Pointer[Conf]
+	defaultMu   sync.Mutex // serializes the creation of defaultConf
+)
+
+// Default returns the Conf used by the package-level functions, like Load and Get, which is
+// created with New on first use.
+func Default() *Conf {
+	if c := defaultConf.Load(); c != nil {
+		return c
+	}
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	if defaultConf.Load() == nil {
+		defaultConf.Store(New())
+	}
+	return defaultConf.Load()
+}
+
+// SetDefault replaces the Conf used by the package-level functions, e.g. to isolate tests.
+// Nil makes the next call create a new one.
+func SetDefault(c *Conf) {
+	defaultConf.Store(c)
+}
+
+// Load loads configuration data from one or multiple files into the default Conf, see Conf.Load.
+func Load(files ...string) error {
+	return Default().Load(files...)
+}
+
+// Get returns the configuration value at the key of the default Conf, see Conf.Get.
+func Get(key string, def ...interface{}) interface{} {
+	return Default().Get(key, def...)
+}
+
+// GetString returns the value at the key of the default Conf as a string, see Conf.GetString.
+func GetString(key string, def ...string) string {
+	return Default().GetString(key, def...)
+}
+
+// GetInt returns the value at the key of the default Conf as an int, see Conf.GetInt.
+func GetInt(key string, def ...int) int {
+	return Default().GetInt(key, def...)
+}
+
+// GetBool returns the value at the key of the default Conf as a bool, see Conf.GetBool.
+func GetBool(key string, def ...bool) bool {
+	return Default().GetBool(key, def...)
+}
+
+// Set sets the configuration value at the key of the default Conf, see Conf.Set.
+func Set(key string, val interface{}) error {
+	return Default().Set(key, val)
+}
+
+// Populate populates v with the configuration of the default Conf, see Conf.Populate.
+func Populate(v interface{}, key ...string) error {
+	return Default().Populate(v, key...)
+}
@@ -0,0 +1,61 @@
+package cconf
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestDefault(t *testing.T) {
+	old := defaultConf.Load()
+	defer SetDefault(old)
+	SetDefault(nil)
+
+	file := filepath.Join(t.TempDir(), "app.json")
+	if err := os.WriteFile(file, []byte(`{"name": "app", "http": {"port": 8080, "tls": true}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := Load(file); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, "app", GetString("name"))
+	equal(t, 8080, GetInt("http.port"))
+	equal(t, true, GetBool("http.tls"))
+	equal(t, "x", Get("missing", "x"))
+	if err := Set("http.port", 9090); err != nil {
+		t.Fatal(err)
+	}
+	var s struct {
+		Port int  `cconf:"port"`
+		TLS  bool `cconf:"tls"`
+	}
+	if err := Populate(&s, "http"); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, 9090, s.Port)
+	equal(t, true, s.TLS)
+
+	// SetDefault swaps the instance.
+	c := New()
+	SetDefault(c)
+	equal(t, c, Default())
+	equal(t, "", GetString("name"))
+
+	// concurrent first uses create a single instance.
+	SetDefault(nil)
+	var wg sync.WaitGroup
+	confs := make([]*Conf, 8)
+	for i := range confs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			confs[i] = Default()
+			Set("n", i)
+		}(i)
+	}
+	wg.Wait()
+	for _, c := range confs {
+		equal(t, confs[0], c)
+	}
+}
@@ -0,0 +1,123 @@
+package cconf
+
+import (
+	"reflect"
+)
+
+// SetDefaults replaces the defaults layer with the data, merged in order like SetStore.
+// The defaults layer lies below all other sources: every loaded file, variable and value
+// set by Set takes precedence over it, and it is kept by SetStore and Reload.
+// See SaveDiff to save only the values that differ from the defaults.
+func (c *Conf) SetDefaults(data ...interface{}) error {
+	c.mu.Lock()
+	defer c.unlock()
+	var defaults interface{}
+	for _, d := range data {
+		defaults = merge(defaults, normalize(d))
+	}
+	old := c.sources
+	srcs := c.sources
+	if c.defaults() != nil {
+		srcs = srcs[1:]
+	}
+	c.sources = append([]source{{data: defaults, defaults: true, origin: named("SetDefaults()")}}, srcs...)
+	if err := c.rebuild(false); err != nil {
+		c.sources = old
+		return err
+	}
+	return nil
+}
+
+// defaults returns the source of the defaults layer, or nil if none is set.
+// The caller must hold c.mu.
+func (c *Conf) defaults() *source {
+	if len(c.sources) > 0 && c.sources[0].defaults {
+		return &c.sources[0]
+	}
+	return nil
+}
+
+// storeDiff returns a copy of the values of the store that differ from the defaults layer,
+// as a nested document. If redact is true, the values of secret keys are redacted.
+func (c *Conf) storeDiff(redact bool) interface{} {
+	c.mu.Lock()
+	store, secrets := c.snap.Load().store, c.secretKeys()
+	var defaults interface{}
+	if d := c.defaults(); d != nil {
+		// compare with the defaults converted to their declared types, like the store.
+		defaults = c.applyTypesLenient(d.data)
+	}
+	c.mu.Unlock()
+	d, _ := delta(defaults, store)
+	if d == nil {
+		d = map[string]interface{}{}
+	}
+	if redact && len(secrets) > 0 {
+		return c.redact(d, "", secrets)
+	}
+	return normalize(d)
+}
+
+// delta returns the parts of v that differ from the default value def, and whether any differ.
+// Maps are compared key by key, other values as a whole, with numbers compared by value.
+func delta(def, v interface{}) (interface{}, bool) {
+	m, ok := v.(map[string]interface{})
+	dm, dok := def.(map[string]interface{})
+	if !ok || !dok {
+		return v, !sameValue(def, v)
+	}
+	d := make(map[string]interface{})
+	for k, e := range m {
+		if e, changed := delta(dm[k], e); changed {
+			d[k] = e
+		}
+	}
+	return d, len(d) > 0
+}
+
+// sameValue reports whether the values are deeply equal, with numbers of different types
+// equal if their values are, like the int of a default and the float64 of a JSON file.
+func sameValue(a, b interface{}) bool {
+	if fa, ok := number(a); ok {
+		fb, ok := number(b)
+		return ok && fa == fb
+	}
+	switch a := a.(type) {
+	case map[string]interface{}:
+		b, ok := b.(map[string]interface{})
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for k, e := range a {
+			if f, ok := b[k]; !ok || !sameValue(e, f) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		b, ok := b.([]interface{})
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if !sameValue(a[i], b[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// number returns the value of a plain number as float64.
+func number(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
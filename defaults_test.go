@@ -0,0 +1,119 @@
+package cconf
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSetDefaults(t *testing.T) {
+	c := New()
+	err := c.SetDefaults(map[string]interface{}{
+		"name":    "default",
+		"version": 0.1,
+		"debug":   false,
+		"ext":     map[string]interface{}{"author": "syyong.x", "email": "none"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	equal(t, "default", c.GetString("name"))
+	if err := c.Load("./testdata/app.json"); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, "cconf", c.GetString("name"))
+	equal(t, false, c.GetBool("debug"))
+	equal(t, "SetDefaults()", c.Source("debug"))
+	equal(t, "./testdata/app.json", c.Source("name"))
+
+	// the defaults stay below Set, SetStore and Reload.
+	if err := c.Set("debug", true); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, true, c.GetBool("debug"))
+	c.SetStore(map[string]interface{}{"name": "store"})
+	equal(t, "store", c.GetString("name"))
+	equal(t, "none", c.GetString("ext.email"))
+
+	// replacing the defaults keeps the other sources.
+	if err := c.SetDefaults(map[string]interface{}{"name": "other", "port": 80}); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, "store", c.GetString("name"))
+	equal(t, 80, c.GetInt("port"))
+	equal(t, "", c.GetString("ext.email"))
+}
+
+func TestSaveDiff(t *testing.T) {
+	c := New()
+	err := c.SetDefaults(map[string]interface{}{
+		"name":    "default",
+		"version": 0.1,
+		"debug":   false,
+		"ext":     map[string]interface{}{"author": "syyong.x", "email": "none"},
+		"hosts":   []interface{}{"a", "b"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Load("./testdata/app.json"); err != nil {
+		t.Fatal(err)
+	}
+	// explicitly set to the default value.
+	if err := c.Set("debug", false); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Set("hosts.1", "c"); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"name":  "cconf",
+		"ext":   map[string]interface{}{"email": "syyong.x@gmail.com"},
+		"hosts": []interface{}{"a", "c"},
+	}
+
+	file := filepath.Join(t.TempDir(), "tenant.json")
+	if err := c.SaveDiff(file); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var saved interface{}
+	if err := json.Unmarshal(b, &saved); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, want, saved)
+
+	c.MarkSecret("ext.email")
+	var buf bytes.Buffer
+	if _, err := c.WriteDiffTo(&buf, "json"); err != nil {
+		t.Fatal(err)
+	}
+	saved = nil
+	if err := json.Unmarshal(buf.Bytes(), &saved); err != nil {
+		t.Fatal(err)
+	}
+	want["ext"] = map[string]interface{}{"email": Redacted}
+	equal(t, want, saved)
+
+	// loading the defaults and the diff restores the store.
+	restored := New()
+	restored.SetDefaults(c.sources[0].data)
+	if err := restored.Load(file); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, c.GetStore(), restored.GetStore())
+
+	buf.Reset()
+	if _, err := New().WriteDiffTo(&buf, ""); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, "{}\n", buf.String())
+}
@@ -0,0 +1,83 @@
+package cconf
+
+import (
+	"reflect"
+	"sort"
+	"strconv"
+)
+
+// Diff describes the differences between two stores by flattened keys, like "db.hosts.0".
+// Every list is sorted by key.
+type Diff struct {
+	Added   []KeyChange // keys with a New value only
+	Removed []KeyChange // keys with an Old value only
+	Changed []KeyChange // keys whose value changed
+}
+
+// KeyChange describes the change of the value of a key.
+type KeyChange struct {
+	Key      string
+	Old, New interface{}
+}
+
+// Empty reports whether there are no differences.
+func (d Diff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// diff returns the differences between the old and the new store.
+func diff(old, new interface{}, sep string) Diff {
+	ol := make(map[string]interface{})
+	flatten(ol, "", old, sep)
+	nl := make(map[string]interface{})
+	flatten(nl, "", new, sep)
+
+	var d Diff
+	for k, nv := range nl {
+		ov, ok := ol[k]
+		switch {
+		case !ok:
+			d.Added = append(d.Added, KeyChange{Key: k, New: nv})
+		case !reflect.DeepEqual(ov, nv):
+			d.Changed = append(d.Changed, KeyChange{Key: k, Old: ov, New: nv})
+		}
+	}
+	for k, ov := range ol {
+		if _, ok := nl[k]; !ok {
+			d.Removed = append(d.Removed, KeyChange{Key: k, Old: ov})
+		}
+	}
+	for _, l := range [][]KeyChange{d.Added, d.Removed, d.Changed} {
+		sort.Slice(l, func(i, j int) bool { return l[i].Key < l[j].Key })
+	}
+	return d
+}
+
+// flatten adds the leaf values below the key to leaves: the scalars and the empty maps and slices.
+func flatten(leaves map[string]interface{}, key string, v interface{}, sep string) {
+	prefix := key
+	if key != "" {
+		prefix += sep
+	}
+	switch d := v.(type) {
+	case nil:
+		return
+	case map[string]interface{}:
+		if len(d) > 0 {
+			for k, e := range d {
+				flatten(leaves, prefix+k, e, sep)
+			}
+			return
+		}
+	case []interface{}:
+		if len(d) > 0 {
+			for i, e := range d {
+				flatten(leaves, prefix+strconv.Itoa(i), e, sep)
+			}
+			return
+		}
+	}
+	if key != "" {
+		leaves[key] = v
+	}
+}
@@ -0,0 +1,30 @@
+package cconf
+
+import (
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	old := map[string]interface{}{
+		"name":  "app",
+		"hosts": []interface{}{"a", "b"},
+		"db":    map[string]interface{}{"host": "localhost", "port": 5432.0},
+		"empty": map[string]interface{}{},
+	}
+	new := map[string]interface{}{
+		"name":  "app",
+		"hosts": []interface{}{"a"},
+		"db":    map[string]interface{}{"host": "db", "user": "root"},
+		"empty": []interface{}{},
+	}
+	d := diff(old, new, ".")
+	equal(t, []KeyChange{{Key: "db.user", New: "root"}}, d.Added)
+	equal(t, []KeyChange{{Key: "db.port", Old: 5432.0}, {Key: "hosts.1", Old: "b"}}, d.Removed)
+	equal(t, []KeyChange{
+		{Key: "db.host", Old: "localhost", New: "db"},
+		{Key: "empty", Old: map[string]interface{}{}, New: []interface{}{}},
+	}, d.Changed)
+	equal(t, false, d.Empty())
+	equal(t, true, diff(old, old, ".").Empty())
+	equal(t, []KeyChange{{Key: "name", New: "app"}}, diff(nil, map[string]interface{}{"name": "app"}, ".").Added)
+}
@@ -0,0 +1,157 @@
+package cconf
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+// loadEnvFile reads and parses an env-file, see EnvFileLoadFunc.
+func loadEnvFile(file string, data interface{}) error {
+	return EnvFileLoadFunc(nil)(file, data)
+}
+
+// decodeEnvFile parses an env-file from the reader into data, which must be a *interface{}.
+func decodeEnvFile(r io.Reader, data interface{}) error {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	return parseEnvFile(string(b), nil, data)
+}
+
+// EnvFileLoadFunc returns a load function for RegisterLoadFunc reading env-files (.env) of
+// "NAME=value" lines, optionally prefixed with "export", with "#" comments. Values may be
+// single-quoted, taken literally, or double-quoted, with the escape sequences \n, \r, \t,
+// \" and \\ replaced; quoted values may span lines. The values are strings.
+//
+// toKey maps the variable names to keys separated by DefaultSeparator. When it is nil or
+// returns an empty string, the names are lowercased like by LoadEnv, with single underscores
+// separating the segments of the key and double underscores standing for a literal
+// underscore: DB_HOST sets "db.host" and DB_POOL__SIZE sets "db.pool_size".
+func EnvFileLoadFunc(toKey func(name string) string) func(file string, data interface{}) error {
+	return func(file string, data interface{}) error {
+		b, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		return parseEnvFile(string(b), toKey, data)
+	}
+}
+
+// parseEnvFile parses the env-file into data, see EnvFileLoadFunc.
+// Malformed lines fail with a *SyntaxError.
+func parseEnvFile(s string, toKey func(name string) string, data interface{}) error {
+	root := make(map[string]interface{})
+	lines := strings.Split(strings.ReplaceAll(strings.TrimPrefix(s, "\ufeff"), "\r\n", "\n"), "\n")
+	errorf := func(i, off int, format string, args ...interface{}) error {
+		col := utf8.RuneCountInString(lines[i][:off]) + 1
+		return &SyntaxError{Line: i + 1, Column: col, Err: fmt.Errorf(format, args...)}
+	}
+	for i := 0; i < len(lines); i++ {
+		at, line := i, lines[i]
+		text := strings.TrimLeft(line, " \t")
+		if text == "" || text[0] == '#' {
+			continue
+		}
+		if rest := strings.TrimPrefix(text, "export"); rest != text && (rest == "" || rest[0] == ' ' || rest[0] == '\t') {
+			text = strings.TrimLeft(rest, " \t")
+		}
+		start := len(line) - len(text)
+		end := 0
+		for end < len(text) && (isNameByte(text[end], end == 0) || end > 0 && text[end] == '.') {
+			end++
+		}
+		if end == 0 {
+			return errorf(i, start, "expected a variable name")
+		}
+		name := text[:end]
+		rest := strings.TrimLeft(text[end:], " \t")
+		if rest == "" || rest[0] != '=' {
+			return errorf(i, len(line)-len(rest), "expected '=' after %s", name)
+		}
+		off := len(line) - len(rest) + 1
+		val := strings.TrimLeft(rest[1:], " \t")
+		off += len(rest[1:]) - len(val)
+
+		switch {
+		case val != "" && (val[0] == '"' || val[0] == '\''):
+			// quoted values continue on the following lines up to the closing quote.
+			first, q, body := i, val[0], val[1:]
+			for {
+				closing := envFileClosing(body, q)
+				if closing >= 0 {
+					if tail := strings.TrimLeft(body[closing+1:], " \t"); tail != "" && tail[0] != '#' {
+						return errorf(i, len(lines[i])-len(tail), "unexpected %q after the quoted value", tail)
+					}
+					body = body[:closing]
+					break
+				}
+				if i+1 == len(lines) {
+					return errorf(first, off, "unterminated quoted value of %s", name)
+				}
+				i++
+				body += "\n" + lines[i]
+			}
+			if q == '"' {
+				body = envFileUnescape(body)
+			} else {
+				body = strings.ReplaceAll(body, `'\''`, "'")
+			}
+			val = body
+		default:
+			for j := 1; j < len(val); j++ {
+				if val[j] == '#' && (val[j-1] == ' ' || val[j-1] == '\t') {
+					val = val[:j]
+					break
+				}
+			}
+			val = strings.TrimRight(val, " \t")
+		}
+
+		key := ""
+		if toKey != nil {
+			key = toKey(name)
+		}
+		if key == "" {
+			key = envToKey(name, DefaultSeparator)
+		}
+		segs := strings.Split(key, DefaultSeparator)
+		m, err := nestedMap(root, segs[:len(segs)-1])
+		if err == nil {
+			if _, ok := m[segs[len(segs)-1]].(map[string]interface{}); ok {
+				err = fmt.Errorf("%q has nested keys", key)
+			}
+		}
+		if err != nil {
+			return errorf(at, start, "cannot set %s: %v", name, err)
+		}
+		m[segs[len(segs)-1]] = val
+	}
+	*data.(*interface{}) = root
+	return nil
+}
+
+// envFileClosing returns the index of the closing quote in s, or -1. Escaped quotes are
+// skipped in double-quoted values, and in single-quoted ones the quotes that the shell
+// quoting of EnvEncodeFunc closes, escapes and reopens.
+func envFileClosing(s string, q byte) int {
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && q == '"':
+			i++
+		case q == '\'' && strings.HasPrefix(s[i:], `'\''`):
+			i += 3
+		case s[i] == q:
+			return i
+		}
+	}
+	return -1
+}
+
+// envFileUnescape replaces the escape sequences of a double-quoted value.
+func envFileUnescape(s string) string {
+	return strings.NewReplacer(`\n`, "\n", `\r`, "\r", `\t`, "\t", `\"`, `"`, `\\`, `\`).Replace(s)
+}
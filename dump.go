@@ -0,0 +1,232 @@
+package cconf
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// RegisterDumpFunc registers the function writing the data of the store to a file of the type.
+func (c *Conf) RegisterDumpFunc(typ string, fn dumpFunc) {
+	c.mu.Lock()
+	defer c.unlock()
+	c.DumpFuncs[typ] = fn
+}
+
+// RegisterEncodeFunc registers the function writing the data of the store to a writer in the type.
+func (c *Conf) RegisterEncodeFunc(typ string, fn encodeFunc) {
+	c.mu.Lock()
+	defer c.unlock()
+	c.EncodeFuncs[typ] = fn
+}
+
+// WriteTo writes a copy of the store to the writer in the type, like "json", with the encode
+// function of the type; an empty type means indented JSON. The values of the keys marked with
+// MarkSecret are redacted, so that the output can be served or logged. It returns the number
+// of bytes written, like io.WriterTo.
+func (c *Conf) WriteTo(w io.Writer, typ string) (int64, error) {
+	return c.writeTo(w, typ, func() interface{} {
+		return c.GetStoreCopy(true)
+	})
+}
+
+// WriteDiffTo is like WriteTo, but writes only the values that differ from the defaults
+// layer, see SaveDiff.
+func (c *Conf) WriteDiffTo(w io.Writer, typ string) (int64, error) {
+	return c.writeTo(w, typ, func() interface{} {
+		return c.storeDiff(true)
+	})
+}
+
+// writeTo writes the data to the writer with the encode function of the type.
+func (c *Conf) writeTo(w io.Writer, typ string, data func() interface{}) (int64, error) {
+	if typ == "" {
+		typ = "json"
+	}
+	c.mu.Lock()
+	fn, ok := c.EncodeFuncs[typ]
+	c.mu.Unlock()
+	if !ok {
+		return 0, &wrapError{"please register " + typ + " type encoding function", ErrUnknownType}
+	}
+	cw := &countingWriter{w: w}
+	err := fn(cw, data())
+	return cw.n, err
+}
+
+// countingWriter counts the bytes written to the writer.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
+
+// String returns the flattened store like Dump.
+func (c *Conf) String() string {
+	var b strings.Builder
+	c.Dump(&b)
+	return b.String()
+}
+
+// Dump writes the flattened store to the writer for debugging, e.g. at startup, as lines
+// "key = value (type)" sorted by key, with a trailing comment naming the source of the value
+// (see Source). The values of the keys marked with MarkSecret are redacted and strings are
+// quoted, so that values containing newlines stay on their line.
+func (c *Conf) Dump(w io.Writer) error {
+	var b strings.Builder
+	c.mu.Lock()
+	secrets := c.secretKeys()
+	leaves := make(map[string]interface{})
+	flatten(leaves, "", c.redact(c.snap.Load().store, "", secrets), c.Separator)
+	keys := make([]string, 0, len(leaves))
+	for k := range leaves {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		b.WriteString(k + " = ")
+		if v := leaves[k]; v == Redacted && c.isSecret(k, secrets) {
+			b.WriteString(Redacted)
+		} else {
+			b.WriteString(dumpValue(v) + " (" + fmt.Sprintf("%T", v) + ")")
+		}
+		if srcs := c.sourcesOf(k); len(srcs) > 0 {
+			b.WriteString("  # " + srcs[len(srcs)-1])
+		}
+		b.WriteByte('\n')
+	}
+	c.mu.Unlock()
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// dumpValue formats a leaf value of the store on a single line.
+func dumpValue(v interface{}) string {
+	switch d := v.(type) {
+	case string:
+		return strconv.Quote(d)
+	case map[string]interface{}:
+		return "{}"
+	case []interface{}:
+		return "[]"
+	}
+	s := fmt.Sprint(v)
+	if strings.ContainsAny(s, "\r\n") {
+		s = strconv.Quote(s)
+	}
+	return s
+}
+
+// Save writes a copy of the store (see GetStoreCopy) to the file with the dump function of
+// its extension, or else with the encode function of its extension. The data is written to
+// a temporary file in the same directory, which is then renamed to the file, so that the
+// file is never left partially written.
+func (c *Conf) Save(file string) error {
+	return c.save(file, func() interface{} {
+		return c.GetStoreCopy(false)
+	})
+}
+
+// SaveKey is like Save, but writes only the value at the key, like a section of the store,
+// which LoadInto can load again below the key. Formats like TOML require a map.
+func (c *Conf) SaveKey(key, file string) error {
+	store := c.snap.Load().store
+	v, ok := walk(store, key, c.Separator)
+	if !ok {
+		return &ConfigKeyError{Key: key, Message: "no configuration value was found", Err: ErrKeyNotFound, Segment: c.missingSegment(store, key)}
+	}
+	return c.save(file, func() interface{} {
+		return normalize(v)
+	})
+}
+
+// SaveDiff is like Save, but writes only the values that differ from the defaults layer
+// (see SetDefaults), as a nested document: maps are compared key by key and other values,
+// like lists, as a whole. Values equal to their defaults are omitted, even if they were
+// set explicitly; without defaults layer, the whole store is written.
+func (c *Conf) SaveDiff(file string) error {
+	return c.save(file, func() interface{} {
+		return c.storeDiff(false)
+	})
+}
+
+// save writes the data to the file with the dump or encode function of its extension.
+func (c *Conf) save(file string, data func() interface{}) error {
+	typ := strings.TrimLeft(filepath.Ext(file), ".")
+	c.mu.Lock()
+	fn, ok := c.DumpFuncs[typ]
+	if encode, found := c.EncodeFuncs[typ]; !ok && found {
+		fn = func(file string, data interface{}) error {
+			return dumpFile(file, data, encode)
+		}
+		ok = true
+	}
+	c.mu.Unlock()
+	if !ok {
+		return &wrapError{"please register " + typ + " type dumping function", ErrUnknownType}
+	}
+	return writeFile(file, func(tmp string) error {
+		return fn(tmp, data())
+	})
+}
+
+// dumpFile creates the file and writes the data to it with the encode function.
+func dumpFile(file string, data interface{}, encode encodeFunc) error {
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	if err := encode(f, data); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// writeFile writes the file atomically: write writes a temporary file in the same directory,
+// which replaces the file if write succeeds. The temporary file gets the mode of the file.
+// If the file already has the written contents, it is left untouched, keeping its mtime.
+func writeFile(file string, write func(tmp string) error) error {
+	f, err := os.CreateTemp(filepath.Dir(file), "."+filepath.Base(file)+".tmp*")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	err = f.Close()
+	if fi, serr := os.Stat(file); err == nil && serr == nil {
+		err = os.Chmod(tmp, fi.Mode().Perm())
+	}
+	if err == nil {
+		err = write(tmp)
+	}
+	if err == nil && sameContents(tmp, file) {
+		return os.Remove(tmp)
+	}
+	if err == nil {
+		err = os.Rename(tmp, file)
+	}
+	if err != nil {
+		os.Remove(tmp)
+	}
+	return err
+}
+
+// sameContents reports whether both files can be read and have the same contents.
+func sameContents(file1, file2 string) bool {
+	b1, err := os.ReadFile(file1)
+	if err != nil {
+		return false
+	}
+	b2, err := os.ReadFile(file2)
+	return err == nil && bytes.Equal(b1, b2)
+}
@@ -0,0 +1,238 @@
+package cconf
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestSave(t *testing.T) {
+	c := New()
+	if err := c.Load("./testdata/app.json"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Set("ext.site", "example.com"); err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	file := filepath.Join(dir, "app.json")
+	if err := os.WriteFile(file, []byte("{}"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Save(file); err != nil {
+		t.Fatal(err)
+	}
+	fi, err := os.Stat(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	equal(t, os.FileMode(0o600), fi.Mode().Perm())
+
+	loaded := New()
+	if err := loaded.Load(file); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, c.GetStore(), loaded.GetStore())
+
+	if err := c.Save(filepath.Join(dir, "app.xyz")); err == nil {
+		t.Fatal("expected an error")
+	}
+
+	// a failed dump leaves the file and no temporary file.
+	c.RegisterDumpFunc("json", func(file string, data interface{}) error {
+		if err := os.WriteFile(file, []byte("{"), 0o644); err != nil {
+			return err
+		}
+		return errors.New("dump failed")
+	})
+	if err := c.Save(file); err == nil {
+		t.Fatal("expected an error")
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	equal(t, 1, len(entries))
+	if err := loaded.Load(file); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWriteTo(t *testing.T) {
+	c := New()
+	if err := c.Load("./testdata/app.json"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Set("db.password", "secret"); err != nil {
+		t.Fatal(err)
+	}
+	c.MarkSecret("db.password")
+
+	var buf bytes.Buffer
+	n, err := c.WriteTo(&buf, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	equal(t, int64(buf.Len()), n)
+	var data interface{}
+	if err := json.Unmarshal(buf.Bytes(), &data); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, Redacted, data.(map[string]interface{})["db"].(map[string]interface{})["password"])
+	delete(data.(map[string]interface{}), "db")
+	loaded := New()
+	if err := loaded.Load("./testdata/app.json"); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, loaded.GetStore(), data)
+
+	if _, err := c.WriteTo(&buf, "xyz"); err == nil {
+		t.Fatal("expected an error")
+	}
+
+	// the output is consistent while the store changes.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			c.Set("counter.a", i)
+			c.Set("counter.b", i)
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		buf.Reset()
+		if _, err := c.WriteTo(&buf, "json"); err != nil {
+			t.Fatal(err)
+		}
+		var data struct{ Counter struct{ A, B *int } }
+		if err := json.Unmarshal(buf.Bytes(), &data); err != nil {
+			t.Fatal(err)
+		}
+		if a, b := data.Counter.A, data.Counter.B; a != nil && b != nil && *a != *b && *a != *b+1 {
+			t.Fatalf("torn read: %d, %d", *a, *b)
+		}
+	}
+	<-done
+}
+
+var update = flag.Bool("update", false, "update the golden files")
+
+func TestDump(t *testing.T) {
+	c := New()
+	if err := c.Load("./testdata/app.json"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Set("ext.bio", "line 1\nline 2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Set("ext.langs", []interface{}{"go", "c"}); err != nil {
+		t.Fatal(err)
+	}
+	c.MarkSecret("ext.email")
+
+	var buf bytes.Buffer
+	if err := c.Dump(&buf); err != nil {
+		t.Fatal(err)
+	}
+	golden := "./testdata/app.dump"
+	if *update {
+		if err := os.WriteFile(golden, buf.Bytes(), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	equal(t, string(want), buf.String())
+	equal(t, buf.String(), c.String())
+}
+
+func TestSaveCanonical(t *testing.T) {
+	store := map[string]interface{}{"million": 1e6, "count": 1000000, "big": 1e21, "small": 1.5e-7}
+	for i := 0; i < 20; i++ {
+		store["key"+strconv.Itoa(i)] = map[string]interface{}{"b": i, "a": []interface{}{float64(i), "x"}}
+	}
+	c := New()
+	c.SetStore(store)
+	clone := c.Clone()
+	dir := t.TempDir()
+	for _, typ := range []string{"json", "yaml", "toml"} {
+		file := filepath.Join(dir, "app."+typ)
+		var want []byte
+		for i := 0; i < 100; i++ {
+			conf := c
+			if i%2 == 1 {
+				conf = clone
+			}
+			if err := conf.Save(file); err != nil {
+				t.Fatal(err)
+			}
+			b, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want == nil {
+				want = b
+			} else if !bytes.Equal(want, b) {
+				t.Fatalf("%s dump %d differs:\n%s\nwant:\n%s", typ, i, b, want)
+			}
+		}
+		out := string(want)
+		equal(t, true, strings.HasSuffix(out, "\n"))
+		equal(t, false, strings.Contains(out, "e+06"))
+		equal(t, true, strings.Contains(out, "1000000"))
+		equal(t, true, strings.Contains(out, "1e+21"))
+		equal(t, true, strings.Contains(out, "1.5e-7"))
+	}
+}
+
+func TestSaveKey(t *testing.T) {
+	c := New()
+	if err := c.Load("./testdata/app.json"); err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	for _, typ := range []string{"json", "yaml", "toml"} {
+		file := filepath.Join(dir, "ext."+typ)
+		if err := c.SaveKey("ext", file); err != nil {
+			t.Fatal(err)
+		}
+		if typ != "json" {
+			continue
+		}
+		fresh := New()
+		if err := fresh.LoadInto("section.ext", file); err != nil {
+			t.Fatal(err)
+		}
+		equal(t, c.Get("ext"), fresh.Get("section.ext"))
+		equal(t, file, fresh.Source("section.ext.author"))
+	}
+
+	if err := c.SaveKey("name", filepath.Join(dir, "name.json")); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(filepath.Join(dir, "name.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	equal(t, "\"cconf\"\n", string(b))
+
+	err = c.SaveKey("ext.missing", filepath.Join(dir, "missing.json"))
+	var ke *ConfigKeyError
+	if !errors.As(err, &ke) || ke.Key != "ext.missing" {
+		t.Fatalf("expected a ConfigKeyError, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "missing.json")); !os.IsNotExist(err) {
+		t.Fatal("expected no file")
+	}
+	if err := c.SaveKey("name", filepath.Join(dir, "name.toml")); err == nil {
+		t.Fatal("expected an error")
+	}
+}
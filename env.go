@@ -0,0 +1,339 @@
+package cconf
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// LoadEnv loads the environment variables whose names start with prefix (e.g. "MYAPP_")
+// and merges them into the store like any other source, so that loading the environment
+// after files overrides them.
+//
+// The prefix is stripped and the rest of the name is lowercased, with single underscores
+// separating the segments of the key and double underscores standing for a literal
+// underscore: MYAPP_DB_HOST sets "db.host" and MYAPP_DB_POOL__SIZE sets "db.pool_size".
+// The values are stored as strings. Unlike strings of other sources, the typed getters and
+// Populate parse them into numbers, booleans, durations and comma-separated lists, see parseEnv.
+func (c *Conf) LoadEnv(prefix string) error {
+	c.mu.Lock()
+	defer c.unlock()
+	var names map[string]string
+	return c.loadSources(source{name: "env:" + prefix, fetch: func() (interface{}, error) {
+		data, n, err := c.readEnv(prefix)
+		names = n
+		return data, err
+	}, origin: func(key string) string {
+		return envOrigin(names[key])
+	}})
+}
+
+// envOrigin returns the source name of the environment variable, see Sources. The values
+// of such sources are parsed by the typed getters and Populate, see fromEnv.
+func envOrigin(name string) string {
+	return "env:" + name
+}
+
+// isEnvOrigin reports whether the source name is that of an environment variable.
+func isEnvOrigin(source string) bool {
+	return strings.HasPrefix(source, "env:")
+}
+
+// readEnv returns the environment variables with the prefix as configuration data and the
+// names of the variables by key. The caller must hold c.mu.
+func (c *Conf) readEnv(prefix string) (interface{}, map[string]string, error) {
+	env := os.Environ()
+	sort.Strings(env)
+	var data interface{} = make(map[string]interface{})
+	names := make(map[string]string)
+	for _, kv := range env {
+		name, val, _ := strings.Cut(kv, "=")
+		if !strings.HasPrefix(name, prefix) || len(name) == len(prefix) {
+			continue
+		}
+		key := c.env.key(name[len(prefix):], c.Separator)
+		var err error
+		if data, err = c.set(data, strings.Split(key, c.Separator), 0, val); err != nil {
+			return nil, nil, err
+		}
+		names[key] = name
+	}
+	return data, names, nil
+}
+
+// parseEnv parses a string from the environment into the type t: numbers, booleans and
+// durations like "1m30s" are parsed, and slices from comma-separated elements.
+func parseEnv(s string, t reflect.Type) (interface{}, error) {
+	v := reflect.New(t).Elem()
+	if err := parseEnvValue(s, v); err != nil {
+		return nil, err
+	}
+	return v.Interface(), nil
+}
+
+// parseEnvValue parses the string into v, see parseEnv.
+func parseEnvValue(s string, v reflect.Value) error {
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Interface:
+		if v.NumMethod() > 0 {
+			return fmt.Errorf("cannot parse %q as %v", s, v.Type())
+		}
+		v.Set(reflect.ValueOf(s))
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		var elems []string
+		if s != "" {
+			elems = strings.Split(s, ",")
+		}
+		sv := reflect.MakeSlice(v.Type(), len(elems), len(elems))
+		for i, e := range elems {
+			if err := parseEnvValue(strings.TrimSpace(e), sv.Index(i)); err != nil {
+				return err
+			}
+		}
+		v.Set(sv)
+	default:
+		return fmt.Errorf("cannot parse %q as %v", s, v.Type())
+	}
+	return nil
+}
+
+// envToKey converts an environment variable name without its prefix to a configuration key.
+func envToKey(name, sep string) string {
+	segs := strings.Split(strings.ToLower(name), "__")
+	for i, seg := range segs {
+		segs[i] = strings.ReplaceAll(seg, "_", sep)
+	}
+	return strings.Join(segs, "_")
+}
+
+// keyToEnv converts a configuration key to an environment variable name without prefix,
+// as the inverse of envToKey.
+func keyToEnv(key, sep string) string {
+	segs := strings.Split(key, sep)
+	for i, seg := range segs {
+		segs[i] = strings.ReplaceAll(seg, "_", "__")
+	}
+	return strings.ToUpper(strings.Join(segs, "_"))
+}
+
+// EnvMap flattens the store into environment variables, as the inverse of LoadEnv with the prefix:
+// the key "db.host" becomes the variable PREFIX_DB_HOST for the prefix "PREFIX_".
+// Scalar values are stringified, slices are joined with commas and maps are flattened.
+// Characters that are not allowed in variable names are replaced with underscores.
+func (c *Conf) EnvMap(prefix string) map[string]string {
+	s := c.snap.Load()
+	vars := make(map[string]string)
+	c.envVars(vars, s.env, prefix, "", s.store)
+	return vars
+}
+
+// EnvSlice returns the variables of EnvMap as sorted "NAME=value" pairs, like exec.Cmd.Env.
+func (c *Conf) EnvSlice(prefix string) []string {
+	vars := c.EnvMap(prefix)
+	env := make([]string, 0, len(vars))
+	for name, v := range vars {
+		env = append(env, name+"="+v)
+	}
+	sort.Strings(env)
+	return env
+}
+
+// envVars adds the variables of the node at the key to vars.
+func (c *Conf) envVars(vars map[string]string, env *envConfig, prefix, key string, node interface{}) {
+	switch n := node.(type) {
+	case nil:
+		return
+	case map[string]interface{}:
+		for k, v := range n {
+			c.envVars(vars, env, prefix, c.join(key, k), v)
+		}
+		return
+	}
+	if key == "" {
+		return
+	}
+	v := stringify(node)
+	if s, ok := node.([]interface{}); ok {
+		elems := make([]string, len(s))
+		for i, e := range s {
+			elems[i] = stringify(e)
+		}
+		v = strings.Join(elems, ",")
+	}
+	vars[prefix+envName(env.name(key, c.Separator))] = v
+}
+
+// envName replaces the characters that are not allowed in environment variable names with underscores.
+func envName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r < utf8.RuneSelf && isNameByte(byte(r), false) {
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+// envConfig holds the settings of the environment fallback of Get.
+// A published envConfig is never modified.
+type envConfig struct {
+	prefix string // see AutomaticEnv
+	auto   bool
+	toEnv  func(key string) string // see SetEnvKeyReplacer
+	toKey  func(name string) string
+	binds  map[string][]string // see BindEnv
+}
+
+// AutomaticEnv makes Get consult the environment for keys missing from the store:
+// the key "db.host" is looked up as the variable PREFIX_DB_HOST (or DB_HOST for an empty prefix),
+// using the reverse of the name mapping of LoadEnv. Environment values are returned as strings
+// and are never written into the store. Values of the store take precedence, defaults come last.
+func (c *Conf) AutomaticEnv(prefix string) {
+	c.mu.Lock()
+	defer c.unlock()
+	if c.frozen {
+		return
+	}
+	env := c.envConfig()
+	env.prefix = prefix
+	env.auto = true
+	c.env = env
+	c.republish()
+}
+
+// envConfig returns a copy of the environment settings to be modified.
+// The caller must hold c.mu.
+func (c *Conf) envConfig() *envConfig {
+	if c.env == nil {
+		return &envConfig{}
+	}
+	env := *c.env
+	return &env
+}
+
+// lookup returns the value of the environment variable for the key.
+// Bound variables take precedence over AutomaticEnv.
+func (e *envConfig) lookup(key, sep string) (interface{}, bool) {
+	if e == nil {
+		return nil, false
+	}
+	if names, ok := e.binds[key]; ok {
+		for _, name := range names {
+			if v := os.Getenv(name); v != "" {
+				return v, true
+			}
+		}
+	}
+	if !e.auto {
+		return nil, false
+	}
+	if v, ok := os.LookupEnv(e.prefixed(e.name(key, sep))); ok {
+		return v, true
+	}
+	return nil, false
+}
+
+// prefixed returns the environment variable name with the AutomaticEnv prefix.
+func (e *envConfig) prefixed(name string) string {
+	if e.prefix != "" {
+		return e.prefix + "_" + name
+	}
+	return name
+}
+
+// BindEnv binds the key to the environment variables: when the store has no value for the key,
+// Get returns the first non-empty one of them. Without variables, the key is bound to the
+// variable named as by AutomaticEnv. Values of the store take precedence, defaults come last.
+// The bindings are kept across loads.
+func (c *Conf) BindEnv(key string, envVars ...string) {
+	c.mu.Lock()
+	defer c.unlock()
+	if c.frozen {
+		return
+	}
+	env := c.envConfig()
+	if len(envVars) == 0 {
+		envVars = []string{env.prefixed(env.name(key, c.Separator))}
+	}
+	binds := make(map[string][]string, len(env.binds)+1)
+	for k, v := range env.binds {
+		binds[k] = v
+	}
+	binds[key] = append([]string(nil), envVars...)
+	env.binds = binds
+	c.env = env
+	c.republish()
+}
+
+// SetEnvKeyReplacer customizes the translation between configuration keys and environment
+// variable names (both without prefix) used by LoadEnv and AutomaticEnv:
+// toEnv maps a key to a variable name, and toKey maps a variable name to a key.
+// When a function is nil or returns an empty string, the default mapping applies, so that
+// the functions only need to handle special cases, like aliases or keys containing dashes.
+func (c *Conf) SetEnvKeyReplacer(toEnv func(key string) string, toKey func(name string) string) {
+	c.mu.Lock()
+	defer c.unlock()
+	env := c.envConfig()
+	env.toEnv = toEnv
+	env.toKey = toKey
+	c.env = env
+	c.republish()
+}
+
+// name returns the environment variable name without prefix of the key.
+func (e *envConfig) name(key, sep string) string {
+	if e != nil && e.toEnv != nil {
+		if name := e.toEnv(key); name != "" {
+			return name
+		}
+	}
+	return keyToEnv(key, sep)
+}
+
+// key returns the key of the environment variable name without prefix.
+func (e *envConfig) key(name, sep string) string {
+	if e != nil && e.toKey != nil {
+		if key := e.toKey(name); key != "" {
+			return key
+		}
+	}
+	return envToKey(name, sep)
+}
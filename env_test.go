@@ -0,0 +1,235 @@
+package cconf
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadEnv(t *testing.T) {
+	t.Setenv("CCONFTEST_NAME", "from-env")
+	t.Setenv("CCONFTEST_EXT_EMAIL", "env@example.com")
+	t.Setenv("CCONFTEST_DB_POOL__SIZE", "10")
+	t.Setenv("OTHER_NAME", "ignored")
+
+	c := New()
+	if err := c.Load("./testdata/app.json"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.LoadEnv("CCONFTEST_"); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, "from-env", c.GetString("name"))
+	equal(t, "env@example.com", c.GetString("ext.email"))
+	equal(t, "syyong.x", c.GetString("ext.author"))
+	equal(t, "10", c.Get("db.pool_size"))
+	equal(t, 0.1, c.Get("version"))
+
+	equal(t, "DB_POOL__SIZE", keyToEnv("db.pool_size", "."))
+	equal(t, "db.pool_size", envToKey(keyToEnv("db.pool_size", "."), "."))
+
+	t.Setenv("CCONFTEST_NAME_FIRST", "conflict")
+	if err := c.LoadEnv("CCONFTEST_"); err == nil {
+		t.Error("Expected an error for conflicting variables")
+	}
+}
+
+func TestAutomaticEnv(t *testing.T) {
+	c := New()
+	if err := c.Load("./testdata/app.json"); err != nil {
+		t.Fatal(err)
+	}
+	c.AutomaticEnv("CCONFTEST")
+	t.Setenv("CCONFTEST_DB_HOST", "db.local")
+	t.Setenv("CCONFTEST_NAME", "ignored")
+
+	equal(t, "db.local", c.Get("db.host"))
+	equal(t, "db.local", c.GetString("db.host", "localhost"))
+	// the store takes precedence over the environment.
+	equal(t, "cconf", c.GetString("name"))
+	// misses fall back to the default.
+	equal(t, "3306", c.GetString("db.port", "3306"))
+	equal(t, nil, c.Get("db"))
+
+	// variables exported after a miss are visible.
+	t.Setenv("CCONFTEST_DB_PORT", "5432")
+	equal(t, "5432", c.GetString("db.port", "3306"))
+
+	// environment values are not written into the store.
+	equal(t, nil, c.GetStore().(map[string]interface{})["db"])
+}
+
+func TestSetEnvKeyReplacer(t *testing.T) {
+	t.Setenv("CCONFTEST_DB_URL", "postgres://db")
+	t.Setenv("CCONFTEST_HTTP_READ_TIMEOUT", "5s")
+
+	c := New()
+	c.SetEnvKeyReplacer(func(key string) string {
+		switch key {
+		case "database.url":
+			return "DB_URL"
+		case "http.read-timeout":
+			return "HTTP_READ_TIMEOUT"
+		}
+		return ""
+	}, func(name string) string {
+		switch name {
+		case "DB_URL":
+			return "database.url"
+		case "HTTP_READ_TIMEOUT":
+			return "http.read-timeout"
+		}
+		return ""
+	})
+
+	c.AutomaticEnv("CCONFTEST")
+	equal(t, "postgres://db", c.GetString("database.url"))
+	equal(t, "5s", c.GetString("http.read-timeout"))
+
+	if err := c.LoadEnv("CCONFTEST_"); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, map[string]interface{}{
+		"database": map[string]interface{}{"url": "postgres://db"},
+		"http":     map[string]interface{}{"read-timeout": "5s"},
+	}, c.GetStore())
+}
+
+func TestBindEnv(t *testing.T) {
+	t.Setenv("CCONFTEST_DATABASE_URL", "")
+	t.Setenv("CCONFTEST_DB_URL", "postgres://db")
+	t.Setenv("CCONFTEST_PORT", "9090")
+
+	c := New()
+	c.BindEnv("database.url", "CCONFTEST_DATABASE_URL", "CCONFTEST_DB_URL")
+	c.BindEnv("server.Port", "CCONFTEST_PORT")
+	c.BindEnv("missing", "CCONFTEST_MISSING")
+	equal(t, "postgres://db", c.Get("database.url"))
+	equal(t, "9090", c.Get("server.Port"))
+	equal(t, "default", c.GetString("missing", "default"))
+
+	// values of the store take precedence, the bindings survive loads.
+	if err := c.Load("testdata/server.json"); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, 8080, c.GetInt("server.Port"))
+	equal(t, "postgres://db", c.Get("database.url"))
+}
+
+func TestEnvMap(t *testing.T) {
+	c := New()
+	c.SetStore(map[string]interface{}{
+		"name":  "app",
+		"db":    map[string]interface{}{"host": "localhost", "pool_size": 10, "ssl": true},
+		"hosts": []interface{}{"a", "b"},
+		"http":  map[string]interface{}{"read-timeout": "5s", "ratio": 0.5},
+		"none":  nil,
+	})
+	vars := c.EnvMap("CCONFTEST_")
+	equal(t, map[string]string{
+		"CCONFTEST_NAME":              "app",
+		"CCONFTEST_DB_HOST":           "localhost",
+		"CCONFTEST_DB_POOL__SIZE":     "10",
+		"CCONFTEST_DB_SSL":            "true",
+		"CCONFTEST_HOSTS":             "a,b",
+		"CCONFTEST_HTTP_READ_TIMEOUT": "5s",
+		"CCONFTEST_HTTP_RATIO":        "0.5",
+	}, vars)
+	equal(t, []string{"CCONFTEST_DB_HOST=localhost", "CCONFTEST_DB_POOL__SIZE=10"}, c.EnvSlice("CCONFTEST_")[:2])
+
+	// the variables load back into the same keys.
+	for name, v := range vars {
+		t.Setenv(name, v)
+	}
+	l := New()
+	if err := l.LoadEnv("CCONFTEST_"); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, "app", l.GetString("name"))
+	equal(t, "localhost", l.GetString("db.host"))
+	equal(t, "10", l.GetString("db.pool_size"))
+	equal(t, "true", l.GetString("db.ssl"))
+	equal(t, "a,b", l.GetString("hosts"))
+	equal(t, "0.5", l.GetString("http.ratio"))
+	equal(t, vars, l.EnvMap("CCONFTEST_"))
+}
+
+func TestEnvTypes(t *testing.T) {
+	t.Setenv("CCONFTEST_SERVER_PORT", "8080")
+	t.Setenv("CCONFTEST_SERVER_DEBUG", "true")
+	t.Setenv("CCONFTEST_SERVER_TIMEOUT", "5s")
+	t.Setenv("CCONFTEST_SERVER_HOSTS", "a, b")
+	t.Setenv("CCONFTEST_SERVER_RATIO", "0.5")
+	t.Setenv("CCONFTEST_LIMIT", "10")
+
+	type server struct {
+		Port    int
+		Debug   bool
+		Timeout time.Duration
+		Hosts   []string
+		Ratio   float64
+	}
+
+	c := New()
+	c.SetEnvKeyReplacer(nil, func(name string) string {
+		return strings.Replace(strings.ToLower(name), "_", ".", 1)
+	})
+	if err := c.LoadEnv("CCONFTEST_"); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, "8080", c.Get("server.port"))
+	equal(t, 8080, c.GetInt("server.port"))
+	equal(t, int64(8080), c.GetInt64("server.port"))
+	equal(t, 8080, c.Get("server.port", 0))
+	equal(t, true, c.GetBool("server.debug"))
+	equal(t, 0.5, c.GetFloat("server.ratio"))
+
+	var s server
+	m := New()
+	m.SetEnvKeyReplacer(nil, func(name string) string {
+		return map[string]string{"SERVER_PORT": "server.Port", "SERVER_DEBUG": "server.Debug",
+			"SERVER_TIMEOUT": "server.Timeout", "SERVER_HOSTS": "server.Hosts", "SERVER_RATIO": "server.Ratio"}[name]
+	})
+	if err := m.LoadEnv("CCONFTEST_"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Populate(&s, "server"); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, server{8080, true, 5 * time.Second, []string{"a", "b"}, 0.5}, s)
+
+	// strings of files stay strict.
+	f := New()
+	if err := f.LoadReader("json", strings.NewReader(`{"server": {"Port": "8080"}}`)); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, 0, f.GetInt("server.Port"))
+	if err := f.Populate(&s, "server"); err == nil {
+		t.Fatal("expected an error")
+	}
+	// even if a file sets the value loaded from the environment again.
+	if err := m.LoadReader("json", strings.NewReader(`{"server": {"Port": "8080"}}`)); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, "reader:json", m.Source("server.Port"))
+	equal(t, 0, m.GetInt("server.Port"))
+	if err := m.Populate(&s, "server"); err == nil {
+		t.Fatal("expected an error")
+	}
+	if err := m.LoadEnv("CCONFTEST_"); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, 8080, m.GetInt("server.Port"))
+
+	// values of the environment fallbacks are parsed as well.
+	a := New()
+	a.AutomaticEnv("CCONFTEST")
+	a.BindEnv("port", "CCONFTEST_SERVER_PORT")
+	equal(t, 10, a.GetInt("limit"))
+	equal(t, 8080, a.GetInt("port"))
+	var port int
+	if err := a.Populate(&port, "port"); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, 8080, port)
+}
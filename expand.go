@@ -0,0 +1,235 @@
+package cconf
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// UnsetPolicy tells how references to unset environment variables are expanded, see ExpandEnv.
+type UnsetPolicy int
+
+// unset policies
+const (
+	UnsetKeep  UnsetPolicy = iota // keep the reference as is
+	UnsetEmpty                    // replace the reference with an empty string
+	UnsetError                    // fail the load
+)
+
+// expandFunc returns the value of the referenced name and whether it is set.
+type expandFunc func(name string) (string, bool, error)
+
+// expand replaces the ${NAME} and $NAME references in s with the values returned by lookup,
+// and $$ with a single $.
+// A reference ${NAME:-default} expands to the expanded default if NAME is not set or empty.
+// Other references which are not set are expanded with unset, or kept as is if unset is nil
+// or reports that it has no value either.
+func expand(s string, lookup, unset expandFunc) (string, error) {
+	if strings.IndexByte(s, '$') < 0 {
+		return s, nil
+	}
+	var b strings.Builder
+	for {
+		i := strings.IndexByte(s, '$')
+		if i < 0 || i == len(s)-1 {
+			b.WriteString(s)
+			return b.String(), nil
+		}
+		b.WriteString(s[:i])
+		s = s[i+1:]
+
+		var name, ref, def string
+		hasDef := false
+		switch s[0] {
+		case '$':
+			b.WriteByte('$')
+			s = s[1:]
+			continue
+		case '{':
+			j := closingBrace(s)
+			if j < 0 {
+				b.WriteByte('$')
+				continue
+			}
+			name, ref = s[1:j], s[:j+1]
+			if k := strings.Index(name, ":-"); k >= 0 {
+				name, def, hasDef = name[:k], name[k+2:], true
+			}
+		default:
+			j := 0
+			for j < len(s) && isNameByte(s[j], j == 0) {
+				j++
+			}
+			if j == 0 {
+				b.WriteByte('$')
+				continue
+			}
+			name, ref = s[:j], s[:j]
+		}
+		s = s[len(ref):]
+
+		v, ok, err := lookup(name)
+		if err == nil && hasDef && (!ok || v == "") {
+			ok = true
+			v, err = expand(def, lookup, unset)
+		}
+		if err == nil && !ok && unset != nil {
+			v, ok, err = unset(name)
+		}
+		if err != nil {
+			return "", err
+		}
+		if !ok {
+			v = "$" + ref
+		}
+		b.WriteString(v)
+	}
+}
+
+// closingBrace returns the index of the brace closing the one at the start of s, or -1.
+// Nested braces, like those of nested or escaped references, are skipped.
+func closingBrace(s string) int {
+	depth := 0
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '{':
+			depth++
+		case '}':
+			if depth == 0 {
+				return i
+			}
+			depth--
+		}
+	}
+	return -1
+}
+
+// isNameByte reports whether c may be part of a $NAME reference.
+func isNameByte(c byte, first bool) bool {
+	return c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || !first && '0' <= c && c <= '9'
+}
+
+// interpolator resolves the references in the string values of data being loaded.
+// A reference names a key of the data or of the store if Interpolate is enabled,
+// and an environment variable if ExpandEnv is enabled; keys take precedence.
+//
+// References to keys of the data are resolved depth-first, chasing chains of references:
+// the keys on the path being resolved are tracked to report cycles, and the resolved values
+// are memoized, so that every value is resolved once per load. The data itself is left raw.
+type interpolator struct {
+	c         *Conf
+	data      interface{}          // the merged raw data of the sources being loaded
+	store     interface{}          // the other values, which are already resolved
+	resolved  map[string][2]string // the string values of data and their resolved values by key
+	chain     []string             // keys being resolved, in order
+	resolving map[string]bool      // keys of the chain
+}
+
+// newInterpolator returns an interpolator resolving references against the normalized data
+// and then the store. The caller must hold c.mu.
+func (c *Conf) newInterpolator(data, store interface{}) *interpolator {
+	return &interpolator{c: c, data: data, store: store,
+		resolved: make(map[string][2]string), resolving: make(map[string]bool)}
+}
+
+// values resolves the string values below the node at the key.
+func (in *interpolator) values(node interface{}, key string) (interface{}, error) {
+	return in.c.mapStrings(node, key, in.value)
+}
+
+// value resolves the string value s at the key of the data.
+func (in *interpolator) value(key, s string) (string, error) {
+	// a value overridden by a later source is resolved on its own.
+	if v, ok := in.resolved[key]; ok && v[0] == s {
+		return v[1], nil
+	}
+	if in.resolving[key] {
+		i := len(in.chain) - 1
+		for in.chain[i] != key {
+			i--
+		}
+		chain := append(in.chain[i:len(in.chain):len(in.chain)], key)
+		return "", &ConfigValueError{Key: in.chain[0], Message: "reference cycle " + strings.Join(chain, " -> ")}
+	}
+	in.chain = append(in.chain, key)
+	in.resolving[key] = true
+	v, err := expand(s, in.lookup, in.unset)
+	in.chain = in.chain[:len(in.chain)-1]
+	delete(in.resolving, key)
+	if err != nil {
+		return "", err
+	}
+	in.resolved[key] = [2]string{s, v}
+	return v, nil
+}
+
+// lookup returns the value of the referenced name.
+func (in *interpolator) lookup(name string) (string, bool, error) {
+	if in.c.Interpolate {
+		if v, ok := walk(in.data, name, in.c.Separator); ok {
+			if s, ok := v.(string); ok {
+				v, err := in.value(name, s)
+				return v, err == nil, err
+			}
+			// resolve the strings of a copy of a referenced map or slice before formatting it.
+			v, err := in.values(normalize(v), name)
+			if err != nil {
+				return "", false, err
+			}
+			return stringify(v), true, nil
+		}
+		if v, ok := walk(in.store, name, in.c.Separator); ok {
+			return stringify(v), true, nil
+		}
+	}
+	if in.c.ExpandEnv {
+		v, ok := os.LookupEnv(name)
+		return v, ok, nil
+	}
+	return "", false, nil
+}
+
+// unset expands the reference to the name that is neither a key nor a set variable.
+func (in *interpolator) unset(name string) (string, bool, error) {
+	if in.c.ExpandEnv {
+		return in.c.unsetEnv(name)
+	}
+	return "", false, nil
+}
+
+// stringify formats a referenced value: scalars in their usual text form, maps and slices as JSON.
+func stringify(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case map[string]interface{}, []interface{}:
+		if b, err := json.Marshal(v); err == nil {
+			return string(b)
+		}
+	}
+	return fmt.Sprint(v)
+}
+
+// join joins the key and the segment with the separator.
+func (c *Conf) join(key, seg string) string {
+	if key == "" {
+		return seg
+	}
+	return key + c.Separator + seg
+}
+
+// unsetEnv expands the reference to the unset environment variable according to the UnsetEnv policy.
+func (c *Conf) unsetEnv(name string) (string, bool, error) {
+	switch c.UnsetEnv {
+	case UnsetEmpty:
+		return "", true, nil
+	case UnsetError:
+		return "", false, errors.New("environment variable " + name + " is not set")
+	}
+	return "", false, nil
+}
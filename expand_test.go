@@ -0,0 +1,301 @@
+package cconf
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExpandEnv(t *testing.T) {
+	t.Setenv("CCONFTEST_PASSWORD", "secret")
+	t.Setenv("CCONFTEST_EMPTY", "")
+	data := `{
+		"dsn": "postgres://app:${CCONFTEST_PASSWORD}@db:5432/app",
+		"short": "$CCONFTEST_PASSWORD!",
+		"empty": "[${CCONFTEST_EMPTY}]",
+		"price": "$$5 and $$CCONFTEST_PASSWORD",
+		"list": ["${CCONFTEST_UNSET}", "$CCONFTEST_UNSET/x"]
+	}`
+
+	load := func(policy UnsetPolicy) (*Conf, error) {
+		c := New()
+		c.ExpandEnv = true
+		c.UnsetEnv = policy
+		return c, c.LoadReader("json", strings.NewReader(data))
+	}
+
+	c, err := load(UnsetKeep)
+	if err != nil {
+		t.Fatal(err)
+	}
+	equal(t, "postgres://app:secret@db:5432/app", c.GetString("dsn"))
+	equal(t, "secret!", c.GetString("short"))
+	equal(t, "[]", c.GetString("empty"))
+	equal(t, "$5 and $CCONFTEST_PASSWORD", c.GetString("price"))
+	equal(t, "${CCONFTEST_UNSET}", c.GetString("list.0"))
+	equal(t, "$CCONFTEST_UNSET/x", c.GetString("list.1"))
+
+	c, err = load(UnsetEmpty)
+	if err != nil {
+		t.Fatal(err)
+	}
+	equal(t, "", c.GetString("list.0"))
+	equal(t, "/x", c.GetString("list.1"))
+
+	c, err = load(UnsetError)
+	if err == nil || !strings.Contains(err.Error(), "CCONFTEST_UNSET") || !strings.Contains(err.Error(), `"list.0"`) {
+		t.Fatalf("unexpected error %v", err)
+	}
+	equal(t, nil, c.GetStore())
+
+	// expansion is opt-in.
+	c = New()
+	if err := c.LoadReader("json", strings.NewReader(data)); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, "$$5 and $$CCONFTEST_PASSWORD", c.GetString("price"))
+}
+
+func TestExpand(t *testing.T) {
+	fn := func(name string) (string, bool, error) {
+		return "<" + name + ">", name != "unset", nil
+	}
+	for s, expected := range map[string]string{
+		"":               "",
+		"plain":          "plain",
+		"$":              "$",
+		"a$":             "a$",
+		"$$":             "$",
+		"$1":             "$1",
+		"${a}${b}":       "<a><b>",
+		"$a.$b_1-c":      "<a>.<b_1>-c",
+		"${unterminated": "${unterminated",
+		"${unset}$unset": "${unset}$unset",
+	} {
+		actual, err := expand(s, fn, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		equal(t, expected, actual)
+	}
+}
+
+func TestInterpolate(t *testing.T) {
+	t.Setenv("CCONFTEST_PASSWORD", "secret")
+	c := New()
+	c.Interpolate = true
+	c.ExpandEnv = true
+	if err := c.LoadReader("json", strings.NewReader(`{"http": {"host": "example.com", "port": 8080}}`)); err != nil {
+		t.Fatal(err)
+	}
+	err := c.LoadReader("json", strings.NewReader(`{
+		"url": "${base}/api",
+		"base": "http://${http.host}:${http.port}",
+		"api": {"urls": ["${url}/v1", "${api.version}"], "version": true},
+		"limits": "${limits2}",
+		"limits2": {"conns": 10, "hosts": ["a"]},
+		"dsn": "app:${CCONFTEST_PASSWORD}@${http.host}",
+		"unknown": "${no.such.key}"
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	equal(t, "http://example.com:8080", c.GetString("base"))
+	equal(t, "http://example.com:8080/api", c.GetString("url"))
+	equal(t, "http://example.com:8080/api/v1", c.GetString("api.urls.0"))
+	equal(t, "true", c.GetString("api.urls.1"))
+	equal(t, `{"conns":10,"hosts":["a"]}`, c.GetString("limits"))
+	equal(t, "app:secret@example.com", c.GetString("dsn"))
+	equal(t, "${no.such.key}", c.GetString("unknown"))
+
+	c = New()
+	c.Interpolate = true
+	err = c.LoadReader("json", strings.NewReader(`{"a": "${b}", "b": "x${c}", "c": "${a}", "d": "${CCONFTEST_PASSWORD}"}`))
+	if err == nil || !strings.Contains(err.Error(), "reference cycle") {
+		t.Fatalf("unexpected error %v", err)
+	}
+	found := false
+	for _, chain := range []string{"a -> b -> c -> a", "b -> c -> a -> b", "c -> a -> b -> c"} {
+		found = found || strings.Contains(err.Error(), chain)
+	}
+	if !found {
+		t.Fatalf("the error %v does not name the chain", err)
+	}
+	equal(t, nil, c.GetStore())
+
+	// environment variables are only expanded with ExpandEnv.
+	if err := c.LoadReader("json", strings.NewReader(`{"d": "${CCONFTEST_PASSWORD}"}`)); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, "${CCONFTEST_PASSWORD}", c.GetString("d"))
+}
+
+func TestExpandDefault(t *testing.T) {
+	t.Setenv("CCONFTEST_PORT", "9090")
+	t.Setenv("CCONFTEST_EMPTY", "")
+	t.Setenv("CCONFTEST_DEFAULT_PORT", "7070")
+	c := New()
+	c.ExpandEnv = true
+	c.Interpolate = true
+	c.UnsetEnv = UnsetError
+	err := c.LoadReader("json", strings.NewReader(`{
+		"set": "${CCONFTEST_PORT:-8080}",
+		"unset": "${CCONFTEST_UNSET:-8080}",
+		"empty": "${CCONFTEST_EMPTY:-8080}",
+		"nested": "${CCONFTEST_UNSET:-${CCONFTEST_DEFAULT_PORT}}",
+		"nested2": "${CCONFTEST_UNSET:-${CCONFTEST_UNSET2:-${set}}}",
+		"literal": "${CCONFTEST_UNSET:-a:-b}",
+		"blank": "${CCONFTEST_UNSET:-}",
+		"key": "${http.port:-${set}}",
+		"http": {"host": "${http.name:-localhost}"}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	equal(t, "9090", c.GetString("set"))
+	equal(t, "8080", c.GetString("unset"))
+	equal(t, "8080", c.GetString("empty"))
+	equal(t, "7070", c.GetString("nested"))
+	equal(t, "9090", c.GetString("nested2"))
+	equal(t, "a:-b", c.GetString("literal"))
+	equal(t, "", c.GetString("blank"))
+	equal(t, "9090", c.GetString("key"))
+	equal(t, "localhost", c.GetString("http.host"))
+
+	// without a default, the unset policy applies.
+	err = c.LoadReader("json", strings.NewReader(`{"port": "${CCONFTEST_UNSET}"}`))
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func TestExpandEscape(t *testing.T) {
+	t.Setenv("CCONFTEST_HOME", "/home/app")
+	data := `{
+		"shell": "echo $${HOME} $$CCONFTEST_HOME $$$$ in $CCONFTEST_HOME",
+		"template": "{{ .Name }} costs $$5",
+		"ref": "$${shell}",
+		"copy": "${ref}",
+		"default": "${CCONFTEST_UNSET:-$${literal}}",
+		"nested": "${CCONFTEST_UNSET:-{$${a:-b}}}"
+	}`
+	c := New()
+	c.ExpandEnv = true
+	c.Interpolate = true
+	c.UnsetEnv = UnsetError
+	if err := c.LoadReader("json", strings.NewReader(data)); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, "echo ${HOME} $CCONFTEST_HOME $$ in /home/app", c.GetString("shell"))
+	equal(t, "{{ .Name }} costs $5", c.GetString("template"))
+	equal(t, "${shell}", c.GetString("ref"))
+	equal(t, "${shell}", c.GetString("copy"))
+	equal(t, "${literal}", c.GetString("default"))
+	equal(t, "{${a:-b}}", c.GetString("nested"))
+
+	// escaped references of the store are not expanded again by later loads.
+	if err := c.LoadReader("json", strings.NewReader(`{"other": "${ref}"}`)); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, "${shell}", c.GetString("ref"))
+	equal(t, "${shell}", c.GetString("other"))
+}
+
+func TestInterpolateMerged(t *testing.T) {
+	c := New()
+	c.Interpolate = true
+	if err := c.Load("./testdata/interpolate/base.json", "./testdata/interpolate/prod.json"); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, "prod", c.GetString("http.host"))
+	equal(t, "http://prod:80/api", c.GetString("url"))
+	equal(t, `{"host":"prod","port":80}`, c.GetString("upstream"))
+
+	// a later load changes the values referenced by the loaded files.
+	if err := c.Load("./testdata/interpolate/port.json"); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, "http://prod:8443/api", c.GetString("url"))
+	equal(t, "http://prod:8443/api/status", c.GetString("status"))
+
+	// values passed to Set are referenced, but kept as is.
+	if err := c.Set("http.host", "${literal}"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, "${literal}", c.GetString("http.host"))
+	equal(t, "http://${literal}:8443/api", c.GetString("url"))
+
+	// escaped references are resolved once, however often the data is resolved again.
+	c = New()
+	c.Interpolate = true
+	err := c.LoadReader("json", strings.NewReader(`{"a": "x", "b": "$${a}", "c": {"d": "$$"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = c.LoadReader("json", strings.NewReader(`{"a": "${b}-y", "e": "${c}"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	equal(t, "${a}-y", c.GetString("a"))
+	equal(t, "${a}", c.GetString("b"))
+	equal(t, `{"d":"$"}`, c.GetString("e"))
+	equal(t, "$", c.GetString("c.d"))
+
+	// an overridden value is resolved on its own.
+	c = New()
+	c.Interpolate = true
+	var existing interface{}
+	c.SetMergeConflictFunc(func(key string, old, incoming interface{}) (interface{}, error) {
+		existing = old
+		return incoming, nil
+	})
+	if err := c.LoadReader("json", strings.NewReader(`{"x": "${y}/1", "y": "v"}`)); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.LoadReader("json", strings.NewReader(`{"x": "${y}/2"}`)); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, "v/1", existing)
+	equal(t, "v/2", c.GetString("x"))
+}
+
+func TestInterpolateChains(t *testing.T) {
+	c := New()
+	c.Interpolate = true
+	err := c.LoadReader("json", strings.NewReader(`{
+		"a": "${b}/a",
+		"b": "${c}/b",
+		"c": "${d}/c",
+		"d": "d",
+		"m": {"x": "${a}", "y": ["${d}"]},
+		"n": "${m}"
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	equal(t, "d/c/b/a", c.GetString("a"))
+	equal(t, "d/c/b", c.GetString("b"))
+	equal(t, `{"x":"d/c/b/a","y":["d"]}`, c.GetString("n"))
+
+	for data, cycles := range map[string][]string{
+		`{"a": "${b}", "b": "${a}"}`:                      {"a -> b -> a", "b -> a -> b"},
+		`{"a": "${a}"}`:                                   {"a -> a"},
+		`{"a": "x${b}", "b": "${m}", "m": {"x": "${b}"}}`: {"b -> m.x -> b", "m.x -> b -> m.x"},
+	} {
+		c := New()
+		c.Interpolate = true
+		err := c.LoadReader("json", strings.NewReader(data))
+		if err == nil {
+			t.Fatalf("%s: expected an error", data)
+		}
+		found := false
+		for _, cycle := range cycles {
+			found = found || strings.Contains(err.Error(), "reference cycle "+cycle)
+		}
+		if !found {
+			t.Fatalf("%s: the error %v does not name the cycle", data, err)
+		}
+	}
+}
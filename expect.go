@@ -0,0 +1,186 @@
+package cconf
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// typeExpr is a parsed type expression, see ExpectTypes.
+type typeExpr struct {
+	name string    // the expression
+	elem *typeExpr // the element type of lists and maps
+}
+
+// parseTypeExpr parses the type expression.
+func parseTypeExpr(s string) (*typeExpr, error) {
+	e := &typeExpr{name: s}
+	switch {
+	case strings.HasPrefix(s, "[]"):
+		elem, err := parseTypeExpr(s[2:])
+		if err != nil {
+			return nil, err
+		}
+		e.elem = elem
+	case strings.HasPrefix(s, "map[string]"):
+		elem, err := parseTypeExpr(s[len("map[string]"):])
+		if err != nil {
+			return nil, err
+		}
+		e.elem = elem
+	default:
+		switch s {
+		case "any", "string", "bool", "int", "uint", "float", "duration", "map":
+		default:
+			return nil, fmt.Errorf("unknown type expression %q", s)
+		}
+	}
+	return e, nil
+}
+
+// ExpectTypes registers the types expected for the values at the keys, like
+//
+//	c.ExpectTypes(map[string]string{"http.port": "int", "hosts": "[]string", "debug": "bool"})
+//
+// The expectations are checked whenever values are loaded, set or reloaded, which fail with
+// a ConfigValueError for a value of another type, or a *MultiError of one for every such value
+// in CollectAll mode (see ValidationMode), so that a quoted port number is reported at startup
+// rather than at first use. Unlike DeclareTypes, values are not
+// converted, but checked after the conversion to their declared types. Absent values are not
+// checked: see RequireKeys.
+//
+// The type expressions are "any", "string", "bool", "int" and "uint" (which accept whole
+// numbers, also when loaded as floats from JSON), "float" (any number), "duration" (a
+// time.Duration or a string parsed by time.ParseDuration), "map", "[]T" (a list of T) and
+// "map[string]T" (a map of T), like "[]map[string]int".
+//
+// The expectations are checked against the current store immediately; if a value does not
+// match, an error is returned and the expectations are not changed.
+func (c *Conf) ExpectTypes(types map[string]string) error {
+	expected := make(map[string]*typeExpr, len(c.expected)+len(types))
+	for k, e := range types {
+		te, err := parseTypeExpr(e)
+		if err != nil {
+			return &ConfigKeyError{Key: k, Message: err.Error(), Err: ErrUnknownType}
+		}
+		expected[k] = te
+	}
+
+	c.mu.Lock()
+	defer c.unlock()
+	for k, e := range c.expected {
+		if _, ok := expected[k]; !ok {
+			expected[k] = e
+		}
+	}
+	old := c.expected
+	c.expected = expected
+	if err := c.checkTypes(c.snap.Load().store, ""); err != nil {
+		c.expected = old
+		return err
+	}
+	return nil
+}
+
+// checkTypes checks the values of the store against their expected types, see ExpectTypes.
+// If prefix is not empty, only the expected keys at, below or above the prefix are checked.
+// The caller must hold c.mu.
+func (c *Conf) checkTypes(store interface{}, prefix string) error {
+	var errs []*ConfigValueError
+	for k, e := range c.expected {
+		if prefix != "" && k != prefix && !strings.HasPrefix(k, prefix+c.Separator) && !strings.HasPrefix(prefix, k+c.Separator) {
+			continue
+		}
+		if val, ok := walk(store, k, c.Separator); ok && (len(errs) == 0 || !c.failFast()) {
+			errs = c.checkType(e, val, k, errs)
+		}
+	}
+	sort.SliceStable(errs, func(i, j int) bool { return errs[i].Key < errs[j].Key })
+	all := make([]error, len(errs))
+	for i, err := range errs {
+		all[i] = err
+	}
+	return c.aggregate(all)
+}
+
+// checkType checks the value at the key against the type expression and appends the
+// mismatches, at the keys of the elements of lists and maps, to errs.
+func (c *Conf) checkType(e *typeExpr, val interface{}, key string, errs []*ConfigValueError) []*ConfigValueError {
+	v := reflect.ValueOf(val)
+	ok := false
+	switch {
+	case e.name == "any":
+		ok = val != nil
+	case e.name == "string":
+		ok = v.Kind() == reflect.String
+	case e.name == "bool":
+		ok = v.Kind() == reflect.Bool
+	case e.name == "int", e.name == "uint":
+		ok = isWhole(v, e.name == "uint")
+	case e.name == "float":
+		ok = isWhole(v, false) || v.Kind() == reflect.Float32 || v.Kind() == reflect.Float64
+	case e.name == "duration":
+		if _, isDuration := val.(time.Duration); isDuration {
+			ok = true
+		} else if s, isString := val.(string); isString {
+			_, err := time.ParseDuration(s)
+			ok = err == nil
+		}
+	case e.name == "map":
+		ok = v.Kind() == reflect.Map
+	case strings.HasPrefix(e.name, "[]"):
+		if ok = (v.Kind() == reflect.Slice || v.Kind() == reflect.Array) && v.Type().Elem().Kind() != reflect.Uint8; ok {
+			for i := 0; i < v.Len() && (len(errs) == 0 || !c.failFast()); i++ {
+				errs = c.checkType(e.elem, v.Index(i).Interface(), c.join(key, strconv.Itoa(i)), errs)
+			}
+		}
+	default: // map[string]T
+		if ok = v.Kind() == reflect.Map; ok {
+			keys := v.MapKeys()
+			sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+			for _, k := range keys {
+				if len(errs) > 0 && c.failFast() {
+					break
+				}
+				errs = c.checkType(e.elem, v.MapIndex(k).Interface(), c.join(key, k.String()), errs)
+			}
+		}
+	}
+	if !ok {
+		errs = append(errs, &ConfigValueError{Key: key, Message: fmt.Sprintf("expected %s, got %s", e.name, describe(val)), Err: ErrTypeMismatch, Expected: e.name, Actual: val})
+	}
+	return errs
+}
+
+// isWhole reports whether the value is a whole number, non-negative if unsigned.
+func isWhole(v reflect.Value, unsigned bool) bool {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return !unsigned || v.Int() >= 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	case reflect.Float32, reflect.Float64:
+		f := v.Float()
+		return f == math.Trunc(f) && f >= math.MinInt64 && f < math.MaxInt64 && (!unsigned || f >= 0)
+	}
+	return false
+}
+
+// describe describes the type and value of the value for messages.
+func describe(val interface{}) string {
+	switch v := val.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string " + strconv.Quote(v)
+	case map[string]interface{}:
+		return "a map"
+	case []interface{}:
+		return "a list"
+	}
+	return fmt.Sprintf("%T %v", val, val)
+}
@@ -0,0 +1,132 @@
+package cconf
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExpectTypes(t *testing.T) {
+	c := New()
+	c.ValidationMode = CollectAll
+	err := c.LoadReader("json", strings.NewReader(`{
+		"http": {"port": 8080, "timeout": "5s"},
+		"hosts": ["a", "b"],
+		"debug": true,
+		"ratio": 0.5,
+		"workers": 4,
+		"limits": {"conns": 10, "rate": 2.5},
+		"extra": {"any": null}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = c.ExpectTypes(map[string]string{
+		"http":         "map",
+		"http.port":    "int",
+		"http.timeout": "duration",
+		"hosts":        "[]string",
+		"debug":        "bool",
+		"ratio":        "float",
+		"workers":      "uint",
+		"limits":       "map[string]float",
+		"missing":      "string",
+		"extra":        "any",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		key, expr string
+		val       interface{}
+		msg       string
+	}{
+		{"http.port", "int", "8080", `expected int, got string "8080"`},
+		{"http.port", "int", 80.5, "expected int, got float64 80.5"},
+		{"workers", "uint", -1, "expected uint, got int -1"},
+		{"ratio", "float", "0.5", `expected float, got string "0.5"`},
+		{"debug", "bool", "true", `expected bool, got string "true"`},
+		{"http.timeout", "duration", "5 seconds", `expected duration, got string "5 seconds"`},
+		{"http", "map", "localhost:8080", `expected map, got string "localhost:8080"`},
+		{"hosts", "[]string", "a,b", `expected []string, got string "a,b"`},
+		{"hosts.1", "[]string", 2, "expected string, got int 2"},
+		{"limits.conns", "map[string]float", "10", `expected float, got string "10"`},
+	}
+	for _, test := range tests {
+		err := c.Set(test.key, test.val)
+		msgs := validationErrors(t, err)
+		equal(t, map[string]string{test.key: test.msg}, msgs)
+		// the value is not set.
+		if c.Get(test.key) == test.val {
+			t.Errorf("Expected %v not to be set at %v", test.val, test.key)
+		}
+	}
+
+	for key, val := range map[string]interface{}{
+		"http.port":    443,
+		"http.timeout": time.Second,
+		"hosts":        []string{"c"},
+		"workers":      8.0,
+		"ratio":        1,
+	} {
+		if err := c.Set(key, val); err != nil {
+			t.Errorf("Expected %v to be accepted at %v, got %v", val, key, err)
+		}
+	}
+
+	if err := c.ExpectTypes(map[string]string{"debug": "int"}); err == nil {
+		t.Error("Expected an error for a mismatching current value")
+	}
+	if err := c.Set("debug", false); err != nil {
+		t.Errorf("Expected the rejected expectation to be dropped, got %v", err)
+	}
+	if err := c.ExpectTypes(map[string]string{"debug": "integer"}); err == nil {
+		t.Error("Expected an error for an unknown type expression")
+	}
+}
+
+func TestExpectTypesLoad(t *testing.T) {
+	c := New()
+	c.ValidationMode = CollectAll
+	if err := c.ExpectTypes(map[string]string{"port": "int", "hosts": "[]string"}); err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(t.TempDir(), "app.json")
+	if err := os.WriteFile(file, []byte(`{"port": "8080", "hosts": ["a", 1]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	msgs := validationErrors(t, c.Load(file))
+	equal(t, map[string]string{
+		"port":    `expected int, got string "8080"`,
+		"hosts.1": "expected string, got float64 1",
+	}, msgs)
+	if c.Get("port") != nil {
+		t.Error("Expected the load to fail atomically")
+	}
+
+	// the declared types are converted before the check.
+	if err := c.DeclareTypes(map[string]interface{}{"timeout": time.Duration(0)}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.ExpectTypes(map[string]string{"timeout": "int"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(file, []byte(`{"port": 8080, "hosts": ["a"], "timeout": "2s"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Load(file); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, 2*time.Second, c.Get("timeout"))
+
+	if err := os.WriteFile(file, []byte(`{"port": 8080, "hosts": "a", "timeout": "2s"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Reload(); err == nil {
+		t.Error("Expected the reload to fail")
+	}
+	equal(t, []interface{}{"a"}, c.Get("hosts"))
+}
@@ -0,0 +1,112 @@
+package cconf
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BindFlagSet copies the values of the flags that were set on the command line into the store,
+// in a layer above all loaded data: later loads do not replace them. Flags left at their
+// defaults are ignored, so that they do not clobber loaded values.
+// The mapping maps flag names to keys; other flags are mapped to their names with dashes
+// replaced by the Separator, like "db-host" to "db.host". Values keep their Go types if the
+// flag.Value is a flag.Getter. BindFlagSet must be called after fs.Parse.
+func (c *Conf) BindFlagSet(fs *flag.FlagSet, mapping map[string]string) error {
+	vals := make(map[string]interface{})
+	fs.Visit(func(f *flag.Flag) {
+		key, ok := mapping[f.Name]
+		if !ok {
+			key = strings.ReplaceAll(f.Name, "-", c.Separator)
+		}
+		if g, ok := f.Value.(flag.Getter); ok {
+			vals[key] = g.Get()
+		} else {
+			vals[key] = f.Value.String()
+		}
+	})
+
+	c.mu.Lock()
+	defer c.unlock()
+	return c.setOverrides("flags", vals)
+}
+
+// ParseArgs sets the keys of command-line overrides like "--db.host=localhost", with the
+// precedence of BindFlagSet. A bare "--key" sets the key to true. Values are parsed as JSON
+// numbers, booleans or strings when possible, and kept as text otherwise.
+// If any argument is malformed, nothing is set and the error lists the malformed arguments.
+func (c *Conf) ParseArgs(args []string) error {
+	vals := make(map[string]interface{}, len(args))
+	var bad []string
+	for _, arg := range args {
+		kv := strings.TrimPrefix(arg, "--")
+		key, val, hasVal := strings.Cut(kv, "=")
+		if kv == arg || key == "" || strings.HasPrefix(key, "-") {
+			bad = append(bad, strconv.Quote(arg))
+			continue
+		}
+		if !hasVal {
+			vals[key] = true
+			continue
+		}
+		vals[key] = parseArg(val)
+	}
+	if len(bad) > 0 {
+		return errors.New("malformed arguments: " + strings.Join(bad, ", "))
+	}
+
+	c.mu.Lock()
+	defer c.unlock()
+	return c.setOverrides("args", vals)
+}
+
+// parseArg parses the value of a command-line override as a JSON scalar, or returns it as is.
+func parseArg(val string) interface{} {
+	var v interface{}
+	if err := json.Unmarshal([]byte(val), &v); err == nil {
+		switch v.(type) {
+		case float64, bool, string:
+			return v
+		}
+	}
+	return val
+}
+
+// parseFlag converts the text of a flag with the pflag type name, like "int", "duration" or
+// "stringSlice", to a store value: scalars are parsed into their Go types and slice flags
+// into slices of parsed elements, given as elems. Values of other types are kept as text.
+func parseFlag(typ, val string, elems []string) interface{} {
+	if elem := strings.TrimSuffix(strings.TrimSuffix(typ, "Slice"), "Array"); elem != typ {
+		s := make([]interface{}, len(elems))
+		for i, e := range elems {
+			s[i] = parseFlag(elem, e, nil)
+		}
+		return s
+	}
+
+	var v interface{}
+	var err error
+	switch typ {
+	case "bool":
+		v, err = strconv.ParseBool(val)
+	case "int", "int8", "int16", "int32", "count":
+		v, err = strconv.Atoi(val)
+	case "int64":
+		v, err = strconv.ParseInt(val, 0, 64)
+	case "uint", "uint8", "uint16", "uint32", "uint64":
+		v, err = strconv.ParseUint(val, 0, 64)
+	case "float32", "float64":
+		v, err = strconv.ParseFloat(val, 64)
+	case "duration":
+		v, err = time.ParseDuration(val)
+	default:
+		return val
+	}
+	if err != nil {
+		return val
+	}
+	return v
+}
@@ -0,0 +1,110 @@
+package cconf
+
+import (
+	"flag"
+	"testing"
+	"time"
+)
+
+func TestBindFlagSet(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.Int("port", 80, "")
+	fs.String("host", "0.0.0.0", "")
+	fs.Duration("read-timeout", time.Second, "")
+	fs.Bool("debug", false, "")
+	if err := fs.Parse([]string{"-port=9090", "-read-timeout=5s"}); err != nil {
+		t.Fatal(err)
+	}
+
+	c := New()
+	if err := c.Load("testdata/server.json"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.BindFlagSet(fs, map[string]string{"port": "server.Port", "host": "server.Host"}); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, 9090, c.Get("server.Port"))
+	equal(t, 5*time.Second, c.Get("read.timeout"))
+	// flags left at their defaults do not clobber loaded values.
+	equal(t, "localhost", c.GetString("server.Host"))
+	equal(t, nil, c.Get("debug"))
+
+	// later loads do not replace flag values.
+	if err := c.Load("testdata/server.json"); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, 9090, c.Get("server.Port"))
+}
+
+func TestParseFlag(t *testing.T) {
+	equal(t, true, parseFlag("bool", "true", nil))
+	equal(t, 42, parseFlag("int", "42", nil))
+	equal(t, int64(42), parseFlag("int64", "42", nil))
+	equal(t, uint64(42), parseFlag("uint", "42", nil))
+	equal(t, 1.5, parseFlag("float64", "1.5", nil))
+	equal(t, 90*time.Second, parseFlag("duration", "1m30s", nil))
+	equal(t, "text", parseFlag("string", "text", nil))
+	equal(t, "a=b", parseFlag("stringToString", "a=b", nil))
+	equal(t, "x", parseFlag("int", "x", nil))
+	equal(t, []interface{}{"a", "b"}, parseFlag("stringSlice", "[a,b]", []string{"a", "b"}))
+	equal(t, []interface{}{1, 2}, parseFlag("intSlice", "[1,2]", []string{"1", "2"}))
+	equal(t, []interface{}{time.Second}, parseFlag("durationSlice", "[1s]", []string{"1s"}))
+}
+
+func TestFlagPrecedence(t *testing.T) {
+	t.Setenv("CCONFTEST_SERVER_HOST", "env-host")
+	t.Setenv("CCONFTEST_SERVER_PORT", "7070")
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.Int("server-port", 80, "")
+	if err := fs.Parse([]string{"-server-port=9090"}); err != nil {
+		t.Fatal(err)
+	}
+
+	c := New()
+	c.SetEnvKeyReplacer(nil, func(name string) string {
+		return map[string]string{"SERVER_HOST": "server.Host", "SERVER_PORT": "server.Port"}[name]
+	})
+	if err := c.BindFlagSet(fs, map[string]string{"server-port": "server.Port"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Load("testdata/server.json"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.LoadEnv("CCONFTEST_"); err != nil {
+		t.Fatal(err)
+	}
+	// flag > env > file > default
+	equal(t, 9090, c.GetInt("server.Port"))
+	equal(t, "env-host", c.GetString("server.Host"))
+	equal(t, true, c.GetBool("server.Debug"))
+	equal(t, "default", c.GetString("server.Name", "default"))
+}
+
+func TestParseArgs(t *testing.T) {
+	c := New()
+	if err := c.Load("testdata/server.json"); err != nil {
+		t.Fatal(err)
+	}
+	err := c.ParseArgs([]string{"--server.Port=9090", "--server.Debug=false", "--debug", "--db.host=localhost",
+		"--ratio=0.5", `--name="quoted"`, "--list=[1,2]", "--empty="})
+	if err != nil {
+		t.Fatal(err)
+	}
+	equal(t, 9090, c.GetInt("server.Port"))
+	equal(t, false, c.GetBool("server.Debug", true))
+	equal(t, true, c.Get("debug"))
+	equal(t, "localhost", c.Get("db.host"))
+	equal(t, 0.5, c.Get("ratio"))
+	equal(t, "quoted", c.Get("name"))
+	equal(t, "[1,2]", c.Get("list"))
+	equal(t, "", c.Get("empty"))
+	equal(t, "localhost", c.GetString("server.Host"))
+
+	err = c.ParseArgs([]string{"--port=1", "-x", "--=1", "---y", "z", "--"})
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	equal(t, `malformed arguments: "-x", "--=1", "---y", "z", "--"`, err.Error())
+	equal(t, nil, c.Get("port"))
+}
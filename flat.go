@@ -0,0 +1,158 @@
+package cconf
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Flatten returns the values of the nested data by their keys joined with the separator,
+// like "db.host". Lists of scalars are kept as values, while the elements of lists holding
+// maps or lists are flattened with their index as key segment, like "servers.0.host".
+// Empty maps and nil values are skipped.
+func Flatten(data interface{}, sep string) map[string]interface{} {
+	leaves := make(map[string]interface{})
+	flattenLists(leaves, "", normalize(data), sep)
+	return leaves
+}
+
+// flattenLists adds the values below the key to leaves, see Flatten.
+func flattenLists(leaves map[string]interface{}, key string, v interface{}, sep string) {
+	prefix := key
+	if key != "" {
+		prefix += sep
+	}
+	switch d := v.(type) {
+	case nil:
+		return
+	case map[string]interface{}:
+		for k, e := range d {
+			flattenLists(leaves, prefix+k, e, sep)
+		}
+		return
+	case []interface{}:
+		for _, e := range d {
+			switch e.(type) {
+			case map[string]interface{}, []interface{}:
+				for i, e := range d {
+					flattenLists(leaves, prefix+strconv.Itoa(i), e, sep)
+				}
+				return
+			}
+		}
+	}
+	if key != "" {
+		leaves[key] = v
+	}
+}
+
+// flatValue formats a value of Flatten as a string, joining lists with commas.
+func flatValue(v interface{}) string {
+	s, ok := v.([]interface{})
+	if !ok {
+		return stringify(v)
+	}
+	elems := make([]string, len(s))
+	for i, e := range s {
+		elems[i] = stringify(e)
+	}
+	return strings.Join(elems, ",")
+}
+
+// writeFlat writes the flattened data as sorted lines formatted by the function.
+func writeFlat(w io.Writer, data interface{}, sep string, line func(key, val string) string) error {
+	leaves := Flatten(data, sep)
+	keys := make([]string, 0, len(leaves))
+	for k := range leaves {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	for _, k := range keys {
+		b.WriteString(line(k, flatValue(leaves[k])) + "\n")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// encodeProperties writes the data to the writer in the Java properties format, as sorted
+// "key=value" lines whose keys are joined with DefaultSeparator and whose lists are joined
+// with commas. Special characters are escaped, so that the output is ISO 8859-1.
+func encodeProperties(w io.Writer, data interface{}) error {
+	return writeFlat(w, data, DefaultSeparator, func(key, val string) string {
+		return propertiesEscape(key, true) + "=" + propertiesEscape(val, false)
+	})
+}
+
+// propertiesEscape escapes the key or the value for the properties format.
+func propertiesEscape(s string, key bool) string {
+	var b strings.Builder
+	for i, r := range s {
+		switch r {
+		case '\\':
+			b.WriteString(`\\`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\f':
+			b.WriteString(`\f`)
+		case '=', ':', '#', '!', ' ':
+			// leading whitespace of values is skipped by parsers.
+			if key || r == ' ' && i == 0 {
+				b.WriteByte('\\')
+			}
+			b.WriteRune(r)
+		default:
+			if r < 0x20 || r > 0x7e {
+				for _, u := range utf16Units(r) {
+					fmt.Fprintf(&b, `\u%04X`, u)
+				}
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	return b.String()
+}
+
+// utf16Units returns the UTF-16 code units of the rune.
+func utf16Units(r rune) []rune {
+	if r < 0x10000 {
+		return []rune{r}
+	}
+	r -= 0x10000
+	return []rune{0xd800 + r>>10, 0xdc00 + r&0x3ff}
+}
+
+// encodeEnv writes the data to the writer as an env-file without prefix, see EnvEncodeFunc.
+func encodeEnv(w io.Writer, data interface{}) error {
+	return EnvEncodeFunc("")(w, data)
+}
+
+// EnvEncodeFunc returns an encode function writing the data as an env-file, suitable for
+// sourcing in a shell or as a container env-file, for RegisterEncodeFunc: every value becomes
+// a sorted "PREFIX_KEY=value" line named like the variables of EnvMap, so that "db.host"
+// becomes PREFIX_DB_HOST. Lists are joined with commas and values with characters special
+// to the shell are single-quoted.
+func EnvEncodeFunc(prefix string) func(w io.Writer, data interface{}) error {
+	return func(w io.Writer, data interface{}) error {
+		return writeFlat(w, data, DefaultSeparator, func(key, val string) string {
+			return prefix + envName(keyToEnv(key, DefaultSeparator)) + "=" + shellQuote(val)
+		})
+	}
+}
+
+// shellQuote single-quotes the string if the shell would not read it back as is.
+func shellQuote(s string) string {
+	for _, r := range s {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("_@%+=:,./-", r)) {
+			return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+		}
+	}
+	return s
+}
@@ -0,0 +1,102 @@
+package cconf
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var flatData = map[string]interface{}{
+	"app": map[string]interface{}{
+		"name":  "my app",
+		"quote": `it's "quoted"`,
+		"port":  8080.0,
+		"tags":  []interface{}{"a", "b", 1},
+		"empty": map[string]interface{}{},
+	},
+	"db": map[string]interface{}{
+		"max_conns": 10,
+		"servers": []interface{}{
+			map[string]interface{}{"host": "db1"},
+			map[string]interface{}{"host": "db2:5432"},
+		},
+	},
+	"motd": "héllo\n#1",
+}
+
+func TestFlatten(t *testing.T) {
+	equal(t, map[string]interface{}{
+		"app.name":          "my app",
+		"app.quote":         `it's "quoted"`,
+		"app.port":          8080.0,
+		"app.tags":          []interface{}{"a", "b", 1},
+		"db.max_conns":      10,
+		"db.servers.0.host": "db1",
+		"db.servers.1.host": "db2:5432",
+		"motd":              "héllo\n#1",
+	}, Flatten(flatData, "."))
+	equal(t, map[string]interface{}{"a/b": true}, Flatten(map[string]interface{}{"a": map[string]interface{}{"b": true}}, "/"))
+	equal(t, map[string]interface{}{}, Flatten(nil, "."))
+}
+
+func TestEncodeProperties(t *testing.T) {
+	var buf bytes.Buffer
+	if err := encodeProperties(&buf, flatData); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, `app.name=my app
+app.port=8080
+app.quote=it's "quoted"
+app.tags=a,b,1
+db.max_conns=10
+db.servers.0.host=db1
+db.servers.1.host=db2:5432
+motd=h\u00E9llo\n#1
+`, buf.String())
+
+	buf.Reset()
+	encodeProperties(&buf, map[string]interface{}{"a b": " x", "c=d": "😀"})
+	equal(t, "a\\ b=\\ x\nc\\=d=\\uD83D\\uDE00\n", buf.String())
+}
+
+func TestEncodeEnv(t *testing.T) {
+	var buf bytes.Buffer
+	if err := EnvEncodeFunc("MYAPP_")(&buf, flatData); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, `MYAPP_APP_NAME='my app'
+MYAPP_APP_PORT=8080
+MYAPP_APP_QUOTE='it'\''s "quoted"'
+MYAPP_APP_TAGS=a,b,1
+MYAPP_DB_MAX__CONNS=10
+MYAPP_DB_SERVERS_0_HOST=db1
+MYAPP_DB_SERVERS_1_HOST=db2:5432
+MYAPP_MOTD='héllo
+#1'
+`, buf.String())
+
+	// the shell reads back the values.
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("no shell")
+	}
+	c := New()
+	c.SetStore(flatData)
+	file := filepath.Join(t.TempDir(), "app.env")
+	if err := c.Save(file); err != nil {
+		t.Fatal(err)
+	}
+	out, err := exec.Command(sh, "-c", `. "$0" && printf '%s|%s|%s' "$APP_NAME" "$APP_QUOTE" "$MOTD"`, file).Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	equal(t, "my app|it's \"quoted\"|héllo\n#1", string(out))
+	b, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	equal(t, true, strings.HasPrefix(string(b), "APP_NAME='my app'\n"))
+}
@@ -0,0 +1,28 @@
+package cconf
+
+// Freeze makes the configuration read-only, e.g. once the application is configured: the
+// writers, like Set, SetOverride, Load, LoadEnv and Reload, fail with an error wrapping
+// ErrFrozen, so do Watch and Poll reloads, and SetStore, ClearOverrides, Alias, BindEnv and
+// AutomaticEnv, which return no error, leave the configuration unchanged. Get, Populate and the other readers
+// keep working. Freezing a frozen Conf does nothing; a Conf cannot be unfrozen.
+func (c *Conf) Freeze() {
+	c.mu.Lock()
+	defer c.unlock()
+	c.frozen = true
+}
+
+// Frozen reports whether the configuration is read-only, see Freeze.
+func (c *Conf) Frozen() bool {
+	c.mu.Lock()
+	defer c.unlock()
+	return c.frozen
+}
+
+// errFrozen returns the error of a write to a frozen configuration, if it is frozen.
+// The caller must hold c.mu.
+func (c *Conf) errFrozen() error {
+	if !c.frozen {
+		return nil
+	}
+	return &wrapError{"the configuration is frozen", ErrFrozen}
+}
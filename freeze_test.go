@@ -0,0 +1,52 @@
+package cconf
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestFreeze(t *testing.T) {
+	c := New()
+	if err := c.Load("./testdata/app.json"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Set("app.name", "frozen"); err != nil {
+		t.Fatal(err)
+	}
+	c.Freeze()
+	c.Freeze()
+	equal(t, true, c.Frozen())
+
+	var ke *ConfigKeyError
+	if err := c.Set("app.name", "other"); !errors.Is(err, ErrFrozen) || !errors.As(err, &ke) || ke.Key != "app.name" {
+		t.Errorf("Set: expected a ConfigKeyError wrapping ErrFrozen, got %v", err)
+	}
+	tests := map[string]error{
+		"load":          c.Load("./testdata/app.json"),
+		"load reader":   c.LoadReader("json", strings.NewReader(`{"a": 1}`)),
+		"reload":        c.Reload(),
+		"override":      c.SetOverride("app.name", "other"),
+		"clear":         c.ClearOverride("app.name"),
+		"defaults":      c.SetDefaults(map[string]interface{}{"a": 1}),
+		"declare types": c.DeclareTypes(map[string]interface{}{"a": 0}),
+	}
+	for name, err := range tests {
+		if !errors.Is(err, ErrFrozen) {
+			t.Errorf("%s: expected an error wrapping ErrFrozen, got %v", name, err)
+		}
+	}
+
+	var events []Event
+	c.OnEvent(func(e Event) {
+		events = append(events, e)
+	})
+	c.SetStore(map[string]interface{}{"a": 1})
+	c.ClearOverrides()
+	c.Alias("app.name", "name")
+	if len(events) != 1 || !errors.Is(events[0].Err, ErrFrozen) {
+		t.Errorf("Expected an event wrapping ErrFrozen, got %v", events)
+	}
+	equal(t, "frozen", c.GetString("app.name"))
+	equal(t, nil, c.Get("a"))
+}
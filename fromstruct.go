@@ -0,0 +1,127 @@
+package cconf
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// StructOption modifies how SetFromStruct converts a struct.
+type StructOption int
+
+const (
+	// OmitZero skips the fields with zero values, like a struct of defaults that only sets some fields.
+	OmitZero StructOption = iota + 1
+)
+
+// SetFromStruct sets the exported fields of the struct, or pointer to struct, v at the key,
+// or at the root of the store for an empty key, as the reverse of Populate: every field is set
+// at its key, the name of its cconf tag or its field name, so that Populate reproduces the struct. Nested structs and maps become
+// maps and slices and arrays become lists, recursively; fields of embedded structs are set
+// like the fields of the struct, and values implementing encoding.TextMarshaler, like
+// time.Time, are set as they are. Nil pointers, maps, slices and interfaces are skipped.
+// The fields are set like Set, atomically.
+func (c *Conf) SetFromStruct(key string, v interface{}, opts ...StructOption) error {
+	omitZero := false
+	for _, opt := range opts {
+		omitZero = omitZero || opt == OmitZero
+	}
+	val := reflect.ValueOf(v)
+	for val.Kind() == reflect.Ptr && !val.IsNil() {
+		val = val.Elem()
+	}
+	if val.Kind() != reflect.Struct {
+		return fmt.Errorf("SetFromStruct requires a struct, got %T", v)
+	}
+	data, _ := fromValue(val, omitZero)
+	if data == nil {
+		data = map[string]interface{}{}
+	}
+
+	c.mu.Lock()
+	defer c.unlock()
+	if key != "" {
+		return c.setValue(key, data)
+	}
+	m := data.(map[string]interface{})
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if err := c.setValue(k, m[k]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// textMarshaler is the type of encoding.TextMarshaler.
+var textMarshaler = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+
+// fromValue converts the value into a value of the store, see SetFromStruct.
+// It reports false for values to skip.
+func fromValue(v reflect.Value, omitZero bool) (interface{}, bool) {
+	if !v.IsValid() || omitZero && v.IsZero() {
+		return nil, false
+	}
+	if v.Type().Implements(textMarshaler) && v.Kind() != reflect.Ptr && v.Kind() != reflect.Interface {
+		return v.Interface(), true
+	}
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil, false
+		}
+		return fromValue(v.Elem(), omitZero)
+	case reflect.Struct:
+		m := make(map[string]interface{})
+		for _, f := range structFields(v.Type()) {
+			if f.Anonymous || f.PkgPath != "" {
+				continue
+			}
+			fv, err := v.FieldByIndexErr(f.Index)
+			if err != nil {
+				// promoted through a nil embedded pointer.
+				continue
+			}
+			if e, ok := fromValue(fv, omitZero); ok {
+				m[f.tag.name] = e
+			}
+		}
+		return m, true
+	case reflect.Map:
+		if v.IsNil() {
+			return nil, false
+		}
+		m := make(map[string]interface{}, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			if e, ok := fromValue(iter.Value(), omitZero); ok {
+				m[fmt.Sprint(iter.Key().Interface())] = e
+			}
+		}
+		return m, true
+	case reflect.Slice:
+		if v.IsNil() {
+			return nil, false
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return v.Interface(), true
+		}
+		fallthrough
+	case reflect.Array:
+		s := make([]interface{}, v.Len())
+		for i := range s {
+			var ok bool
+			if s[i], ok = fromValue(v.Index(i), omitZero); !ok {
+				// keep the positions of the elements, even of zero ones.
+				s[i], _ = fromValue(v.Index(i), false)
+			}
+		}
+		return s, true
+	}
+	return v.Interface(), true
+}
@@ -0,0 +1,85 @@
+package cconf
+
+import (
+	"testing"
+	"time"
+)
+
+type structTimeouts struct {
+	Read  time.Duration
+	Write time.Duration
+}
+
+type structDatabase struct {
+	structTimeouts
+	Host     string
+	Port     int
+	Replicas []string
+	Pool     *structPool
+	Labels   map[string]string
+	Weights  [2]float64
+	Started  time.Time
+	Shards   []structPool
+	internal string
+}
+
+type structPool struct {
+	Size    int
+	Enabled bool
+}
+
+func TestSetFromStruct(t *testing.T) {
+	db := structDatabase{
+		structTimeouts: structTimeouts{Read: time.Second},
+		Host:           "localhost",
+		Port:           5432,
+		Replicas:       []string{"r1", "r2"},
+		Pool:           &structPool{Size: 10, Enabled: true},
+		Labels:         map[string]string{"env": "prod"},
+		Weights:        [2]float64{0.5, 1.5},
+		Started:        time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		Shards:         []structPool{{Size: 1}, {Size: 2, Enabled: true}},
+		internal:       "skipped",
+	}
+	c := New()
+	if err := c.Load("./testdata/app.json"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.SetFromStruct("database", &db); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, "localhost", c.GetString("database.Host"))
+	equal(t, 10, c.GetInt("database.Pool.Size"))
+	equal(t, "r2", c.GetString("database.Replicas.1"))
+	equal(t, "prod", c.GetString("database.Labels.env"))
+	equal(t, nil, c.Get("database.internal"))
+	equal(t, "cconf", c.GetString("name"))
+	equal(t, "Set()", c.Source("database.Host"))
+
+	// round trip
+	var got structDatabase
+	if err := c.Populate(&got, "database"); err != nil {
+		t.Fatal(err)
+	}
+	db.internal = ""
+	equal(t, db, got)
+
+	// zero values are skipped with OmitZero.
+	c = New()
+	if err := c.SetFromStruct("", structDatabase{Host: "h", Weights: [2]float64{0, 1}}, OmitZero); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, map[string]interface{}{
+		"Host":    "h",
+		"Weights": []interface{}{0.0, 1.0},
+	}, c.GetStore())
+	c = New()
+	if err := c.SetFromStruct("", structPool{}); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, map[string]interface{}{"Size": 0, "Enabled": false}, c.GetStore())
+
+	if err := c.SetFromStruct("x", map[string]int{}); err == nil {
+		t.Fatal("expected an error")
+	}
+}
@@ -0,0 +1,58 @@
+package cconf
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+)
+
+// Hash returns the hex-encoded SHA-256 digest of the canonical JSON encoding of the store,
+// with sorted keys and numbers formatted alike whatever their type, so that equal stores
+// have the same hash however they were loaded. It serves to detect configuration changes,
+// e.g. across restarts. If excludeSecrets is true, the keys marked with MarkSecret are left
+// out, so that rotating a password does not change the hash; so are the keys, which may
+// be patterns like those of MarkSecret.
+func (c *Conf) Hash(excludeSecrets bool, keys ...string) string {
+	c.mu.Lock()
+	store := c.snap.Load().store
+	patterns := keys
+	if excludeSecrets {
+		patterns = append(append([]string(nil), keys...), c.secretKeys()...)
+	}
+	c.mu.Unlock()
+	if store == nil {
+		store = map[string]interface{}{}
+	}
+	if len(patterns) > 0 {
+		store = c.exclude(store, "", patterns)
+	}
+	b, err := json.Marshal(store)
+	if err != nil {
+		// values that cannot be encoded as JSON, like functions, are hashed as formatted.
+		b = []byte(stringify(store))
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// exclude returns a copy of the node at the key without the values of keys matching the patterns.
+func (c *Conf) exclude(node interface{}, key string, patterns []string) interface{} {
+	switch n := node.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(n))
+		for k, v := range n {
+			if fk := c.join(key, k); !c.isSecret(fk, patterns) {
+				m[k] = c.exclude(v, fk, patterns)
+			}
+		}
+		return m
+	case []interface{}:
+		s := make([]interface{}, len(n))
+		for i, v := range n {
+			s[i] = c.exclude(v, c.join(key, strconv.Itoa(i)), patterns)
+		}
+		return s
+	}
+	return node
+}
@@ -0,0 +1,60 @@
+package cconf
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestHash(t *testing.T) {
+	dir := t.TempDir()
+	a, b := filepath.Join(dir, "a.json"), filepath.Join(dir, "b.json")
+	if err := os.WriteFile(a, []byte(`{"name": "app", "db": {"host": "h", "port": 5432}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(b, []byte(`{"db": {"password": "p1", "pool": [1, 2]}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	c1, c2 := New(), New()
+	if err := c1.Load(a, b); err != nil {
+		t.Fatal(err)
+	}
+	if err := c2.Load(b, a); err != nil {
+		t.Fatal(err)
+	}
+	c3 := New()
+	c3.SetStore(map[string]interface{}{
+		"db":   map[string]interface{}{"pool": []int{1, 2}, "password": "p1", "port": 5432, "host": "h"},
+		"name": "app",
+	})
+	h := c1.Hash(false)
+	equal(t, 64, len(h))
+	equal(t, h, c1.Hash(false))
+	equal(t, h, c2.Hash(false))
+	equal(t, h, c3.Hash(false))
+
+	// a change of one key changes the hash.
+	if err := c2.Set("db.port", 5433); err != nil {
+		t.Fatal(err)
+	}
+	if c2.Hash(false) == h {
+		t.Fatal("expected a different hash")
+	}
+
+	// excluded keys do not change the hash.
+	c1.MarkSecret("*.password")
+	hs := c1.Hash(true)
+	if hs == h {
+		t.Fatal("expected a different hash")
+	}
+	if err := c1.Set("db.password", "p2"); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, hs, c1.Hash(true))
+	hn := c1.Hash(true, "name")
+	if err := c1.Set("name", "other"); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, hn, c1.Hash(true, "name"))
+	equal(t, New().Hash(false), c1.Hash(false, "*"))
+}
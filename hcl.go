@@ -0,0 +1,478 @@
+package cconf
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// loadHCL reads and parses an HCL file.
+func loadHCL(file string, data interface{}) error {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	return parseHCL(string(b), data)
+}
+
+// decodeHCL parses an HCL document from the reader into data, which must be a *interface{}.
+func decodeHCL(r io.Reader, data interface{}) error {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	return parseHCL(string(b), data)
+}
+
+// parseHCL parses an HCL document of the native syntax into data, which must be a
+// *interface{}. Attributes become keys and blocks nested maps below their type and labels,
+// so that the port of
+//
+//	server "web" {
+//	  port = 80
+//	}
+//
+// is "server.web.port". A block repeated with the same type and labels becomes a list of
+// maps, while a single block stays a map. Attribute values must be literals: strings,
+// heredocs, numbers, booleans, null, tuples and objects. Interpolations like "${var.x}" are
+// kept as written, and other expressions are an error. Malformed documents fail with a
+// *SyntaxError.
+func parseHCL(s string, data interface{}) error {
+	p := &hclParser{s: s, blocks: make(map[string]bool)}
+	m := make(map[string]interface{})
+	if err := p.body(m, nil); err != nil {
+		return err
+	}
+	if p.pos < len(p.s) {
+		return p.errorf(p.pos, "unexpected %q", p.s[p.pos])
+	}
+	*data.(*interface{}) = m
+	return nil
+}
+
+// hclParser parses an HCL document.
+type hclParser struct {
+	s      string
+	pos    int
+	blocks map[string]bool // the paths of the maps holding the bodies of blocks
+}
+
+// errorf returns a *SyntaxError at the position.
+func (p *hclParser) errorf(pos int, format string, args ...interface{}) error {
+	return newSyntaxError(p.s, pos, fmt.Errorf(format, args...))
+}
+
+// ws skips white space and comments, and line breaks if nl is set. It reports whether it
+// skipped a line break.
+func (p *hclParser) ws(nl bool) bool {
+	skipped := false
+	for p.pos < len(p.s) {
+		switch c := p.s[p.pos]; {
+		case c == ' ' || c == '\t' || c == '\r':
+			p.pos++
+		case c == '\n':
+			if !nl {
+				return skipped
+			}
+			skipped = true
+			p.pos++
+		case c == '#' || strings.HasPrefix(p.s[p.pos:], "//"):
+			if i := strings.IndexByte(p.s[p.pos:], '\n'); i >= 0 {
+				p.pos += i
+			} else {
+				p.pos = len(p.s)
+			}
+		case strings.HasPrefix(p.s[p.pos:], "/*"):
+			i := strings.Index(p.s[p.pos+2:], "*/")
+			if i < 0 {
+				p.pos = len(p.s)
+				return skipped
+			}
+			skipped = skipped || strings.Contains(p.s[p.pos:p.pos+2+i], "\n")
+			p.pos += i + 4
+		default:
+			return skipped
+		}
+	}
+	return skipped
+}
+
+// ident parses an identifier, returning "" if there is none.
+func (p *hclParser) ident() string {
+	start := p.pos
+	for p.pos < len(p.s) {
+		c := p.s[p.pos]
+		if !(c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || p.pos > start && (c == '-' || c >= '0' && c <= '9')) {
+			break
+		}
+		p.pos++
+	}
+	return p.s[start:p.pos]
+}
+
+// body parses the attributes and blocks of a body into m, whose path is given, up to the
+// closing brace or the end of the document.
+func (p *hclParser) body(m map[string]interface{}, path []string) error {
+	for {
+		p.ws(true)
+		if p.pos == len(p.s) || p.s[p.pos] == '}' {
+			return nil
+		}
+		start := p.pos
+		name := p.ident()
+		if name == "" {
+			r, _ := utf8.DecodeRuneInString(p.s[p.pos:])
+			return p.errorf(p.pos, "expected an attribute or a block, found %q", r)
+		}
+		p.ws(false)
+
+		if p.pos < len(p.s) && p.s[p.pos] == '=' && !strings.HasPrefix(p.s[p.pos:], "==") {
+			p.pos++
+			p.ws(false)
+			v, err := p.expr()
+			if err != nil {
+				return err
+			}
+			if _, ok := m[name]; ok {
+				return p.errorf(start, "%q is already defined", strings.Join(append(path, name), "."))
+			}
+			m[name] = v
+		} else {
+			labels := []string{name}
+			for p.pos < len(p.s) && p.s[p.pos] != '{' {
+				at := p.pos
+				label := p.ident()
+				if label == "" && p.s[p.pos] == '"' {
+					var err error
+					if label, err = p.str(); err != nil {
+						return err
+					}
+				}
+				if label == "" {
+					return p.errorf(at, "expected '=', a block label or '{' after %s", name)
+				}
+				labels = append(labels, label)
+				p.ws(false)
+			}
+			if p.pos == len(p.s) {
+				return p.errorf(p.pos, "expected '{' after %s", strings.Join(labels, " "))
+			}
+			p.pos++
+			body := make(map[string]interface{})
+			// the position tells the paths in the bodies of repeated blocks apart.
+			bodyPath := append(append(path[:len(path):len(path)], labels...), strconv.Itoa(start))
+			if err := p.body(body, bodyPath); err != nil {
+				return err
+			}
+			if p.pos == len(p.s) {
+				return p.errorf(start, "unterminated block %s", strings.Join(labels, " "))
+			}
+			p.pos++
+			if err := p.addBlock(start, m, path, labels, body); err != nil {
+				return err
+			}
+		}
+
+		// an attribute or a block ends with the line, or with the closing brace of a
+		// single-line block.
+		if !p.ws(false) && p.pos < len(p.s) && p.s[p.pos] != '\n' && p.s[p.pos] != '}' {
+			return p.errorf(p.pos, "expected a line break, found %q", p.s[p.pos])
+		}
+	}
+}
+
+// addBlock adds the body of a block below the map at the path, at the type and labels of
+// the block. A repeated block turns the body into a list of bodies.
+func (p *hclParser) addBlock(pos int, m map[string]interface{}, path, labels []string, body map[string]interface{}) error {
+	for i, label := range labels {
+		path = append(path[:len(path):len(path)], label)
+		key := strings.Join(path, "\x00")
+		last := i == len(labels)-1
+		switch v := m[label].(type) {
+		case nil:
+			if last {
+				m[label] = body
+				p.blocks[key] = true
+				return nil
+			}
+			sub := make(map[string]interface{})
+			m[label] = sub
+			m = sub
+			continue
+		case map[string]interface{}:
+			switch {
+			case last && p.blocks[key]:
+				m[label] = []interface{}{v, body}
+				return nil
+			case !last && !p.blocks[key]:
+				m = v
+				continue
+			}
+		case []interface{}:
+			if last && p.blocks[key] {
+				m[label] = append(v, body)
+				return nil
+			}
+		}
+		return p.errorf(pos, "block %s conflicts with %q", strings.Join(labels, " "), strings.Join(path, "."))
+	}
+	return nil
+}
+
+// expr parses a literal expression.
+func (p *hclParser) expr() (interface{}, error) {
+	if p.pos == len(p.s) {
+		return nil, p.errorf(p.pos, "expected a value")
+	}
+	start := p.pos
+	switch c := p.s[p.pos]; {
+	case c == '"':
+		return p.str()
+	case strings.HasPrefix(p.s[p.pos:], "<<"):
+		return p.heredoc()
+	case c == '[':
+		return p.tuple()
+	case c == '{':
+		return p.object()
+	case c == '-' || c >= '0' && c <= '9':
+		return p.number()
+	}
+	switch name := p.ident(); name {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "null":
+		return nil, nil
+	case "":
+		r, _ := utf8.DecodeRuneInString(p.s[p.pos:])
+		return nil, p.errorf(p.pos, "expected a value, found %q", r)
+	}
+	return nil, p.errorf(start, "unsupported expression %q, only literal values are supported", p.s[start:p.pos])
+}
+
+// number parses a decimal number, as an int if it has no fraction and no exponent.
+func (p *hclParser) number() (interface{}, error) {
+	start := p.pos
+	if p.s[p.pos] == '-' {
+		p.pos++
+	}
+	for p.pos < len(p.s) && strings.IndexByte("0123456789.eE+-", p.s[p.pos]) >= 0 {
+		if c := p.s[p.pos]; (c == '+' || c == '-') && p.s[p.pos-1] != 'e' && p.s[p.pos-1] != 'E' {
+			break
+		}
+		p.pos++
+	}
+	tok := p.s[start:p.pos]
+	if n, err := strconv.ParseInt(tok, 10, 64); err == nil {
+		return int(n), nil
+	}
+	if f, err := strconv.ParseFloat(tok, 64); err == nil && !strings.HasSuffix(tok, ".") {
+		return f, nil
+	}
+	return nil, p.errorf(start, "invalid number %q", tok)
+}
+
+// str parses a quoted string, keeping its interpolations as written.
+func (p *hclParser) str() (string, error) {
+	start := p.pos
+	p.pos++
+	var b strings.Builder
+	for p.pos < len(p.s) {
+		c := p.s[p.pos]
+		switch {
+		case c == '"':
+			p.pos++
+			return b.String(), nil
+		case c == '\n':
+			return "", p.errorf(start, "unterminated string")
+		case c == '\\':
+			if err := p.escape(&b); err != nil {
+				return "", err
+			}
+			continue
+		case strings.HasPrefix(p.s[p.pos:], "$${") || strings.HasPrefix(p.s[p.pos:], "%%{"):
+			// escaped template sequences.
+			b.WriteString(p.s[p.pos+1 : p.pos+3])
+			p.pos += 3
+			continue
+		case strings.HasPrefix(p.s[p.pos:], "${") || strings.HasPrefix(p.s[p.pos:], "%{"):
+			end := strings.IndexByte(p.s[p.pos:], '}')
+			if end < 0 || strings.Contains(p.s[p.pos:p.pos+end], "\n") {
+				return "", p.errorf(p.pos, "unterminated template sequence")
+			}
+			b.WriteString(p.s[p.pos : p.pos+end+1])
+			p.pos += end + 1
+			continue
+		}
+		b.WriteByte(c)
+		p.pos++
+	}
+	return "", p.errorf(start, "unterminated string")
+}
+
+// escape writes the character of the escape sequence at the position.
+func (p *hclParser) escape(b *strings.Builder) error {
+	start := p.pos
+	p.pos++
+	if p.pos == len(p.s) {
+		return p.errorf(start, "unterminated string")
+	}
+	c := p.s[p.pos]
+	if e, ok := map[byte]byte{'n': '\n', 'r': '\r', 't': '\t', '"': '"', '\\': '\\'}[c]; ok {
+		b.WriteByte(e)
+		p.pos++
+		return nil
+	}
+	if n := map[byte]int{'u': 4, 'U': 8}[c]; n > 0 && p.pos+n < len(p.s) {
+		if r, err := strconv.ParseUint(p.s[p.pos+1:p.pos+1+n], 16, 32); err == nil && utf8.ValidRune(rune(r)) {
+			b.WriteRune(rune(r))
+			p.pos += n + 1
+			return nil
+		}
+	}
+	return p.errorf(start, "invalid escape sequence \\%c", c)
+}
+
+// heredoc parses a heredoc like <<EOF or, with the indentation of its lines removed, <<-EOF.
+func (p *hclParser) heredoc() (string, error) {
+	start := p.pos
+	p.pos += 2
+	indented := p.pos < len(p.s) && p.s[p.pos] == '-'
+	if indented {
+		p.pos++
+	}
+	marker := p.ident()
+	if marker == "" {
+		return "", p.errorf(start, "expected a heredoc marker")
+	}
+	if p.ws(false); p.pos < len(p.s) && p.s[p.pos] != '\n' {
+		return "", p.errorf(p.pos, "expected a line break after the heredoc marker")
+	}
+	p.pos++
+	var lines []string
+	for p.pos < len(p.s) {
+		end := strings.IndexByte(p.s[p.pos:], '\n')
+		if end < 0 {
+			end = len(p.s) - p.pos
+		}
+		line := strings.TrimSuffix(p.s[p.pos:p.pos+end], "\r")
+		if strings.TrimSpace(line) == marker {
+			// the closing marker ends the expression, without its line break.
+			p.pos += end
+			if indented {
+				lines = dedent(lines)
+			}
+			if len(lines) == 0 {
+				return "", nil
+			}
+			return strings.Join(lines, "\n") + "\n", nil
+		}
+		lines = append(lines, line)
+		p.pos = min(p.pos+end+1, len(p.s))
+	}
+	return "", p.errorf(start, "unterminated heredoc %s", marker)
+}
+
+// dedent removes the indentation common to the non-empty lines.
+func dedent(lines []string) []string {
+	indent := -1
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if n := len(line) - len(strings.TrimLeft(line, " \t")); indent < 0 || n < indent {
+			indent = n
+		}
+	}
+	for i, line := range lines {
+		lines[i] = line[min(max(indent, 0), len(line)):]
+	}
+	return lines
+}
+
+// tuple parses a tuple like [1, 2, 3], which may span lines.
+func (p *hclParser) tuple() (interface{}, error) {
+	start := p.pos
+	p.pos++
+	s := make([]interface{}, 0)
+	for {
+		if p.ws(true); p.pos == len(p.s) {
+			return nil, p.errorf(start, "unterminated tuple")
+		}
+		if p.s[p.pos] == ']' {
+			p.pos++
+			return s, nil
+		}
+		v, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		s = append(s, v)
+		if p.ws(true); p.pos == len(p.s) {
+			return nil, p.errorf(start, "unterminated tuple")
+		}
+		switch p.s[p.pos] {
+		case ',':
+			p.pos++
+		case ']':
+		default:
+			return nil, p.errorf(p.pos, "expected ',' or ']'")
+		}
+	}
+}
+
+// object parses an object like { a = 1, "b" = 2 }, whose items are separated by commas or
+// line breaks.
+func (p *hclParser) object() (interface{}, error) {
+	start := p.pos
+	p.pos++
+	m := make(map[string]interface{})
+	for {
+		if p.ws(true); p.pos == len(p.s) {
+			return nil, p.errorf(start, "unterminated object")
+		}
+		if p.s[p.pos] == '}' {
+			p.pos++
+			return m, nil
+		}
+		at := p.pos
+		key := p.ident()
+		if key == "" && p.s[p.pos] == '"' {
+			var err error
+			if key, err = p.str(); err != nil {
+				return nil, err
+			}
+		}
+		if key == "" {
+			return nil, p.errorf(at, "expected an object key")
+		}
+		if p.ws(false); p.pos == len(p.s) || p.s[p.pos] != '=' && p.s[p.pos] != ':' {
+			return nil, p.errorf(p.pos, "expected '=' or ':' after the object key")
+		}
+		p.pos++
+		p.ws(false)
+		v, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := m[key]; ok {
+			return nil, p.errorf(at, "duplicate object key %q", key)
+		}
+		m[key] = v
+		nl := p.ws(false)
+		if p.pos == len(p.s) {
+			return nil, p.errorf(start, "unterminated object")
+		}
+		switch c := p.s[p.pos]; {
+		case c == ',':
+			p.pos++
+		case c == '}' || c == '\n' || nl:
+		default:
+			return nil, p.errorf(p.pos, "expected ',', a line break or '}'")
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package cconf
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadHCL(t *testing.T) {
+	c := New()
+	if err := c.Load("./testdata/app.json", "./testdata/app.hcl"); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, "cconf-hcl", c.GetString("name"))
+	equal(t, nil, c.Get("version"))
+	equal(t, "syyong.x", c.GetString("ext.author"))
+	equal(t, "hcl@example.com", c.GetString("ext.email"))
+	equal(t, 8080, c.GetInt("server.web.http.port"))
+	equal(t, 9090, c.GetInt("server.api.http.port"))
+	equal(t, []interface{}{"a", "b"}, c.Get("server.web.tags"))
+	equal(t, "10.0.0.2", c.GetString("upstream.1.host"))
+	equal(t, "Welcome!\n  indented\n", c.GetString("motd"))
+
+	var upstreams []struct {
+		Host   string  `cconf:"host"`
+		Weight float64 `cconf:"weight"`
+	}
+	if err := c.Populate(&upstreams, "upstream"); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, 2, len(upstreams))
+	equal(t, "10.0.0.1", upstreams[0].Host)
+	equal(t, 2.5, upstreams[1].Weight)
+
+	if err := c.LoadReader("hcl", strings.NewReader(`ext { email = "reader@example.com" }`)); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, "reader@example.com", c.GetString("ext.email"))
+	equal(t, "syyong.x", c.GetString("ext.author"))
+}
+
+func TestParseHCL(t *testing.T) {
+	for doc, want := range map[string]interface{}{
+		"":                             map[string]interface{}{},
+		"/* only\n comments */ // x\n": map[string]interface{}{},
+		"a = 1\nb = -2.5e1\nc = true\nd = null\ne = \"x\\ty \\u00e9\"": map[string]interface{}{
+			"a": 1, "b": -25.0, "c": true, "d": nil, "e": "x\ty é",
+		},
+		`s = "${var.x} and $${literal}"`: map[string]interface{}{"s": "${var.x} and ${literal}"},
+		"o = {\n  a = 1\n  \"b c\": [1, \"x\",]\n}": map[string]interface{}{
+			"o": map[string]interface{}{"a": 1, "b c": []interface{}{1, "x"}},
+		},
+		"h = <<EOF\n  x\nEOF\n": map[string]interface{}{"h": "  x\n"},
+		"a \"b\" c {\n  d = 1\n}": map[string]interface{}{
+			"a": map[string]interface{}{"b": map[string]interface{}{"c": map[string]interface{}{"d": 1}}},
+		},
+		"r \"x\" { a = 1 }\nr \"x\" { a = 2 }\nr \"y\" {}\nr \"x\" { a = 3 }": map[string]interface{}{"r": map[string]interface{}{
+			"x": []interface{}{
+				map[string]interface{}{"a": 1}, map[string]interface{}{"a": 2}, map[string]interface{}{"a": 3},
+			},
+			"y": map[string]interface{}{},
+		}},
+		"u {\n  c {}\n}\nu {\n  c \"x\" {}\n}": map[string]interface{}{"u": []interface{}{
+			map[string]interface{}{"c": map[string]interface{}{}},
+			map[string]interface{}{"c": map[string]interface{}{"x": map[string]interface{}{}}},
+		}},
+	} {
+		var v interface{}
+		if err := parseHCL(doc, &v); err != nil {
+			t.Errorf("%q: %v", doc, err)
+			continue
+		}
+		if !reflect.DeepEqual(want, v) {
+			t.Errorf("%q: expected %#v, got %#v", doc, want, v)
+		}
+	}
+}
+
+func TestParseHCLErrors(t *testing.T) {
+	for doc, want := range map[string]string{
+		"a = 1\na = 2":      `line 2, column 1: "a" is already defined`,
+		"a = 1\na {}":       `line 2, column 1: block a conflicts with "a"`,
+		"a \"x\" {}\na {}":  `line 2, column 1: block a conflicts with "a"`,
+		"a = var.x":         `line 1, column 5: unsupported expression "var", only literal values are supported`,
+		"a = 1 b = 2":       `line 1, column 7: expected a line break, found 'b'`,
+		"a = \"x":           "line 1, column 5: unterminated string",
+		"a = \"\\q\"":       `line 1, column 6: invalid escape sequence \q`,
+		"a = [1 2]":         "line 1, column 8: expected ',' or ']'",
+		"a = {b = 1 c = 2}": "line 1, column 12: expected ',', a line break or '}'",
+		"a = 1.":            `line 1, column 5: invalid number "1."`,
+		"a {\n  b = 1\n":    "line 1, column 1: unterminated block a",
+		"a b":               "line 1, column 4: expected '{' after a b",
+		"a 1 {}":            "line 1, column 3: expected '=', a block label or '{' after a",
+		"a = <<EOF\nx\n":    "line 1, column 5: unterminated heredoc EOF",
+		"}":                 `line 1, column 1: unexpected '}'`,
+		"= 1":               `line 1, column 1: expected an attribute or a block, found '='`,
+		"a = \"${x\"":       "line 1, column 6: unterminated template sequence",
+	} {
+		var v interface{}
+		err := parseHCL(doc, &v)
+		var se *SyntaxError
+		if !errors.As(err, &se) {
+			t.Errorf("%q: expected a *SyntaxError, got %v", doc, err)
+			continue
+		}
+		equal(t, want, err.Error())
+	}
+}
@@ -0,0 +1,96 @@
+package cconf
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// HTTPProvider is a RemoteProvider fetching configuration data from a URL.
+// It sends conditional requests based on the ETag and Last-Modified headers of the previous
+// response, so that an unchanged configuration is answered with a cheap 304 Not Modified.
+type HTTPProvider struct {
+	URL string
+	// Client sends the requests, http.DefaultClient if nil.
+	Client *http.Client
+	// Decode decodes the response body, as JSON if nil.
+	Decode func(io.Reader, interface{}) error
+	// Interval is the interval at which Poll polls the URL, or 0 for the interval passed to Poll.
+	Interval time.Duration
+
+	mu       sync.Mutex
+	etag     string
+	modified string
+	data     interface{} // the data of the last response
+}
+
+// Name returns the URL.
+func (p *HTTPProvider) Name() string {
+	return p.URL
+}
+
+// Fetch returns the configuration data of the URL.
+func (p *HTTPProvider) Fetch() (interface{}, error) {
+	return p.FetchContext(context.Background())
+}
+
+// FetchContext returns the configuration data of the URL, canceling the request when ctx is done.
+func (p *HTTPProvider) FetchContext(ctx context.Context) (interface{}, error) {
+	data, _, err := p.FetchIfChanged(ctx)
+	return data, err
+}
+
+// FetchIfChanged returns the configuration data of the URL and whether it changed since the
+// last fetch, canceling the request when ctx is done. Unchanged data is not downloaded again.
+func (p *HTTPProvider) FetchIfChanged(ctx context.Context) (interface{}, bool, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.URL, nil)
+	if err != nil {
+		return nil, false, err
+	}
+	if p.data != nil {
+		if p.etag != "" {
+			req.Header.Set("If-None-Match", p.etag)
+		}
+		if p.modified != "" {
+			req.Header.Set("If-Modified-Since", p.modified)
+		}
+	}
+	client := p.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, false, err
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusNotModified && p.data != nil:
+		return p.data, false, nil
+	case resp.StatusCode != http.StatusOK:
+		return nil, false, fmt.Errorf("%s: unexpected status %s", p.URL, resp.Status)
+	}
+
+	decode := p.Decode
+	if decode == nil {
+		decode = decodeJSON
+	}
+	var data interface{}
+	if err := decode(resp.Body, &data); err != nil {
+		return nil, false, err
+	}
+	p.etag = resp.Header.Get("ETag")
+	p.modified = resp.Header.Get("Last-Modified")
+	p.data = data
+	return data, true, nil
+}
+
+// PollInterval returns the Interval.
+func (p *HTTPProvider) PollInterval() time.Duration {
+	return p.Interval
+}
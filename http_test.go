@@ -0,0 +1,81 @@
+package cconf
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+// configServer serves a JSON document with an ETag, answering conditional requests.
+type configServer struct {
+	mu          sync.Mutex
+	body, etag  string
+	requests    int
+	notModified int
+}
+
+func (s *configServer) set(body, etag string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.body, s.etag = body, etag
+}
+
+func (s *configServer) counts() (int, int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests, s.notModified
+}
+
+func (s *configServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests++
+	if r.Header.Get("If-None-Match") == s.etag {
+		s.notModified++
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("ETag", s.etag)
+	w.Write([]byte(s.body))
+}
+
+func TestHTTPProvider(t *testing.T) {
+	s := &configServer{}
+	s.set(`{"name": "app"}`, `"v1"`)
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+
+	p := &HTTPProvider{URL: srv.URL}
+	data, changed, err := p.FetchIfChanged(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	equal(t, true, changed)
+	equal(t, map[string]interface{}{"name": "app"}, data)
+
+	data, changed, err = p.FetchIfChanged(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	equal(t, false, changed)
+	equal(t, map[string]interface{}{"name": "app"}, data)
+	requests, notModified := s.counts()
+	equal(t, 2, requests)
+	equal(t, 1, notModified)
+
+	s.set(`{"name": "new app"}`, `"v2"`)
+	c := New()
+	if err := c.LoadRemote(p); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, "new app", c.GetString("name"))
+
+	// broken data
+	p = &HTTPProvider{URL: srv.URL, Client: &http.Client{}}
+	s.set(`{"name": `, `"v3"`)
+	if _, _, err := p.FetchIfChanged(context.Background()); err == nil {
+		t.Fatal("expected an error")
+	}
+}
@@ -0,0 +1,86 @@
+package cconf
+
+import (
+	"strconv"
+	"strings"
+)
+
+// EnableIndex makes the Conf maintain a flattened index from every path of the store to its value,
+// so that Get becomes a single map lookup even for keys that have never been read.
+// The index is built on every Load/SetStore and updated incrementally on Set,
+// at the cost of memory proportional to the number of nodes in the store.
+func (c *Conf) EnableIndex() {
+	c.mu.Lock()
+	defer c.unlock()
+	if c.indexed {
+		return
+	}
+	c.indexed = true
+	c.reset(c.snap.Load().store)
+}
+
+// publish builds the index of the snapshot if needed and swaps it in.
+// The caller must hold c.mu.
+func (c *Conf) publish(s *snapshot) {
+	if !c.changed && (c.changeHandlers.Load() != nil || c.reloadHandlers.Load() != nil) {
+		// remember the store to compare for OnReload and OnChange, see unlock.
+		c.prev, c.changed = c.snap.Load().store, true
+	}
+	if c.indexed && s.index == nil {
+		s.index = make(map[string]interface{})
+		indexValue(s.index, "", s.store, c.Separator)
+	}
+	c.snap.Store(s)
+}
+
+// reset publishes the store with an empty cache.
+// The caller must hold c.mu.
+func (c *Conf) reset(store interface{}) {
+	c.publish(c.newSnapshot(store))
+	c.queue(Event{Type: EventCacheReset})
+}
+
+// reindex derives the index of the snapshot from the old one after the value at key was set.
+func (s *snapshot) reindex(old *snapshot, key, sep string) {
+	s.index = make(map[string]interface{}, len(old.index))
+	for k, v := range old.index {
+		if k != key && !strings.HasPrefix(k, key+sep) && !strings.HasPrefix(key, k+sep) {
+			s.index[k] = v
+		}
+	}
+
+	// the containers on the path were copied, index them again.
+	v := s.store
+	segs := strings.Split(key, sep)
+	for i, seg := range segs {
+		var ok bool
+		if v, ok = getElement(v, seg); !ok {
+			return
+		}
+		if i < len(segs)-1 {
+			s.index[strings.Join(segs[:i+1], sep)] = v
+		}
+	}
+	indexValue(s.index, key, v, sep)
+}
+
+// indexValue adds the value and all of its elements to the index.
+func indexValue(index map[string]interface{}, key string, v interface{}, sep string) {
+	if v == nil {
+		return
+	}
+	if key != "" {
+		index[key] = v
+		key += sep
+	}
+	switch d := v.(type) {
+	case map[string]interface{}:
+		for k, e := range d {
+			indexValue(index, key+k, e, sep)
+		}
+	case []interface{}:
+		for i, e := range d {
+			indexValue(index, key+strconv.Itoa(i), e, sep)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package cconf
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestEnableIndex(t *testing.T) {
+	c := New()
+	c.EnableIndex()
+	c.SetStore(map[string]interface{}{
+		"db": map[string]interface{}{
+			"hosts": []interface{}{"a", "b"},
+			"port":  3306,
+		},
+		"name": "app",
+	})
+
+	index := c.snap.Load().index
+	equal(t, 6, len(index))
+	equal(t, "b", index["db.hosts.1"])
+	equal(t, 3306, c.Get("db.port"))
+	equal(t, []interface{}{"a", "b"}, c.Get("db.hosts"))
+
+	if err := c.Set("db.user.name", "root"); err != nil {
+		t.Fatal(err)
+	}
+	index = c.snap.Load().index
+	equal(t, "root", index["db.user.name"])
+	equal(t, map[string]interface{}{"name": "root"}, index["db.user"])
+	equal(t, "root", c.GetString("db.user.name"))
+	equal(t, "root", c.Get("db").(map[string]interface{})["user"].(map[string]interface{})["name"])
+
+	if err := c.Set("db", map[string]interface{}{"port": 5432}); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, 5432, c.Get("db.port"))
+	equal(t, nil, c.Get("db.hosts.0"))
+	equal(t, "app", c.Get("name"))
+	equal(t, 3, len(c.snap.Load().index))
+}
+
+func BenchmarkColdGet(b *testing.B) {
+	for _, indexed := range []bool{false, true} {
+		name := "walk"
+		if indexed {
+			name = "index"
+		}
+		b.Run(name, func(b *testing.B) {
+			c := New()
+			if indexed {
+				c.EnableIndex()
+			}
+			if err := c.Load("./testdata/server.json"); err != nil {
+				b.Fatal(err)
+			}
+			s := c.snap.Load()
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				s.cache = sync.Map{}
+				c.GetInt("server.Limits.Conns")
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package cconf
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+// loadINI reads and parses an INI file.
+func loadINI(file string, data interface{}) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return decodeINI(f, data)
+}
+
+// decodeINI parses an INI document from the reader into data, which must be a *interface{}.
+// The keys before the first section are set at the root, and the keys of a section below
+// the key of the section, with the dotted names of sections like [database.replica] and of
+// keys nested like other keys, so that "host" in [database] is "database.host".
+// Values are strings, with surrounding quotes removed; comments start with ";" or "#",
+// also after unquoted values. A repeated key replaces the value of the earlier one, and a
+// repeated section adds to it. Malformed lines fail with a *SyntaxError.
+func decodeINI(r io.Reader, data interface{}) error {
+	root := make(map[string]interface{})
+	section, sectionName := root, ""
+	scanner := bufio.NewScanner(r)
+	for num := 1; scanner.Scan(); num++ {
+		line := scanner.Text()
+		if num == 1 {
+			line = strings.TrimPrefix(line, "\ufeff")
+		}
+		text := strings.TrimSpace(line)
+		errorf := func(off int, format string, args ...interface{}) error {
+			col := utf8.RuneCountInString(line[:len(line)-len(strings.TrimLeft(line, " \t"))+off]) + 1
+			return &SyntaxError{Line: num, Column: col, Err: fmt.Errorf(format, args...)}
+		}
+		switch {
+		case text == "" || text[0] == ';' || text[0] == '#':
+			continue
+		case text[0] == '[':
+			end := strings.IndexByte(text, ']')
+			if end < 0 {
+				return errorf(0, "expected ']' after the section name")
+			}
+			if rest := strings.TrimSpace(text[end+1:]); rest != "" && rest[0] != ';' && rest[0] != '#' {
+				return errorf(end+1, "unexpected %q after the section", rest)
+			}
+			sectionName = strings.TrimSpace(text[1:end])
+			if sectionName == "" {
+				return errorf(0, "empty section name")
+			}
+			m, err := nestedMap(root, iniPath(sectionName))
+			if err != nil {
+				return errorf(0, "cannot define section %q: %v", sectionName, err)
+			}
+			section = m
+			continue
+		}
+
+		eq := strings.IndexAny(text, "=:")
+		if eq < 0 {
+			return errorf(0, "expected '=' after the key")
+		}
+		key := strings.TrimSpace(text[:eq])
+		if key == "" {
+			return errorf(0, "empty key")
+		}
+		val, err := iniValue(strings.TrimSpace(text[eq+1:]))
+		if err != nil {
+			return errorf(eq+1, "%v", err)
+		}
+		segs := iniPath(key)
+		m, err := nestedMap(section, segs[:len(segs)-1])
+		if err == nil {
+			if _, ok := m[segs[len(segs)-1]].(map[string]interface{}); ok {
+				err = fmt.Errorf("%q is a section", key)
+			}
+		}
+		if err != nil {
+			return errorf(0, "cannot set %q: %v", strings.TrimPrefix(sectionName+"."+key, "."), err)
+		}
+		m[segs[len(segs)-1]] = val
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	*data.(*interface{}) = root
+	return nil
+}
+
+// iniPath returns the segments of a dotted section name or key.
+func iniPath(name string) []string {
+	segs := strings.Split(name, ".")
+	for i, seg := range segs {
+		segs[i] = strings.TrimSpace(seg)
+	}
+	return segs
+}
+
+// iniValue returns the value without its comment and its surrounding quotes.
+func iniValue(s string) (string, error) {
+	if s != "" && (s[0] == '"' || s[0] == '\'') {
+		end := strings.IndexByte(s[1:], s[0])
+		if end < 0 {
+			return "", fmt.Errorf("unterminated quoted value")
+		}
+		if rest := strings.TrimSpace(s[end+2:]); rest != "" && rest[0] != ';' && rest[0] != '#' {
+			return "", fmt.Errorf("unexpected %q after the quoted value", rest)
+		}
+		return s[1 : end+1], nil
+	}
+	for i := 1; i < len(s); i++ {
+		if (s[i] == ';' || s[i] == '#') && (s[i-1] == ' ' || s[i-1] == '\t') {
+			return strings.TrimSpace(s[:i]), nil
+		}
+	}
+	return s, nil
+}
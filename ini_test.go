@@ -0,0 +1,68 @@
+package cconf
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestLoadINI(t *testing.T) {
+	c := New()
+	if err := c.Load("./testdata/app.json", "./testdata/app.ini"); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, "cconf-ini", c.GetString("name"))
+	equal(t, "true", c.Get("debug"))
+	equal(t, "syyong.x", c.GetString("ext.author"))
+	equal(t, "ini@example.com", c.GetString("ext.email"))
+	equal(t, "keep it; simple", c.GetString("ext.motto"))
+	equal(t, "db.internal", c.GetString("database.host"))
+	equal(t, "5432", c.Get("database.port"))
+	equal(t, "replica.internal", c.GetString("database.replica.host"))
+
+	var db struct {
+		Host    string `cconf:"host"`
+		Port    string `cconf:"port"`
+		Replica struct {
+			Host string `cconf:"host"`
+		} `cconf:"replica"`
+	}
+	if err := c.Populate(&db, "database"); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, "replica.internal", db.Replica.Host)
+}
+
+func TestDecodeINI(t *testing.T) {
+	var v interface{}
+	doc := "a = 1\n# comment\n[s]\nb.c = x#y\nd =\n[s]\ne = \"\"\n[t.u]\n"
+	if err := decodeINI(strings.NewReader(doc), &v); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, map[string]interface{}{
+		"a": "1",
+		"s": map[string]interface{}{"b": map[string]interface{}{"c": "x#y"}, "d": "", "e": ""},
+		"t": map[string]interface{}{"u": map[string]interface{}{}},
+	}, v)
+
+	for doc, want := range map[string]string{
+		"[s":                  "line 1, column 1: expected ']' after the section name",
+		"[s] x":               `line 1, column 4: unexpected "x" after the section`,
+		"[]":                  "line 1, column 1: empty section name",
+		"a = 1\n  key":        "line 2, column 3: expected '=' after the key",
+		" = 1":                "line 1, column 2: empty key",
+		"a = \"x":             "line 1, column 4: unterminated quoted value",
+		"a = 'x' y":           `line 1, column 4: unexpected "y" after the quoted value`,
+		"[s]\na = 1\n[s.a]":   `line 3, column 1: cannot define section "s.a": "s.a" is a value`,
+		"[s.a]\n[s]\na = 1":   `line 3, column 1: cannot set "s.a": "a" is a section`,
+		"[s]\na = 1\na.b = 2": `line 3, column 1: cannot set "s.a.b": "a" is a value`,
+	} {
+		err := decodeINI(strings.NewReader(doc), &v)
+		var se *SyntaxError
+		if !errors.As(err, &se) {
+			t.Errorf("%q: expected a *SyntaxError, got %v", doc, err)
+			continue
+		}
+		equal(t, want, err.Error())
+	}
+}
@@ -1,15 +1,97 @@
 package cconf
 
 import (
+	"bufio"
 	"encoding/json"
-	"io/ioutil"
+	"fmt"
+	"io"
+	"os"
 )
 
 // load reads and parses a special format file.
 func loadJSON(file string, data interface{}) error {
-	bytes, err := ioutil.ReadFile(file)
+	f, err := os.Open(file)
 	if err != nil {
 		return err
 	}
-	return json.Unmarshal(bytes, data)
+	defer f.Close()
+	return decodeJSON(bufio.NewReader(f), data)
+}
+
+// dumpJSON writes the data to the file as indented JSON.
+func dumpJSON(file string, data interface{}) error {
+	return dumpFile(file, data, encodeJSON)
+}
+
+// encodeJSON writes the data to the writer as indented JSON.
+func encodeJSON(w io.Writer, data interface{}) error {
+	b, err := json.MarshalIndent(data, "", "\t")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(b, '\n'))
+	return err
+}
+
+// decodeJSON parses JSON from the reader into data, which must be a *interface{}.
+// The input is decoded token by token, so that only the current token is buffered
+// instead of the whole document.
+func decodeJSON(r io.Reader, data interface{}) error {
+	dec := json.NewDecoder(r)
+	v, err := decodeJSONValue(dec)
+	if err != nil {
+		return err
+	}
+	// only a single top-level value is allowed.
+	if t, err := dec.Token(); err != io.EOF {
+		if err != nil {
+			return err
+		}
+		return fmt.Errorf("invalid token %v after top-level value at offset %d", t, dec.InputOffset())
+	}
+	*data.(*interface{}) = v
+	return nil
+}
+
+// decodeJSONValue decodes the next value from dec into maps, slices and scalars.
+func decodeJSONValue(dec *json.Decoder) (interface{}, error) {
+	t, err := dec.Token()
+	if err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	switch t {
+	case json.Delim('{'):
+		m := make(map[string]interface{})
+		for dec.More() {
+			k, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			if m[k.(string)], err = decodeJSONValue(dec); err != nil {
+				return nil, err
+			}
+		}
+		// consume the closing delimiter.
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+		return m, nil
+	case json.Delim('['):
+		s := make([]interface{}, 0)
+		for dec.More() {
+			v, err := decodeJSONValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			s = append(s, v)
+		}
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+		return s, nil
+	}
+	return t, nil
 }
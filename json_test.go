@@ -0,0 +1,102 @@
+package cconf
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDecodeJSON(t *testing.T) {
+	valid := []string{
+		`{"a":1,"b":[true,null,"x",{"c":1.5}],"a":2}`,
+		` [1, 2] `,
+		`"scalar"`,
+	}
+	for _, in := range valid {
+		var expected, actual interface{}
+		if err := json.Unmarshal([]byte(in), &expected); err != nil {
+			t.Fatal(err)
+		}
+		if err := decodeJSON(strings.NewReader(in), &actual); err != nil {
+			t.Fatalf("%q: %v", in, err)
+		}
+		equal(t, expected, actual)
+	}
+
+	invalid := []string{``, `{"a":`, `{"a" 1}`, `[1,]`, `{"a":1} {}`, `{"a":1} x`}
+	for _, in := range invalid {
+		var actual interface{}
+		if err := decodeJSON(strings.NewReader(in), &actual); err == nil {
+			t.Errorf("%q: Expected an error", in)
+		}
+	}
+}
+
+func TestLoadReader(t *testing.T) {
+	c := New()
+	if err := c.Load("./testdata/app.json"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.LoadReader("json", strings.NewReader(`{"ext": {"email": "new@example.com"}}`)); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, "new@example.com", c.GetString("ext.email"))
+	equal(t, "syyong.x", c.GetString("ext.author"))
+
+	if err := c.LoadReader("cue", strings.NewReader("")); err == nil {
+		t.Error("Expected an error for an unregistered type")
+	}
+}
+
+// writeLargeJSON writes a generated JSON file of about n entries.
+func writeLargeJSON(tb testing.TB, n int) string {
+	file := filepath.Join(tb.TempDir(), "large.json")
+	f, err := os.Create(file)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	w := bufio.NewWriter(f)
+	w.WriteString("{")
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			w.WriteString(",")
+		}
+		fmt.Fprintf(w, `"key%d":{"name":"value %d","port":%d,"tags":["a","b","c"]}`, i, i, i)
+	}
+	w.WriteString("}")
+	if err := w.Flush(); err != nil {
+		tb.Fatal(err)
+	}
+	f.Close()
+	return file
+}
+
+func BenchmarkLoadLargeJSON(b *testing.B) {
+	file := writeLargeJSON(b, 50000)
+	b.Run("stream", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			var data interface{}
+			if err := loadJSON(file, &data); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("readfile", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			bytes, err := os.ReadFile(file)
+			if err != nil {
+				b.Fatal(err)
+			}
+			var data interface{}
+			if err := json.Unmarshal(bytes, &data); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
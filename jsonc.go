@@ -0,0 +1,85 @@
+package cconf
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+)
+
+// loadJSONC reads and parses a JSON file with comments, see decodeJSONC.
+func loadJSONC(file string, data interface{}) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return decodeJSONC(f, data)
+}
+
+// decodeJSONC parses JSON with // and /* */ comments and trailing commas before '}' and ']'
+// from the reader into data, which must be a *interface{}. Of JSON5 only these extensions
+// are supported. The comments and trailing commas are blanked out instead of removed, so
+// that syntax errors are reported at their position in the original document.
+func decodeJSONC(r io.Reader, data interface{}) error {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	stripped, err := stripJSONC(b)
+	if err != nil {
+		return err
+	}
+	if err := decodeJSON(bytes.NewReader(stripped), data); err != nil {
+		return positioned(string(b), err)
+	}
+	return nil
+}
+
+// stripJSONC returns a copy of the document with the comments and trailing commas replaced by
+// spaces, keeping the line breaks of block comments. Strings are copied unchanged.
+func stripJSONC(b []byte) ([]byte, error) {
+	out := make([]byte, len(b))
+	copy(out, b)
+	comma := -1   // the offset of the last comma after a value not yet followed by another
+	var prev byte // the last character outside of comments and whitespace
+	for i := 0; i < len(b); i++ {
+		switch c := b[i]; {
+		case c == '"':
+			// skip the string, which is left to the JSON decoder if unterminated.
+			for i++; i < len(b) && b[i] != '"'; i++ {
+				if b[i] == '\\' {
+					i++
+				}
+			}
+			comma, prev = -1, c
+		case c == '/' && i+1 < len(b) && b[i+1] == '/':
+			for ; i < len(b) && b[i] != '\n'; i++ {
+				out[i] = ' '
+			}
+		case c == '/' && i+1 < len(b) && b[i+1] == '*':
+			end := bytes.Index(b[i+2:], []byte("*/"))
+			if end < 0 {
+				return nil, newSyntaxError(string(b), i, errors.New("unterminated comment"))
+			}
+			end += i + 4
+			for ; i < end; i++ {
+				if out[i] != '\n' && out[i] != '\r' {
+					out[i] = ' '
+				}
+			}
+			i--
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+		default:
+			if (c == '}' || c == ']') && comma >= 0 {
+				out[comma] = ' '
+			}
+			comma = -1
+			if c == ',' && prev != ',' && prev != '[' && prev != '{' && prev != 0 {
+				comma = i
+			}
+			prev = c
+		}
+	}
+	return out, nil
+}
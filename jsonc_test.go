@@ -0,0 +1,56 @@
+package cconf
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestLoadJSONC(t *testing.T) {
+	c := New()
+	if err := c.Load("./testdata/app.json", "./testdata/app.jsonc"); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, "cconf-jsonc", c.GetString("name"))
+	equal(t, "syyong.x", c.GetString("ext.author"))
+	equal(t, "jsonc@example.com", c.GetString("ext.email"))
+	equal(t, "https://github.com/syyongx/cconf", c.GetString("ext.home"))
+	equal(t, "/* kept */", c.GetString("ext.note"))
+	equal(t, []interface{}{"a", "b"}, c.Get("tags"))
+
+	if err := c.LoadReader("json5", strings.NewReader(`{"ext": {"email": "reader@example.com",},}`)); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, "reader@example.com", c.GetString("ext.email"))
+}
+
+func TestDecodeJSONC(t *testing.T) {
+	for doc, want := range map[string]interface{}{
+		`[1, /* , */ 2 /**/,// ]` + "\n]": []interface{}{1.0, 2.0},
+		`{"a\"//": "\\", "b": {}, }`:      map[string]interface{}{`a"//`: `\`, "b": map[string]interface{}{}},
+		"/* a\n b */ \"x\" // end":        "x",
+	} {
+		var v interface{}
+		if err := decodeJSONC(strings.NewReader(doc), &v); err != nil {
+			t.Errorf("%q: %v", doc, err)
+			continue
+		}
+		equal(t, want, v)
+	}
+
+	for doc, want := range map[string]string{
+		"{\n  // comment\n  \"a\": 1 x\n}": "line 3, column 10: invalid character 'x' after object key:value pair",
+		"[1, /* two */ ,]":                 "line 1, column 15: invalid character ',' looking for beginning of value",
+		"[,]":                              "line 1, column 2: invalid character ',' looking for beginning of value",
+		"[1] /* open":                      "line 1, column 5: unterminated comment",
+	} {
+		var v interface{}
+		err := decodeJSONC(strings.NewReader(doc), &v)
+		var se *SyntaxError
+		if !errors.As(err, &se) {
+			t.Errorf("%q: expected a *SyntaxError, got %v", doc, err)
+			continue
+		}
+		equal(t, want, err.Error())
+	}
+}
@@ -0,0 +1,80 @@
+package cconf
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+// now returns the current time, replaced by tests.
+var now = time.Now
+
+// lazyProvider fetches the values of the keys below a prefix on demand, see RegisterLazy.
+type lazyProvider struct {
+	c      *Conf
+	prefix string
+	ttl    time.Duration
+	fetch  func(ctx context.Context, key string) (interface{}, error)
+	values sync.Map // the fetched values by key
+}
+
+// lazyValue is a value fetched by a lazyProvider.
+type lazyValue struct {
+	val     interface{}
+	fetched time.Time
+}
+
+// RegisterLazy registers a function fetching the values of the keys below the prefix on demand,
+// like feature flags of a remote service. Get and the typed getters return the value fetched
+// for the key, which is fetched again once it is older than the ttl; concurrent fetches of a key
+// are coalesced. If a fetch fails, the last fetched value is returned and the error is reported
+// by an EventLoad of the source "lazy:KEY" (see OnEvent). A nil value means there is none.
+// The values are not part of the store; keys below the prefix are not looked up in the store.
+func (c *Conf) RegisterLazy(prefix string, ttl time.Duration, fetch func(ctx context.Context, key string) (interface{}, error)) {
+	c.mu.Lock()
+	defer c.unlock()
+	p := &lazyProvider{c: c, prefix: strings.TrimSuffix(prefix, c.Separator), ttl: ttl, fetch: fetch}
+	c.lazy = append(append([]*lazyProvider(nil), c.lazy...), p)
+	c.republish()
+}
+
+// lookupLazy returns the value of the key fetched by the lazy provider of its prefix,
+// and whether the key has a lazy provider.
+func (s *snapshot) lookupLazy(key, sep string) (interface{}, bool, bool) {
+	for _, p := range s.lazy {
+		if key == p.prefix || strings.HasPrefix(key, p.prefix+sep) {
+			v, ok := p.get(key)
+			return v, ok, true
+		}
+	}
+	return nil, false, false
+}
+
+// get returns the value of the key, fetching it if there is no fresh one.
+func (p *lazyProvider) get(key string) (interface{}, bool) {
+	var stale *lazyValue
+	if e, ok := p.values.Load(key); ok {
+		stale = e.(*lazyValue)
+		if now().Sub(stale.fetched) < p.ttl {
+			return stale.val, stale.val != nil
+		}
+	}
+	v, err := p.c.flight.do("lazy:"+key, func() (interface{}, error) {
+		start := now()
+		v, err := p.fetch(p.c.ctx, key)
+		if err != nil {
+			p.c.fire([]Event{{Type: EventLoad, Source: "lazy:" + key, Duration: now().Sub(start), Err: err}})
+			return nil, err
+		}
+		p.values.Store(key, &lazyValue{val: normalize(v), fetched: now()})
+		return normalize(v), nil
+	})
+	if err != nil {
+		if stale == nil {
+			return nil, false
+		}
+		return stale.val, stale.val != nil
+	}
+	return v, v != nil
+}
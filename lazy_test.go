@@ -0,0 +1,83 @@
+package cconf
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRegisterLazy(t *testing.T) {
+	defer func(n func() time.Time) { now = n }(now)
+	clock := time.Unix(0, 0)
+	now = func() time.Time { return clock }
+
+	c := New()
+	if err := c.Set("flags.local", true); err != nil {
+		t.Fatal(err)
+	}
+	var fetches atomic.Int32
+	var fail atomic.Bool
+	c.RegisterLazy("flags.", time.Minute, func(ctx context.Context, key string) (interface{}, error) {
+		n := fetches.Add(1)
+		if fail.Load() {
+			return nil, errors.New("service unavailable")
+		}
+		if key == "flags.missing" {
+			return nil, nil
+		}
+		return n%2 == 1, nil
+	})
+	var errs []error
+	c.OnEvent(func(e Event) {
+		if e.Type == EventLoad && e.Err != nil {
+			errs = append(errs, e.Err)
+		}
+	})
+
+	equal(t, true, c.GetBool("flags.search.enabled"))
+	equal(t, int32(1), fetches.Load())
+	equal(t, false, c.GetBool("flags.local", false))
+	equal(t, "default", c.Get("flags.missing", "default"))
+	equal(t, int32(3), fetches.Load())
+	equal(t, true, c.Get("local", true))
+
+	// the value is fresh for the ttl.
+	clock = clock.Add(59 * time.Second)
+	equal(t, true, c.GetBool("flags.search.enabled"))
+	equal(t, int32(3), fetches.Load())
+
+	clock = clock.Add(time.Second)
+	equal(t, false, c.GetBool("flags.search.enabled", true))
+	equal(t, int32(4), fetches.Load())
+
+	// failed fetches return the stale value.
+	fail.Store(true)
+	clock = clock.Add(time.Minute)
+	equal(t, false, c.GetBool("flags.search.enabled", true))
+	equal(t, 1, len(errs))
+	equal(t, "service unavailable", errs[0].Error())
+	equal(t, "default", c.Get("flags.other", "default"))
+}
+
+func TestRegisterLazySingleflight(t *testing.T) {
+	c := New()
+	var fetches atomic.Int32
+	c.RegisterLazy("flags", time.Minute, func(ctx context.Context, key string) (interface{}, error) {
+		fetches.Add(1)
+		time.Sleep(20 * time.Millisecond)
+		return "on", nil
+	})
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			equal(t, "on", c.GetString("flags.beta"))
+		}()
+	}
+	wg.Wait()
+	equal(t, int32(1), fetches.Load())
+}
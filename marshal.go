@@ -0,0 +1,57 @@
+package cconf
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// MarshalJSON implements json.Marshaler: it encodes the store as JSON, an empty object if
+// nothing is loaded, with the values of the keys marked with MarkSecret redacted, so that a
+// Conf can be passed to structured logs and debug endpoints as is. See WithRedaction to
+// encode the real values.
+func (c *Conf) MarshalJSON() ([]byte, error) {
+	return c.WithRedaction(true).MarshalJSON()
+}
+
+// WithRedaction returns a json.Marshaler encoding the store like MarshalJSON, which redacts
+// the values of the keys marked with MarkSecret only if redact is true.
+func (c *Conf) WithRedaction(redact bool) json.Marshaler {
+	return redaction{c: c, redact: redact}
+}
+
+// redaction encodes the store of a Conf, see WithRedaction.
+type redaction struct {
+	c      *Conf
+	redact bool
+}
+
+func (r redaction) MarshalJSON() ([]byte, error) {
+	store := r.c.GetStoreCopy(r.redact)
+	if store == nil {
+		return []byte("{}"), nil
+	}
+	return json.Marshal(store)
+}
+
+// UnmarshalJSON implements json.Unmarshaler: it replaces the store with the decoded JSON object,
+// like SetStore, so that a Conf embedded in another struct can be decoded with it. The Conf
+// must have been created by New. A null leaves the Conf unchanged.
+func (c *Conf) UnmarshalJSON(b []byte) error {
+	if c.DecodeFuncs == nil {
+		return errors.New("cannot unmarshal JSON into a Conf not created by New")
+	}
+	var data interface{}
+	if err := decodeJSON(bytes.NewReader(b), &data); err != nil {
+		return err
+	}
+	switch data.(type) {
+	case nil:
+		return nil
+	case map[string]interface{}:
+		c.SetStore(data)
+		return nil
+	}
+	return fmt.Errorf("cannot unmarshal JSON %T into a Conf, an object is required", data)
+}
@@ -0,0 +1,71 @@
+package cconf
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestMarshalJSON(t *testing.T) {
+	c := New()
+	b, err := json.Marshal(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	equal(t, "{}", string(b))
+
+	c.SetStore(map[string]interface{}{
+		"app": map[string]interface{}{"name": "cconf", "port": 8080},
+		"db":  map[string]interface{}{"password": "secret"},
+	})
+	c.MarkSecret("db.password")
+	b, err = json.Marshal(struct {
+		Conf *Conf `json:"conf"`
+	}{c})
+	if err != nil {
+		t.Fatal(err)
+	}
+	equal(t, `{"conf":{"app":{"name":"cconf","port":8080},"db":{"password":"[REDACTED]"}}}`, string(b))
+
+	b, err = json.Marshal(c.WithRedaction(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	equal(t, `{"app":{"name":"cconf","port":8080},"db":{"password":"secret"}}`, string(b))
+}
+
+func TestUnmarshalJSON(t *testing.T) {
+	status := struct {
+		Version string `json:"version"`
+		Conf    *Conf  `json:"conf"`
+	}{Conf: New()}
+	err := json.Unmarshal([]byte(`{"version":"1.0","conf":{"app":{"name":"cconf","port":8080}}}`), &status)
+	if err != nil {
+		t.Fatal(err)
+	}
+	equal(t, "1.0", status.Version)
+	equal(t, "cconf", status.Conf.GetString("app.name"))
+	equal(t, 8080, status.Conf.GetInt("app.port"))
+	equal(t, "SetStore()", status.Conf.Source("app.name"))
+
+	// round trip
+	b, err := json.Marshal(status.Conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := New()
+	if err := json.Unmarshal(b, c); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, status.Conf.GetStore(), c.GetStore())
+
+	if err := json.Unmarshal([]byte(`null`), c); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, "cconf", c.GetString("app.name"))
+	if err := json.Unmarshal([]byte(`[1, 2]`), c); err == nil {
+		t.Fatal("expected an error")
+	}
+	if err := json.Unmarshal([]byte(`{}`), new(Conf)); err == nil {
+		t.Fatal("expected an error")
+	}
+}
@@ -0,0 +1,82 @@
+package cconf
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// Option configures a Conf created by NewFromMap or NewFromJSON before the data is loaded,
+// like
+//
+//	func(c *Conf) { c.Separator = "/" }
+type Option func(c *Conf)
+
+// SyntaxError describes malformed configuration data at a position.
+type SyntaxError struct {
+	Line   int // the line of the error, starting at 1
+	Column int // the column of the error in characters, starting at 1
+	Err    error
+}
+
+// Error returns the error message represented by SyntaxError
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("line %d, column %d: %v", e.Line, e.Column, e.Err)
+}
+
+// Unwrap returns the error of the parser.
+func (e *SyntaxError) Unwrap() error {
+	return e.Err
+}
+
+// NewFromMap returns a Conf holding the data, normalized like SetStore, after applying the
+// options, e.g. to set up tests in a single expression.
+func NewFromMap(data map[string]interface{}, opts ...Option) *Conf {
+	c := New()
+	for _, opt := range opts {
+		opt(c)
+	}
+	c.SetStore(data)
+	return c
+}
+
+// NewFromJSON returns a Conf holding the JSON document, loaded like LoadReader, after applying
+// the options. Malformed JSON fails with a *SyntaxError noting the line and column.
+func NewFromJSON(s string, opts ...Option) (*Conf, error) {
+	c := New()
+	for _, opt := range opts {
+		opt(c)
+	}
+	if err := c.LoadReader("json", strings.NewReader(s)); err != nil {
+		return nil, positioned(s, err)
+	}
+	return c, nil
+}
+
+// positioned returns the error of decoding the JSON document as a *SyntaxError if its
+// position is known.
+func positioned(s string, err error) error {
+	var se *json.SyntaxError
+	offset := -1
+	switch {
+	case errors.As(err, &se):
+		// the offset is after the offending character.
+		offset = max(int(se.Offset)-1, 0)
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		offset = len(s)
+	}
+	if offset < 0 || offset > len(s) {
+		return err
+	}
+	return newSyntaxError(s, offset, err)
+}
+
+// newSyntaxError returns a *SyntaxError at the byte offset of the document.
+func newSyntaxError(s string, offset int, err error) *SyntaxError {
+	line := strings.Count(s[:offset], "\n") + 1
+	start := strings.LastIndex(s[:offset], "\n") + 1
+	return &SyntaxError{Line: line, Column: utf8.RuneCountInString(s[start:offset]) + 1, Err: err}
+}
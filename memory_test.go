@@ -0,0 +1,45 @@
+package cconf
+
+import (
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestNewFromMap(t *testing.T) {
+	c := NewFromMap(map[string]interface{}{
+		"http":  map[interface{}]interface{}{"port": 8080},
+		"hosts": []string{"a", "b"},
+	})
+	equal(t, 8080, c.GetInt("http.port"))
+	equal(t, []interface{}{"a", "b"}, c.Get("hosts"))
+
+	c = NewFromMap(map[string]interface{}{"http": map[string]interface{}{"port": 8080}}, func(c *Conf) {
+		c.Separator = "/"
+	})
+	equal(t, 8080, c.GetInt("http/port"))
+}
+
+func TestNewFromJSON(t *testing.T) {
+	c, err := NewFromJSON(`{"http": {"port": 8080}, "name": "app"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	equal(t, 8080, c.GetInt("http.port"))
+	equal(t, "app", c.GetString("name"))
+
+	_, err = NewFromJSON("{\n  \"a\": 1,\n  \"b\": ]\n}")
+	var se *SyntaxError
+	if !errors.As(err, &se) {
+		t.Fatalf("Expected a *SyntaxError, got %v", err)
+	}
+	equal(t, 3, se.Line)
+	equal(t, 8, se.Column)
+
+	_, err = NewFromJSON("{\n  \"a\": ")
+	if !errors.As(err, &se) || !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("Expected a *SyntaxError at the end, got %v", err)
+	}
+	equal(t, 2, se.Line)
+	equal(t, 8, se.Column)
+}
@@ -0,0 +1,112 @@
+package cconf
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// DefaultVersionKey is the default key of the version of configuration files, see
+// RegisterMigration.
+var DefaultVersionKey = "config_version"
+
+// MigrationError describes a failed migration of loaded data, see RegisterMigration.
+type MigrationError struct {
+	Source  string // the source of the data, like the file name
+	Version int    // the version the data failed to migrate from
+	Err     error
+}
+
+// Error returns the error message represented by MigrationError
+func (e *MigrationError) Error() string {
+	return fmt.Sprintf("%s: migrating from config version %d: %v", e.Source, e.Version, e.Err)
+}
+
+// Unwrap returns the error of the migration.
+func (e *MigrationError) Unwrap() error {
+	return e.Err
+}
+
+// migration migrates configuration data to the next version, see RegisterMigration.
+type migration func(store map[string]interface{}) (map[string]interface{}, error)
+
+// RegisterMigration registers the function migrating configuration data of the version to the
+// next version, replacing the migration registered for the version before, if any, like
+//
+//	c.RegisterMigration(0, func(m map[string]interface{}) (map[string]interface{}, error) {
+//		m["http"] = map[string]interface{}{"port": m["port"]}
+//		delete(m, "port")
+//		return m, nil
+//	})
+//
+// The version of the data is the whole number at VersionKey, 0 if missing. Every loaded file,
+// reader and remote source (not the environment, flags or Set values) is migrated on load
+// and reload, before it is merged into the store: the migrations run in sequence from its
+// version up to the latest registered version, each getting a tree of plain maps, lists and
+// values of its own, and the version at VersionKey is updated after every step. If a
+// migration fails or a step is missing, the load fails with a *MigrationError and the store
+// is left unchanged. Data of a newer version than the latest is loaded as is.
+func (c *Conf) RegisterMigration(fromVersion int, fn func(store map[string]interface{}) (map[string]interface{}, error)) {
+	c.mu.Lock()
+	defer c.unlock()
+	migrations := make(map[int]migration, len(c.migrations)+1)
+	for v, m := range c.migrations {
+		migrations[v] = m
+	}
+	migrations[fromVersion] = fn
+	c.migrations = migrations
+}
+
+// migrate returns the data of the source migrated to the latest version, see RegisterMigration.
+// The caller must hold c.mu.
+func (c *Conf) migrate(name string, data interface{}) (interface{}, error) {
+	if len(c.migrations) == 0 || c.VersionKey == "" {
+		return data, nil
+	}
+	m, ok := normalize(data).(map[string]interface{})
+	if !ok {
+		return data, nil
+	}
+	latest := 0
+	for v := range c.migrations {
+		latest = max(latest, v+1)
+	}
+	version, err := c.version(m)
+	if err != nil {
+		return nil, &MigrationError{name, version, err}
+	}
+	segs := strings.Split(c.VersionKey, c.Separator)
+	for ; version < latest; version++ {
+		fn, ok := c.migrations[version]
+		if !ok {
+			return nil, &MigrationError{name, version, fmt.Errorf("no migration to version %d is registered", version+1)}
+		}
+		if m, err = fn(m); err != nil {
+			return nil, &MigrationError{name, version, err}
+		}
+		v, err := c.set(normalize(m), segs, 0, version+1)
+		if err != nil {
+			return nil, &MigrationError{name, version, err}
+		}
+		m = v.(map[string]interface{})
+	}
+	return m, nil
+}
+
+// version returns the version of the data at VersionKey, 0 if missing.
+func (c *Conf) version(m map[string]interface{}) (int, error) {
+	val, ok := walk(m, c.VersionKey, c.Separator)
+	if !ok {
+		return 0, nil
+	}
+	if s, isString := val.(string); isString {
+		if v, err := strconv.Atoi(s); err == nil && v >= 0 {
+			return v, nil
+		}
+	} else if isWhole(reflect.ValueOf(val), true) {
+		v, _ := toInt(val)
+		return v, nil
+	}
+	return 0, fmt.Errorf("invalid version %s at %q", describe(val), c.VersionKey)
+}
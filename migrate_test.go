@@ -0,0 +1,83 @@
+package cconf
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRegisterMigration(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "app.json")
+	// version 0: a flat port and a comma separated host list.
+	if err := os.WriteFile(file, []byte(`{"port": 8080, "hosts": "a,b"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	c := New()
+	// version 1 nests the port below http.
+	c.RegisterMigration(0, func(m map[string]interface{}) (map[string]interface{}, error) {
+		m["http"] = map[string]interface{}{"port": m["port"]}
+		delete(m, "port")
+		return m, nil
+	})
+	// version 2 splits the host list.
+	c.RegisterMigration(1, func(m map[string]interface{}) (map[string]interface{}, error) {
+		if s, ok := m["hosts"].(string); ok {
+			m["hosts"] = strings.Split(s, ",")
+		}
+		return m, nil
+	})
+	if err := c.Load(file); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, 8080, c.GetInt("http.port"))
+	equal(t, nil, c.Get("port"))
+	equal(t, []interface{}{"a", "b"}, c.Get("hosts"))
+	equal(t, 2, c.GetInt("config_version"))
+
+	// a file of version 1 is migrated by the second step only.
+	if err := c.LoadReader("json", strings.NewReader(`{"config_version": 1, "port": 1, "hosts": "c"}`)); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, 1, c.GetInt("port"))
+	equal(t, []interface{}{"c"}, c.Get("hosts"))
+
+	if err := c.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, 8080, c.GetInt("http.port"))
+	equal(t, []interface{}{"c"}, c.Get("hosts"))
+}
+
+func TestMigrationErrors(t *testing.T) {
+	c := New()
+	c.VersionKey = "meta.version"
+	c.RegisterMigration(0, func(m map[string]interface{}) (map[string]interface{}, error) {
+		return nil, errors.New("unsupported layout")
+	})
+	c.RegisterMigration(2, func(m map[string]interface{}) (map[string]interface{}, error) {
+		return m, nil
+	})
+	c.SetStore(map[string]interface{}{"a": 1})
+
+	err := c.LoadReader("json", strings.NewReader(`{"b": 2}`))
+	var me *MigrationError
+	if !errors.As(err, &me) {
+		t.Fatalf("Expected a *MigrationError, got %v", err)
+	}
+	equal(t, 0, me.Version)
+	equal(t, "reader:json: migrating from config version 0: unsupported layout", err.Error())
+	equal(t, map[string]interface{}{"a": 1}, c.GetStore())
+
+	err = c.LoadReader("json", strings.NewReader(`{"meta": {"version": 1}}`))
+	equal(t, "reader:json: migrating from config version 1: no migration to version 2 is registered", err.Error())
+
+	err = c.LoadReader("json", strings.NewReader(`{"meta": {"version": "two"}}`))
+	equal(t, `reader:json: migrating from config version 0: invalid version string "two" at "meta.version"`, err.Error())
+
+	if err := c.LoadReader("json", strings.NewReader(`{"meta": {"version": "3"}, "b": 2}`)); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, 2, c.GetInt("b"))
+}
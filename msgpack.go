@@ -0,0 +1,267 @@
+package cconf
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"time"
+)
+
+// loadMsgpack reads and parses a MessagePack file, see decodeMsgpack.
+func loadMsgpack(file string, data interface{}) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return decodeMsgpack(f, data)
+}
+
+// decodeMsgpack parses a single MessagePack value from the reader into data, which must be a
+// *interface{}. Integers are decoded as int64, floats as float64, binary data as []byte and
+// timestamps as time.Time. Map keys are converted to strings, binary keys as their bytes and
+// scalar keys like the integer 1 as "1".
+func decodeMsgpack(r io.Reader, data interface{}) error {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	d := &msgpackDecoder{binaryReader{format: "msgpack", b: b}}
+	v, err := d.value()
+	if err != nil {
+		return err
+	}
+	if d.off < len(b) {
+		return fmt.Errorf("msgpack: unexpected data after the top-level value at offset %d", d.off)
+	}
+	*data.(*interface{}) = v
+	return nil
+}
+
+// msgpackDecoder decodes MessagePack values from a buffer.
+type msgpackDecoder struct {
+	binaryReader
+}
+
+// binaryReader reads the data items of a binary format like MessagePack from a buffer.
+type binaryReader struct {
+	format string // the name of the format in error messages
+	b      []byte
+	off    int // the offset of the next byte
+}
+
+// next returns the next n bytes.
+func (d *binaryReader) next(n int) ([]byte, error) {
+	if n < 0 || n > len(d.b)-d.off {
+		return nil, d.eof()
+	}
+	b := d.b[d.off : d.off+n]
+	d.off += n
+	return b, nil
+}
+
+// uint returns the next big-endian unsigned integer of n bytes.
+func (d *binaryReader) uint(n int) (uint64, error) {
+	b, err := d.next(n)
+	if err != nil {
+		return 0, err
+	}
+	var u uint64
+	for _, c := range b {
+		u = u<<8 | uint64(c)
+	}
+	return u, nil
+}
+
+// length returns the length u of a string or a collection as an int.
+func (d *binaryReader) length(u uint64) (int, error) {
+	if u > uint64(len(d.b)-d.off) {
+		// every element takes a byte at least.
+		return 0, d.eof()
+	}
+	return int(u), nil
+}
+
+// eof returns the error of data ending in the middle of an item.
+func (d *binaryReader) eof() error {
+	return fmt.Errorf("%s: %w at offset %d", d.format, io.ErrUnexpectedEOF, len(d.b))
+}
+
+// size returns the next length of n bytes.
+func (d *msgpackDecoder) size(n int) (int, error) {
+	u, err := d.uint(n)
+	if err != nil {
+		return 0, err
+	}
+	return d.length(u)
+}
+
+// value decodes the next value.
+func (d *msgpackDecoder) value() (interface{}, error) {
+	start := d.off
+	b, err := d.next(1)
+	if err != nil {
+		return nil, err
+	}
+	switch c := b[0]; {
+	case c <= 0x7f:
+		return int64(c), nil
+	case c >= 0xe0:
+		return int64(int8(c)), nil
+	case c <= 0x8f:
+		return d.mapOf(int(c & 0x0f))
+	case c <= 0x9f:
+		return d.array(int(c & 0x0f))
+	case c <= 0xbf:
+		return d.str(int(c & 0x1f))
+	}
+
+	switch c := b[0]; c {
+	case 0xc0:
+		return nil, nil
+	case 0xc2, 0xc3:
+		return c == 0xc3, nil
+	case 0xc4, 0xc5, 0xc6:
+		n, err := d.size(1 << (c - 0xc4))
+		if err != nil {
+			return nil, err
+		}
+		b, err := d.next(n)
+		if err != nil {
+			return nil, err
+		}
+		return append([]byte(nil), b...), nil
+	case 0xc7, 0xc8, 0xc9:
+		n, err := d.size(1 << (c - 0xc7))
+		if err != nil {
+			return nil, err
+		}
+		return d.ext(start, n)
+	case 0xca:
+		u, err := d.uint(4)
+		return float64(math.Float32frombits(uint32(u))), err
+	case 0xcb:
+		u, err := d.uint(8)
+		return math.Float64frombits(u), err
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		u, err := d.uint(1 << (c - 0xcc))
+		if err != nil {
+			return nil, err
+		}
+		if u > math.MaxInt64 {
+			return nil, fmt.Errorf("msgpack: integer %d at offset %d overflows int64", u, start)
+		}
+		return int64(u), nil
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		n := 1 << (c - 0xd0)
+		u, err := d.uint(n)
+		if err != nil {
+			return nil, err
+		}
+		// sign-extend the integer of n bytes.
+		shift := 64 - 8*n
+		return int64(u<<shift) >> shift, nil
+	case 0xd4, 0xd5, 0xd6, 0xd7, 0xd8:
+		return d.ext(start, 1<<(c-0xd4))
+	case 0xd9, 0xda, 0xdb:
+		n, err := d.size(1 << (c - 0xd9))
+		if err != nil {
+			return nil, err
+		}
+		return d.str(n)
+	case 0xdc, 0xdd:
+		n, err := d.size(2 << (c - 0xdc))
+		if err != nil {
+			return nil, err
+		}
+		return d.array(n)
+	case 0xde, 0xdf:
+		n, err := d.size(2 << (c - 0xde))
+		if err != nil {
+			return nil, err
+		}
+		return d.mapOf(n)
+	}
+	return nil, fmt.Errorf("msgpack: invalid byte 0x%02x at offset %d", b[0], start)
+}
+
+// str decodes a string of n bytes.
+func (d *msgpackDecoder) str(n int) (interface{}, error) {
+	b, err := d.next(n)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// array decodes an array of n elements.
+func (d *msgpackDecoder) array(n int) (interface{}, error) {
+	s := make([]interface{}, 0, min(n, len(d.b)-d.off))
+	for i := 0; i < n; i++ {
+		v, err := d.value()
+		if err != nil {
+			return nil, err
+		}
+		s = append(s, v)
+	}
+	return s, nil
+}
+
+// mapOf decodes a map of n key-value pairs.
+func (d *msgpackDecoder) mapOf(n int) (interface{}, error) {
+	m := make(map[string]interface{}, min(n, (len(d.b)-d.off)/2))
+	for i := 0; i < n; i++ {
+		start := d.off
+		k, err := d.value()
+		if err != nil {
+			return nil, err
+		}
+		key, ok := scalarKey(k)
+		if !ok {
+			return nil, fmt.Errorf("msgpack: invalid map key at offset %d", start)
+		}
+		if m[key], err = d.value(); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// ext decodes an extension value of n bytes. Only timestamps are supported.
+func (d *msgpackDecoder) ext(start, n int) (interface{}, error) {
+	b, err := d.next(n + 1)
+	if err != nil {
+		return nil, err
+	}
+	if typ := int8(b[0]); typ != -1 {
+		return nil, fmt.Errorf("msgpack: unsupported extension type %d at offset %d", typ, start)
+	}
+	b = b[1:]
+	switch len(b) {
+	case 4:
+		return time.Unix(int64(binary.BigEndian.Uint32(b)), 0).UTC(), nil
+	case 8:
+		u := binary.BigEndian.Uint64(b)
+		return time.Unix(int64(u&(1<<34-1)), int64(u>>34)).UTC(), nil
+	case 12:
+		sec := int64(binary.BigEndian.Uint64(b[4:]))
+		return time.Unix(sec, int64(binary.BigEndian.Uint32(b))).UTC(), nil
+	}
+	return nil, fmt.Errorf("msgpack: invalid timestamp at offset %d", start)
+}
+
+// scalarKey returns the map key of a binary format as a string: byte strings as their bytes
+// and other scalars like the integer 1 as "1". Maps, arrays and nil are no valid keys.
+func scalarKey(k interface{}) (string, bool) {
+	switch k := k.(type) {
+	case string:
+		return k, true
+	case []byte:
+		return string(k), true
+	case map[string]interface{}, []interface{}, nil:
+		return "", false
+	}
+	return fmt.Sprint(k), true
+}
@@ -0,0 +1,171 @@
+package cconf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// appendMsgpack appends the MessagePack encoding of v, which holds maps, slices, strings,
+// []byte, int64, float64, bool and nil, using the widest formats for the large values.
+func appendMsgpack(b []byte, v interface{}) []byte {
+	switch v := v.(type) {
+	case nil:
+		return append(b, 0xc0)
+	case bool:
+		if v {
+			return append(b, 0xc3)
+		}
+		return append(b, 0xc2)
+	case int64:
+		if v >= -32 && v <= 127 {
+			return append(b, byte(v))
+		}
+		return binary.BigEndian.AppendUint64(append(b, 0xd3), uint64(v))
+	case float64:
+		return binary.BigEndian.AppendUint64(append(b, 0xcb), math.Float64bits(v))
+	case string:
+		if len(v) < 32 {
+			return append(append(b, 0xa0|byte(len(v))), v...)
+		}
+		return append(binary.BigEndian.AppendUint32(append(b, 0xdb), uint32(len(v))), v...)
+	case []byte:
+		return append(append(b, 0xc4, byte(len(v))), v...)
+	case []interface{}:
+		b = binary.BigEndian.AppendUint16(append(b, 0xdc), uint16(len(v)))
+		for _, e := range v {
+			b = appendMsgpack(b, e)
+		}
+		return b
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		b = append(b, 0x80|byte(len(v)))
+		for _, k := range keys {
+			b = appendMsgpack(appendMsgpack(b, k), v[k])
+		}
+		return b
+	}
+	panic("unsupported type")
+}
+
+func TestLoadMsgpack(t *testing.T) {
+	in := map[string]interface{}{
+		"name":    "cconf-msgpack",
+		"version": 0.2,
+		"debug":   true,
+		"port":    int64(8080),
+		"offset":  int64(-1),
+		"big":     int64(math.MaxInt64),
+		"key":     []byte{0, 1, 0xff},
+		"none":    nil,
+		"ext":     map[string]interface{}{"email": "msgpack@example.com"},
+		"servers": []interface{}{
+			map[string]interface{}{"host": "a", "port": int64(1)},
+			map[string]interface{}{"host": strings.Repeat("b", 40), "port": int64(70000)},
+		},
+	}
+	file := filepath.Join(t.TempDir(), "app.msgpack")
+	if err := os.WriteFile(file, appendMsgpack(nil, in), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	c := New()
+	if err := c.Load("./testdata/app.json", file); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, "cconf-msgpack", c.GetString("name"))
+	equal(t, 0.2, c.Get("version"))
+	equal(t, int64(8080), c.Get("port"))
+	equal(t, int64(-1), c.Get("offset"))
+	equal(t, int64(math.MaxInt64), c.Get("big"))
+	equal(t, []byte{0, 1, 0xff}, c.Get("key"))
+	equal(t, "syyong.x", c.GetString("ext.author"))
+	equal(t, "msgpack@example.com", c.GetString("ext.email"))
+	equal(t, 70000, c.GetInt("servers.1.port"))
+
+	var s struct {
+		Name    string  `cconf:"name"`
+		Version float64 `cconf:"version"`
+		Debug   bool    `cconf:"debug"`
+		Port    int     `cconf:"port"`
+		Offset  int64   `cconf:"offset"`
+		Big     int64   `cconf:"big"`
+		Key     []byte  `cconf:"key"`
+		None    *string `cconf:"none"`
+		Ext     struct {
+			Author string `cconf:"author"`
+			Email  string `cconf:"email"`
+		} `cconf:"ext"`
+		Servers []struct {
+			Host string `cconf:"host"`
+			Port int    `cconf:"port"`
+		} `cconf:"servers"`
+	}
+	if err := c.Populate(&s); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, 8080, s.Port)
+	equal(t, int64(-1), s.Offset)
+	equal(t, []byte{0, 1, 0xff}, s.Key)
+	equal(t, "msgpack@example.com", s.Ext.Email)
+	equal(t, 2, len(s.Servers))
+	equal(t, 70000, s.Servers[1].Port)
+	equal(t, strings.Repeat("b", 40), s.Servers[1].Host)
+
+	if err := c.LoadReader("mp", bytes.NewReader(appendMsgpack(nil, map[string]interface{}{"port": int64(9090)}))); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, int64(9090), c.Get("port"))
+}
+
+func TestDecodeMsgpack(t *testing.T) {
+	ts := []byte{0xd6, 0xff, 0, 0, 0, 1}
+	for doc, want := range map[string]interface{}{
+		// binary, integer and boolean keys.
+		"\x83\xc4\x01k\x01\x02\x03\xc3\x04": map[string]interface{}{"k": int64(1), "2": int64(3), "true": int64(4)},
+		"\x92\xff\xd0\x80":                  []interface{}{int64(-1), int64(-128)},
+		"\xcd\x01\x00":                      int64(256),
+		"\xd1\xff\x00":                      int64(-256),
+		"\xca\x3f\xc0\x00\x00":              1.5,
+		"\xd9\x02hi":                        "hi",
+		"\xde\x00\x01\xa1a\xc0":             map[string]interface{}{"a": nil},
+		string(ts):                          time.Unix(1, 0).UTC(),
+	} {
+		var v interface{}
+		if err := decodeMsgpack(strings.NewReader(doc), &v); err != nil {
+			t.Errorf("%q: %v", doc, err)
+			continue
+		}
+		equal(t, want, v)
+	}
+
+	for doc, want := range map[string]string{
+		"":                     "msgpack: unexpected EOF at offset 0",
+		"\x92\x01":             "msgpack: unexpected EOF at offset 2",
+		"\xdb\xff\xff\xff\xff": "msgpack: unexpected EOF at offset 5",
+		"\x01\x02":             "msgpack: unexpected data after the top-level value at offset 1",
+		"\xc1":                 "msgpack: invalid byte 0xc1 at offset 0",
+		"\xcf" + string(bytes.Repeat([]byte{0xff}, 8)): "msgpack: integer 18446744073709551615 at offset 0 overflows int64",
+		"\x81\x90\x01":          "msgpack: invalid map key at offset 1",
+		"\x81\xa1a\xd4\x01\x00": "msgpack: unsupported extension type 1 at offset 3",
+		"\xd5\xff\x00\x00":      "msgpack: invalid timestamp at offset 0",
+	} {
+		var v interface{}
+		err := decodeMsgpack(strings.NewReader(doc), &v)
+		if err == nil {
+			t.Errorf("%q: expected an error", doc)
+			continue
+		}
+		equal(t, want, err.Error())
+	}
+}
@@ -0,0 +1,13 @@
+//go:build !fsnotify
+
+package cconf
+
+import (
+	"context"
+	"errors"
+)
+
+// notify is not available without the fsnotify build tag, so that Watch polls.
+func notify(ctx context.Context, files []string) (<-chan struct{}, error) {
+	return nil, errors.New("fsnotify is not available")
+}
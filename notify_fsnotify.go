@@ -0,0 +1,52 @@
+//go:build fsnotify
+
+package cconf
+
+import (
+	"context"
+	"path/filepath"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// notify signals the changes of the files detected by fsnotify until ctx is done.
+// The directories of the files are watched, so that files replaced by renames are noticed.
+func notify(ctx context.Context, files []string) (<-chan struct{}, error) {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	watched := make(map[string]bool, len(files))
+	for _, file := range files {
+		file = filepath.Clean(file)
+		watched[file] = true
+		if err := w.Add(filepath.Dir(file)); err != nil {
+			w.Close()
+			return nil, err
+		}
+	}
+
+	changes := make(chan struct{}, 1)
+	go func() {
+		defer close(changes)
+		defer w.Close()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case e, ok := <-w.Events:
+				if !ok {
+					return
+				}
+				if watched[filepath.Clean(e.Name)] && e.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename|fsnotify.Remove) != 0 {
+					signal(changes)
+				}
+			case _, ok := <-w.Errors:
+				if !ok {
+					return
+				}
+			}
+		}
+	}()
+	return changes, nil
+}
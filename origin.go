@@ -0,0 +1,130 @@
+package cconf
+
+import (
+	"sort"
+	"strings"
+)
+
+// origin records that a source wrote the value at a key, see Sources.
+type origin struct {
+	key    string
+	source string
+}
+
+// Source returns the source that set the current value at the key, like the file
+// "app.prod.json", the variable "env:MYAPP_DB_HOST" or "Set()", or "" if there is no value.
+// See Sources.
+func (c *Conf) Source(key string) string {
+	srcs := c.Sources(key)
+	if len(srcs) == 0 {
+		return ""
+	}
+	return srcs[len(srcs)-1]
+}
+
+// Sources returns the sources that wrote the value at the key or the values below it,
+// in the order they were merged, so that the last one wins; overrides come last.
+// It returns nil if there is no value at the key.
+//
+// Files are named by their path, remote sources by their name, environment variables by
+// "env:NAME", and values of Set, SetStore, SetOverride and SetDefaults by "Set()", "SetStore()",
+// "SetOverride()" and "SetDefaults()". Command-line overrides are named "flags" and "args", values of LoadReader
+// "reader:TYPE" and values changed by ApplyProfile "ApplyProfile(NAME)".
+func (c *Conf) Sources(key string) []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := walk(c.snap.Load().store, key, c.Separator); !ok {
+		return nil
+	}
+	return c.sourcesOf(key)
+}
+
+// sourcesOf returns the sources that wrote the value at the key or the values below it.
+// The caller must hold c.mu.
+func (c *Conf) sourcesOf(key string) []string {
+	var srcs []string
+	for _, origins := range [][]origin{c.origins, c.overrideOrigins} {
+		for _, o := range origins {
+			if !c.related(o.key, key) {
+				continue
+			}
+			if n := len(srcs); n == 0 || srcs[n-1] != o.source {
+				srcs = append(srcs, o.source)
+			}
+		}
+	}
+	return srcs
+}
+
+// related reports whether one of the keys is the other or below it.
+func (c *Conf) related(a, b string) bool {
+	if len(a) < len(b) {
+		a, b = b, a
+	}
+	return a == b || strings.HasPrefix(a, b+c.Separator)
+}
+
+// originsOf returns the origins of the leaf values of the data below the key,
+// named by the function.
+func (c *Conf) originsOf(key string, data interface{}, source func(key string) string) []origin {
+	leaves := make(map[string]interface{})
+	flatten(leaves, key, data, c.Separator)
+	origins := make([]origin, 0, len(leaves))
+	for k := range leaves {
+		origins = append(origins, origin{key: k, source: source(k)})
+	}
+	sort.Slice(origins, func(i, j int) bool { return origins[i].key < origins[j].key })
+	return origins
+}
+
+// appendOrigins returns the origins with the added ones appended. Earlier origins of the same
+// keys by the same sources are dropped, so that writing a key again does not add an origin.
+// The origins are not modified.
+func appendOrigins(origins, added []origin) []origin {
+	replaced := make(map[origin]bool, len(added))
+	for _, o := range added {
+		replaced[o] = true
+	}
+	n := 0
+	for _, o := range origins {
+		if replaced[o] {
+			n++
+		}
+	}
+	if n == 0 {
+		return append(origins, added...)
+	}
+	kept := make([]origin, 0, len(origins)-n+len(added))
+	for _, o := range origins {
+		if !replaced[o] {
+			kept = append(kept, o)
+		}
+	}
+	return append(kept, added...)
+}
+
+// setOrigins sets the origins of the base layer and of the overrides, and the keys whose value
+// was loaded from the environment, see fromEnv. The caller must hold c.mu.
+func (c *Conf) setOrigins(origins, overrideOrigins []origin) {
+	c.origins, c.overrideOrigins = origins, overrideOrigins
+	var envKeys map[string]bool
+	for _, origins := range [][]origin{origins, overrideOrigins} {
+		for _, o := range origins {
+			switch {
+			case isEnvOrigin(o.source):
+				if envKeys == nil {
+					envKeys = make(map[string]bool)
+				}
+				envKeys[o.key] = true
+			case envKeys[o.key]:
+				delete(envKeys, o.key)
+			}
+		}
+	}
+	c.envKeys = envKeys
+}
+
+// named returns a function naming every key by the source.
+func named(source string) func(string) string {
+	return func(string) string { return source }
+}
@@ -0,0 +1,87 @@
+package cconf
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSources(t *testing.T) {
+	dir := t.TempDir()
+	app := filepath.Join(dir, "app.json")
+	prod := filepath.Join(dir, "app.prod.json")
+	write := func(file, data string) {
+		if err := os.WriteFile(file, []byte(data), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(app, `{"name": "app", "db": {"host": "localhost", "port": 5432}}`)
+	write(prod, `{"db": {"host": "db.prod"}}`)
+	t.Setenv("CCONF_ORIGIN_DB_HOST", "db.env")
+
+	c := New()
+	if err := c.Load(app, prod); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.LoadEnv("CCONF_ORIGIN_"); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, "env:CCONF_ORIGIN_DB_HOST", c.Source("db.host"))
+	equal(t, []string{app, prod, "env:CCONF_ORIGIN_DB_HOST"}, c.Sources("db.host"))
+	equal(t, []string{app}, c.Sources("db.port"))
+	equal(t, []string{app, prod, "env:CCONF_ORIGIN_DB_HOST"}, c.Sources("db"))
+	equal(t, app, c.Source("name"))
+	equal(t, "", c.Source("missing"))
+	equal(t, 0, len(c.Sources("db.host.missing")))
+
+	if err := c.Set("db.port", 5433); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.SetOverride("name", "override"); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, []string{app, "Set()"}, c.Sources("db.port"))
+	equal(t, []string{app, "SetOverride()"}, c.Sources("name"))
+
+	// reloads keep the provenance.
+	if err := c.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, []string{app, prod, "env:CCONF_ORIGIN_DB_HOST"}, c.Sources("db.host"))
+	equal(t, []string{app, "Set()"}, c.Sources("db.port"))
+
+	// writing a key again replaces its origin.
+	n := len(c.origins) + len(c.overrideOrigins)
+	for i := 0; i < 10; i++ {
+		if err := c.Set("db.port", i); err != nil {
+			t.Fatal(err)
+		}
+		if err := c.SetOverride("name", "override"); err != nil {
+			t.Fatal(err)
+		}
+		if err := c.LoadEnv("CCONF_ORIGIN_"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	equal(t, n, len(c.origins)+len(c.overrideOrigins))
+	equal(t, []string{app, prod, "Set()", "env:CCONF_ORIGIN_DB_HOST"}, c.Sources("db"))
+	equal(t, 9, c.GetInt("db.port"))
+
+	c.ClearOverrides()
+	equal(t, app, c.Source("name"))
+	c.SetStore(map[string]interface{}{"name": "store"})
+	equal(t, []string{"SetStore()"}, c.Sources("name"))
+}
+
+func TestSourcesApplyProfile(t *testing.T) {
+	c := New()
+	if err := c.LoadReader("json", strings.NewReader(`{"defaults": {"debug": false}, "profiles": {"dev": {"debug": true}}, "name": "app"}`)); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.ApplyProfile("dev"); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, []string{"ApplyProfile(dev)"}, c.Sources("debug"))
+	equal(t, []string{"reader:json"}, c.Sources("name"))
+}
@@ -0,0 +1,103 @@
+package cconf
+
+import (
+	"sort"
+	"strings"
+)
+
+// SetOverride sets the value at the key in the override layer, which takes precedence over
+// all other sources: loaded files, the environment and values set by Set. Overrides are kept
+// when sources are loaded again. Command-line overrides (see ParseArgs and BindFlagSet) are
+// written to the same layer.
+func (c *Conf) SetOverride(key string, val interface{}) error {
+	c.mu.Lock()
+	defer c.unlock()
+	return c.setOverrides("SetOverride()", map[string]interface{}{key: val})
+}
+
+// ClearOverrides removes all values of the override layer, so that the values of the other
+// sources take effect again. Values that cannot be converted to their declared types
+// (see DeclareTypes) are kept as is.
+func (c *Conf) ClearOverrides() {
+	c.mu.Lock()
+	defer c.unlock()
+	if c.frozen {
+		return
+	}
+	c.overrides = nil
+	c.setOrigins(c.origins, nil)
+	c.reset(c.applyTypesLenient(c.effective(c.base)))
+}
+
+// ClearOverride removes the value at the key from the override layer and the value set at
+// the key by Set, so that the value of the other sources takes effect again: after a reload,
+// Get returns the value of the reloaded sources. The other sources are not loaded again.
+func (c *Conf) ClearOverride(key string) error {
+	c.mu.Lock()
+	defer c.unlock()
+	segs := strings.Split(key, c.Separator)
+	overrides := c.overrides
+	if overrides != nil {
+		overrides, _ = remove(overrides, segs)
+	}
+	srcs := append([]source(nil), c.sources...)
+	for i, src := range srcs {
+		if src.set {
+			srcs[i].data, _ = remove(src.data, segs)
+		}
+	}
+
+	var origins []origin
+	for _, o := range c.overrideOrigins {
+		if !c.related(o.key, key) {
+			origins = append(origins, o)
+		}
+	}
+	oldOverrides, oldSources, oldOrigins := c.overrides, c.sources, c.overrideOrigins
+	c.overrides, c.sources = overrides, srcs
+	c.setOrigins(c.origins, origins)
+	if err := c.rebuild(false); err != nil {
+		c.overrides, c.sources = oldOverrides, oldSources
+		c.setOrigins(c.origins, oldOrigins)
+		return err
+	}
+	c.queue(Event{Type: EventSet, Key: key})
+	return nil
+}
+
+// setOverrides sets the values of the source in the override layer and publishes the store.
+// It is atomic. The caller must hold c.mu.
+func (c *Conf) setOverrides(source string, vals map[string]interface{}) error {
+	keys := make([]string, 0, len(vals))
+	for k := range vals {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	overrides := c.overrides
+	if overrides == nil {
+		overrides = make(map[string]interface{})
+	}
+	var err error
+	for _, k := range keys {
+		if overrides, err = c.set(overrides, strings.Split(k, c.Separator), 0, normalize(vals[k])); err != nil {
+			return err
+		}
+	}
+	old, oldOrigins := c.overrides, c.overrideOrigins
+	c.overrides = overrides
+	origins := oldOrigins
+	for _, k := range keys {
+		origins = appendOrigins(origins, c.originsOf(k, vals[k], named(source)))
+	}
+	c.setOrigins(c.origins, origins)
+	if err := c.commit(c.base); err != nil {
+		c.overrides = old
+		c.setOrigins(c.origins, oldOrigins)
+		return err
+	}
+	for _, k := range keys {
+		c.queue(Event{Type: EventSet, Key: k})
+	}
+	return nil
+}
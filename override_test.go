@@ -0,0 +1,99 @@
+package cconf
+
+import (
+	"os"
+	"testing"
+)
+
+func TestOverrides(t *testing.T) {
+	t.Setenv("CCONFTEST_DB_HOST", "env-host")
+
+	c := New()
+	load := func() {
+		if err := c.LoadProfile("testdata/profile/app.json", "prod"); err != nil {
+			t.Fatal(err)
+		}
+		if err := c.LoadEnv("CCONFTEST_"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	load()
+	equal(t, "env-host", c.GetString("db.host"))
+
+	if err := c.ParseArgs([]string{"--db.host=cli-host"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.SetOverride("db.port", 6543); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, "cli-host", c.GetString("db.host"))
+	equal(t, 6543, c.GetInt("db.port"))
+
+	// the overrides are kept when the sources are loaded again.
+	load()
+	equal(t, "cli-host", c.GetString("db.host"))
+	equal(t, 6543, c.GetInt("db.port"))
+	equal(t, "app", c.GetString("name"))
+
+	// Set does not replace overrides.
+	if err := c.Set("db.port", 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Set("name", "set"); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, 6543, c.GetInt("db.port"))
+	equal(t, "set", c.GetString("name"))
+
+	c.ClearOverrides()
+	equal(t, "env-host", c.GetString("db.host"))
+	equal(t, 1, c.GetInt("db.port"))
+	equal(t, "set", c.GetString("name"))
+}
+
+func TestClearOverride(t *testing.T) {
+	files := copyTestdata(t, "server.json")
+	c := New()
+	if err := c.Load(files...); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Set("server.Host", "set-host"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.ParseArgs([]string{"--server.Port=9090"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// reloads keep the overrides and take the fresh values of the other keys.
+	data := `{"server": {"Host": "file-host", "Port": 8081, "Debug": false}}`
+	if err := os.WriteFile(files[0], []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, "set-host", c.GetString("server.Host"))
+	equal(t, 9090, c.GetInt("server.Port"))
+	equal(t, false, c.GetBool("server.Debug"))
+
+	if err := c.ClearOverride("server.Host"); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, "file-host", c.GetString("server.Host"))
+	equal(t, 9090, c.GetInt("server.Port"))
+	if err := c.ClearOverride("server.Port"); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, 8081, c.GetInt("server.Port"))
+	equal(t, []string{files[0]}, c.Sources("server.Port"))
+
+	// the cleared values stay cleared after reloads.
+	if err := c.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, "file-host", c.GetString("server.Host"))
+	equal(t, 8081, c.GetInt("server.Port"))
+	if err := c.ClearOverride("missing.key"); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,42 @@
+//go:build pflag
+
+package cconf
+
+import (
+	"strings"
+
+	"github.com/spf13/pflag"
+)
+
+// BindPFlag copies the value of the pflag into the store at the key if the flag was set
+// on the command line, with the precedence of BindFlagSet.
+func (c *Conf) BindPFlag(key string, f *pflag.Flag) error {
+	if f == nil || !f.Changed {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.unlock()
+	return c.setOverrides("flags", map[string]interface{}{key: pflagValue(f)})
+}
+
+// BindPFlags is like BindFlagSet for a pflag.FlagSet: the values of the flags set on the
+// command line are copied into the store at their names with dashes replaced by the Separator.
+func (c *Conf) BindPFlags(fs *pflag.FlagSet) error {
+	vals := make(map[string]interface{})
+	fs.Visit(func(f *pflag.Flag) {
+		vals[strings.ReplaceAll(f.Name, "-", c.Separator)] = pflagValue(f)
+	})
+
+	c.mu.Lock()
+	defer c.unlock()
+	return c.setOverrides("flags", vals)
+}
+
+// pflagValue returns the value of the flag as a store value.
+func pflagValue(f *pflag.Flag) interface{} {
+	var elems []string
+	if s, ok := f.Value.(pflag.SliceValue); ok {
+		elems = s.GetSlice()
+	}
+	return parseFlag(f.Value.Type(), f.Value.String(), elems)
+}
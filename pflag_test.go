@@ -0,0 +1,72 @@
+//go:build pflag
+
+package cconf
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spf13/pflag"
+)
+
+func TestBindPFlags(t *testing.T) {
+	fs := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	fs.Int("server-Port", 80, "")
+	fs.String("server-Host", "0.0.0.0", "")
+	fs.Duration("read-timeout", time.Second, "")
+	fs.StringSlice("hosts", []string{"a"}, "")
+	fs.IntSlice("ports", nil, "")
+	fs.DurationSlice("delays", nil, "")
+	if err := fs.Parse([]string{"--server-Port=9090", "--read-timeout=5s", "--hosts=b,c", "--ports=1,2", "--delays=1s,2m"}); err != nil {
+		t.Fatal(err)
+	}
+
+	c := New()
+	if err := c.Load("testdata/server.json"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.BindPFlags(fs); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, 9090, c.Get("server.Port"))
+	equal(t, 5*time.Second, c.Get("read.timeout"))
+	equal(t, []interface{}{"b", "c"}, c.Get("hosts"))
+	equal(t, []interface{}{1, 2}, c.Get("ports"))
+	equal(t, []interface{}{time.Second, 2 * time.Minute}, c.Get("delays"))
+	// flags left at their defaults do not clobber loaded values.
+	equal(t, "localhost", c.GetString("server.Host"))
+
+	// later loads do not replace flag values.
+	if err := c.Load("testdata/server.json"); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, 9090, c.Get("server.Port"))
+}
+
+func TestBindPFlag(t *testing.T) {
+	fs := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	fs.Int("port", 80, "")
+	fs.String("host", "0.0.0.0", "")
+	fs.Duration("timeout", time.Second, "")
+	if err := fs.Parse([]string{"--port=9090", "--timeout=1m30s"}); err != nil {
+		t.Fatal(err)
+	}
+
+	c := New()
+	if err := c.Load("testdata/server.json"); err != nil {
+		t.Fatal(err)
+	}
+	for key, name := range map[string]string{"server.Port": "port", "server.Host": "host", "server.Timeout": "timeout"} {
+		if err := c.BindPFlag(key, fs.Lookup(name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := c.BindPFlag("missing", fs.Lookup("missing")); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, 9090, c.Get("server.Port"))
+	equal(t, 90*time.Second, c.Get("server.Timeout"))
+	// the default of an unchanged flag does not override the loaded value.
+	equal(t, "localhost", c.GetString("server.Host"))
+	equal(t, nil, c.Get("missing"))
+}
@@ -0,0 +1,88 @@
+package cconf
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"time"
+)
+
+// PollingProvider is a RemoteProvider whose changes can be polled cheaply, see Poll.
+type PollingProvider interface {
+	RemoteProvider
+	// FetchIfChanged returns the data of the source and whether it changed since the last fetch,
+	// or the error of ctx when it is done before.
+	FetchIfChanged(ctx context.Context) (data interface{}, changed bool, err error)
+	// PollInterval returns the interval at which the source is polled, or 0 for the interval passed to Poll.
+	PollInterval() time.Duration
+}
+
+// Poll polls the PollingProviders loaded so far by LoadRemote, like HTTPProvider, in the
+// background and, when the data of one of them changed, reloads all tracked sources like Watch.
+// Every source is polled at its own interval, or at the interval if it has none, with a jitter
+// of up to 10% so that a fleet of instances does not poll in lockstep. Errors of the polls are
+// reported by an EventLoad of the source (see OnEvent); a failed reload is retried at the next
+// poll, even if the data did not change again. Polling stops when ctx is done or the Conf is
+// closed, canceling a pending poll.
+func (c *Conf) Poll(ctx context.Context, interval time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var providers []PollingProvider
+	for _, src := range c.sources {
+		if p, ok := src.remote.(PollingProvider); ok {
+			providers = append(providers, p)
+		}
+	}
+	if len(providers) == 0 {
+		return errors.New("no remote sources to poll")
+	}
+
+	for _, p := range providers {
+		p, d := p, p.PollInterval()
+		if d <= 0 {
+			d = interval
+		}
+		err := c.start(ctx, func(ctx context.Context) {
+			c.pollProvider(ctx, p, d)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pollProvider polls the provider at the interval until ctx is done.
+func (c *Conf) pollProvider(ctx context.Context, p PollingProvider, interval time.Duration) {
+	timer := time.NewTimer(jitter(interval))
+	defer timer.Stop()
+	failed := false // whether the reload of a change failed, so that it is retried
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+		start := time.Now()
+		_, changed, err := p.FetchIfChanged(ctx)
+		switch {
+		case ctx.Err() != nil:
+			return
+		case err != nil:
+			c.mu.Lock()
+			c.recordLoad(p.Name(), start, err)
+			c.unlock()
+		case changed || failed:
+			failed = c.Reload() != nil
+		}
+		timer.Reset(jitter(interval))
+	}
+}
+
+// jitter returns the duration changed randomly by up to 10%.
+func jitter(d time.Duration) time.Duration {
+	if j := int64(d / 10); j > 0 {
+		d += time.Duration(rand.Int63n(2*j+1) - j)
+	}
+	return d
+}
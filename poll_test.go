@@ -0,0 +1,124 @@
+package cconf
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestPoll(t *testing.T) {
+	s := &configServer{}
+	s.set(`{"name": "app", "port": 80}`, `"v1"`)
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+
+	c := New()
+	if err := c.Poll(context.Background(), time.Millisecond); err == nil {
+		t.Fatal("expected an error without remote sources")
+	}
+	if err := c.LoadRemote(&HTTPProvider{URL: srv.URL, Interval: 5 * time.Millisecond}); err != nil {
+		t.Fatal(err)
+	}
+	reloads := make(chan error, 10)
+	c.OnEvent(func(e Event) {
+		if e.Type == EventReload {
+			reloads <- e.Err
+		}
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := c.Poll(ctx, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+
+	// unchanged data is not reloaded.
+	time.Sleep(50 * time.Millisecond)
+	select {
+	case <-reloads:
+		t.Fatal("unexpected reload")
+	default:
+	}
+	requests, notModified := s.counts()
+	equal(t, requests-1, notModified)
+
+	s.set(`{"name": "new app", "port": 80}`, `"v2"`)
+	if err := waitReload(reloads); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, "new app", c.GetString("name"))
+
+	// a failed reload is retried without another change.
+	rejected := false
+	c.SetReloadValidator(func(candidate *Conf) error {
+		if !rejected {
+			rejected = true
+			return errors.New("rejected")
+		}
+		return nil
+	})
+	s.set(`{"name": "newer app", "port": 80}`, `"v3"`)
+	if err := waitReload(reloads); err == nil {
+		t.Fatal("expected the reload to be rejected")
+	}
+	if err := waitReload(reloads); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, "newer app", c.GetString("name"))
+
+	cancel()
+	time.Sleep(20 * time.Millisecond)
+	requests, _ = s.counts()
+	time.Sleep(20 * time.Millisecond)
+	after, _ := s.counts()
+	equal(t, requests, after)
+}
+
+func TestPollClose(t *testing.T) {
+	hang := make(chan struct{})
+	defer close(hang)
+	requests := make(chan struct{}, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == "" {
+			w.Header().Set("ETag", `"v1"`)
+			w.Write([]byte(`{"name": "app"}`))
+			return
+		}
+		requests <- struct{}{}
+		select {
+		case <-r.Context().Done():
+		case <-hang:
+		}
+	}))
+	defer srv.Close()
+
+	c := New()
+	if err := c.LoadRemote(&HTTPProvider{URL: srv.URL, Interval: time.Millisecond}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Poll(context.Background(), time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	<-requests
+	closed := make(chan struct{})
+	go func() {
+		c.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close did not cancel the pending poll")
+	}
+}
+
+func TestJitter(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		if d := jitter(time.Second); d < 900*time.Millisecond || d > 1100*time.Millisecond {
+			t.Fatalf("jitter out of range: %v", d)
+		}
+	}
+	equal(t, time.Duration(5), jitter(5))
+}
@@ -1,17 +1,37 @@
 package cconf
 
 import (
+	"errors"
 	"fmt"
 	"reflect"
 	"runtime"
+	"sort"
 	"strconv"
 	"strings"
 )
 
+// The sentinel errors wrapped by the errors of the package, to be tested with errors.Is.
+var (
+	// ErrKeyNotFound is wrapped by the errors about keys without value, like missing required keys.
+	ErrKeyNotFound = errors.New("key not found")
+	// ErrTypeMismatch is wrapped by the errors about values of a type that does not fit, like
+	// a string configuring an int or a value that cannot be set below.
+	ErrTypeMismatch = errors.New("type mismatch")
+	// ErrUnknownType is wrapped by the errors about unregistered types, like a file type without
+	// load function or a type name without provider (see Register).
+	ErrUnknownType = errors.New("unknown type")
+	// ErrFrozen is wrapped by the errors about writes to a frozen configuration, see Freeze.
+	ErrFrozen = errors.New("configuration frozen")
+)
+
 // ConfigKeyError describes a key which cannot be used to set a configuration value.
 type ConfigKeyError struct {
 	Key     string
 	Message string
+	Err     error // the sentinel error, like ErrKeyNotFound, if any
+	// Segment is the index of the offending segment of Key, like the first one without value
+	// or the one that cannot be set below, for the errors of Populate and Set.
+	Segment int
 }
 
 // Error
@@ -19,10 +39,19 @@ func (ck *ConfigKeyError) Error() string {
 	return fmt.Sprintf("%q is not a valid key: %v", ck.Key, ck.Message)
 }
 
+// Unwrap returns the sentinel error, if any.
+func (ck *ConfigKeyError) Unwrap() error {
+	return ck.Err
+}
+
 // ConfigValueError describes a configuration that cannot be used to configure a target value
 type ConfigValueError struct {
-	Key     string // path to the configuration value
-	Message string // the detailed error message
+	Key      string      // path to the configuration value
+	Message  string      // the detailed error message
+	Err      error       // the sentinel error, like ErrTypeMismatch, if any
+	Expected string      // the type the value was expected to have, like "int", if known
+	Actual   interface{} // the configuration value, if any
+	Source   string      // the source of the value, like a file name, if known (see Source)
 }
 
 // Error returns the error message represented by ConfigValueError
@@ -31,6 +60,34 @@ func (cv *ConfigValueError) Error() string {
 	return fmt.Sprintf("%q points to an inappropriate configuration value: %v", key, cv.Message)
 }
 
+// Unwrap returns the sentinel error, if any.
+func (cv *ConfigValueError) Unwrap() error {
+	return cv.Err
+}
+
+// mismatch returns the error of the configuration at the key that cannot configure a value of the type.
+func mismatch(key string, config reflect.Value, t reflect.Type) *ConfigValueError {
+	msg := fmt.Sprintf("%v cannot be used to configure %v", config.Type(), t)
+	if config.Kind() == reflect.Map {
+		msg = "a map cannot be used to configure " + t.String()
+	}
+	return &ConfigValueError{Key: key, Message: msg, Err: ErrTypeMismatch, Expected: t.String(), Actual: config.Interface()}
+}
+
+// wrapError is an error with its own message wrapping a sentinel error.
+type wrapError struct {
+	msg string
+	err error
+}
+
+func (e *wrapError) Error() string {
+	return e.msg
+}
+
+func (e *wrapError) Unwrap() error {
+	return e.err
+}
+
 // ConfigTargetError describes a target value that cannot be configured
 type ConfigTargetError struct {
 	Value reflect.Value
@@ -72,12 +129,78 @@ func (c *Conf) Register(name string, provider interface{}) error {
 	if v.Kind() != reflect.Func || v.Type().NumOut() != 1 {
 		return &ProviderError{v}
 	}
+	c.typesMu.Lock()
 	c.types[name] = v
+	c.typesMu.Unlock()
 	return nil
 }
 
 // Populate populate.
+// The configuration keys match the struct fields by name, or by the name of the cconf tag
+// of the field, like `cconf:"port"`. The tag options are "required", which fails Populate if
+// the configuration has no value for the field, and "default=value", which sets a field
+// without configuration value to the value, parsed like a value of the environment:
+//
+//	Port  int      `cconf:"port,required"`
+//	Level string   `cconf:"level,default=info"`
+//	Hosts []string `cconf:"hosts,default=a,b"`
+//
+// The constraints "min=n" and "max=n", "oneof=a b c" and "regexp=expr" are checked after the
+// conversion of the value, or of each element of a slice: min and max bound numbers, the
+// lengths of strings and durations, like "min=1s".
+//
+// Populate stops at the first problem of a field, like a value that cannot configure it or a
+// violation of a constraint. In CollectAll mode (see ValidationMode), it reports the problems
+// of all the fields as a *MultiError if there are several, and populates the other fields
+// nonetheless.
+//
+//	Port  int    `cconf:"port,min=1,max=65535"`
+//	Level string `cconf:"level,oneof=debug info warn error"`
+//	Name  string `cconf:"name,regexp=^[a-z-]+$"`
 func (c *Conf) Populate(v interface{}, key ...string) (err error) {
+	val := reflect.ValueOf(v)
+	if val.Kind() != reflect.Ptr || val.IsNil() {
+		return &ConfigTargetError{val}
+	}
+	return (&populator{c: c}).run(val, key...)
+}
+
+// Report describes how a configuration would be mapped onto a target value.
+// All keys are full paths of the configuration.
+type Report struct {
+	MatchedKeys      []string // configuration keys that were assigned to a target
+	UnusedConfigKeys []string // configuration keys without a corresponding struct field
+	UnsetFields      []string // struct fields that got no configuration value
+}
+
+// PopulateReport performs a dry run of Populate and reports which configuration keys map to fields,
+// which configuration keys are unused and which struct fields got no value.
+// The target value is never modified: the configuration is populated into a throwaway copy.
+func (c *Conf) PopulateReport(v interface{}, key ...string) (Report, error) {
+	val := reflect.ValueOf(v)
+	if val.Kind() != reflect.Ptr || val.IsNil() {
+		return Report{}, &ConfigTargetError{val}
+	}
+	report := &Report{}
+	err := (&populator{c: c, report: report}).run(reflect.New(val.Type().Elem()), key...)
+	sort.Strings(report.MatchedKeys)
+	sort.Strings(report.UnusedConfigKeys)
+	sort.Strings(report.UnsetFields)
+	return *report, err
+}
+
+// populator holds the state of a single populate operation.
+type populator struct {
+	c      *Conf
+	report *Report // not nil for a dry run
+	s      *snapshot
+	envKey string // the key of a value of the environment fallback
+	// the violations of the constraints of the tags and the errors of the struct validators
+	violations []error
+}
+
+// run populates the value with the configuration at the optional key.
+func (p *populator) run(val reflect.Value, key ...string) (err error) {
 	defer func() {
 		if r := recover(); r != nil {
 			if _, ok := r.(runtime.Error); ok {
@@ -87,21 +210,91 @@ func (c *Conf) Populate(v interface{}, key ...string) (err error) {
 		}
 	}()
 
-	val := reflect.ValueOf(v)
-	if val.Kind() != reflect.Ptr || val.IsNil() {
-		return &ConfigTargetError{val}
+	config, f, err := p.lookup(key...)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		p.c.annotate(err)
+	}()
+	if err := p.populate(val, config, f); err != nil {
+		return err
 	}
-	f := ""
-	config := c.store
-	if len(key) > 0 {
-		d := c.Get(key[0])
-		if d == nil {
-			return &ConfigKeyError{key[0], "no configuration value was found"}
+	if len(p.violations) == 1 {
+		return p.violations[0]
+	}
+	return p.c.aggregate(p.violations)
+}
+
+// annotate sets the sources of the values of the ConfigValueErrors in the error tree.
+func (c *Conf) annotate(err error) {
+	switch e := err.(type) {
+	case *ConfigValueError:
+		if key := strings.Trim(e.Key, "."); e.Source == "" && key != "" {
+			e.Source = c.Source(strings.ReplaceAll(key, ".", c.Separator))
+		}
+	case interface{ Unwrap() []error }:
+		for _, err := range e.Unwrap() {
+			c.annotate(err)
 		}
-		f = key[0]
-		config = reflect.ValueOf(d)
+	case interface{ Unwrap() error }:
+		c.annotate(e.Unwrap())
+	}
+}
+
+// missingSegment returns the index of the first segment of the key without value in the store.
+func (c *Conf) missingSegment(store interface{}, key string) int {
+	segs := strings.Split(key, c.Separator)
+	for i := range segs {
+		if _, ok := walk(store, strings.Join(segs[:i+1], c.Separator), c.Separator); !ok {
+			return i
+		}
+	}
+	return len(segs) - 1
+}
+
+// lookup loads the snapshot and returns the configuration at the optional key with the key.
+func (p *populator) lookup(key ...string) (reflect.Value, string, error) {
+	s := p.c.snap.Load()
+	p.s = s
+	if len(key) == 0 {
+		return reflect.ValueOf(s.tree()), "", nil
+	}
+	d, env, ok, err := s.lookupLayers(key[0], p.c.Separator)
+	if err != nil {
+		return reflect.Value{}, "", &ConfigValueError{Key: key[0] + s.fileSuffix, Message: err.Error()}
+	}
+	if env {
+		p.envKey = key[0]
+	}
+	d, ok = s.withParent(key[0], p.c.Separator, nil, d, ok)
+	if !ok {
+		msg := "no configuration value was found" + p.c.suggest(key[0], p.c.allKeys(s.store))
+		return reflect.Value{}, "", &ConfigKeyError{Key: key[0], Message: msg, Err: ErrKeyNotFound, Segment: p.c.missingSegment(s.store, key[0])}
+	}
+	return reflect.ValueOf(d), key[0], nil
+}
+
+// matched records that the configuration key was assigned to a target.
+func (p *populator) matched(key string) {
+	if p.report != nil {
+		p.report.MatchedKeys = append(p.report.MatchedKeys, strings.Trim(key, "."))
+	}
+}
+
+// fromEnv reports whether the string at the key was loaded from the environment.
+func (p *populator) fromEnv(key string) bool {
+	key = strings.Trim(key, ".")
+	return key == p.envKey || p.s.fromEnv(key)
+}
+
+// mapIndex
+func mapIndex(data reflect.Value, index reflect.Value) reflect.Value {
+	v := data.MapIndex(index)
+	if v.Kind() == reflect.Interface {
+		v = v.Elem()
 	}
-	return c.populate(val, config, f)
+	return v
 }
 
 // indirect
@@ -113,9 +306,16 @@ func indirect(v reflect.Value) reflect.Value {
 		// Load value from interface, but only if the result will be usefully addressable.
 		if v.Kind() == reflect.Interface && !v.IsNil() {
 			e := v.Elem()
-			if e.Kind() == reflect.Ptr && !e.IsNil() {
-				v = e
-				continue
+			if e.Kind() == reflect.Ptr {
+				// a typed nil pointer stored in the interface: allocate its target.
+				if e.IsNil() && v.CanSet() {
+					e = reflect.New(e.Type().Elem())
+					v.Set(e)
+				}
+				if !e.IsNil() {
+					v = e
+					continue
+				}
 			}
 		}
 
@@ -132,7 +332,7 @@ func indirect(v reflect.Value) reflect.Value {
 }
 
 // populate populate the value with the configuration.
-func (c *Conf) populate(v, config reflect.Value, key string) error {
+func (p *populator) populate(v, config reflect.Value, key string) error {
 	// get the concrete value, may allocate space if needed.
 	v = indirect(v)
 
@@ -145,25 +345,25 @@ func (c *Conf) populate(v, config reflect.Value, key string) error {
 
 	switch config.Kind() {
 	case reflect.Array, reflect.Slice:
-		return c.populateArray(v, config, key)
+		return p.populateArray(v, config, key)
 	case reflect.Map:
 		switch v.Kind() {
 		case reflect.Interface:
-			return c.populateInterface(v, config, key)
+			return p.populateInterface(v, config, key)
 		case reflect.Struct:
-			return c.populateStruct(v, config, key)
+			return p.populateStruct(v, config, key)
 		case reflect.Map:
-			return c.populateMap(v, config, key)
+			return p.populateMap(v, config, key)
 		default:
-			return &ConfigValueError{key, "a map cannot be used to configure " + v.Type().String()}
+			return mismatch(key, config, v.Type())
 		}
 	default:
-		return c.populateScalar(v, config, key)
+		return p.populateScalar(v, config, key)
 	}
 }
 
 // populateArray
-func (c *Conf) populateArray(v, config reflect.Value, key string) error {
+func (p *populator) populateArray(v, config reflect.Value, key string) error {
 	vkind := v.Kind()
 
 	// nil interface
@@ -173,7 +373,7 @@ func (c *Conf) populateArray(v, config reflect.Value, key string) error {
 	}
 
 	if vkind != reflect.Array && vkind != reflect.Slice {
-		return &ConfigValueError{key, fmt.Sprintf("%v cannot be used to configure %v", config.Type(), v.Type())}
+		return mismatch(key, config, v.Type())
 	}
 
 	n := config.Len()
@@ -189,7 +389,7 @@ func (c *Conf) populateArray(v, config reflect.Value, key string) error {
 		n = v.Cap()
 	}
 	for i := 0; i < n; i++ {
-		if err := c.populate(v.Index(i), config.Index(i), key+"."+strconv.Itoa(i)); err != nil {
+		if err := p.populate(v.Index(i), config.Index(i), key+"."+strconv.Itoa(i)); err != nil {
 			return err
 		}
 	}
@@ -210,7 +410,7 @@ func (c *Conf) populateArray(v, config reflect.Value, key string) error {
 }
 
 // populateMap
-func (c *Conf) populateMap(v, config reflect.Value, key string) error {
+func (p *populator) populateMap(v, config reflect.Value, key string) error {
 	// map must have string kind
 	t := v.Type()
 	if v.IsNil() {
@@ -220,7 +420,8 @@ func (c *Conf) populateMap(v, config reflect.Value, key string) error {
 	for _, k := range config.MapKeys() {
 		elemType := v.Type().Elem()
 		mapElem := reflect.New(elemType).Elem()
-		if err := c.populate(mapElem, mapIndex(config, k), key+"."+k.String()); err != nil {
+		p.matched(key + "." + k.String())
+		if err := p.populate(mapElem, mapIndex(config, k), key+"."+k.String()); err != nil {
 			return err
 		}
 		v.SetMapIndex(k.Convert(v.Type().Key()), mapElem)
@@ -233,35 +434,168 @@ func (c *Conf) populateMap(v, config reflect.Value, key string) error {
 var typeKey = reflect.ValueOf("type")
 
 // populateStruct
-func (c *Conf) populateStruct(v, config reflect.Value, key string) error {
+func (p *populator) populateStruct(v, config reflect.Value, key string) error {
+	set := make(map[string]bool)
 	for _, k := range config.MapKeys() {
 		if k.String() == typeKey.String() {
 			continue
 		}
-		key = key + "." + k.String()
-		field := v.FieldByName(k.Interface().(string))
-		if !field.IsValid() {
-			return &ConfigValueError{key, fmt.Sprintf("field %v not found in struct %v", k.String(), v.Type())}
+		fkey := key + "." + k.String()
+		f, ok := fieldByKey(v.Type(), k.String())
+		var field reflect.Value
+		if ok {
+			field, ok = fieldByIndex(v, f.Index)
 		}
-		if !field.CanSet() {
-			return &ConfigValueError{key, fmt.Sprintf("field %v cannot be set", k.String())}
+		if !ok {
+			ok, err := p.populateSecretFile(v, config, k, key)
+			if err != nil {
+				if err := p.fail(err); err != nil {
+					return err
+				}
+				continue
+			}
+			if ok {
+				set[strings.TrimSuffix(k.String(), p.s.fileSuffix)] = true
+				continue
+			}
+			if p.report != nil {
+				p.report.UnusedConfigKeys = append(p.report.UnusedConfigKeys, strings.Trim(fkey, "."))
+				continue
+			}
+			if err := p.fail(&ConfigValueError{Key: fkey, Message: p.c.fieldNotFound(k.String(), v.Type())}); err != nil {
+				return err
+			}
+			continue
 		}
-		if field.Kind() == reflect.Ptr {
-			if field.IsNil() {
-				field.Set(reflect.New(field.Type().Elem()))
+		if !field.CanSet() {
+			if err := p.fail(&ConfigValueError{Key: fkey, Message: fmt.Sprintf("field %v cannot be set", k.String())}); err != nil {
+				return err
 			}
-			field = field.Elem()
+			continue
+		}
+		// null values count as missing.
+		if mapIndex(config, k).IsValid() {
+			set[k.String()] = true
 		}
-		if err := c.populate(field, mapIndex(config, k), key); err != nil {
+		p.matched(fkey)
+		err := p.populate(field, mapIndex(config, k), fkey)
+		if err == nil {
+			err = p.check(f.tag, field, fkey)
+		}
+		if err := p.fail(err); err != nil {
 			return err
 		}
 	}
 
+	for _, f := range structFields(v.Type()) {
+		if f.Anonymous || f.PkgPath != "" || set[f.tag.name] {
+			continue
+		}
+		fkey := key + "." + f.tag.name
+		if f.tag.hasDef {
+			field, ok := fieldByIndex(v, f.Index)
+			if !ok {
+				continue
+			}
+			var err error
+			if perr := parseEnvValue(f.tag.def, indirect(field)); perr != nil {
+				err = &ConfigValueError{Key: fkey, Message: fmt.Sprintf("invalid default %q: %v", f.tag.def, perr)}
+			} else {
+				err = p.check(f.tag, field, fkey)
+			}
+			if err := p.fail(err); err != nil {
+				return err
+			}
+			continue
+		}
+		if f.tag.required && p.report == nil {
+			if err := p.fail(&ConfigValueError{Key: fkey, Message: "the required value is missing", Err: ErrKeyNotFound, Expected: f.Type.String()}); err != nil {
+				return err
+			}
+			continue
+		}
+		if p.report != nil {
+			p.report.UnsetFields = append(p.report.UnsetFields, strings.Trim(fkey, "."))
+		}
+	}
+
+	return p.validateStruct(v, key)
+}
+
+// fail records the error of a field in CollectAll mode and returns nil, so that the other
+// fields are populated, or returns the error in FailFast mode, see ValidationMode.
+func (p *populator) fail(err error) error {
+	if err == nil || p.c.failFast() {
+		return err
+	}
+	p.violations = append(p.violations, err)
 	return nil
 }
 
+// fieldNotFound returns the message of a configuration key without field in the struct type.
+func (c *Conf) fieldNotFound(key string, t reflect.Type) string {
+	return fmt.Sprintf("field %v not found in struct %v", key, t) + c.suggest(key, fieldKeys(t))
+}
+
+// check checks the field at the key against the constraints of the tag. It records the
+// violations, or returns the first one in FailFast mode.
+func (p *populator) check(tag fieldTag, field reflect.Value, key string) error {
+	errs := checkConstraints(tag, field, key)
+	if len(errs) > 0 && p.c.failFast() {
+		return errs[0]
+	}
+	for _, err := range errs {
+		p.violations = append(p.violations, err)
+	}
+	return nil
+}
+
+// validateStruct calls the struct validators of the type of the populated struct at the key,
+// see AddStructValidator.
+func (p *populator) validateStruct(v reflect.Value, key string) error {
+	p.c.typesMu.RLock()
+	validators := p.c.structValidators[v.Type()]
+	p.c.typesMu.RUnlock()
+	for _, fn := range validators {
+		if err := fn(v.Interface()); err != nil {
+			if err := p.fail(&StructValidationError{strings.Trim(key, "."), err}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// populateSecretFile populates the field named like the key k without the file suffix with
+// the contents of the secret file at k, if the configuration has no value for the field.
+func (p *populator) populateSecretFile(v, config, k reflect.Value, key string) (bool, error) {
+	name := strings.TrimSuffix(k.String(), p.s.fileSuffix)
+	if p.s.fileSuffix == "" || name == k.String() || mapIndex(config, reflect.ValueOf(name)).IsValid() {
+		return false, nil
+	}
+	f, ok := fieldByKey(v.Type(), name)
+	if !ok {
+		return false, nil
+	}
+	field, ok := fieldByIndex(v, f.Index)
+	if !ok || !field.CanSet() {
+		return false, nil
+	}
+	fkey := key + "." + name
+	var path interface{}
+	if pv := mapIndex(config, k); pv.IsValid() {
+		path = pv.Interface()
+	}
+	s, err := readSecretFile(path)
+	if err != nil {
+		return false, &ConfigValueError{Key: key + "." + k.String(), Message: err.Error()}
+	}
+	p.matched(fkey)
+	return true, p.populate(field, reflect.ValueOf(s), fkey)
+}
+
 // populateInterface
-func (c *Conf) populateInterface(v, config reflect.Value, key string) error {
+func (p *populator) populateInterface(v, config reflect.Value, key string) error {
 	// nil interface
 	if v.NumMethod() == 0 {
 		v.Set(config)
@@ -270,30 +604,40 @@ func (c *Conf) populateInterface(v, config reflect.Value, key string) error {
 
 	tk := mapIndex(config, typeKey)
 	if !tk.IsValid() {
-		return &ConfigValueError{key, "missing the type element"}
+		return &ConfigValueError{Key: key, Message: "missing the type element"}
 	}
 	if tk.Kind() != reflect.String {
-		return &ConfigValueError{key, "type must be a string"}
+		return &ConfigValueError{Key: key, Message: "type must be a string"}
 	}
 
-	builder, ok := c.types[tk.String()]
+	p.c.typesMu.RLock()
+	builder, ok := p.c.types[tk.String()]
+	p.c.typesMu.RUnlock()
 	if !ok {
-		return &ConfigValueError{key, fmt.Sprintf("type %q is unknown", tk.String())}
+		return &ConfigValueError{Key: key, Message: fmt.Sprintf("type %q is unknown", tk.String()), Err: ErrUnknownType, Expected: v.Type().String(), Actual: tk.String()}
 	}
 
 	object := builder.Call([]reflect.Value{})[0]
+	// make sure the object is a non-nil pointer, so that it can be populated.
+	if object.Kind() != reflect.Ptr {
+		p := reflect.New(object.Type())
+		p.Elem().Set(object)
+		object = p
+	} else if object.IsNil() {
+		object = reflect.New(object.Type().Elem())
+	}
 
 	s := indirect(object)
 	if !s.Addr().Type().Implements(v.Type()) {
-		return &ConfigValueError{key, fmt.Sprintf("%v does not implement %v", s.Type(), v.Type())}
+		return &ConfigValueError{Key: key, Message: fmt.Sprintf("%v does not implement %v", s.Type(), v.Type()), Err: ErrTypeMismatch, Expected: v.Type().String(), Actual: config.Interface()}
 	}
 	v.Set(object)
 
-	return c.populateStruct(s, config, key)
+	return p.populateStruct(s, config, key)
 }
 
 // populateScalar
-func (c *Conf) populateScalar(v, config reflect.Value, key string) error {
+func (p *populator) populateScalar(v, config reflect.Value, key string) error {
 	if !config.IsValid() {
 		switch v.Kind() {
 		case reflect.Interface, reflect.Ptr, reflect.Map, reflect.Slice:
@@ -309,10 +653,18 @@ func (c *Conf) populateScalar(v, config reflect.Value, key string) error {
 		return nil
 	}
 
+	// strings from the environment are parsed.
+	if config.Kind() == reflect.String && p.fromEnv(key) {
+		if err := parseEnvValue(config.String(), v); err != nil {
+			return &ConfigValueError{Key: key, Message: err.Error(), Err: ErrTypeMismatch, Expected: v.Type().String(), Actual: config.String()}
+		}
+		return nil
+	}
+
 	if config.Type().ConvertibleTo(v.Type()) {
 		v.Set(config.Convert(v.Type()))
 		return nil
 	}
 
-	return &ConfigValueError{key, fmt.Sprintf("%v cannot be used to configure %v", config.Type(), v.Type())}
+	return mismatch(key, config, v.Type())
 }
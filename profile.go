@@ -0,0 +1,92 @@
+package cconf
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// DefaultProfileTemplate is the default naming scheme of profile overlays, see LoadProfile.
+var DefaultProfileTemplate = "{name}.{profile}{ext}"
+
+// LoadProfile loads the base file and then, in order, the existing overlay files of the profiles,
+// skipping absent ones. The overlay file names are derived from the base path with the
+// ProfileTemplate, in which {name} stands for the base path without its extension, {ext} for
+// the extension and {profile} for the profile: app.json has the overlay app.prod.json for the
+// profile "prod" by default. Like Load, it is atomic.
+func (c *Conf) LoadProfile(base string, profiles ...string) error {
+	ext := filepath.Ext(base)
+	files := []string{base}
+	for _, profile := range profiles {
+		file := strings.NewReplacer("{name}", strings.TrimSuffix(base, ext), "{profile}", profile,
+			"{ext}", ext).Replace(c.ProfileTemplate)
+		if _, err := os.Stat(file); err == nil {
+			files = append(files, file)
+		} else if !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return c.Load(files...)
+}
+
+// ApplyProfile applies a profile section of the store: the "defaults" section and then the
+// section of the profile below "profiles" are merged over the rest of the store, and both
+// sections are removed, so that
+//
+//	{"defaults": {"debug": false}, "profiles": {"dev": {"debug": true}}, "name": "app"}
+//
+// becomes {"debug": true, "name": "app"} for the profile "dev". With a key, the sections below
+// the key are applied to the value at the key instead. An unknown profile is an error that
+// lists the available profiles.
+func (c *Conf) ApplyProfile(name string, key ...string) error {
+	c.mu.Lock()
+	defer c.unlock()
+	return c.loadSources(source{apply: func(base interface{}) (interface{}, error) {
+		return c.applyProfile(base, name, key...)
+	}, origin: named("ApplyProfile(" + name + ")")})
+}
+
+// applyProfile returns the base layer with the profile applied, see ApplyProfile.
+func (c *Conf) applyProfile(store interface{}, name string, key ...string) (interface{}, error) {
+	node := store
+	if len(key) > 0 {
+		var ok bool
+		if node, ok = walk(store, key[0], c.Separator); !ok {
+			return nil, &ConfigKeyError{Key: key[0], Message: "no configuration value was found", Err: ErrKeyNotFound}
+		}
+	}
+	m, ok := node.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("the configuration has no profiles")
+	}
+	profiles, _ := m["profiles"].(map[string]interface{})
+	profile, ok := profiles[name]
+	if !ok {
+		names := make([]string, 0, len(profiles))
+		for n := range profiles {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("unknown profile %q, the available profiles are: %s", name, strings.Join(names, ", "))
+	}
+
+	rest := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		if k != "defaults" && k != "profiles" {
+			rest[k] = v
+		}
+	}
+	node = rest
+	for _, section := range []interface{}{m["defaults"], profile} {
+		if section != nil {
+			node = merge(node, section)
+		}
+	}
+	if len(key) == 0 {
+		return node, nil
+	}
+	return c.set(store, strings.Split(key[0], c.Separator), 0, node)
+}
@@ -0,0 +1,90 @@
+package cconf
+
+import (
+	"testing"
+)
+
+func TestLoadProfile(t *testing.T) {
+	c := New()
+	if err := c.LoadProfile("testdata/profile/app.json", "prod", "staging"); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, "app", c.GetString("name"))
+	equal(t, "db.prod", c.GetString("db.host"))
+	equal(t, 5432, c.GetInt("db.port"))
+	equal(t, []string{"testdata/profile/app.json", "testdata/profile/app.prod.json"}, loadedSources(c))
+
+	c = New()
+	c.ProfileTemplate = "{name}-{profile}{ext}"
+	if err := c.LoadProfile("testdata/profile/app.json", "prod", "local"); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, "local", c.GetString("name"))
+	equal(t, "localhost", c.GetString("db.host"))
+
+	// the base file is required.
+	c = New()
+	if err := c.LoadProfile("testdata/profile/none.json", "prod"); err == nil {
+		t.Fatal("expected an error")
+	}
+	equal(t, nil, c.GetStore())
+}
+
+// loadedSources returns the sources of the loads of the Conf.
+func loadedSources(c *Conf) []string {
+	var sources []string
+	for _, l := range c.Stats().Loads {
+		sources = append(sources, l.Source)
+	}
+	return sources
+}
+
+func TestApplyProfile(t *testing.T) {
+	data := map[string]interface{}{
+		"name": "app",
+		"db":   map[string]interface{}{"host": "localhost", "port": 5432},
+		"defaults": map[string]interface{}{
+			"debug": false,
+			"db":    map[string]interface{}{"pool": 10, "host": "db.default"},
+		},
+		"profiles": map[string]interface{}{
+			"prod": map[string]interface{}{"db": map[string]interface{}{"host": "db.prod", "pool": 50}},
+			"dev":  map[string]interface{}{"debug": true},
+		},
+	}
+	c := New()
+	c.SetStore(data)
+	if err := c.ApplyProfile("prod"); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, map[string]interface{}{
+		"name":  "app",
+		"debug": false,
+		"db":    map[string]interface{}{"host": "db.prod", "port": 5432, "pool": 50},
+	}, c.GetStore())
+
+	c.SetStore(data)
+	if err := c.ApplyProfile("dev"); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, true, c.Get("debug"))
+	equal(t, "db.default", c.Get("db.host"))
+	equal(t, nil, c.Get("profiles"))
+
+	c.SetStore(data)
+	err := c.ApplyProfile("staging")
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	equal(t, `unknown profile "staging", the available profiles are: dev, prod`, err.Error())
+	equal(t, "localhost", c.Get("db.host"))
+
+	// the sections below a key.
+	c.SetStore(map[string]interface{}{"service": data, "other": 1})
+	if err := c.ApplyProfile("prod", "service"); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, "db.prod", c.Get("service.db.host"))
+	equal(t, nil, c.Get("service.defaults"))
+	equal(t, 1, c.Get("other"))
+}
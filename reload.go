@@ -0,0 +1,372 @@
+package cconf
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// source is a tracked source of configuration data, which is loaded again by reloads.
+type source struct {
+	name      string                                      // the name recorded in the load stats, if any
+	file      string                                      // the path of a file source
+	key       string                                      // the key the fetched data is loaded into, if any
+	remote    RemoteProvider                              // the provider of a remote source
+	fetch     func() (interface{}, error)                 // returns the data of the source
+	raw       interface{}                                 // the normalized data of the last fetch, see resolveSources
+	cached    interface{}                                 // the resolved data of the last fetch
+	templates map[string]string                           // the executed templates of the raw data by text
+	data      interface{}                                 // the normalized data of a fixed source without fetch
+	set       bool                                        // fixed data of Set calls
+	defaults  bool                                        // fixed data of the defaults layer, see SetDefaults
+	secret    bool                                        // the keys of the data are secret, see LoadDockerSecrets
+	apply     func(base interface{}) (interface{}, error) // transforms the base layer instead
+	origin    func(key string) string                     // names the source of the keys, see Sources
+}
+
+// loadSources loads the sources in order, merges them into the store and tracks them for
+// reloads. It is atomic. The caller must hold c.mu.
+func (c *Conf) loadSources(srcs ...source) error {
+	return c.loadSourcesContext(context.Background(), srcs...)
+}
+
+// loadSourcesContext is like loadSources, but stops before the next source and before the
+// commit when ctx is done. The caller must hold c.mu.
+func (c *Conf) loadSourcesContext(ctx context.Context, srcs ...source) error {
+	if err := c.errFrozen(); err != nil {
+		return err
+	}
+	for i := range srcs {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if srcs[i].fetch != nil {
+			if err := c.fetchSource(&srcs[i]); err != nil {
+				return err
+			}
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if c.Interpolate && c.hasRaw() {
+		// the new sources may change the values referenced by the loaded ones.
+		return c.build(append(c.sources[:len(c.sources):len(c.sources)], srcs...), true, false)
+	}
+	if err := c.resolveSources(c.base, srcs); err != nil {
+		return err
+	}
+	base := c.base
+	var origins []origin
+	for i := range srcs {
+		var err error
+		if base, origins, err = c.mergeSource(base, origins, &srcs[i]); err != nil {
+			return err
+		}
+	}
+	// track the origins first, so that the new snapshot knows them, see fromEnv.
+	old := c.origins
+	c.setOrigins(appendOrigins(c.origins, origins), c.overrideOrigins)
+	if err := c.commit(base); err != nil {
+		c.setOrigins(old, c.overrideOrigins)
+		return err
+	}
+	c.sources = append(c.sources, srcs...)
+	return nil
+}
+
+// hasRaw reports whether a tracked source has raw data to resolve. The caller must hold c.mu.
+func (c *Conf) hasRaw() bool {
+	for _, src := range c.sources {
+		if src.raw != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// fetchSource fetches the data of the source as its raw data, see resolveSources.
+// The caller must hold c.mu.
+func (c *Conf) fetchSource(src *source) error {
+	start := time.Now()
+	data, err := src.fetch()
+	if src.name != "" {
+		c.recordLoad(src.name, start, err)
+	}
+	if err != nil {
+		return err
+	}
+	if src.file != "" || src.remote != nil {
+		if data, err = c.migrate(src.name, data); err != nil {
+			return err
+		}
+	}
+	if data, err = c.nest(src.key, data); err != nil {
+		return err
+	}
+	src.raw, src.templates = data, nil
+	return nil
+}
+
+// resolveSources resolves the references and executes the templates of the raw data of the
+// sources into their cached data. References are resolved once the sources are merged: a key
+// names the value of the last source setting it, or else the value of the base layer.
+// Templates are executed once per fetch of a source, unless their text changes.
+// The caller must hold c.mu.
+func (c *Conf) resolveSources(base interface{}, srcs []source) error {
+	var in *interpolator
+	if c.ExpandEnv || c.Interpolate {
+		// the raw values left after merging the sources, and the other values.
+		var raw interface{}
+		view := base
+		for _, src := range srcs {
+			switch {
+			case src.raw != nil:
+				raw = merge(raw, src.raw)
+				view = merge(view, src.raw)
+			case src.apply == nil:
+				raw = without(raw, src.data)
+				view = merge(view, src.data)
+			}
+		}
+		in = c.newInterpolator(normalize(raw), c.effective(view))
+	}
+	for i := range srcs {
+		src := &srcs[i]
+		if src.raw == nil {
+			continue
+		}
+		data := normalize(src.raw)
+		var err error
+		if in != nil {
+			if data, err = in.values(data, ""); err != nil {
+				return err
+			}
+		}
+		if c.ExecTemplates {
+			if src.templates == nil {
+				src.templates = make(map[string]string)
+			}
+			if data, err = c.execTemplates(data, src.templates); err != nil {
+				return err
+			}
+		}
+		src.cached = data
+	}
+	return nil
+}
+
+// mergeSource merges the resolved data of the source into the base layer, or applies it,
+// and appends the origins of its values. The caller must hold c.mu.
+func (c *Conf) mergeSource(base interface{}, origins []origin, src *source) (interface{}, []origin, error) {
+	name := src.origin
+	if name == nil {
+		name = named(src.name)
+	}
+	if src.apply != nil {
+		applied, err := src.apply(base)
+		if err != nil {
+			return nil, nil, err
+		}
+		d := diff(base, applied, c.Separator)
+		for _, l := range [][]KeyChange{d.Added, d.Changed} {
+			for _, kc := range l {
+				origins = append(origins, origin{key: kc.Key, source: name(kc.Key)})
+			}
+		}
+		return applied, origins, nil
+	}
+	data := src.data
+	if src.raw != nil {
+		data = src.cached
+	}
+	merged := merge(base, data)
+	if !src.set && !src.defaults {
+		var err error
+		if merged, err = c.mergeLoaded("", base, data); err != nil {
+			return nil, nil, err
+		}
+	}
+	return merged, append(origins, c.originsOf("", data, name)...), nil
+}
+
+// nest returns the data normalized and nested below the key, see LoadInto.
+func (c *Conf) nest(key string, data interface{}) (interface{}, error) {
+	data = normalize(data)
+	if key == "" {
+		return data, nil
+	}
+	return c.set(make(map[string]interface{}), strings.Split(key, c.Separator), 0, data)
+}
+
+// trackSet tracks the value set at the key, so that reloads set it again.
+// The caller must hold c.mu.
+func (c *Conf) trackSet(key string, val interface{}) {
+	var data interface{} = make(map[string]interface{})
+	if n := len(c.sources); n > 0 && c.sources[n-1].set {
+		data = c.sources[n-1].data
+		c.sources = c.sources[: n-1 : n-1]
+	}
+	data, err := c.set(data, strings.Split(key, c.Separator), 0, val)
+	if err != nil {
+		// the key is valid for the store, but not for the tracked values.
+		data = map[string]interface{}{}
+	}
+	c.sources = append(c.sources, source{data: data, set: true, origin: named("Set()")})
+	c.setOrigins(appendOrigins(c.origins, c.originsOf(key, val, named("Set()"))), c.overrideOrigins)
+}
+
+// Reload loads all tracked sources again in their original order, like files, remote sources
+// and the environment, into a new store and swaps it in atomically, e.g. on SIGHUP. The values
+// passed to Set are set again in order and the overrides of SetOverride stay on top.
+// The new store is checked against the declared types (see DeclareTypes) and the reload
+// validator (see SetReloadValidator); if a source fails to load or a check fails, the old
+// configuration stays live. The outcome is reported by
+// an EventReload, the changes by the OnReload and OnChange functions.
+func (c *Conf) Reload() error {
+	c.mu.Lock()
+	defer c.unlock()
+	return c.reload()
+}
+
+// reload loads all tracked sources again in order into a new base layer and publishes it.
+// It is atomic. The caller must hold c.mu.
+func (c *Conf) reload() error {
+	start := time.Now()
+	err := c.rebuild(true)
+	if err == nil {
+		c.reloaded = true
+	}
+	c.queue(Event{Type: EventReload, Duration: time.Since(start), Err: err})
+	return err
+}
+
+// rebuild merges all tracked sources in order into a new base layer and publishes it.
+// The sources are fetched again, their references resolved again and the result is validated
+// with fetch, otherwise the data of their last fetch is merged. It is atomic.
+// The caller must hold c.mu.
+func (c *Conf) rebuild(fetch bool) error {
+	if err := c.errFrozen(); err != nil {
+		return err
+	}
+	srcs := append([]source(nil), c.sources...)
+	if fetch {
+		for i := range srcs {
+			if srcs[i].fetch != nil {
+				if err := c.fetchSource(&srcs[i]); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return c.build(srcs, fetch, fetch)
+}
+
+// build merges the sources in order into a new base layer, tracks them and publishes it.
+// The references of their raw data are resolved again with resolve and the result is validated
+// like a reload with validate. It is atomic. The caller must hold c.mu.
+func (c *Conf) build(srcs []source, resolve, validate bool) error {
+	if err := c.errFrozen(); err != nil {
+		return err
+	}
+	if resolve {
+		if err := c.resolveSources(nil, srcs); err != nil {
+			return err
+		}
+	}
+	var base interface{}
+	var origins []origin
+	for i := range srcs {
+		var err error
+		if base, origins, err = c.mergeSource(base, origins, &srcs[i]); err != nil {
+			return err
+		}
+	}
+	store, err := c.applyTypes(c.effective(base), "")
+	if err != nil {
+		return err
+	}
+	if validate && (c.reloadValidator != nil || len(c.validators) > 0 || c.allowedKeys != nil) {
+		if err := c.validateReload(base, store, origins); err != nil {
+			return err
+		}
+	}
+	c.sources = srcs
+	c.base = base
+	c.setOrigins(origins, c.overrideOrigins)
+	c.reset(store)
+	return nil
+}
+
+// ReloadError describes a reloaded configuration rejected by the reload validator.
+type ReloadError struct {
+	Source string // the source of the value of the key the validator failed on, if known
+	Err    error  // the error of the validator
+}
+
+// Error returns the error message represented by ReloadError
+func (e *ReloadError) Error() string {
+	if e.Source != "" {
+		return fmt.Sprintf("reloaded configuration rejected: %v (source %s)", e.Err, e.Source)
+	}
+	return "reloaded configuration rejected: " + e.Err.Error()
+}
+
+// Unwrap returns the error of the validator.
+func (e *ReloadError) Unwrap() error {
+	return e.Err
+}
+
+// SetReloadValidator sets a function that validates every reloaded configuration before it
+// is swapped in (see Reload and Watch). The function gets a throwaway Conf holding the
+// candidate configuration, which it may check with Get, Populate into a throwaway struct
+// and so on; it must not use the Conf being reloaded. If it returns an error, the old
+// configuration stays live and the reload fails with a *ReloadError, which notes the source
+// of the offending value if the error is a ConfigKeyError or a ConfigValueError.
+func (c *Conf) SetReloadValidator(fn func(candidate *Conf) error) {
+	c.mu.Lock()
+	defer c.unlock()
+	c.reloadValidator = fn
+}
+
+// validateReload validates the reloaded configuration with the validators, the allowed keys
+// and the reload validator.
+// The caller must hold c.mu.
+func (c *Conf) validateReload(base, store interface{}, origins []origin) error {
+	candidate := New()
+	candidate.Separator = c.Separator
+	candidate.ValidationMode = c.ValidationMode
+	candidate.env = c.env
+	candidate.fileSuffix = c.fileSuffix
+	candidate.base = base
+	candidate.overrides = c.overrides
+	candidate.setOrigins(origins, c.overrideOrigins)
+	candidate.snap.Store(candidate.newSnapshot(store))
+
+	err := candidate.validate(store, c.validators)
+	if err == nil && c.allowedKeys != nil {
+		err = candidate.checkUnknownKeys(store, c.allowedKeys)
+	}
+	if err == nil && c.reloadValidator != nil {
+		err = c.reloadValidator(candidate)
+	}
+	if err == nil {
+		return nil
+	}
+	var key string
+	var ke *ConfigKeyError
+	var ve *ConfigValueError
+	switch {
+	case errors.As(err, &ke):
+		key = ke.Key
+	case errors.As(err, &ve):
+		key = ve.Key
+	}
+	rerr := &ReloadError{Err: err}
+	if key = strings.Trim(key, c.Separator); key != "" {
+		rerr.Source = candidate.Source(key)
+	}
+	return rerr
+}
@@ -0,0 +1,148 @@
+package cconf
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// copyTestdata copies the testdata files into a temporary directory and returns their paths.
+func copyTestdata(t *testing.T, names ...string) []string {
+	dir := t.TempDir()
+	files := make([]string, len(names))
+	for i, name := range names {
+		data, err := os.ReadFile(filepath.Join("testdata", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[i] = filepath.Join(dir, name)
+		if err := os.WriteFile(files[i], data, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return files
+}
+
+func TestReload(t *testing.T) {
+	files := copyTestdata(t, "app.json", "server.json")
+	c := New()
+	if err := c.Load(files...); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Set("server.Port", 9090); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.SetOverride("name", "override"); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("CCONF_RELOAD_DEBUG", "false")
+	if err := c.LoadEnv("CCONF_RELOAD_"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.DeclareTypes(map[string]interface{}{"version": 0.0}); err != nil {
+		t.Fatal(err)
+	}
+
+	write := func(file, data string) {
+		if err := os.WriteFile(file, []byte(data), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(files[0], `{"version": 0.2, "name": "app"}`)
+	write(files[1], `{"server": {"Host": "example.com", "Port": 8080}}`)
+	t.Setenv("CCONF_RELOAD_DEBUG", "true")
+	if err := c.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, 0.2, c.GetFloat("version"))
+	equal(t, "override", c.GetString("name"))
+	equal(t, "example.com", c.GetString("server.Host"))
+	equal(t, 9090, c.GetInt("server.Port"))
+	equal(t, true, c.GetBool("debug"))
+	equal(t, nil, c.Get("ext"))
+
+	// errors leave the old configuration untouched.
+	write(files[0], `{"version": "new"}`)
+	if err := c.Reload(); err == nil {
+		t.Fatal("expected an error")
+	}
+	write(files[1], `{"server": `)
+	if err := c.Reload(); err == nil {
+		t.Fatal("expected an error")
+	}
+	equal(t, 0.2, c.GetFloat("version"))
+	equal(t, "example.com", c.GetString("server.Host"))
+
+	if err := os.Remove(files[1]); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Reload(); err == nil {
+		t.Fatal("expected an error")
+	}
+	equal(t, "example.com", c.GetString("server.Host"))
+}
+
+func TestSetReloadValidator(t *testing.T) {
+	files := copyTestdata(t, "server.json")
+	c := New()
+	if err := c.Load(files...); err != nil {
+		t.Fatal(err)
+	}
+	var candidates []*Conf
+	c.SetReloadValidator(func(candidate *Conf) error {
+		candidates = append(candidates, candidate)
+		if candidate.Get("server.Host") == nil {
+			return &ConfigKeyError{Key: "server.Host", Message: "the key is required"}
+		}
+		if _, ok := candidate.Get("server.Port").(float64); !ok {
+			return &ConfigValueError{Key: "server.Port", Message: "the port must be a number"}
+		}
+		return nil
+	})
+	var events []Event
+	c.OnEvent(func(e Event) {
+		if e.Type == EventReload {
+			events = append(events, e)
+		}
+	})
+
+	write := func(data string) {
+		if err := os.WriteFile(files[0], []byte(data), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(`{"server": {"Port": 8081}}`)
+	err := c.Reload()
+	var rerr *ReloadError
+	if !errors.As(err, &rerr) {
+		t.Fatalf("expected a ReloadError, got %v", err)
+	}
+	equal(t, "", rerr.Source)
+	equal(t, `reloaded configuration rejected: "server.Host" is not a valid key: the key is required`, err.Error())
+	equal(t, "localhost", c.GetString("server.Host"))
+	equal(t, 8080, c.GetInt("server.Port"))
+	equal(t, 1, len(events))
+	equal(t, err, events[0].Err)
+
+	write(`{"server": {"Host": "example.com", "Port": "80"}}`)
+	if err := c.Reload(); !errors.As(err, &rerr) {
+		t.Fatalf("expected a ReloadError, got %v", err)
+	}
+	equal(t, files[0], rerr.Source)
+	equal(t, "localhost", c.GetString("server.Host"))
+
+	write(`{"server": {"Host": "example.com", "Port": 80}}`)
+	if err := c.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, "example.com", c.GetString("server.Host"))
+	equal(t, 3, len(candidates))
+	equal(t, "example.com", candidates[2].GetString("server.Host"))
+
+	// loads are not validated.
+	if err := c.Set("server.Host", nil); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, 3, len(candidates))
+}
@@ -0,0 +1,176 @@
+package cconf
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// RemoteProvider provides configuration data from a remote source, such as a key-value store
+// or an HTTP endpoint.
+type RemoteProvider interface {
+	// Name identifies the source. Concurrent fetches of sources with the same name are coalesced.
+	Name() string
+	// Fetch returns the configuration data of the source.
+	Fetch() (interface{}, error)
+}
+
+// ContextProvider is a RemoteProvider whose fetches can be canceled, see LoadRemoteContext.
+type ContextProvider interface {
+	RemoteProvider
+	// FetchContext returns the configuration data of the source, or the error of ctx when it is
+	// done before.
+	FetchContext(ctx context.Context) (interface{}, error)
+}
+
+// LoadRemote fetches configuration data from one or multiple remote providers and merges it
+// into the store in order. Like Load, it is atomic.
+//
+// Concurrent fetches of the same source, whether by LoadRemote or by other remote lookups,
+// are coalesced into a single request whose result (or error) is shared by all callers.
+func (c *Conf) LoadRemote(providers ...RemoteProvider) error {
+	return c.LoadRemoteContext(context.Background(), providers...)
+}
+
+// LoadRemoteContext is like LoadRemote, but stops when ctx is done, returning its error and
+// leaving the store unchanged. The fetches of ContextProviders get a context that is canceled
+// when every caller waiting for the coalesced fetch stopped waiting; the other providers are
+// left to complete their fetch in the background.
+func (c *Conf) LoadRemoteContext(ctx context.Context, providers ...RemoteProvider) error {
+	type fetched struct {
+		start time.Time
+		data  interface{}
+		err   error
+	}
+	// fetch outside the lock, so that concurrent loads can share the requests.
+	results := make([]fetched, len(providers))
+	for i, p := range providers {
+		results[i].start = time.Now()
+		results[i].data, results[i].err = c.fetchContext(ctx, p)
+		if results[i].err != nil {
+			results = results[:i+1]
+			break
+		}
+	}
+
+	c.mu.Lock()
+	defer c.unlock()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	srcs := make([]source, len(results))
+	for i, r := range results {
+		p, r, fetched := providers[i], r, false
+		srcs[i] = source{name: p.Name(), remote: p, fetch: func() (interface{}, error) {
+			// the first load uses the data fetched above, reloads fetch again.
+			if !fetched {
+				fetched = true
+				return r.data, r.err
+			}
+			return c.fetch(p)
+		}}
+	}
+	return c.loadSources(srcs...)
+}
+
+// fetch fetches the data of the provider, coalescing concurrent fetches of the same source.
+func (c *Conf) fetch(p RemoteProvider) (interface{}, error) {
+	return c.fetchContext(context.Background(), p)
+}
+
+// fetchContext is like fetch, but stops waiting for the fetch when ctx is done.
+func (c *Conf) fetchContext(ctx context.Context, p RemoteProvider) (interface{}, error) {
+	return c.flight.doContext(ctx, "remote:"+p.Name(), func(ctx context.Context) (interface{}, error) {
+		if cp, ok := p.(ContextProvider); ok {
+			return cp.FetchContext(ctx)
+		}
+		return p.Fetch()
+	})
+}
+
+// WatchingProvider is a RemoteProvider that streams its changes, like a watch of etcd or Consul.
+type WatchingProvider interface {
+	RemoteProvider
+	// Watch returns a channel receiving the complete data of the source whenever it changes.
+	// The channel is closed when ctx is done or the stream fails.
+	Watch(ctx context.Context) (<-chan map[string]interface{}, error)
+}
+
+// remoteBackoff is the initial and remoteMaxBackoff the maximal delay before WatchRemote
+// reconnects a failed stream.
+var remoteBackoff, remoteMaxBackoff = 100 * time.Millisecond, 30 * time.Second
+
+// WatchRemote watches a provider loaded by LoadRemote in the background: every data it streams
+// replaces the data of the provider and all tracked sources are reloaded with it, like Reload,
+// so that a failed or rejected reload (see SetReloadValidator) keeps the old configuration
+// and the old data. When the stream fails, it is reconnected with an exponential backoff;
+// the failures are reported by an EventLoad of the provider (see OnEvent). Watching stops
+// when ctx is done or the Conf is closed.
+func (c *Conf) WatchRemote(ctx context.Context, p WatchingProvider) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	loaded := false
+	for _, src := range c.sources {
+		if src.remote != nil && src.remote.Name() == p.Name() {
+			loaded = true
+		}
+	}
+	if !loaded {
+		return errors.New("the provider " + p.Name() + " was not loaded by LoadRemote")
+	}
+	return c.start(ctx, func(ctx context.Context) {
+		c.watchRemote(ctx, p)
+	})
+}
+
+// watchRemote applies the data streamed by the provider, reconnecting failed streams,
+// until ctx is done.
+func (c *Conf) watchRemote(ctx context.Context, p WatchingProvider) {
+	backoff := remoteBackoff
+	for {
+		start := time.Now()
+		ch, err := p.Watch(ctx)
+		if err == nil {
+			for data := range ch {
+				backoff = remoteBackoff
+				c.applyRemote(p, data)
+			}
+			err = errors.New("the watch of " + p.Name() + " was interrupted")
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		c.mu.Lock()
+		c.recordLoad(p.Name(), start, err)
+		c.unlock()
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		if backoff *= 2; backoff > remoteMaxBackoff {
+			backoff = remoteMaxBackoff
+		}
+	}
+}
+
+// applyRemote reloads the tracked sources with the data of the provider.
+func (c *Conf) applyRemote(p RemoteProvider, data map[string]interface{}) {
+	c.mu.Lock()
+	defer c.unlock()
+	old := append([]source(nil), c.sources...)
+	for i, src := range c.sources {
+		if src.remote != nil && src.remote.Name() == p.Name() {
+			src.fetch = func() (interface{}, error) {
+				return data, nil
+			}
+			c.sources[i] = src
+		}
+	}
+	if err := c.reload(); err != nil {
+		c.sources = old
+	}
+}
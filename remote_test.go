@@ -0,0 +1,273 @@
+package cconf
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingProvider is a slow remote provider that counts its fetches.
+type countingProvider struct {
+	name    string
+	data    interface{}
+	err     error
+	fetches atomic.Int32
+	ready   *sync.WaitGroup // waited for before the fetch completes
+}
+
+func (p *countingProvider) Name() string { return p.name }
+
+func (p *countingProvider) Fetch() (interface{}, error) {
+	p.fetches.Add(1)
+	if p.ready != nil {
+		p.ready.Wait()
+	}
+	time.Sleep(20 * time.Millisecond)
+	return p.data, p.err
+}
+
+func TestLoadRemoteSingleflight(t *testing.T) {
+	for _, fail := range []bool{false, true} {
+		var ready sync.WaitGroup
+		p := &countingProvider{
+			name:  "kv",
+			data:  map[string]interface{}{"feature": map[string]interface{}{"enabled": true}},
+			ready: &ready,
+		}
+		if fail {
+			p.err = errors.New("backend unavailable")
+		}
+		c := New()
+
+		var wg sync.WaitGroup
+		errs := make([]error, 50)
+		for i := 0; i < 50; i++ {
+			ready.Add(1)
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				ready.Done()
+				errs[i] = c.LoadRemote(p)
+			}(i)
+		}
+		wg.Wait()
+
+		equal(t, int32(1), p.fetches.Load())
+		for _, err := range errs {
+			equal(t, p.err, err)
+		}
+		equal(t, !fail, c.GetBool("feature.enabled"))
+	}
+}
+
+func TestLoadRemoteIsAtomic(t *testing.T) {
+	c := New()
+	good := &countingProvider{name: "good", data: map[string]interface{}{"a": 1}}
+	bad := &countingProvider{name: "bad", err: errors.New("backend unavailable")}
+	if err := c.LoadRemote(good, bad); err == nil {
+		t.Fatal("Expected an error")
+	}
+	equal(t, nil, c.GetStore())
+	if err := c.LoadRemote(good); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, 1, c.Get("a"))
+	equal(t, "good", c.Stats().Loads[0].Source)
+}
+
+// scriptedProvider is a watching provider whose watches stream the scripted data or fail.
+type scriptedProvider struct {
+	mu      sync.Mutex
+	watches [][]map[string]interface{} // the data of every watch, nil to fail it
+	calls   int
+}
+
+func (p *scriptedProvider) Name() string { return "scripted" }
+
+func (p *scriptedProvider) Fetch() (interface{}, error) {
+	return map[string]interface{}{"version": 0.0}, nil
+}
+
+func (p *scriptedProvider) Watch(ctx context.Context) (<-chan map[string]interface{}, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.calls++
+	if len(p.watches) == 0 {
+		// stream nothing until ctx is done.
+		ch := make(chan map[string]interface{})
+		go func() {
+			<-ctx.Done()
+			close(ch)
+		}()
+		return ch, nil
+	}
+	data := p.watches[0]
+	p.watches = p.watches[1:]
+	if data == nil {
+		return nil, errors.New("connection refused")
+	}
+	ch := make(chan map[string]interface{}, len(data))
+	for _, d := range data {
+		ch <- d
+	}
+	close(ch)
+	return ch, nil
+}
+
+func TestWatchRemote(t *testing.T) {
+	defer func(b time.Duration) { remoteBackoff = b }(remoteBackoff)
+	remoteBackoff = time.Millisecond
+
+	p := &scriptedProvider{watches: [][]map[string]interface{}{
+		{{"version": 1.0}, {"version": 2.0, "name": "app"}},
+		nil,
+	}}
+	c := New()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := c.WatchRemote(ctx, p); err == nil {
+		t.Fatal("expected an error for a provider that was not loaded")
+	}
+	if err := c.LoadRemote(p); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, 0.0, c.GetFloat("version"))
+
+	var versions []float64
+	var errs []error
+	var mu sync.Mutex
+	c.OnChange("version", func(old, new interface{}) {
+		mu.Lock()
+		versions = append(versions, new.(float64))
+		mu.Unlock()
+	})
+	c.OnEvent(func(e Event) {
+		if e.Type == EventLoad && e.Err != nil {
+			mu.Lock()
+			errs = append(errs, e.Err)
+			mu.Unlock()
+		}
+	})
+	if err := c.WatchRemote(ctx, p); err != nil {
+		t.Fatal(err)
+	}
+
+	// the first stream is interrupted, the second watch fails, the third one stays open.
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		p.mu.Lock()
+		calls := p.calls
+		p.mu.Unlock()
+		if calls == 3 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("no reconnect")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	mu.Lock()
+	equal(t, []float64{1, 2}, versions)
+	equal(t, 2, len(errs))
+	equal(t, "connection refused", errs[1].Error())
+	mu.Unlock()
+	equal(t, "app", c.GetString("name"))
+
+	// reloads keep the streamed data.
+	if err := c.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, 2.0, c.GetFloat("version"))
+}
+
+// blockingProvider is a remote provider whose fetches block until their context is done.
+type blockingProvider struct {
+	started  chan struct{}
+	canceled chan struct{}
+}
+
+func (p *blockingProvider) Name() string { return "blocking" }
+
+func (p *blockingProvider) Fetch() (interface{}, error) {
+	return p.FetchContext(context.Background())
+}
+
+func (p *blockingProvider) FetchContext(ctx context.Context) (interface{}, error) {
+	close(p.started)
+	<-ctx.Done()
+	close(p.canceled)
+	return nil, ctx.Err()
+}
+
+func TestLoadRemoteContext(t *testing.T) {
+	c := New()
+	c.SetStore(map[string]interface{}{"a": 1})
+	p := &blockingProvider{started: make(chan struct{}), canceled: make(chan struct{})}
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-p.started
+		cancel()
+	}()
+	if err := c.LoadRemoteContext(ctx, p); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected the load to be canceled, got %v", err)
+	}
+	select {
+	case <-p.canceled:
+	case <-time.After(time.Second):
+		t.Fatal("Expected the fetch to be canceled")
+	}
+	equal(t, map[string]interface{}{"a": 1}, c.GetStore())
+
+	// providers without FetchContext complete their fetch in the background.
+	slow := &countingProvider{name: "slow", data: map[string]interface{}{"b": 2}}
+	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel()
+	if err := c.LoadRemoteContext(ctx, slow); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected the load to time out, got %v", err)
+	}
+	equal(t, map[string]interface{}{"a": 1}, c.GetStore())
+	if err := c.LoadRemote(slow); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, 2, c.GetInt("b"))
+}
+
+func TestLoadContext(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "app.json")
+	if err := os.WriteFile(file, []byte(`{"a": 1}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	c := New()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := c.LoadContext(ctx, file); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected the load to be canceled, got %v", err)
+	}
+	equal(t, nil, c.GetStore())
+	if err := c.LoadContext(context.Background(), file); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, 1, c.GetInt("a"))
+
+	// the reader is canceled midway.
+	ctx, cancel = context.WithCancel(context.Background())
+	r := io.MultiReader(strings.NewReader(`{"b": `), readerFunc(func(p []byte) (int, error) {
+		cancel()
+		return copy(p, "2}"), nil
+	}), strings.NewReader(" "))
+	if err := c.LoadReaderContext(ctx, "json", r); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected the load to be canceled, got %v", err)
+	}
+	equal(t, map[string]interface{}{"a": 1.0}, c.GetStore())
+}
+
+type readerFunc func(p []byte) (int, error)
+
+func (f readerFunc) Read(p []byte) (int, error) { return f(p) }
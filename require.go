@@ -0,0 +1,101 @@
+package cconf
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// MissingKeysError describes the mandatory keys without value, see RequireKeys.
+type MissingKeysError struct {
+	Keys  []string // the missing keys, in the order they were required
+	Empty bool     // whether empty values count as missing, see RequireNonEmpty
+	// Suggestions maps missing keys to the existing keys they are likely misspellings of.
+	Suggestions map[string]string
+}
+
+// Error returns the error message represented by MissingKeysError
+func (e *MissingKeysError) Error() string {
+	keys := make([]string, len(e.Keys))
+	for i, k := range e.Keys {
+		keys[i] = strconv.Quote(k)
+		if s, ok := e.Suggestions[k]; ok {
+			keys[i] += fmt.Sprintf(" (did you mean %q?)", s)
+		}
+	}
+	if e.Empty {
+		return "missing or empty configuration keys: " + strings.Join(keys, ", ")
+	}
+	return "missing configuration keys: " + strings.Join(keys, ", ")
+}
+
+// Unwrap returns a ConfigKeyError for every missing key.
+func (e *MissingKeysError) Unwrap() []error {
+	msg := "no configuration value was found"
+	if e.Empty {
+		msg = "no or an empty configuration value was found"
+	}
+	errs := make([]error, len(e.Keys))
+	for i, k := range e.Keys {
+		errs[i] = &ConfigKeyError{Key: k, Message: msg, Err: ErrKeyNotFound}
+		if s, ok := e.Suggestions[k]; ok {
+			errs[i] = &ConfigKeyError{Key: k, Message: fmt.Sprintf("%s, did you mean %q?", msg, s), Err: ErrKeyNotFound}
+		}
+	}
+	return errs
+}
+
+// RequireKeys checks that all keys have a value, like Lookup, e.g. at startup. It returns a
+// *MissingKeysError listing the first missing key, or every one in CollectAll mode (see
+// ValidationMode), or nil. An explicit null counts as missing. A missing key that looks like a
+// typo of an existing key comes with a suggestion, like `did you mean "http.port"?`.
+func (c *Conf) RequireKeys(keys ...string) error {
+	return c.require(keys, false)
+}
+
+// RequireNonEmpty is like RequireKeys, but also counts empty strings and zero numbers as missing.
+func (c *Conf) RequireNonEmpty(keys ...string) error {
+	return c.require(keys, true)
+}
+
+// require checks the keys against a single version of the store.
+func (c *Conf) require(keys []string, nonEmpty bool) error {
+	s := c.snap.Load()
+	var missing []string
+	for _, k := range keys {
+		v, ok := s.lookup(k, c.Separator)
+		if !ok || nonEmpty && isEmpty(v) {
+			missing = append(missing, k)
+			if c.failFast() {
+				break
+			}
+		}
+	}
+	if len(missing) > 0 {
+		err := &MissingKeysError{Keys: missing, Empty: nonEmpty}
+		keys := c.allKeys(s.store)
+		for _, k := range missing {
+			if near := c.suggestion(k, keys); near != "" {
+				if err.Suggestions == nil {
+					err.Suggestions = make(map[string]string)
+				}
+				err.Suggestions[k] = near
+			}
+		}
+		return err
+	}
+	return nil
+}
+
+// isEmpty reports whether the value is an empty string or a zero number.
+func isEmpty(v interface{}) bool {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.String, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return rv.IsZero()
+	}
+	return false
+}
@@ -0,0 +1,37 @@
+package cconf
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestRequireKeys(t *testing.T) {
+	c := New()
+	c.ValidationMode = CollectAll
+	if err := c.LoadReader("json", strings.NewReader(`{"db": {"dsn": "", "pool": 0, "null": null}, "http": {"port": 80}, "debug": false}`)); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, nil, c.RequireKeys("db.dsn", "db.pool", "http.port", "debug"))
+
+	err := c.RequireKeys("db.dsn", "db.null", "auth.jwt.secret", "http.port", "http.host")
+	var me *MissingKeysError
+	if !errors.As(err, &me) {
+		t.Fatalf("expected a MissingKeysError, got %v", err)
+	}
+	equal(t, []string{"db.null", "auth.jwt.secret", "http.host"}, me.Keys)
+	equal(t, `missing configuration keys: "db.null", "auth.jwt.secret", "http.host"`, err.Error())
+
+	err = c.RequireNonEmpty("db.dsn", "db.pool", "http.port", "debug", "auth.jwt.secret")
+	if !errors.As(err, &me) {
+		t.Fatalf("expected a MissingKeysError, got %v", err)
+	}
+	equal(t, []string{"db.dsn", "db.pool", "auth.jwt.secret"}, me.Keys)
+	equal(t, `missing or empty configuration keys: "db.dsn", "db.pool", "auth.jwt.secret"`, err.Error())
+
+	v, ok := c.Lookup("http.port")
+	equal(t, 80.0, v)
+	equal(t, true, ok)
+	_, ok = c.Lookup("db.null")
+	equal(t, false, ok)
+}
@@ -0,0 +1,119 @@
+package cconf
+
+import (
+	"sort"
+	"strings"
+)
+
+// SkippedKey describes a changed value that SaveBack did not write back.
+type SkippedKey struct {
+	Key    string // the key of the value, see Flatten
+	Source string // the source of the value, see Source
+}
+
+// SaveBack writes the values changed by Set back into the files they were loaded from, so that
+// tooling can adjust a value in its own file, like conf.d/20-db.json, instead of saving the
+// merged configuration into a single file. Every value of the store (see Flatten) that was
+// set by Set and differs from the value of the last loaded file holding the key is set in the
+// file as it was loaded, which is then written with the dump function of its extension, like
+// Save. Files without changed values are left untouched.
+//
+// Values changed by the environment, flags, arguments and SetOverride, and values set by
+// Set at keys that no file holds, are not written back: they are returned, sorted by key.
+func (c *Conf) SaveBack() ([]SkippedKey, error) {
+	c.mu.Lock()
+	writes, skipped, err := c.saveBack()
+	c.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	files := make([]string, 0, len(writes))
+	for file := range writes {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+	for _, file := range files {
+		data := writes[file]
+		if err := c.save(file, func() interface{} { return data }); err != nil {
+			return skipped, err
+		}
+	}
+	return skipped, nil
+}
+
+// saveBack returns the data to write back by file and the skipped values, see SaveBack.
+// The caller must hold c.mu.
+func (c *Conf) saveBack() (map[string]interface{}, []SkippedKey, error) {
+	type loaded struct {
+		src  *source
+		data interface{} // the data converted to the declared types, like the store
+	}
+	var files []loaded
+	for i := range c.sources {
+		if src := &c.sources[i]; src.file != "" {
+			files = append(files, loaded{src, c.applyTypesLenient(src.cached)})
+		}
+	}
+
+	leaves := Flatten(c.snap.Load().store, c.Separator)
+	keys := make([]string, 0, len(leaves))
+	for k := range leaves {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	writes := make(map[string]interface{})
+	var skipped []SkippedKey
+	for _, k := range keys {
+		srcs := c.sourcesOf(k)
+		if len(srcs) == 0 || !isModifier(srcs[len(srcs)-1]) {
+			continue
+		}
+		v, from := leaves[k], srcs[len(srcs)-1]
+		var file *source
+		var old interface{}
+		for i := len(files) - 1; i >= 0 && file == nil; i-- {
+			if fv, ok := walk(files[i].data, k, c.Separator); ok {
+				file, old = files[i].src, fv
+			}
+		}
+		switch {
+		case file != nil && sameValue(old, v):
+			continue
+		case file == nil || from != "Set()":
+			skipped = append(skipped, SkippedKey{Key: k, Source: from})
+			continue
+		}
+
+		data, ok := writes[file.file]
+		if !ok {
+			// write back the data as loaded, without references resolved.
+			raw, err := file.fetch()
+			if err == nil {
+				data, err = c.nest(file.key, raw)
+			}
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+		data, err := c.set(data, strings.Split(k, c.Separator), 0, v)
+		if err != nil {
+			return nil, nil, err
+		}
+		writes[file.file] = data
+	}
+	for _, f := range files {
+		if data, ok := writes[f.src.file]; ok && f.src.key != "" {
+			writes[f.src.file], _ = walk(data, f.src.key, c.Separator)
+		}
+	}
+	return writes, skipped, nil
+}
+
+// isModifier reports whether the source modifies loaded values, like Set and the environment.
+func isModifier(source string) bool {
+	switch source {
+	case "Set()", "SetOverride()", "flags", "args":
+		return true
+	}
+	return isEnvOrigin(source)
+}
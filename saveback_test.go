@@ -0,0 +1,106 @@
+package cconf
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSaveBack(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"10-app.json": `{"app": {"name": "a", "port": 80}}`,
+		"20-db.json":  `{"db": {"host": "h", "pool": 5}}`,
+		"30-log.json": `{"log": {"level": "info"}}`,
+	}
+	old := time.Now().Add(-time.Hour).Truncate(time.Second)
+	for name, data := range files {
+		file := filepath.Join(dir, name)
+		if err := os.WriteFile(file, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(file, old, old); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c := New()
+	if err := c.LoadWithPattern(filepath.Join(dir, "*.json")); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("SAVEBACK_LOG_LEVEL", "debug")
+	if err := c.LoadEnv("SAVEBACK_"); err != nil {
+		t.Fatal(err)
+	}
+	for k, v := range map[string]interface{}{"app.port": 8080, "db.host": "db.internal", "app.name": "a", "new.key": 1} {
+		if err := c.Set(k, v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := c.SetOverride("db.pool", 10); err != nil {
+		t.Fatal(err)
+	}
+
+	skipped, err := c.SaveBack()
+	if err != nil {
+		t.Fatal(err)
+	}
+	equal(t, []SkippedKey{
+		{Key: "db.pool", Source: "SetOverride()"},
+		{Key: "log.level", Source: "env:SAVEBACK_LOG_LEVEL"},
+		{Key: "new.key", Source: "Set()"},
+	}, skipped)
+
+	saved := New()
+	for name, want := range map[string]interface{}{
+		"10-app.json": map[string]interface{}{"app": map[string]interface{}{"name": "a", "port": 8080.0}},
+		"20-db.json":  map[string]interface{}{"db": map[string]interface{}{"host": "db.internal", "pool": 5.0}},
+		"30-log.json": map[string]interface{}{"log": map[string]interface{}{"level": "info"}},
+	} {
+		var data interface{}
+		if err := saved.LoadFuncs["json"](filepath.Join(dir, name), &data); err != nil {
+			t.Fatal(err)
+		}
+		equal(t, want, normalize(data))
+	}
+	fi, err := os.Stat(filepath.Join(dir, "30-log.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	equal(t, old, fi.ModTime())
+
+	// the written files are left untouched by saving back again.
+	if err := os.Chtimes(filepath.Join(dir, "10-app.json"), old, old); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.SaveBack(); err != nil {
+		t.Fatal(err)
+	}
+	fi, err = os.Stat(filepath.Join(dir, "10-app.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	equal(t, old, fi.ModTime())
+}
+
+func TestSaveBackLoadInto(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "db.json")
+	if err := os.WriteFile(file, []byte(`{"host": "h"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	c := New()
+	if err := c.LoadInto("db", file); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Set("db.host", "db.internal"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.SaveBack(); err != nil {
+		t.Fatal(err)
+	}
+	saved := New()
+	if err := saved.Load(file); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, map[string]interface{}{"host": "db.internal"}, saved.GetStore())
+}
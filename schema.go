@@ -0,0 +1,279 @@
+package cconf
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SchemaError describes the violations of a JSON Schema by the store, see ValidateSchema.
+type SchemaError struct {
+	Violations []*ConfigValueError // sorted by key
+}
+
+// Error returns the error message represented by SchemaError
+func (e *SchemaError) Error() string {
+	msgs := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		msgs[i] = v.Error()
+	}
+	return "the configuration does not match the schema: " + strings.Join(msgs, "; ")
+}
+
+// Unwrap returns the violations.
+func (e *SchemaError) Unwrap() []error {
+	errs := make([]error, len(e.Violations))
+	for i, v := range e.Violations {
+		errs[i] = v
+	}
+	return errs
+}
+
+// ValidateSchema validates the store against the JSON Schema, e.g. after loading, and returns
+// a *SchemaError listing the first violation, or every one in CollectAll mode (see
+// ValidationMode), or nil. The violations are keyed like Get, with the
+// Separator, rather than by JSON pointers.
+//
+// A subset of JSON Schema (draft-07 and 2020-12) is supported: type, enum, const, required,
+// properties, additionalProperties, items, minItems, maxItems, minLength, maxLength, pattern,
+// minimum, maximum, exclusiveMinimum and exclusiveMaximum; other keywords are ignored.
+func (c *Conf) ValidateSchema(schema []byte) error {
+	var s interface{}
+	if err := json.Unmarshal(schema, &s); err != nil {
+		return fmt.Errorf("invalid schema: %v", err)
+	}
+	v := &schemaValidator{sep: c.Separator, failFast: c.failFast()}
+	var store interface{} = c.GetStoreCopy(false)
+	if store == nil {
+		store = map[string]interface{}{}
+	}
+	if err := v.validate(s, store, ""); err != nil {
+		return err
+	}
+	if len(v.violations) == 0 {
+		return nil
+	}
+	sort.SliceStable(v.violations, func(i, j int) bool { return v.violations[i].Key < v.violations[j].Key })
+	return &SchemaError{Violations: v.violations}
+}
+
+// schemaValidator collects the violations of a schema.
+type schemaValidator struct {
+	sep        string
+	failFast   bool // whether to stop at the first violation
+	violations []*ConfigValueError
+}
+
+// violate records a violation at the key.
+func (v *schemaValidator) violate(key, format string, args ...interface{}) {
+	if v.failFast && len(v.violations) > 0 {
+		return
+	}
+	v.violations = append(v.violations, &ConfigValueError{Key: key, Message: fmt.Sprintf(format, args...)})
+}
+
+// join joins the key and the segment with the separator.
+func (v *schemaValidator) join(key, seg string) string {
+	if key == "" {
+		return seg
+	}
+	return key + v.sep + seg
+}
+
+// validate validates the value at the key against the schema. It returns an error only for
+// an invalid schema.
+func (v *schemaValidator) validate(schema, val interface{}, key string) error {
+	if v.failFast && len(v.violations) > 0 {
+		return nil
+	}
+	switch s := schema.(type) {
+	case bool:
+		if !s {
+			v.violate(key, "no value is allowed")
+		}
+		return nil
+	case map[string]interface{}:
+		return v.validateObject(s, val, key)
+	}
+	return fmt.Errorf("invalid schema at %q: a schema must be an object or a boolean", key)
+}
+
+// validateObject validates the value at the key against the schema object.
+func (v *schemaValidator) validateObject(s map[string]interface{}, val interface{}, key string) error {
+	typ := jsonType(val)
+	if t, ok := s["type"]; ok {
+		types, ok := t.([]interface{})
+		if !ok {
+			types = []interface{}{t}
+		}
+		match := false
+		names := make([]string, len(types))
+		for i, t := range types {
+			names[i] = fmt.Sprint(t)
+			match = match || t == typ || t == "number" && typ == "integer"
+		}
+		if !match {
+			v.violate(key, "expected %s, got %s", strings.Join(names, " or "), typ)
+			// the other keywords assume the type.
+			return nil
+		}
+	}
+	if e, ok := s["enum"]; ok {
+		values, ok := e.([]interface{})
+		if !ok {
+			return fmt.Errorf("invalid schema at %q: enum must be an array", key)
+		}
+		match := false
+		for _, e := range values {
+			match = match || sameValue(e, val)
+		}
+		if !match {
+			v.violate(key, "%s is not one of %s", jsonText(val), jsonText(values))
+		}
+	}
+	if e, ok := s["const"]; ok && !sameValue(e, val) {
+		v.violate(key, "%s is not %s", jsonText(val), jsonText(e))
+	}
+
+	switch typ {
+	case "object":
+		return v.validateProperties(s, val.(map[string]interface{}), key)
+	case "array":
+		return v.validateItems(s, val.([]interface{}), key)
+	case "string":
+		str := stringify(val)
+		n := float64(len([]rune(str)))
+		if min, ok := s["minLength"].(float64); ok && n < min {
+			v.violate(key, "%q is shorter than %v characters", str, min)
+		}
+		if max, ok := s["maxLength"].(float64); ok && n > max {
+			v.violate(key, "%q is longer than %v characters", str, max)
+		}
+		if p, ok := s["pattern"].(string); ok {
+			re, err := regexp.Compile(p)
+			if err != nil {
+				return fmt.Errorf("invalid schema at %q: %v", key, err)
+			}
+			if !re.MatchString(str) {
+				v.violate(key, "%q does not match the pattern %q", str, p)
+			}
+		}
+	case "number", "integer":
+		n, _ := toFloat(val)
+		if min, ok := s["minimum"].(float64); ok && n < min {
+			v.violate(key, "%v is less than the minimum %v", n, min)
+		}
+		if max, ok := s["maximum"].(float64); ok && n > max {
+			v.violate(key, "%v is greater than the maximum %v", n, max)
+		}
+		if min, ok := s["exclusiveMinimum"].(float64); ok && n <= min {
+			v.violate(key, "%v is not greater than %v", n, min)
+		}
+		if max, ok := s["exclusiveMaximum"].(float64); ok && n >= max {
+			v.violate(key, "%v is not less than %v", n, max)
+		}
+	}
+	return nil
+}
+
+// validateProperties validates the properties of the map at the key.
+func (v *schemaValidator) validateProperties(s map[string]interface{}, m map[string]interface{}, key string) error {
+	if r, ok := s["required"].([]interface{}); ok {
+		for _, name := range r {
+			if name, ok := name.(string); ok && m[name] == nil {
+				v.violate(v.join(key, name), "the required value is missing")
+			}
+		}
+	}
+	props, _ := s["properties"].(map[string]interface{})
+	names := make([]string, 0, len(m))
+	for k := range m {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	for _, k := range names {
+		if m[k] == nil {
+			continue
+		}
+		if ps, ok := props[k]; ok {
+			if err := v.validate(ps, m[k], v.join(key, k)); err != nil {
+				return err
+			}
+			continue
+		}
+		if ap, ok := s["additionalProperties"]; ok {
+			if ap == false {
+				v.violate(v.join(key, k), "the key is not allowed")
+				continue
+			}
+			if err := v.validate(ap, m[k], v.join(key, k)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// validateItems validates the elements of the list at the key.
+func (v *schemaValidator) validateItems(s map[string]interface{}, l []interface{}, key string) error {
+	n := float64(len(l))
+	if min, ok := s["minItems"].(float64); ok && n < min {
+		v.violate(key, "the list has less than %v elements", min)
+	}
+	if max, ok := s["maxItems"].(float64); ok && n > max {
+		v.violate(key, "the list has more than %v elements", max)
+	}
+	items, ok := s["items"]
+	if !ok {
+		return nil
+	}
+	for i, e := range l {
+		if err := v.validate(items, e, v.join(key, strconv.Itoa(i))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// jsonType returns the JSON Schema type of the value.
+func jsonType(val interface{}) string {
+	switch val.(type) {
+	case nil:
+		return "null"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case time.Time:
+		return "string"
+	}
+	rv := reflect.ValueOf(val)
+	switch rv.Kind() {
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return "integer"
+	case reflect.Float32, reflect.Float64:
+		if f := rv.Float(); f == math.Trunc(f) && !math.IsInf(f, 0) {
+			return "integer"
+		}
+		return "number"
+	}
+	return "string"
+}
+
+// jsonText formats the value as JSON for messages.
+func jsonText(val interface{}) string {
+	b, err := json.Marshal(val)
+	if err != nil {
+		return fmt.Sprint(val)
+	}
+	return string(b)
+}
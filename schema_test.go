@@ -0,0 +1,83 @@
+package cconf
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+const testSchema = `{
+	"$schema": "https://json-schema.org/draft/2020-12/schema",
+	"type": "object",
+	"required": ["name", "db"],
+	"properties": {
+		"name": {"type": "string", "minLength": 1, "pattern": "^[a-z]+$"},
+		"version": {"type": "number"},
+		"mode": {"enum": ["dev", "prod"]},
+		"db": {
+			"type": "object",
+			"required": ["host", "port"],
+			"additionalProperties": false,
+			"properties": {
+				"host": {"type": "string"},
+				"port": {"type": "integer", "minimum": 1, "maximum": 65535},
+				"replicas": {"type": "array", "maxItems": 2, "items": {"type": "string"}}
+			}
+		}
+	}
+}`
+
+func TestValidateSchema(t *testing.T) {
+	c := New()
+	if err := c.Load("./testdata/app.json"); err != nil {
+		t.Fatal(err)
+	}
+	c.Set("db", map[string]interface{}{"host": "h", "port": 5432, "replicas": []interface{}{"a"}})
+	c.Set("mode", "dev")
+	equal(t, nil, c.ValidateSchema([]byte(testSchema)))
+
+	c = New()
+	c.ValidationMode = CollectAll
+	c.SetStore(map[string]interface{}{
+		"name":    "cconf",
+		"version": "0.1",
+		"mode":    "test",
+		"db": map[string]interface{}{
+			"port":     70000,
+			"user":     "root",
+			"replicas": []interface{}{"a", 1, "c"},
+		},
+	})
+	err := c.ValidateSchema([]byte(testSchema))
+	var se *SchemaError
+	if !errors.As(err, &se) {
+		t.Fatalf("expected a SchemaError, got %v", err)
+	}
+	var got []string
+	for _, v := range se.Violations {
+		got = append(got, v.Key+": "+v.Message)
+	}
+	equal(t, []string{
+		"db.host: the required value is missing",
+		"db.port: 70000 is greater than the maximum 65535",
+		"db.replicas: the list has more than 2 elements",
+		"db.replicas.1: expected string, got integer",
+		"db.user: the key is not allowed",
+		`mode: "test" is not one of ["dev","prod"]`,
+		"version: expected number, got string",
+	}, got)
+	equal(t, true, strings.HasPrefix(err.Error(), `the configuration does not match the schema: "db.host" points to`))
+
+	c.SetStore(map[string]interface{}{"db": map[string]interface{}{"host": "h", "port": 1.5}, "name": "Cconf"})
+	err = c.ValidateSchema([]byte(testSchema))
+	if !errors.As(err, &se) {
+		t.Fatalf("expected a SchemaError, got %v", err)
+	}
+	equal(t, 2, len(se.Violations))
+	equal(t, "expected integer, got number", se.Violations[0].Message)
+	equal(t, `"Cconf" does not match the pattern "^[a-z]+$"`, se.Violations[1].Message)
+
+	if err := c.ValidateSchema([]byte(`{"type": "object"`)); err == nil || errors.As(err, &se) {
+		t.Fatalf("expected an invalid schema error, got %v", err)
+	}
+}
@@ -0,0 +1,214 @@
+package cconf
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// DefaultFileSuffix is the default suffix of keys pointing at secret files, see SetFileSuffix.
+var DefaultFileSuffix = "_file"
+
+// SetFileSuffix sets the suffix of keys whose values are paths of files holding the values
+// of other keys, like secrets delivered as files by Docker and Kubernetes: if the store has
+// no value for "db.password" but a path for "db.password_file", Get and Populate return the
+// contents of that file, without a trailing newline. The file is read on every lookup,
+// so that rotated secrets are picked up. A file that cannot be read is reported by an
+// EventLoad of the file with the key (see OnEvent): Get returns no value and Populate fails.
+// An empty suffix turns the indirection off.
+func (c *Conf) SetFileSuffix(suffix string) {
+	c.mu.Lock()
+	defer c.unlock()
+	c.fileSuffix = suffix
+	c.republish()
+}
+
+// secretFile returns the contents of the file at the path of the key with the file suffix,
+// and false if there is no such key. Errors reading the file are reported by an EventLoad.
+func (s *snapshot) secretFile(key, sep string) (string, bool, error) {
+	if s.fileSuffix == "" {
+		return "", false, nil
+	}
+	path, ok := walk(s.store, key+s.fileSuffix, sep)
+	if !ok {
+		return "", false, nil
+	}
+	v, err := readSecretFile(path)
+	if err != nil {
+		name, _ := path.(string)
+		s.fire([]Event{{Type: EventLoad, Source: name, Key: key, Err: err}})
+		return "", false, err
+	}
+	return v, true, nil
+}
+
+// readSecretFile returns the contents of the file at the path without a trailing newline.
+func readSecretFile(path interface{}) (string, error) {
+	p, ok := path.(string)
+	if !ok {
+		return "", fmt.Errorf("the secret file path must be a string, got %T", path)
+	}
+	b, err := os.ReadFile(p)
+	if err != nil {
+		return "", fmt.Errorf("cannot read the secret file: %v", err)
+	}
+	s := strings.TrimSuffix(string(b), "\n")
+	return strings.TrimSuffix(s, "\r"), nil
+}
+
+// Redacted replaces the values of secret keys in dumps.
+const Redacted = "[REDACTED]"
+
+// MarkSecret marks keys as secret, so that their values are replaced with Redacted in
+// redacted dumps such as GetStoreCopy(true), while Get and Populate return the real values.
+// A key may be a pattern: "*" matches any single key segment, and a leading "*" segment
+// matches keys at any depth, so that "*.password" matches "password" and "db.main.password".
+// Values below a secret key are redacted as a whole.
+func (c *Conf) MarkSecret(keys ...string) {
+	c.mu.Lock()
+	defer c.unlock()
+	secrets := make([]string, 0, len(c.secrets)+len(keys))
+	secrets = append(secrets, c.secrets...)
+	c.secrets = append(secrets, keys...)
+}
+
+// GetStoreCopy returns a deep copy of the store, which the caller may modify.
+// If redact is true, the values of the keys marked with MarkSecret are replaced with Redacted.
+func (c *Conf) GetStoreCopy(redact bool) interface{} {
+	c.mu.Lock()
+	store, secrets := c.snap.Load().store, c.secretKeys()
+	c.mu.Unlock()
+	if !redact || len(secrets) == 0 {
+		return normalize(store)
+	}
+	return c.redact(store, "", secrets)
+}
+
+// redact returns a copy of the node at the key with the values of secret keys redacted.
+func (c *Conf) redact(node interface{}, key string, secrets []string) interface{} {
+	if key != "" && c.isSecret(key, secrets) {
+		return Redacted
+	}
+	switch n := node.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(n))
+		for k, v := range n {
+			m[k] = c.redact(v, c.join(key, k), secrets)
+		}
+		return m
+	case []interface{}:
+		s := make([]interface{}, len(n))
+		for i, v := range n {
+			s[i] = c.redact(v, c.join(key, strconv.Itoa(i)), secrets)
+		}
+		return s
+	}
+	return normalize(node)
+}
+
+// isSecret reports whether the key matches any of the secret patterns.
+func (c *Conf) isSecret(key string, secrets []string) bool {
+	segs := strings.Split(key, c.Separator)
+	for _, pattern := range secrets {
+		psegs := strings.Split(pattern, c.Separator)
+		if psegs[0] == "*" && len(psegs) > 1 && len(segs) >= len(psegs)-1 {
+			// suffix pattern: match the trailing segments.
+			if matchSegments(psegs[1:], segs[len(segs)-len(psegs)+1:]) {
+				return true
+			}
+			continue
+		}
+		if len(psegs) == len(segs) && matchSegments(psegs, segs) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchSegments reports whether the key segments match the pattern segments one by one.
+func matchSegments(pattern, segs []string) bool {
+	for i, p := range pattern {
+		if p != "*" && p != segs[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// DefaultDockerSecretsDir is the directory of Docker secrets, see LoadDockerSecrets.
+var DefaultDockerSecretsDir = "/run/secrets"
+
+// DefaultDockerSecretsPrefix is the default key under which Docker secrets are loaded.
+var DefaultDockerSecretsPrefix = "secrets"
+
+// LoadDockerSecrets loads the files of the directory, /run/secrets by default, as Docker secrets:
+// every file sets the key named like the lowercased file name below DockerSecretsPrefix to its
+// contents without trailing whitespace, so that the file "db_password" sets "secrets.db_password"
+// and the file "api.token" sets "secrets.api.token".
+// The keys are marked as secret, see MarkSecret. A missing directory is not an error.
+func (c *Conf) LoadDockerSecrets(dir ...string) error {
+	d := DefaultDockerSecretsDir
+	if len(dir) > 0 {
+		d = dir[0]
+	}
+	c.mu.Lock()
+	defer c.unlock()
+	if _, err := os.Stat(d); os.IsNotExist(err) {
+		return nil
+	}
+	prefix := c.DockerSecretsPrefix
+	return c.loadSources(source{name: d, fetch: func() (interface{}, error) {
+		return c.readDockerSecrets(d, prefix)
+	}, secret: true})
+}
+
+// readDockerSecrets returns the Docker secrets of the directory as configuration data.
+// The caller must hold c.mu.
+func (c *Conf) readDockerSecrets(dir, prefix string) (interface{}, error) {
+	var data interface{} = make(map[string]interface{})
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return data, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		if e.IsDir() || strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		b, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		key := c.join(prefix, strings.ToLower(e.Name()))
+		if data, err = c.set(data, strings.Split(key, c.Separator), 0, strings.TrimRightFunc(string(b), unicode.IsSpace)); err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+// secretKeys returns the keys marked with MarkSecret and the keys of the tracked secret
+// sources, whose keys change with every load. The caller must hold c.mu.
+func (c *Conf) secretKeys() []string {
+	secrets := c.secrets
+	for _, src := range c.sources {
+		if !src.secret {
+			continue
+		}
+		leaves := make(map[string]interface{})
+		flatten(leaves, "", src.raw, c.Separator)
+		keys := make([]string, 0, len(leaves))
+		for k := range leaves {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		secrets = append(secrets[:len(secrets):len(secrets)], keys...)
+	}
+	return secrets
+}
@@ -0,0 +1,190 @@
+package cconf
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSecretFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "password")
+	if err := os.WriteFile(file, []byte("s3cret\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	c := New()
+	c.SetStore(map[string]interface{}{
+		"db": map[string]interface{}{
+			"User":          "app",
+			"Password_file": file,
+		},
+	})
+	equal(t, "s3cret", c.Get("db.Password"))
+	equal(t, "s3cret", c.GetString("db.Password"))
+	equal(t, "default", c.GetString("db.Missing", "default"))
+
+	var db struct {
+		User     string
+		Password string
+	}
+	if err := c.Populate(&db, "db"); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, "s3cret", db.Password)
+
+	var password string
+	if err := c.Populate(&password, "db.Password"); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, "s3cret", password)
+
+	var s struct{ Missing string }
+	m := New()
+	m.SetStore(map[string]interface{}{"Missing_file": "testdata/no-such-file"})
+	equal(t, nil, m.Get("Missing"))
+	err := m.Populate(&s)
+	if err == nil || !strings.Contains(err.Error(), `"Missing_file"`) || !strings.Contains(err.Error(), "cannot read the secret file") {
+		t.Fatalf("unexpected error %v", err)
+	}
+	var missing string
+	err = m.Populate(&missing, "Missing")
+	if err == nil || !strings.Contains(err.Error(), `"Missing_file"`) || !strings.Contains(err.Error(), "cannot read the secret file") {
+		t.Fatalf("unexpected error %v", err)
+	}
+	var events []Event
+	m.OnEvent(func(e Event) {
+		events = append(events, e)
+	})
+	equal(t, "", m.GetString("Missing"))
+	if len(events) != 1 || events[0].Type != EventLoad || events[0].Key != "Missing" ||
+		events[0].Source != "testdata/no-such-file" || events[0].Err == nil {
+		t.Fatalf("expected an EventLoad of the secret file, got %v", events)
+	}
+
+	// a value in the store takes precedence.
+	if err := c.Set("db.Password", "plain"); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, "plain", c.Get("db.Password"))
+
+	// the suffix is configurable.
+	c.SetFileSuffix("")
+	if err := c.Set("db.Password", nil); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, nil, c.Get("db.Password"))
+	c.SetFileSuffix("_path")
+	if err := c.Set("token_path", file); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, "s3cret", c.Get("token"))
+}
+
+func TestMarkSecret(t *testing.T) {
+	c := New()
+	c.SetStore(map[string]interface{}{
+		"password": "root",
+		"db": map[string]interface{}{
+			"host":     "localhost",
+			"password": "s3cret",
+			"replicas": []interface{}{map[string]interface{}{"host": "r1", "password": "r1pw"}},
+		},
+		"api":    map[string]interface{}{"keys": []interface{}{"k1", "k2"}, "url": "http://api"},
+		"tokens": map[string]interface{}{"github": "gh", "gitlab": "gl"},
+	})
+	c.MarkSecret("*.password", "api.keys")
+	c.MarkSecret("tokens.*")
+
+	equal(t, map[string]interface{}{
+		"password": Redacted,
+		"db": map[string]interface{}{
+			"host":     "localhost",
+			"password": Redacted,
+			"replicas": []interface{}{map[string]interface{}{"host": "r1", "password": Redacted}},
+		},
+		"api":    map[string]interface{}{"keys": Redacted, "url": "http://api"},
+		"tokens": map[string]interface{}{"github": Redacted, "gitlab": Redacted},
+	}, c.GetStoreCopy(true))
+
+	// the real values are still returned by Get, Populate and unredacted copies.
+	equal(t, "s3cret", c.Get("db.password"))
+	equal(t, "r1pw", c.Get("db.replicas.0.password"))
+	var keys []string
+	if err := c.Populate(&keys, "api.keys"); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, []string{"k1", "k2"}, keys)
+	store := c.GetStoreCopy(false).(map[string]interface{})
+	equal(t, "root", store["password"])
+
+	// the copy is independent of the store.
+	store["password"] = "changed"
+	equal(t, "root", c.Get("password"))
+}
+
+func TestLoadDockerSecrets(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"DB_PASSWORD":  "s3cret\n",
+		"api.token":    "tok \t\r\n",
+		".hidden":      "ignored",
+		"tls/cert.pem": "ignored",
+	} {
+		file := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	c := New()
+	if err := c.LoadDockerSecrets(dir); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, map[string]interface{}{
+		"secrets": map[string]interface{}{
+			"db_password": "s3cret",
+			"api":         map[string]interface{}{"token": "tok"},
+		},
+	}, c.GetStore())
+	equal(t, map[string]interface{}{
+		"secrets": map[string]interface{}{
+			"db_password": Redacted,
+			"api":         map[string]interface{}{"token": Redacted},
+		},
+	}, c.GetStoreCopy(true))
+
+	// reloads neither add the keys again nor mark those of a rejected reload.
+	for i := 0; i < 3; i++ {
+		if err := c.Reload(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	equal(t, []string{"secrets.api.token", "secrets.db_password"}, c.secretKeys())
+	if err := os.WriteFile(filepath.Join(dir, "new"), []byte("n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	c.SetReloadValidator(func(*Conf) error { return errors.New("rejected") })
+	if err := c.Reload(); err == nil {
+		t.Fatal("expected the reload to be rejected")
+	}
+	equal(t, []string{"secrets.api.token", "secrets.db_password"}, c.secretKeys())
+	if err := os.Remove(filepath.Join(dir, "new")); err != nil {
+		t.Fatal(err)
+	}
+
+	c = New()
+	c.DockerSecretsPrefix = ""
+	if err := c.LoadDockerSecrets(dir); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, "s3cret", c.Get("db_password"))
+
+	// a missing directory is not an error.
+	if err := New().LoadDockerSecrets(filepath.Join(dir, "none")); err != nil {
+		t.Fatal(err)
+	}
+}
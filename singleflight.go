@@ -0,0 +1,87 @@
+package cconf
+
+import (
+	"context"
+	"sync"
+)
+
+// flightGroup coalesces concurrent calls with the same key into a single execution
+// whose result is shared by all callers.
+type flightGroup struct {
+	mu    sync.Mutex
+	calls map[string]*flightCall
+}
+
+// flightCall is an in-flight or completed call of a flightGroup.
+type flightCall struct {
+	done    chan struct{} // closed when the execution completed
+	val     interface{}
+	err     error
+	waiters int                // the callers waiting for the result
+	cancel  context.CancelFunc // cancels the context of the execution
+}
+
+// do executes fn for the key, unless an execution for the key is already in flight,
+// in which case it waits for that execution and returns its result.
+func (g *flightGroup) do(key string, fn func() (interface{}, error)) (interface{}, error) {
+	return g.doContext(context.Background(), key, func(context.Context) (interface{}, error) {
+		return fn()
+	})
+}
+
+// doContext is like do, but stops waiting when ctx is done and returns its error. The context
+// passed to fn is canceled when all callers waiting for the execution stopped waiting, and
+// the next call for the key starts a new execution.
+func (g *flightGroup) doContext(ctx context.Context, key string, fn func(ctx context.Context) (interface{}, error)) (interface{}, error) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*flightCall)
+	}
+	call, ok := g.calls[key]
+	if !ok {
+		fctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		call = &flightCall{done: make(chan struct{}), cancel: cancel}
+		g.calls[key] = call
+		run := func() {
+			defer close(call.done)
+			defer cancel()
+			defer g.forget(key, call)
+			call.val, call.err = fn(fctx)
+		}
+		if ctx.Done() == nil {
+			// a caller that cannot stop waiting executes fn itself, so that panics reach it.
+			call.waiters++
+			g.mu.Unlock()
+			run()
+			return call.val, call.err
+		}
+		go run()
+	}
+	call.waiters++
+	g.mu.Unlock()
+
+	select {
+	case <-call.done:
+		return call.val, call.err
+	case <-ctx.Done():
+		g.mu.Lock()
+		if call.waiters--; call.waiters == 0 {
+			// nobody waits anymore: the next call starts afresh.
+			call.cancel()
+			if g.calls[key] == call {
+				delete(g.calls, key)
+			}
+		}
+		g.mu.Unlock()
+		return nil, ctx.Err()
+	}
+}
+
+// forget removes the call of the key, unless it was replaced.
+func (g *flightGroup) forget(key string, call *flightCall) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.calls[key] == call {
+		delete(g.calls, key)
+	}
+}
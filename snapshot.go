@@ -0,0 +1,196 @@
+package cconf
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// snapshot is a published configuration store together with its read cache.
+// A published store is never modified: writers build a new snapshot and swap it in,
+// so that readers need no locking.
+type snapshot struct {
+	store   interface{}
+	cache   sync.Map
+	entries atomic.Int64 // number of cache entries
+	size    int          // maximum number of cache entries, 0 for unlimited
+	nocache bool         // see DisableCache
+	stats   *cacheStats
+	index   map[string]interface{} // flattened store, see EnableIndex
+	env     *envConfig             // environment fallback, may be nil
+	// suffix of keys pointing at secret files, see SetFileSuffix
+	fileSuffix string
+	envKeys    map[string]bool // the keys loaded from the environment, see fromEnv
+	lazy       []*lazyProvider // see RegisterLazy
+	fire       func([]Event)   // fires the events of lookups, see OnEvent
+	parent     *Conf           // see NewChild
+}
+
+// cacheMiss is cached for keys that have no configuration value.
+type cacheMiss struct{}
+
+// get returns the configuration value at the specified path converted to the type of v,
+// or v if there is none.
+func (s *snapshot) get(key, sep string, v interface{}) interface{} {
+	val, ok := s.lookupAs(key, sep, reflect.TypeOf(v))
+	if !ok {
+		return v
+	}
+	return convert(val, v)
+}
+
+// lookup returns the configuration value at the specified path, see lookupLayers.
+// Both values and misses of the store are cached.
+// The keys of a lazy provider are fetched instead, see RegisterLazy.
+func (s *snapshot) lookup(key, sep string) (interface{}, bool) {
+	if v, ok, lazy := s.lookupLazy(key, sep); lazy {
+		return v, ok
+	}
+	v, _, ok, _ := s.lookupLayers(key, sep)
+	return s.withParent(key, sep, nil, v, ok)
+}
+
+// lookupAs is like lookup, but parses strings from the environment into the type t,
+// see parseEnv. Values that cannot be parsed are returned as is.
+func (s *snapshot) lookupAs(key, sep string, t reflect.Type) (interface{}, bool) {
+	if v, ok, lazy := s.lookupLazy(key, sep); lazy {
+		return v, ok
+	}
+	v, env, ok, _ := s.lookupLayers(key, sep)
+	if env && t != nil {
+		if pv, err := parseEnv(v.(string), t); err == nil {
+			return pv, true
+		}
+	}
+	return s.withParent(key, sep, t, v, ok)
+}
+
+// withParent returns the value at the key, given the value of the snapshot, looked up through
+// the parent of a child Conf (see NewChild): a value of the child wins, a missing value is
+// looked up in the live parent, and a map of the child is merged over a map of the parent.
+// Values of the parent are not cached. The key is empty for the whole store.
+func (s *snapshot) withParent(key, sep string, t reflect.Type, v interface{}, ok bool) (interface{}, bool) {
+	if s.parent == nil {
+		return v, ok
+	}
+	if _, isMap := v.(map[string]interface{}); ok && !isMap {
+		return v, true
+	}
+	ps := s.parent.snap.Load()
+	var pv interface{}
+	var pok bool
+	if key == "" {
+		pv = ps.tree()
+		pok = pv != nil
+	} else {
+		pv, pok = ps.lookupAs(key, sep, t)
+	}
+	switch {
+	case !pok:
+		return v, ok
+	case !ok:
+		return pv, true
+	}
+	if _, isMap := pv.(map[string]interface{}); isMap {
+		return merge(pv, v), true
+	}
+	return v, true
+}
+
+// tree returns the store merged over the stores of the parents, see NewChild.
+func (s *snapshot) tree() interface{} {
+	v, _ := s.withParent("", "", nil, s.store, s.store != nil)
+	return v
+}
+
+// fromEnv reports whether the value of the store at the key was loaded from the environment
+// by LoadEnv, that is whether the last source that wrote it is a variable, see Sources.
+func (s *snapshot) fromEnv(key string) bool {
+	return s.envKeys[key]
+}
+
+// lookupStore returns the value of the store at the specified path.
+// Both values and misses are cached.
+func (s *snapshot) lookupStore(key, sep string) (interface{}, bool) {
+	if v, ok := s.index[key]; ok {
+		s.stats.hits.Add(1)
+		return v, true
+	}
+	// take priority from the cache.
+	if cv, ok := s.cache.Load(key); ok {
+		s.stats.hits.Add(1)
+		if _, miss := cv.(cacheMiss); miss {
+			return nil, false
+		}
+		return cv, true
+	}
+	s.stats.misses.Add(1)
+	cv, ok := walk(s.store, key, sep)
+	if !ok {
+		// only the miss of the store is cached, the fallbacks may change.
+		s.cacheStore(key, cacheMiss{})
+		return nil, false
+	}
+	s.cacheStore(key, cv)
+	return cv, true
+}
+
+// lookupLayers returns the value at the key and whether it is from the environment: the value
+// of the store, or else the contents of the secret file of the key (see SetFileSuffix), or else
+// the value of its bound or automatic environment variable (see BindEnv and AutomaticEnv).
+// The error is that of a secret file that cannot be read, in which case there is no value.
+func (s *snapshot) lookupLayers(key, sep string) (v interface{}, env bool, ok bool, err error) {
+	if v, ok := s.lookupStore(key, sep); ok {
+		_, str := v.(string)
+		return v, str && s.fromEnv(key), true, nil
+	}
+	if v, ok, err := s.secretFile(key, sep); ok {
+		return v, false, true, nil
+	} else if err != nil {
+		return nil, false, false, err
+	}
+	v, ok = s.env.lookup(key, sep)
+	return v, ok, ok, nil
+}
+
+// walk returns the value at the specified path below the node.
+func walk(node interface{}, key, sep string) (interface{}, bool) {
+	for rest := key; ; {
+		seg := rest
+		i := indexSep(rest, sep)
+		if i >= 0 {
+			seg, rest = rest[:i], rest[i+len(sep):]
+		}
+		var ok bool
+		if node, ok = getElement(node, seg); !ok {
+			return nil, false
+		}
+		if i < 0 {
+			return node, true
+		}
+	}
+}
+
+// indexSep returns the index of the first separator in key, or -1 if there is none.
+func indexSep(key, sep string) int {
+	if len(sep) == 1 {
+		return strings.IndexByte(key, sep[0])
+	}
+	return strings.Index(key, sep)
+}
+
+// inherit copies the cache entries of the old snapshot that are not affected by
+// a change of the value at key.
+func (s *snapshot) inherit(old *snapshot, key, sep string) {
+	if s.nocache {
+		return
+	}
+	old.cache.Range(func(k, v interface{}) bool {
+		ck := k.(string)
+		if ck != key && !strings.HasPrefix(ck, key+sep) && !strings.HasPrefix(key, ck+sep) {
+			s.cacheStore(ck, v)
+		}
+		return true
+	})
+}
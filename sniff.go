@@ -0,0 +1,105 @@
+package cconf
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// sniffLen is the number of bytes sniffTypes looks at.
+const sniffLen = 4096
+
+// sniffLoader returns a load function for files of an unknown type, see SniffUnknown. It
+// detects the candidate types of the file when it is loaded and tries the load functions
+// of the registered ones in order, so that a reload detects the type again.
+// The caller must hold c.mu.
+func (c *Conf) sniffLoader() loadFunc {
+	funcs := make(map[string]loadFunc, len(c.LoadFuncs))
+	for typ, fn := range c.LoadFuncs {
+		funcs[typ] = fn
+	}
+	return func(file string, data interface{}) error {
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		b, err := io.ReadAll(io.LimitReader(f, sniffLen))
+		f.Close()
+		if err != nil {
+			return err
+		}
+		var errs []error
+		for _, typ := range sniffTypes(b) {
+			fn, ok := funcs[typ]
+			if !ok {
+				continue
+			}
+			if err := fn(file, data); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", typ, err))
+				continue
+			}
+			return nil
+		}
+		if len(errs) == 0 {
+			return &wrapError{"cannot detect the type of " + file, ErrUnknownType}
+		}
+		return fmt.Errorf("cannot detect the type of %s: %w", file, errors.Join(errs...))
+	}
+}
+
+// sniffTypes returns the types the document may have in the order to try them, judging by
+// its first significant line.
+func sniffTypes(b []byte) []string {
+	if strings.HasPrefix(string(b), "\xd9\xd9\xf7") {
+		// the self-described CBOR tag.
+		return []string{"cbor"}
+	}
+	s := strings.TrimPrefix(string(b), "\ufeff")
+	for {
+		s = strings.TrimLeft(s, " \t\r\n")
+		if !strings.HasPrefix(s, "#") && !strings.HasPrefix(s, ";") {
+			break
+		}
+		// a comment of YAML, TOML, INI, env-files or HCL.
+		i := strings.IndexByte(s, '\n')
+		if i < 0 {
+			return nil
+		}
+		s = s[i+1:]
+	}
+	line, _, _ := strings.Cut(s, "\n")
+	line = strings.TrimSpace(line)
+	switch {
+	case line == "":
+		return nil
+	case line[0] == '{':
+		return []string{"json", "jsonc"}
+	case line[0] == '[':
+		return []string{"json", "jsonc", "toml", "ini"}
+	case line[0] == '<':
+		return []string{"xml"}
+	case strings.HasPrefix(line, "//"), strings.HasPrefix(line, "/*"):
+		return []string{"jsonc", "hcl"}
+	case strings.HasPrefix(line, "---"), strings.HasPrefix(line, "- "):
+		return []string{"yaml"}
+	case strings.HasPrefix(line, "export "):
+		return []string{"env"}
+	}
+	name := line[:strings.IndexFunc(line+" ", func(r rune) bool {
+		return r > 0x7f || !isNameByte(byte(r), false) && r != '.' && r != '-'
+	})]
+	rest := strings.TrimLeft(line[len(name):], " \t")
+	switch {
+	case name == "":
+		return nil
+	case strings.HasPrefix(rest, ":") && !strings.HasPrefix(rest, "://"):
+		return []string{"yaml"}
+	case strings.HasPrefix(rest, "="):
+		return []string{"toml", "ini", "env", "hcl"}
+	case strings.HasPrefix(rest, "{"), strings.HasPrefix(rest, `"`):
+		return []string{"hcl"}
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package cconf
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSniffUnknown(t *testing.T) {
+	c := New()
+	err := c.Load("./testdata/sniff/app.conf")
+	if !errors.Is(err, ErrUnknownType) {
+		t.Fatalf("expected ErrUnknownType, got %v", err)
+	}
+
+	c.SniffUnknown = true
+	if err := c.Load("./testdata/app.json", "./testdata/sniff/app.conf", "./testdata/sniff/server"); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, "cconf-conf", c.GetString("name"))
+	equal(t, "syyong.x", c.GetString("ext.author"))
+	equal(t, "conf@example.com", c.GetString("ext.email"))
+	equal(t, "localhost", c.GetString("server.host"))
+	if err := c.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, "cconf-conf", c.GetString("name"))
+
+	err = c.Load("./testdata/sniff/broken.conf")
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	for _, s := range []string{"cannot detect the type of ./testdata/sniff/broken.conf: json: ", "\njsonc: line 1"} {
+		if !strings.Contains(err.Error(), s) {
+			t.Errorf("expected %q in %q", s, err)
+		}
+	}
+	equal(t, "cconf-conf", c.GetString("name"))
+
+	delete(c.LoadFuncs, "json")
+	delete(c.LoadFuncs, "jsonc")
+	if err := c.Load("./testdata/sniff/app.conf"); !errors.Is(err, ErrUnknownType) {
+		t.Errorf("expected ErrUnknownType, got %v", err)
+	}
+}
+
+func TestSniffTypes(t *testing.T) {
+	for doc, want := range map[string][]string{
+		"":                          nil,
+		" \n":                       nil,
+		"\ufeff{}":                  {"json", "jsonc"},
+		"[1, 2]":                    {"json", "jsonc", "toml", "ini"},
+		"# comment\n; comment\n[a]": {"json", "jsonc", "toml", "ini"},
+		"<?xml version=\"1.0\"?>":   {"xml"},
+		"// comment\n{}":            {"jsonc", "hcl"},
+		"---\na: 1":                 {"yaml"},
+		"- a\n- b":                  {"yaml"},
+		"server.host: localhost":    {"yaml"},
+		"url = http://example.com":  {"toml", "ini", "env", "hcl"},
+		"DB_HOST=localhost":         {"toml", "ini", "env", "hcl"},
+		"export DB_HOST=localhost":  {"env"},
+		`service "web" {`:           {"hcl"},
+		"http://example.com":        nil,
+		"\xd9\xd9\xf7\xa0":          {"cbor"},
+		"just some text":            nil,
+	} {
+		if got := sniffTypes([]byte(doc)); !reflect.DeepEqual(want, got) {
+			t.Errorf("%q: expected %v, got %v", doc, want, got)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package cconf
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// Stats describes the activity of a Conf.
+type Stats struct {
+	CacheStats
+	Loads []LoadStat // the last load of every source, in the order they were first loaded
+	// Digests are the hex-encoded SHA-256 digests of the contents of the files watched by Watch,
+	// as last seen, by file name.
+	Digests map[string]string
+}
+
+// LoadStat describes the last load of a source.
+type LoadStat struct {
+	Source   string        // the file name of the source
+	Size     int64         // the size of the source in bytes, or -1 if unknown
+	Duration time.Duration // the time taken to read and decode the source
+	Time     time.Time     // the time the load started
+}
+
+// EventType is the type of an Event.
+type EventType int
+
+// Event types.
+const (
+	EventLoad       EventType = iota // a source was loaded
+	EventSet                         // a value was set
+	EventCacheReset                  // the Get cache was reset
+	EventReload                      // the tracked sources were reloaded
+	EventPanic                       // an OnChange, OnReload or OnEvent function panicked
+	EventPopulate                    // a struct bound by BindStruct failed to populate
+)
+
+// String returns the name of the event type.
+func (t EventType) String() string {
+	switch t {
+	case EventLoad:
+		return "load"
+	case EventSet:
+		return "set"
+	case EventCacheReset:
+		return "cache reset"
+	case EventReload:
+		return "reload"
+	case EventPanic:
+		return "panic"
+	case EventPopulate:
+		return "populate"
+	}
+	return "unknown"
+}
+
+// Event describes a change of a Conf, see OnEvent.
+type Event struct {
+	Type     EventType
+	Source   string        // the loaded source of an EventLoad
+	Key      string        // the key of an EventSet, EventPanic, EventPopulate or of a secret file
+	Duration time.Duration // the duration of an EventLoad or EventReload
+	Err      error         // the error of a failed operation
+}
+
+// Stats returns the cache statistics and the load timings of the Conf.
+func (c *Conf) Stats() Stats {
+	stats := Stats{CacheStats: c.CacheStats()}
+	c.mu.Lock()
+	stats.Loads = append(stats.Loads, c.loads...)
+	if len(c.digests) > 0 {
+		stats.Digests = make(map[string]string, len(c.digests))
+		for file, digest := range c.digests {
+			stats.Digests[file] = digest
+		}
+	}
+	c.mu.Unlock()
+	return stats
+}
+
+// OnEvent registers a function that is called for every event of the Conf.
+// The functions are called in registration order, after the change has been applied
+// and outside the locks of the Conf, so they may call any method of the Conf.
+// A panic of a function is recovered and reported by an EventPanic, which is not reported
+// again if a function panics on it.
+func (c *Conf) OnEvent(fn func(Event)) {
+	c.mu.Lock()
+	defer c.unlock()
+	var handlers []func(Event)
+	if p := c.handlers.Load(); p != nil {
+		handlers = append(handlers, *p...)
+	}
+	handlers = append(handlers, fn)
+	c.handlers.Store(&handlers)
+}
+
+// recordLoad records the load of a source that started at start.
+// The caller must hold c.mu.
+func (c *Conf) recordLoad(source string, start time.Time, err error) {
+	stat := LoadStat{Source: source, Size: -1, Duration: time.Since(start), Time: start}
+	if fi, err := os.Stat(source); err == nil {
+		stat.Size = fi.Size()
+	}
+	c.queue(Event{Type: EventLoad, Source: source, Duration: stat.Duration, Err: err})
+	if err != nil {
+		return
+	}
+	for i := range c.loads {
+		if c.loads[i].Source == source {
+			c.loads[i] = stat
+			return
+		}
+	}
+	c.loads = append(c.loads, stat)
+}
+
+// queue queues an event to be fired once c.mu is released.
+// The caller must hold c.mu.
+func (c *Conf) queue(e Event) {
+	if c.handlers.Load() != nil {
+		c.pending = append(c.pending, e)
+	}
+}
+
+// unlock releases c.mu, fires the events queued while it was held
+// and the OnDeprecated functions, and calls the OnReload and OnChange functions if the store
+// was changed.
+func (c *Conf) unlock() {
+	events := c.pending
+	old, changed, reloaded := c.prev, c.changed, c.reloaded
+	c.pending, c.prev, c.changed, c.reloaded = nil, nil, false, false
+	deprecations, deprecatedHandlers := c.deprecations, c.deprecatedHandlers
+	c.deprecations = nil
+	var store interface{}
+	if changed {
+		store = c.snap.Load().store
+	}
+	c.mu.Unlock()
+
+	c.fire(events)
+	c.notifyDeprecated(deprecations, deprecatedHandlers)
+	if !changed {
+		return
+	}
+	if reloaded && c.reloadHandlers.Load() != nil {
+		c.notifyReload(old, store)
+	}
+	if c.changeHandlers.Load() != nil {
+		c.notifyChanges(old, store)
+	}
+}
+
+// fire calls the OnEvent functions for the events. A panic of a function is reported by an
+// EventPanic, unless it handles an EventPanic itself, so that a panic is not reported forever.
+func (c *Conf) fire(events []Event) {
+	if len(events) == 0 {
+		return
+	}
+	p := c.handlers.Load()
+	if p == nil {
+		return
+	}
+	for _, e := range events {
+		for _, fn := range *p {
+			e, fn := e, fn
+			if e.Type == EventPanic {
+				func() {
+					defer func() { recover() }()
+					fn(e)
+				}()
+				continue
+			}
+			c.protect(e.Key, fmt.Sprintf("OnEvent function of a %v event", e.Type), func() { fn(e) })
+		}
+	}
+}
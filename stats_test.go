@@ -0,0 +1,72 @@
+package cconf
+
+import (
+	"testing"
+)
+
+func TestStatsAndEvents(t *testing.T) {
+	c := New()
+	var events []Event
+	c.OnEvent(func(e Event) {
+		// handlers may use the Conf.
+		c.Get("name")
+		events = append(events, e)
+	})
+
+	if err := c.Load("./testdata/app.json"); err != nil {
+		t.Fatal(err)
+	}
+	stats := c.Stats()
+	equal(t, 1, len(stats.Loads))
+	equal(t, "./testdata/app.json", stats.Loads[0].Source)
+	if stats.Loads[0].Size <= 0 {
+		t.Errorf("Expected the size of the loaded file - Got %v", stats.Loads[0].Size)
+	}
+
+	c.Get("name")
+	c.Get("name")
+	c.Get("missing")
+	stats = c.Stats()
+	// the handler also read "name" for both events of the load.
+	equal(t, uint64(2), stats.Misses)
+	equal(t, uint64(3), stats.Hits)
+	equal(t, 2, stats.Entries)
+
+	if err := c.Set("ext.email", "new@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Load("./testdata/broken.json"); err == nil {
+		t.Fatal("Expected an error for the malformed file")
+	}
+	equal(t, 1, len(c.Stats().Loads))
+
+	var types []EventType
+	for _, e := range events {
+		types = append(types, e.Type)
+	}
+	equal(t, []EventType{EventLoad, EventCacheReset, EventSet, EventLoad}, types)
+	equal(t, "ext.email", events[2].Key)
+	if events[3].Err == nil {
+		t.Error("Expected the error of the failed load")
+	}
+}
+
+func TestOnEventPanic(t *testing.T) {
+	c := New()
+	c.OnEvent(func(e Event) {
+		panic("boom")
+	})
+	var events []Event
+	c.OnEvent(func(e Event) {
+		events = append(events, e)
+	})
+	if err := c.Set("name", "app"); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, "app", c.GetString("name"))
+	equal(t, 2, len(events))
+	equal(t, EventPanic, events[0].Type)
+	equal(t, "name", events[0].Key)
+	equal(t, "OnEvent function of a set event panicked: boom", events[0].Err.Error())
+	equal(t, EventSet, events[1].Type)
+}
@@ -0,0 +1,129 @@
+package cconf
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// ChangeEvent describes the change of the value of a key, see Subscribe.
+type ChangeEvent struct {
+	Key      string
+	Old, New interface{} // nil for an added or a removed value
+	Source   string      // the source of the new value, see Source
+}
+
+// Subscribe returns a channel receiving an event for every changed value below the prefix,
+// by flattened key like "db.hosts.0", and a function that unsubscribes and closes the channel.
+// An empty prefix subscribes to all keys.
+//
+// Delivery never blocks the changes of the Conf: the events not yet received are buffered,
+// one per key, and a further change of a buffered key is coalesced into its event, which then
+// describes the change from the oldest to the latest value; an event whose value changed back
+// is dropped. So a slow consumer misses intermediate values, but always gets the latest ones.
+//
+// Closing the Conf closes the channel as well; the channel of a closed Conf is closed.
+func (c *Conf) Subscribe(prefix string) (<-chan ChangeEvent, func()) {
+	s := &subscription{
+		c:       c,
+		prefix:  strings.TrimSuffix(prefix, c.Separator),
+		pending: make(map[string]*ChangeEvent),
+		ready:   make(chan struct{}, 1),
+		out:     make(chan ChangeEvent),
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	remove := c.onChange(prefix, s.changed)
+	c.mu.Lock()
+	err := c.start(ctx, s.deliver)
+	c.mu.Unlock()
+	if err != nil {
+		cancel()
+		remove()
+		close(s.out)
+	}
+	return s.out, func() {
+		remove()
+		cancel()
+	}
+}
+
+// subscription buffers the events of a Subscribe channel.
+type subscription struct {
+	c       *Conf
+	prefix  string
+	mu      sync.Mutex
+	pending map[string]*ChangeEvent // the events not yet delivered by key
+	keys    []string                // the keys of pending in order
+	ready   chan struct{}           // signals pending events
+	out     chan ChangeEvent
+}
+
+// changed buffers the events of the changed values of the subtree.
+func (s *subscription) changed(old, new interface{}) {
+	d := diff(old, new, s.c.Separator)
+	var events []ChangeEvent
+	for _, l := range [][]KeyChange{d.Added, d.Removed, d.Changed} {
+		for _, kc := range l {
+			key := s.c.join(s.prefix, kc.Key)
+			events = append(events, ChangeEvent{Key: key, Old: kc.Old, New: kc.New, Source: s.c.Source(key)})
+		}
+	}
+	s.mu.Lock()
+	for _, e := range events {
+		s.add(e)
+	}
+	s.mu.Unlock()
+	select {
+	case s.ready <- struct{}{}:
+	default:
+	}
+}
+
+// add buffers the event, coalescing it with a pending one of its key.
+// The caller must hold s.mu.
+func (s *subscription) add(e ChangeEvent) {
+	if p, ok := s.pending[e.Key]; ok {
+		p.New, p.Source = e.New, e.Source
+		return
+	}
+	s.pending[e.Key] = &e
+	s.keys = append(s.keys, e.Key)
+}
+
+// deliver sends the pending events to the channel in order until ctx is done.
+func (s *subscription) deliver(ctx context.Context) {
+	defer close(s.out)
+	for {
+		e, ok := s.next()
+		if !ok {
+			select {
+			case <-s.ready:
+				continue
+			case <-ctx.Done():
+				return
+			}
+		}
+		select {
+		case s.out <- e:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// next removes the next pending event whose value changed and returns it.
+func (s *subscription) next() (ChangeEvent, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for len(s.keys) > 0 {
+		key := s.keys[0]
+		s.keys = s.keys[1:]
+		e := s.pending[key]
+		delete(s.pending, key)
+		if !reflect.DeepEqual(e.Old, e.New) {
+			return *e, true
+		}
+	}
+	return ChangeEvent{}, false
+}
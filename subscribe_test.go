@@ -0,0 +1,92 @@
+package cconf
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSubscribe(t *testing.T) {
+	c := New()
+	if err := c.Set("db.host", "localhost"); err != nil {
+		t.Fatal(err)
+	}
+	events, cancel := c.Subscribe("db.")
+	receive := func() ChangeEvent {
+		select {
+		case e := <-events:
+			return e
+		case <-time.After(5 * time.Second):
+			t.Fatal("no event")
+		}
+		return ChangeEvent{}
+	}
+
+	if err := c.Set("name", "app"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Set("db.port", 5432); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, ChangeEvent{Key: "db.port", New: 5432, Source: "Set()"}, receive())
+	c.SetStore(map[string]interface{}{"db": map[string]interface{}{"host": "db"}})
+	equal(t, ChangeEvent{Key: "db.port", Old: 5432}, receive())
+	equal(t, ChangeEvent{Key: "db.host", Old: "localhost", New: "db", Source: "SetStore()"}, receive())
+
+	// a slow consumer gets coalesced events, ending with the latest value.
+	for i := 0; i < 100; i++ {
+		if err := c.Set("db.port", i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	time.Sleep(20 * time.Millisecond)
+	var received []ChangeEvent
+	var old interface{}
+	for {
+		e := receive()
+		equal(t, "db.port", e.Key)
+		equal(t, old, e.Old)
+		old = e.New
+		received = append(received, e)
+		if e.New == 99 {
+			break
+		}
+	}
+	if len(received) >= 100 {
+		t.Fatalf("expected coalesced events, got %d", len(received))
+	}
+
+	// values changed back are not reported.
+	if err := c.Set("db.port", 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Set("db.port", 99); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Set("db.user", "root"); err != nil {
+		t.Fatal(err)
+	}
+	for e := receive(); e.Key != "db.user"; e = receive() {
+		equal(t, "db.port", e.Key)
+	}
+
+	cancel()
+	cancel()
+	if err := c.Set("db.port", 1); err != nil {
+		t.Fatal(err)
+	}
+	for range events {
+	}
+}
+
+func TestSubscriptionCoalesce(t *testing.T) {
+	s := &subscription{pending: make(map[string]*ChangeEvent)}
+	s.add(ChangeEvent{Key: "a", Old: 1, New: 2})
+	s.add(ChangeEvent{Key: "b", New: 1})
+	s.add(ChangeEvent{Key: "a", Old: 2, New: 3})
+	s.add(ChangeEvent{Key: "b", Old: 1})
+	e, ok := s.next()
+	equal(t, true, ok)
+	equal(t, ChangeEvent{Key: "a", Old: 1, New: 3}, e)
+	_, ok = s.next()
+	equal(t, false, ok)
+}
@@ -0,0 +1,90 @@
+package cconf
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// nearest returns the candidate closest to the key by edit distance, the first of the closest
+// in the order of the candidates, and its distance, or "" and -1 without candidates.
+func nearest(key string, candidates []string) (string, int) {
+	best, dist := "", -1
+	for _, cand := range candidates {
+		if d := editDistance(key, cand); dist < 0 || d < dist {
+			best, dist = cand, d
+		}
+	}
+	return best, dist
+}
+
+// editDistance returns the number of character insertions, deletions, substitutions and
+// transpositions of adjacent characters turning a into b (optimal string alignment distance).
+func editDistance(a, b string) int {
+	s, t := []rune(a), []rune(b)
+	// rows i-2, i-1 and i of the distance matrix.
+	prev2 := make([]int, len(t)+1)
+	prev := make([]int, len(t)+1)
+	cur := make([]int, len(t)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(s); i++ {
+		cur[0] = i
+		for j := 1; j <= len(t); j++ {
+			cost := 1
+			if s[i-1] == t[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && s[i-1] == t[j-2] && s[i-2] == t[j-1] {
+				cur[j] = min(cur[j], prev2[j-2]+1)
+			}
+		}
+		prev2, prev, cur = prev, cur, prev2
+	}
+	return prev[len(t)]
+}
+
+// suggestion returns the candidate nearest to the key if it is close enough to be what a typo
+// was meant to be, that is at most two edits and a third of the last key segment away, so
+// that "http.prot" suggests "http.port" but "http.host" does not, or "" otherwise.
+func (c *Conf) suggestion(key string, candidates []string) string {
+	near, d := nearest(key, candidates)
+	last := key[strings.LastIndex(key, c.Separator)+1:]
+	if d <= 0 || d > 2 || d*3 > len([]rune(last)) {
+		return ""
+	}
+	return near
+}
+
+// suggest returns `, did you mean "candidate"?` to append to an error message about the key,
+// or "" without suggestion.
+func (c *Conf) suggest(key string, candidates []string) string {
+	if near := c.suggestion(key, candidates); near != "" {
+		return fmt.Sprintf(", did you mean %q?", near)
+	}
+	return ""
+}
+
+// allKeys returns the keys of all the values of the store, maps and lists included, sorted.
+func (c *Conf) allKeys(store interface{}) []string {
+	var keys []string
+	c.visit(store, "", func(key string, _ interface{}) {
+		keys = append(keys, key)
+	})
+	sort.Strings(keys)
+	return keys
+}
+
+// fieldKeys returns the keys of the exported fields of the struct type, see Populate.
+func fieldKeys(t reflect.Type) []string {
+	var keys []string
+	for _, f := range structFields(t) {
+		if !f.Anonymous && f.PkgPath == "" {
+			keys = append(keys, f.tag.name)
+		}
+	}
+	return keys
+}
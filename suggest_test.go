@@ -0,0 +1,75 @@
+package cconf
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"port", "port", 0},
+		{"port", "", 4},
+		{"http.prot", "http.port", 1},
+		{"http.prt", "http.port", 1},
+		{"kitten", "sitting", 3},
+		{"héllo", "hello", 1},
+	}
+	for _, test := range tests {
+		if d := editDistance(test.a, test.b); d != test.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", test.a, test.b, d, test.want)
+		}
+	}
+	near, d := nearest("http.prot", []string{"db.host", "http.port", "http.host"})
+	equal(t, "http.port", near)
+	equal(t, 1, d)
+}
+
+func TestSuggestions(t *testing.T) {
+	c := New()
+	c.ValidationMode = CollectAll
+	if err := c.LoadReader("json", strings.NewReader(`{"http": {"port": 80, "host": "localhost"}, "timeout": 5}`)); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, "http.port", c.suggestion("http.prot", c.allKeys(c.snap.Load().store)))
+	equal(t, "", c.suggestion("http.host", []string{"http.port"}))
+	equal(t, "", c.suggestion("database", c.allKeys(c.snap.Load().store)))
+
+	err := c.RequireKeys("http.prot", "database")
+	equal(t, `missing configuration keys: "http.prot" (did you mean "http.port"?), "database"`, err.Error())
+	var ke *ConfigKeyError
+	if !errors.As(err, &ke) {
+		t.Fatalf("Expected a *ConfigKeyError, got %v", err)
+	}
+	equal(t, `no configuration value was found, did you mean "http.port"?`, ke.Message)
+
+	var s struct {
+		Port int `cconf:"port"`
+		Host string
+	}
+	err = c.Populate(&s, "http.prot")
+	if !errors.As(err, &ke) {
+		t.Fatalf("Expected a *ConfigKeyError, got %v", err)
+	}
+	equal(t, `no configuration value was found, did you mean "http.port"?`, ke.Message)
+
+	c.SetStore(map[string]interface{}{"prot": 80, "hots": "localhost", "timeout": 5})
+	msgs := validationErrors(t, c.Populate(&s))
+	equal(t, map[string]string{
+		".prot":    `field prot not found in struct struct { Port int "cconf:\"port\""; Host string }, did you mean "port"?`,
+		".hots":    `field hots not found in struct struct { Port int "cconf:\"port\""; Host string }`,
+		".timeout": `field timeout not found in struct struct { Port int "cconf:\"port\""; Host string }`,
+	}, msgs)
+
+	err = c.CheckUnknownKeys([]string{"port", "Host"})
+	var me *MultiError
+	if !errors.As(err, &me) || len(me.Errors) != 3 {
+		t.Fatalf("Expected three unknown keys, got %v", err)
+	}
+	equal(t, `"prot" is not a valid key: unknown key, did you mean "port"?`, me.Errors[1].Error())
+	equal(t, `"timeout" is not a valid key: unknown key, the nearest allowed key is "port"`, me.Errors[2].Error())
+}
@@ -0,0 +1,206 @@
+package cconf
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// fieldTag describes the cconf tag of a struct field, like `cconf:"port,required,min=1"` or
+// `cconf:"hosts,default=a,b"`. The value of an option continues after a comma unless another
+// option follows, so that default values and regular expressions may contain commas.
+type fieldTag struct {
+	name        string // the key of the field, the field name by default
+	required    bool   // whether the configuration must have a value for the field
+	def         string // the default value, parsed like a value of the environment
+	hasDef      bool
+	constraints []constraint // see checkConstraints
+}
+
+// constraint is a constraint of a cconf tag, like min=1.
+type constraint struct {
+	name, arg string
+	bound     float64        // the bound of min and max
+	re        *regexp.Regexp // the expression of regexp
+	err       error          // the error of an invalid constraint
+}
+
+// String returns the constraint as written in the tag.
+func (c constraint) String() string {
+	return c.name + "=" + c.arg
+}
+
+// tagOptions are the options of cconf tags with a value.
+var tagOptions = []string{"default", "min", "max", "oneof", "regexp"}
+
+// parseTag parses the cconf tag of the field.
+func parseTag(f reflect.StructField) fieldTag {
+	parts := strings.Split(f.Tag.Get("cconf"), ",")
+	t := fieldTag{name: parts[0]}
+	if t.name == "" {
+		t.name = f.Name
+	}
+	var opts []string
+	for _, part := range parts[1:] {
+		name, _, _ := strings.Cut(part, "=")
+		if len(opts) > 0 && part != "required" && !slices.Contains(tagOptions, name) {
+			opts[len(opts)-1] += "," + part
+			continue
+		}
+		opts = append(opts, part)
+	}
+	for _, opt := range opts {
+		name, arg, _ := strings.Cut(opt, "=")
+		switch name {
+		case "required":
+			t.required = true
+		case "default":
+			t.def, t.hasDef = arg, true
+		case "min", "max":
+			c := constraint{name: name, arg: arg}
+			c.bound, c.err = strconv.ParseFloat(arg, 64)
+			t.constraints = append(t.constraints, c)
+		case "oneof":
+			t.constraints = append(t.constraints, constraint{name: name, arg: arg})
+		case "regexp":
+			c := constraint{name: name, arg: arg}
+			c.re, c.err = regexp.Compile(arg)
+			t.constraints = append(t.constraints, c)
+		}
+	}
+	return t
+}
+
+// structField is a visible field of a struct type with its tag.
+type structField struct {
+	reflect.StructField
+	tag fieldTag
+}
+
+// structFieldsCache caches the fields of struct types, see structFields.
+var structFieldsCache sync.Map // map[reflect.Type][]structField
+
+// structFields returns the visible fields of the struct type, see reflect.VisibleFields.
+func structFields(t reflect.Type) []structField {
+	if fields, ok := structFieldsCache.Load(t); ok {
+		return fields.([]structField)
+	}
+	visible := reflect.VisibleFields(t)
+	fields := make([]structField, len(visible))
+	for i, f := range visible {
+		fields[i] = structField{f, parseTag(f)}
+	}
+	structFieldsCache.Store(t, fields)
+	return fields
+}
+
+// fieldByKey returns the field of the struct type for the configuration key: the field named
+// by its tag like the key, or else the field of that name if its tag does not rename it.
+func fieldByKey(t reflect.Type, key string) (structField, bool) {
+	fields := structFields(t)
+	for _, f := range fields {
+		if f.tag.name == key && f.tag.name != f.Name {
+			return f, true
+		}
+	}
+	for _, f := range fields {
+		if f.Name == key && f.tag.name == key {
+			return f, true
+		}
+	}
+	return structField{}, false
+}
+
+// fieldByIndex returns the field of the struct value at the index, allocating the nil
+// embedded pointers on the way if they can be set. It reports false if it cannot.
+func fieldByIndex(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				if !v.CanSet() {
+					return reflect.Value{}, false
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+// checkConstraints checks the value of the field at the key against the constraints of its
+// tag and returns the violations. The constraints apply to the elements of slices and arrays.
+// The bounds of min and max apply to the values of numbers, to the lengths of strings and
+// to durations, with bounds like "1s".
+func checkConstraints(tag fieldTag, v reflect.Value, key string) []*ConfigValueError {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if (v.Kind() == reflect.Slice || v.Kind() == reflect.Array) && v.Type().Elem().Kind() != reflect.Uint8 {
+		var errs []*ConfigValueError
+		for i := 0; i < v.Len(); i++ {
+			errs = append(errs, checkConstraints(tag, v.Index(i), key+"."+strconv.Itoa(i))...)
+		}
+		return errs
+	}
+
+	var errs []*ConfigValueError
+	for _, c := range tag.constraints {
+		if d, err := time.ParseDuration(c.arg); err == nil && v.Type() == durationType && (c.name == "min" || c.name == "max") {
+			c.bound, c.err = d.Seconds(), nil
+		}
+		if c.err != nil {
+			errs = append(errs, &ConfigValueError{Key: key, Message: fmt.Sprintf("invalid constraint %v: %v", c, c.err)})
+			continue
+		}
+		ok := true
+		switch c.name {
+		case "min", "max":
+			n, isNumber := constraintNumber(v)
+			if !isNumber {
+				errs = append(errs, &ConfigValueError{Key: key, Message: fmt.Sprintf("the constraint %v does not apply to %v", c, v.Type())})
+				continue
+			}
+			ok = c.name == "min" && n >= c.bound || c.name == "max" && n <= c.bound
+		case "oneof":
+			ok = slices.Contains(strings.Fields(c.arg), fmt.Sprint(v.Interface()))
+		case "regexp":
+			ok = c.re.MatchString(fmt.Sprint(v.Interface()))
+		}
+		if !ok {
+			val := fmt.Sprint(v.Interface())
+			if v.Kind() == reflect.String {
+				val = strconv.Quote(v.String())
+			}
+			errs = append(errs, &ConfigValueError{Key: key, Message: fmt.Sprintf("%s does not satisfy %v", val, c), Actual: v.Interface()})
+		}
+	}
+	return errs
+}
+
+// constraintNumber returns the number compared with the bounds of min and max, see checkConstraints.
+func constraintNumber(v reflect.Value) (float64, bool) {
+	if v.Type() == durationType {
+		return time.Duration(v.Int()).Seconds(), true
+	}
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	case reflect.String:
+		return float64(len([]rune(v.String()))), true
+	}
+	return 0, false
+}
@@ -0,0 +1,110 @@
+package cconf
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseTag(t *testing.T) {
+	type tagged struct {
+		A int    `cconf:"a,required,min=1,max=10"`
+		B string `cconf:",default=x,y,oneof=x,y z"`
+		C string `cconf:"c,regexp=^[a-z]{1,3}$,required"`
+	}
+	typ := reflect.TypeOf(tagged{})
+	a := parseTag(typ.Field(0))
+	equal(t, "a", a.name)
+	equal(t, true, a.required)
+	equal(t, 2, len(a.constraints))
+	equal(t, 10.0, a.constraints[1].bound)
+
+	b := parseTag(typ.Field(1))
+	equal(t, "B", b.name)
+	equal(t, "x,y", b.def)
+	equal(t, "oneof=x,y z", b.constraints[0].String())
+
+	c := parseTag(typ.Field(2))
+	equal(t, "^[a-z]{1,3}$", c.constraints[0].arg)
+	equal(t, true, c.required)
+}
+
+type constrainedConfig struct {
+	Port    int           `cconf:"port,min=1,max=65535"`
+	Level   string        `cconf:"level,oneof=debug info warn error"`
+	Name    string        `cconf:"name,regexp=^[a-z-]+$"`
+	Ports   []int         `cconf:"ports,min=1024"`
+	Timeout time.Duration `cconf:"timeout,max=1m"`
+	Mode    string        `cconf:"mode,default=fast,oneof=fast safe"`
+}
+
+func TestPopulateConstraints(t *testing.T) {
+	c := New()
+	c.ValidationMode = CollectAll
+	c.SetStore(map[string]interface{}{
+		"port":    8080,
+		"level":   "info",
+		"name":    "my-app",
+		"ports":   []interface{}{8080, 8443},
+		"timeout": 30 * time.Second,
+	})
+	var s constrainedConfig
+	if err := c.Populate(&s); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, constrainedConfig{8080, "info", "my-app", []int{8080, 8443}, 30 * time.Second, "fast"}, s)
+	if err := c.ValidateStruct(s); err != nil {
+		t.Fatal(err)
+	}
+
+	c.SetStore(map[string]interface{}{
+		"port":    70000,
+		"level":   "verbose",
+		"name":    "My App",
+		"ports":   []interface{}{8080, 80},
+		"timeout": 2 * time.Minute,
+		"mode":    "slow",
+	})
+	want := map[string]string{
+		"port":    "70000 does not satisfy max=65535",
+		"level":   `"verbose" does not satisfy oneof=debug info warn error`,
+		"name":    `"My App" does not satisfy regexp=^[a-z-]+$`,
+		"ports.1": "80 does not satisfy min=1024",
+		"timeout": "2m0s does not satisfy max=1m",
+		"mode":    `"slow" does not satisfy oneof=fast safe`,
+	}
+	err := c.Populate(&s)
+	var me *MultiError
+	if !errors.As(err, &me) {
+		t.Fatalf("Expected a *MultiError, got %v", err)
+	}
+	msgs := make(map[string]string)
+	for _, err := range me.Errors {
+		var ve *ConfigValueError
+		if !errors.As(err, &ve) {
+			t.Fatalf("Expected a *ConfigValueError, got %v", err)
+		}
+		msgs[ve.Key[1:]] = ve.Message
+	}
+	equal(t, want, msgs)
+	// the fields are set nonetheless.
+	equal(t, 70000, s.Port)
+
+	equal(t, want, validationErrors(t, c.ValidateStruct(constrainedConfig{})))
+}
+
+func TestConstraintErrors(t *testing.T) {
+	var s struct {
+		Port  int             `cconf:"port,min=one"`
+		Flags map[string]bool `cconf:"flags,max=1"`
+	}
+	c := New()
+	c.ValidationMode = CollectAll
+	c.SetStore(map[string]interface{}{"port": 1, "flags": map[string]interface{}{"a": true}})
+	msgs := validationErrors(t, c.ValidateStruct(s))
+	equal(t, map[string]string{
+		"port":  `invalid constraint min=one: strconv.ParseFloat: parsing "one": invalid syntax`,
+		"flags": "the constraint max=1 does not apply to map[string]bool",
+	}, msgs)
+}
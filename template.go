@@ -0,0 +1,64 @@
+package cconf
+
+import (
+	"crypto/rand"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// DefaultTemplateFuncs are the functions available to templates in configuration values,
+// see ExecTemplates. New copies them, so changes only affect the Confs created afterwards.
+var DefaultTemplateFuncs = template.FuncMap{
+	"hostname": os.Hostname,
+	"env":      os.Getenv,
+	"uuid":     newUUID,
+	"now": func(layout string) string {
+		return time.Now().Format(layout)
+	},
+}
+
+// RegisterTemplateFunc registers a function for templates in configuration values,
+// see ExecTemplates. The function must be valid for a template.FuncMap.
+func (c *Conf) RegisterTemplateFunc(name string, fn interface{}) {
+	c.mu.Lock()
+	defer c.unlock()
+	c.templateFuncs[name] = fn
+}
+
+// execTemplates executes the string values of the normalized data that contain "{{" as
+// text/template templates, in place. The outputs are memoized by key and text in executed,
+// so that a template is executed once. The caller must hold c.mu.
+func (c *Conf) execTemplates(data interface{}, executed map[string]string) (interface{}, error) {
+	return c.mapStrings(data, "", func(key, s string) (string, error) {
+		if !strings.Contains(s, "{{") {
+			return s, nil
+		}
+		if out, ok := executed[key+"\x00"+s]; ok {
+			return out, nil
+		}
+		t, err := template.New(key).Funcs(c.templateFuncs).Option("missingkey=error").Parse(s)
+		if err != nil {
+			return "", err
+		}
+		var b strings.Builder
+		if err := t.Execute(&b, nil); err != nil {
+			return "", err
+		}
+		executed[key+"\x00"+s] = b.String()
+		return b.String(), nil
+	})
+}
+
+// newUUID returns a random (version 4) UUID.
+func newUUID() (string, error) {
+	var u [16]byte
+	if _, err := rand.Read(u[:]); err != nil {
+		return "", err
+	}
+	u[6] = u[6]&0x0f | 0x40
+	u[8] = u[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", u[0:4], u[4:6], u[6:8], u[8:10], u[10:]), nil
+}
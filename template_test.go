@@ -0,0 +1,64 @@
+package cconf
+
+import (
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExecTemplates(t *testing.T) {
+	t.Setenv("CCONFTEST_REGION", "eu")
+	hostname, _ := os.Hostname()
+
+	c := New()
+	c.ExecTemplates = true
+	c.Interpolate = true
+	c.RegisterTemplateFunc("upper", strings.ToUpper)
+	err := c.LoadReader("json", strings.NewReader(`{
+		"host": "{{hostname}}",
+		"id": "{{uuid}}",
+		"date": "{{now \"2006-01-02\"}}",
+		"region": "{{env \"CCONFTEST_REGION\" | upper}}",
+		"name": "app",
+		"title": "{{upper \"${name}\"}}",
+		"plain": "no markers, ${name}",
+		"list": ["{{upper \"a\"}}"]
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	equal(t, hostname, c.GetString("host"))
+	if !regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`).MatchString(c.GetString("id")) {
+		t.Fatalf("invalid uuid %q", c.GetString("id"))
+	}
+	equal(t, time.Now().Format("2006-01-02"), c.GetString("date"))
+	equal(t, "EU", c.GetString("region"))
+	equal(t, "APP", c.GetString("title"))
+	equal(t, "no markers, app", c.GetString("plain"))
+	equal(t, "A", c.GetString("list.0"))
+
+	// resolving the references again for a later load does not execute the templates again.
+	id := c.GetString("id")
+	if err := c.LoadReader("json", strings.NewReader(`{"name": "web"}`)); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, id, c.GetString("id"))
+	equal(t, "WEB", c.GetString("title"))
+
+	for _, data := range []string{`{"db": {"host": "{{unknown}}"}}`, `{"db": {"host": "{{now}}"}}`} {
+		err = c.LoadReader("json", strings.NewReader(data))
+		if err == nil || !strings.Contains(err.Error(), `"db.host"`) {
+			t.Fatalf("unexpected error %v", err)
+		}
+	}
+	equal(t, nil, c.Get("db"))
+
+	// templates are opt-in.
+	c = New()
+	if err := c.LoadReader("json", strings.NewReader(`{"host": "{{hostname}}"}`)); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, "{{hostname}}", c.GetString("host"))
+}
@@ -0,0 +1,33 @@
+# overrides testdata/app.json
+name = "cconf-hcl"
+version = null # removes the version, like null values of other files
+
+ext {
+  email = "hcl@example.com"
+}
+
+server "web" {
+  http {
+    port = 8080
+  }
+  tags = ["a", "b"]
+}
+
+server "api" {
+  http { port = 9090 }
+}
+
+upstream {
+  host   = "10.0.0.1"
+  weight = 1
+}
+
+upstream {
+  host   = "10.0.0.2"
+  weight = 2.5 // a float
+}
+
+motd = <<-EOT
+  Welcome!
+    indented
+  EOT
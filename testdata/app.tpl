@@ -0,0 +1,4 @@
+{
+	"server": {"port": 9090},
+	"ext": {"email": "tpl@example.com"}
+}
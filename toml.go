@@ -0,0 +1,814 @@
+package cconf
+
+import (
+	"encoding"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// dumpTOML writes the data to the file as TOML.
+func dumpTOML(file string, data interface{}) error {
+	return dumpFile(file, data, encodeTOML)
+}
+
+// encodeTOML writes the data, which must be a map, to the writer as a TOML document, with
+// the keys of tables sorted. Maps become tables and lists of maps arrays of tables, other
+// values are written inline. Times are written as offset date-times, and integral floats,
+// like the numbers loaded from JSON, as integers. TOML has no null, so nil values of maps
+// are skipped and nil values of lists are an error.
+func encodeTOML(w io.Writer, data interface{}) error {
+	var m map[string]interface{}
+	switch d := normalize(data).(type) {
+	case nil:
+	case map[string]interface{}:
+		m = d
+	default:
+		return fmt.Errorf("cannot encode %T as TOML, a map is required", d)
+	}
+	var b strings.Builder
+	if err := writeTOMLTable(&b, nil, m); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, strings.TrimPrefix(b.String(), "\n"))
+	return err
+}
+
+// writeTOMLTable writes the key-value pairs of the table at the path, followed by its
+// tables and arrays of tables.
+func writeTOMLTable(b *strings.Builder, path []string, m map[string]interface{}) error {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var tables, arrays []string
+	for _, k := range keys {
+		switch v := m[k]; {
+		case v == nil:
+		case isTable(v):
+			tables = append(tables, k)
+		case isTableArray(v):
+			arrays = append(arrays, k)
+		default:
+			s, err := tomlValue(v, append(path[:len(path):len(path)], k))
+			if err != nil {
+				return err
+			}
+			b.WriteString(tomlKey(k) + " = " + s + "\n")
+		}
+	}
+	for _, k := range tables {
+		p := append(path[:len(path):len(path)], k)
+		b.WriteString("\n[" + tomlPath(p) + "]\n")
+		if err := writeTOMLTable(b, p, m[k].(map[string]interface{})); err != nil {
+			return err
+		}
+	}
+	for _, k := range arrays {
+		p := append(path[:len(path):len(path)], k)
+		for _, e := range m[k].([]interface{}) {
+			b.WriteString("\n[[" + tomlPath(p) + "]]\n")
+			if err := writeTOMLTable(b, p, e.(map[string]interface{})); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// isTable reports whether the value is a non-empty map.
+func isTable(v interface{}) bool {
+	m, ok := v.(map[string]interface{})
+	return ok && len(m) > 0
+}
+
+// isTableArray reports whether the value is a non-empty list of maps.
+func isTableArray(v interface{}) bool {
+	s, ok := v.([]interface{})
+	if !ok || len(s) == 0 {
+		return false
+	}
+	for _, e := range s {
+		if _, ok := e.(map[string]interface{}); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// tomlValue formats the value at the path as an inline TOML value.
+func tomlValue(v interface{}, path []string) (string, error) {
+	switch d := v.(type) {
+	case nil:
+		return "", fmt.Errorf("cannot encode the null at %s as TOML", tomlPath(path))
+	case map[string]interface{}:
+		keys := make([]string, 0, len(d))
+		for k := range d {
+			if d[k] != nil {
+				keys = append(keys, k)
+			}
+		}
+		if len(keys) == 0 {
+			return "{}", nil
+		}
+		sort.Strings(keys)
+		pairs := make([]string, len(keys))
+		for i, k := range keys {
+			s, err := tomlValue(d[k], append(path[:len(path):len(path)], k))
+			if err != nil {
+				return "", err
+			}
+			pairs[i] = tomlKey(k) + " = " + s
+		}
+		return "{ " + strings.Join(pairs, ", ") + " }", nil
+	case []interface{}:
+		elems := make([]string, len(d))
+		for i, e := range d {
+			s, err := tomlValue(e, append(path[:len(path):len(path)], strconv.Itoa(i)))
+			if err != nil {
+				return "", err
+			}
+			elems[i] = s
+		}
+		return "[" + strings.Join(elems, ", ") + "]", nil
+	case string:
+		return tomlString(d), nil
+	case []byte:
+		return tomlString(string(d)), nil
+	case time.Time:
+		return d.Format(time.RFC3339Nano), nil
+	case encoding.TextMarshaler:
+		if text, err := d.MarshalText(); err == nil {
+			return tomlString(string(text)), nil
+		}
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Bool:
+		return strconv.FormatBool(rv.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(rv.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(rv.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		f := rv.Float()
+		switch {
+		case math.IsNaN(f):
+			return "nan", nil
+		case math.IsInf(f, 1):
+			return "inf", nil
+		case math.IsInf(f, -1):
+			return "-inf", nil
+		}
+		return formatFloat(f), nil
+	}
+	return tomlString(fmt.Sprint(v)), nil
+}
+
+// tomlPath formats the path of a table as a dotted TOML key.
+func tomlPath(path []string) string {
+	keys := make([]string, len(path))
+	for i, k := range path {
+		keys[i] = tomlKey(k)
+	}
+	return strings.Join(keys, ".")
+}
+
+// tomlKey formats the key as a bare key if possible, and as a quoted key otherwise.
+func tomlKey(k string) string {
+	if k == "" {
+		return `""`
+	}
+	for _, r := range k {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '-') {
+			return tomlString(k)
+		}
+	}
+	return k
+}
+
+// tomlString formats the string as a TOML basic string.
+func tomlString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\b':
+			b.WriteString(`\b`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\f':
+			b.WriteString(`\f`)
+		case '\r':
+			b.WriteString(`\r`)
+		default:
+			if r < 0x20 || r == 0x7f {
+				fmt.Fprintf(&b, `\u%04X`, r)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// loadTOML reads and parses a TOML file.
+func loadTOML(file string, data interface{}) error {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	return parseTOML(string(b), data)
+}
+
+// decodeTOML parses a TOML document from the reader into data, which must be a *interface{}.
+func decodeTOML(r io.Reader, data interface{}) error {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	return parseTOML(string(b), data)
+}
+
+// parseTOML parses a TOML document into data, which must be a *interface{}, as maps like
+// loadJSON: tables become maps and arrays of tables lists of maps. Integers are loaded as
+// ints and floats as float64s. Offset date-times are loaded as time.Time, while local
+// date-times, dates and times, which have no time zone, are kept as strings as written,
+// like "1979-05-27" or "07:32:00". Malformed documents fail with a *SyntaxError.
+func parseTOML(s string, data interface{}) error {
+	p := &tomlParser{s: strings.TrimPrefix(s, "\ufeff"), root: make(map[string]interface{}), kinds: make(map[string]tomlKind)}
+	p.cur = p.root
+	if err := p.parse(); err != nil {
+		return err
+	}
+	*data.(*interface{}) = p.root
+	return nil
+}
+
+// tomlKind is how a table or an array was defined, which decides how it may be extended.
+type tomlKind int
+
+const (
+	tomlImplicit   tomlKind = iota // a table created as the parent of another one
+	tomlExplicit                   // a table defined by a [header]
+	tomlDotted                     // a table defined by a dotted key
+	tomlInline                     // an inline table or an array value, which cannot be extended
+	tomlTableArray                 // an array of tables defined by [[headers]]
+)
+
+// tomlParser parses a TOML document.
+type tomlParser struct {
+	s       string
+	pos     int
+	root    map[string]interface{}
+	cur     map[string]interface{} // the current table
+	curPath []string
+	kinds   map[string]tomlKind // the kinds of the tables and arrays by path
+}
+
+// errorf returns a *SyntaxError at the position.
+func (p *tomlParser) errorf(pos int, format string, args ...interface{}) error {
+	return newSyntaxError(p.s, pos, fmt.Errorf(format, args...))
+}
+
+// parse parses the expressions of the document line by line.
+func (p *tomlParser) parse() error {
+	for {
+		p.ws()
+		if p.pos == len(p.s) {
+			return nil
+		}
+		switch p.s[p.pos] {
+		case '\n', '\r', '#':
+		case '[':
+			if err := p.header(); err != nil {
+				return err
+			}
+		default:
+			if err := p.keyval(p.cur, p.curPath); err != nil {
+				return err
+			}
+		}
+		if err := p.eol(); err != nil {
+			return err
+		}
+	}
+}
+
+// ws skips spaces and tabs.
+func (p *tomlParser) ws() {
+	for p.pos < len(p.s) && (p.s[p.pos] == ' ' || p.s[p.pos] == '\t') {
+		p.pos++
+	}
+}
+
+// wsnl skips white space, line breaks and comments, within arrays and inline tables.
+func (p *tomlParser) wsnl() error {
+	for p.pos < len(p.s) {
+		switch p.s[p.pos] {
+		case ' ', '\t', '\n', '\r':
+			p.pos++
+		case '#':
+			if err := p.eol(); err != nil {
+				return err
+			}
+		default:
+			return nil
+		}
+	}
+	return nil
+}
+
+// eol consumes the rest of the line, which may only hold a comment.
+func (p *tomlParser) eol() error {
+	p.ws()
+	if p.pos < len(p.s) && p.s[p.pos] == '#' {
+		for p.pos < len(p.s) && p.s[p.pos] != '\n' {
+			if c := p.s[p.pos]; c < 0x20 && c != '\t' && c != '\r' || c == 0x7f {
+				return p.errorf(p.pos, "control character %U in a comment", c)
+			}
+			p.pos++
+		}
+	}
+	switch {
+	case p.pos == len(p.s):
+	case p.s[p.pos] == '\n':
+		p.pos++
+	case strings.HasPrefix(p.s[p.pos:], "\r\n"):
+		p.pos += 2
+	default:
+		r, _ := utf8.DecodeRuneInString(p.s[p.pos:])
+		return p.errorf(p.pos, "expected the end of the line, found %q", r)
+	}
+	return nil
+}
+
+// key parses a dotted key like a."b.c".d.
+func (p *tomlParser) key() ([]string, error) {
+	var keys []string
+	for {
+		p.ws()
+		start := p.pos
+		switch {
+		case p.pos == len(p.s):
+			return nil, p.errorf(p.pos, "expected a key")
+		case p.s[p.pos] == '"' || p.s[p.pos] == '\'':
+			if strings.HasPrefix(p.s[p.pos:], `"""`) || strings.HasPrefix(p.s[p.pos:], "'''") {
+				return nil, p.errorf(p.pos, "multi-line strings cannot be keys")
+			}
+			v, err := p.str()
+			if err != nil {
+				return nil, err
+			}
+			keys = append(keys, v)
+		default:
+			for p.pos < len(p.s) && isTOMLBare(p.s[p.pos]) {
+				p.pos++
+			}
+			if p.pos == start {
+				r, _ := utf8.DecodeRuneInString(p.s[p.pos:])
+				return nil, p.errorf(p.pos, "expected a key, found %q", r)
+			}
+			keys = append(keys, p.s[start:p.pos])
+		}
+		p.ws()
+		if p.pos == len(p.s) || p.s[p.pos] != '.' {
+			return keys, nil
+		}
+		p.pos++
+	}
+}
+
+// isTOMLBare reports whether the character may be part of a bare key.
+func isTOMLBare(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-'
+}
+
+// tomlJoin returns the path as a key of tomlParser.kinds.
+func tomlJoin(path []string) string {
+	return strings.Join(path, "\x00")
+}
+
+// header parses a [table] or [[array of tables]] header and makes it the current table.
+func (p *tomlParser) header() error {
+	start := p.pos
+	array := strings.HasPrefix(p.s[p.pos:], "[[")
+	if array {
+		p.pos += 2
+	} else {
+		p.pos++
+	}
+	keys, err := p.key()
+	if err != nil {
+		return err
+	}
+	closing := "]"
+	if array {
+		closing = "]]"
+	}
+	if !strings.HasPrefix(p.s[p.pos:], closing) {
+		return p.errorf(p.pos, "expected %q", closing)
+	}
+	p.pos += len(closing)
+
+	m, path, err := p.descend(start, p.root, nil, keys[:len(keys)-1], false)
+	if err != nil {
+		return err
+	}
+	last := keys[len(keys)-1]
+	path = append(path, last)
+	kind, defined := p.kinds[tomlJoin(path)]
+	if array {
+		s, ok := m[last].([]interface{})
+		if _, exists := m[last]; exists && (!ok || kind != tomlTableArray) {
+			return p.errorf(start, "cannot define %q as an array of tables, it is already defined", strings.Join(keys, "."))
+		}
+		table := make(map[string]interface{})
+		m[last] = append(s, table)
+		p.kinds[tomlJoin(path)] = tomlTableArray
+		p.cur, p.curPath = table, append(path, strconv.Itoa(len(s)))
+		return nil
+	}
+	switch v := m[last].(type) {
+	case nil:
+		table := make(map[string]interface{})
+		m[last] = table
+		p.cur = table
+	case map[string]interface{}:
+		if defined && kind != tomlImplicit {
+			return p.errorf(start, "table %q is already defined", strings.Join(keys, "."))
+		}
+		p.cur = v
+	default:
+		return p.errorf(start, "cannot define %q as a table, it is already defined", strings.Join(keys, "."))
+	}
+	p.kinds[tomlJoin(path)] = tomlExplicit
+	p.curPath = path
+	return nil
+}
+
+// descend returns the table at the keys below the table at the path, and its path, creating
+// the missing tables. Headers descend into the last table of arrays of tables, dotted keys
+// do not, and neither extend inline tables or tables defined in another way.
+func (p *tomlParser) descend(pos int, m map[string]interface{}, path, keys []string, dotted bool) (map[string]interface{}, []string, error) {
+	path = append([]string(nil), path...)
+	for i, k := range keys {
+		path = append(path, k)
+		kind, defined := p.kinds[tomlJoin(path)]
+		extendable := false
+		switch v := m[k].(type) {
+		case nil:
+			table := make(map[string]interface{})
+			m[k] = table
+			p.kinds[tomlJoin(path)] = tomlImplicit
+			if dotted {
+				p.kinds[tomlJoin(path)] = tomlDotted
+			}
+			m, extendable = table, true
+		case map[string]interface{}:
+			if kind != tomlInline && !(dotted && defined && kind != tomlDotted) {
+				m, extendable = v, true
+			}
+		case []interface{}:
+			if kind == tomlTableArray && !dotted {
+				path = append(path, strconv.Itoa(len(v)-1))
+				m, extendable = v[len(v)-1].(map[string]interface{}), true
+			}
+		}
+		if !extendable {
+			return nil, nil, p.errorf(pos, "cannot extend %q, it is already defined", strings.Join(keys[:i+1], "."))
+		}
+	}
+	return m, path, nil
+}
+
+// keyval parses a key/value pair into the table at the path.
+func (p *tomlParser) keyval(m map[string]interface{}, path []string) error {
+	start := p.pos
+	keys, err := p.key()
+	if err != nil {
+		return err
+	}
+	if p.pos == len(p.s) || p.s[p.pos] != '=' {
+		return p.errorf(p.pos, "expected '=' after the key")
+	}
+	p.pos++
+	p.ws()
+	v, err := p.value()
+	if err != nil {
+		return err
+	}
+	m, path, err = p.descend(start, m, path, keys[:len(keys)-1], true)
+	if err != nil {
+		return err
+	}
+	last := keys[len(keys)-1]
+	if _, ok := m[last]; ok {
+		return p.errorf(start, "key %q is already defined", strings.Join(keys, "."))
+	}
+	m[last] = v
+	switch v.(type) {
+	case map[string]interface{}, []interface{}:
+		p.kinds[tomlJoin(append(path, last))] = tomlInline
+	}
+	return nil
+}
+
+// value parses a value.
+func (p *tomlParser) value() (interface{}, error) {
+	if p.pos == len(p.s) {
+		return nil, p.errorf(p.pos, "expected a value")
+	}
+	switch p.s[p.pos] {
+	case '"', '\'':
+		return p.str()
+	case '[':
+		return p.array()
+	case '{':
+		return p.inlineTable()
+	}
+	start := p.pos
+	for p.pos < len(p.s) && strings.IndexByte(" \t\r\n,]}#", p.s[p.pos]) < 0 {
+		p.pos++
+	}
+	// a date-time may separate the date and the time with a space.
+	if tok := p.s[start:p.pos]; len(tok) == 10 && tok[4] == '-' && len(p.s) > p.pos+3 &&
+		p.s[p.pos] == ' ' && isDigit(p.s[p.pos+1]) && isDigit(p.s[p.pos+2]) && p.s[p.pos+3] == ':' {
+		for p.pos++; p.pos < len(p.s) && strings.IndexByte(" \t\r\n,]}#", p.s[p.pos]) < 0; p.pos++ {
+		}
+	}
+	tok := p.s[start:p.pos]
+	if v, ok := tomlScalar(tok); ok {
+		return v, nil
+	}
+	if tok == "" {
+		return nil, p.errorf(start, "expected a value")
+	}
+	return nil, p.errorf(start, "invalid value %q", tok)
+}
+
+// isDigit reports whether the character is a decimal digit.
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// tomlScalar returns the value of a boolean, number or date-time.
+func tomlScalar(tok string) (interface{}, bool) {
+	switch tok {
+	case "true":
+		return true, true
+	case "false":
+		return false, true
+	case "inf", "+inf":
+		return math.Inf(1), true
+	case "-inf":
+		return math.Inf(-1), true
+	case "nan", "+nan", "-nan":
+		return math.NaN(), true
+	}
+	for prefix, base := range map[string]int{"0x": 16, "0o": 8, "0b": 2} {
+		if strings.HasPrefix(tok, prefix) {
+			digits := tok[2:]
+			if !tomlDigits(digits, base) {
+				return nil, false
+			}
+			n, err := strconv.ParseInt(strings.ReplaceAll(digits, "_", ""), base, 64)
+			return int(n), err == nil
+		}
+	}
+	if len(tok) >= 8 && isDigit(tok[0]) && isDigit(tok[1]) && (tok[2] == ':' || isDigit(tok[2]) && isDigit(tok[3]) && tok[4] == '-') {
+		return tomlDateTime(tok)
+	}
+
+	// decimal integers and floats: an integer part without leading zeros, followed by an
+	// optional fraction and an optional exponent.
+	s := strings.TrimLeft(tok, "+-")
+	if len(tok)-len(s) > 1 {
+		return nil, false
+	}
+	mant, exp, hasExp := s, "", false
+	if i := strings.IndexAny(s, "eE"); i >= 0 {
+		mant, exp, hasExp = s[:i], strings.TrimLeft(s[i+1:], "+-"), true
+		if len(s[i+1:])-len(exp) > 1 || !tomlDigits(exp, 10) {
+			return nil, false
+		}
+	}
+	whole, frac, hasFrac := strings.Cut(mant, ".")
+	if !tomlDigits(whole, 10) || hasFrac && !tomlDigits(frac, 10) || len(whole) > 1 && whole[0] == '0' {
+		return nil, false
+	}
+	clean := strings.ReplaceAll(tok, "_", "")
+	if !hasFrac && !hasExp {
+		n, err := strconv.ParseInt(clean, 10, 64)
+		return int(n), err == nil
+	}
+	f, err := strconv.ParseFloat(clean, 64)
+	return f, err == nil
+}
+
+// tomlDigits reports whether s holds digits of the base, with single underscores between them.
+func tomlDigits(s string, base int) bool {
+	if s == "" || s[0] == '_' || s[len(s)-1] == '_' || strings.Contains(s, "__") {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		// c|0x20 is the lower case of a letter.
+		if c := s[i]; c != '_' && strings.IndexByte("0123456789abcdef"[:base], c|0x20) < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// tomlDateTime returns an offset date-time as a time.Time, and a local date-time, date or
+// time as the string.
+func tomlDateTime(tok string) (interface{}, bool) {
+	if len(tok) > 10 && (tok[10] == ' ' || tok[10] == 't') {
+		tok = tok[:10] + "T" + tok[11:]
+	}
+	if t, err := time.Parse(time.RFC3339Nano, strings.Replace(tok, "z", "Z", 1)); err == nil {
+		return t, true
+	}
+	for _, layout := range []string{"2006-01-02T15:04:05.999999999", "2006-01-02", "15:04:05.999999999"} {
+		if _, err := time.Parse(layout, tok); err == nil {
+			return tok, true
+		}
+	}
+	return nil, false
+}
+
+// str parses a basic, literal or multi-line string.
+func (p *tomlParser) str() (string, error) {
+	start := p.pos
+	q := p.s[p.pos]
+	multi := strings.HasPrefix(p.s[p.pos:], strings.Repeat(string(q), 3))
+	if multi {
+		p.pos += 3
+		// a line break right after the delimiter is trimmed.
+		if strings.HasPrefix(p.s[p.pos:], "\n") {
+			p.pos++
+		} else if strings.HasPrefix(p.s[p.pos:], "\r\n") {
+			p.pos += 2
+		}
+	} else {
+		p.pos++
+	}
+	var b strings.Builder
+	for p.pos < len(p.s) {
+		c := p.s[p.pos]
+		switch {
+		case c == q && !multi:
+			p.pos++
+			return b.String(), nil
+		case c == q && strings.HasPrefix(p.s[p.pos:], strings.Repeat(string(q), 3)):
+			// up to two quotes may precede the closing delimiter.
+			n := 3
+			for n < 5 && p.pos+n < len(p.s) && p.s[p.pos+n] == q {
+				n++
+			}
+			b.WriteString(strings.Repeat(string(q), n-3))
+			p.pos += n
+			return b.String(), nil
+		case c == '\\' && q == '"':
+			if err := p.escape(&b, multi); err != nil {
+				return "", err
+			}
+			continue
+		case c == '\n' && !multi:
+			return "", p.errorf(start, "unterminated string")
+		case c == '\r' && multi && strings.HasPrefix(p.s[p.pos:], "\r\n"):
+			// line breaks are normalized to "\n".
+			p.pos++
+			continue
+		case (c < 0x20 && c != '\t' && c != '\n') || c == 0x7f:
+			return "", p.errorf(p.pos, "control character %U in a string", c)
+		}
+		b.WriteByte(c)
+		p.pos++
+	}
+	return "", p.errorf(start, "unterminated string")
+}
+
+// tomlEscapes are the escape sequences of basic strings with a single character.
+var tomlEscapes = map[byte]byte{'b': '\b', 't': '\t', 'n': '\n', 'f': '\f', 'r': '\r', 'e': 0x1b, '"': '"', '\\': '\\'}
+
+// escape writes the character of the escape sequence at the position.
+func (p *tomlParser) escape(b *strings.Builder, multi bool) error {
+	start := p.pos
+	p.pos++
+	if p.pos == len(p.s) {
+		return p.errorf(start, "unterminated string")
+	}
+	c := p.s[p.pos]
+	if e, ok := tomlEscapes[c]; ok {
+		b.WriteByte(e)
+		p.pos++
+		return nil
+	}
+	if n := map[byte]int{'u': 4, 'U': 8}[c]; n > 0 {
+		if p.pos+n >= len(p.s) {
+			return p.errorf(start, "unterminated string")
+		}
+		r, err := strconv.ParseUint(p.s[p.pos+1:p.pos+1+n], 16, 32)
+		if err != nil || !utf8.ValidRune(rune(r)) {
+			return p.errorf(start, "invalid escape sequence \\%s", p.s[p.pos:p.pos+1+n])
+		}
+		b.WriteRune(rune(r))
+		p.pos += n + 1
+		return nil
+	}
+	if multi {
+		// a backslash at the end of a line trims the white space up to the next character.
+		rest := strings.TrimLeft(p.s[p.pos:], " \t")
+		if strings.HasPrefix(rest, "\n") || strings.HasPrefix(rest, "\r\n") {
+			p.pos = len(p.s) - len(strings.TrimLeft(rest, " \t\r\n"))
+			return nil
+		}
+	}
+	return p.errorf(start, "invalid escape sequence \\%c", c)
+}
+
+// array parses an array like [1, 2, 3], which may span lines.
+func (p *tomlParser) array() (interface{}, error) {
+	start := p.pos
+	p.pos++
+	s := make([]interface{}, 0)
+	for {
+		if err := p.wsnl(); err != nil {
+			return nil, err
+		}
+		if p.pos == len(p.s) {
+			return nil, p.errorf(start, "unterminated array")
+		}
+		if p.s[p.pos] == ']' {
+			p.pos++
+			return s, nil
+		}
+		v, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		s = append(s, v)
+		if err := p.wsnl(); err != nil {
+			return nil, err
+		}
+		switch {
+		case p.pos == len(p.s):
+			return nil, p.errorf(start, "unterminated array")
+		case p.s[p.pos] == ',':
+			p.pos++
+		case p.s[p.pos] != ']':
+			return nil, p.errorf(p.pos, "expected ',' or ']'")
+		}
+	}
+}
+
+// inlineTable parses an inline table like {a = 1, b.c = 2}.
+func (p *tomlParser) inlineTable() (interface{}, error) {
+	start := p.pos
+	p.pos++
+	m := make(map[string]interface{})
+	// the keys are tracked below a path of their own, as inline tables cannot be extended.
+	path := []string{"\x00inline", strconv.Itoa(start)}
+	for {
+		if err := p.wsnl(); err != nil {
+			return nil, err
+		}
+		if p.pos == len(p.s) {
+			return nil, p.errorf(start, "unterminated inline table")
+		}
+		if p.s[p.pos] == '}' {
+			p.pos++
+			return m, nil
+		}
+		if err := p.keyval(m, path); err != nil {
+			return nil, err
+		}
+		if err := p.wsnl(); err != nil {
+			return nil, err
+		}
+		switch {
+		case p.pos == len(p.s):
+			return nil, p.errorf(start, "unterminated inline table")
+		case p.s[p.pos] == ',':
+			p.pos++
+		case p.s[p.pos] != '}':
+			return nil, p.errorf(p.pos, "expected ',' or '}'")
+		}
+	}
+}
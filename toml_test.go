@@ -0,0 +1,235 @@
+package cconf
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEncodeTOML(t *testing.T) {
+	var buf bytes.Buffer
+	if err := encodeTOML(&buf, tricky); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, `answer = "yes"
+debug = true
+empty = ""
+flag = "true"
+none = "null"
+path = "/var/log"
+port = 8080
+ratio = 0.5
+text = "line 1\nline 2 \"quoted\""
+zip = "08"
+
+[nested]
+matrix = [[1, 2], ["x: y"]]
+none = {}
+
+[[nested.servers]]
+host = "a"
+port = 1
+
+[[nested.servers]]
+host = "b"
+tags = []
+`, buf.String())
+
+	buf.Reset()
+	err := encodeTOML(&buf, map[string]interface{}{
+		"a.b":     map[string]interface{}{"c d": "\t\x01"},
+		"created": time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		"skipped": nil,
+		"inline":  []interface{}{map[string]interface{}{"x": 1}, 2},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	equal(t, `created = 2024-01-02T03:04:05Z
+inline = [{ x = 1 }, 2]
+
+["a.b"]
+"c d" = "\t\u0001"
+`, buf.String())
+
+	if err := encodeTOML(&buf, []interface{}{1}); err == nil {
+		t.Fatal("expected an error")
+	}
+	if err := encodeTOML(&buf, map[string]interface{}{"a": []interface{}{nil}}); err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func TestSaveTOML(t *testing.T) {
+	c := New()
+	if err := c.Load("./testdata/app.json"); err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(t.TempDir(), "app.toml")
+	if err := c.Save(file); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	equal(t, `name = "cconf"
+version = 0.1
+
+[ext]
+author = "syyong.x"
+email = "syyong.x@gmail.com"
+`, string(b))
+}
+
+func TestLoadTOML(t *testing.T) {
+	c := New()
+	if err := c.Load("./testdata/app.json", "./testdata/app.toml"); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, "cconf-toml", c.GetString("name"))
+	equal(t, 0.1, c.Get("version"))
+	equal(t, "syyong.x", c.GetString("ext.author"))
+	equal(t, "toml@example.com", c.GetString("ext.email"))
+	equal(t, "https://github.com/syyongx/cconf", c.GetString("ext.links.home"))
+	equal(t, "beta", c.GetString("servers.1.name"))
+	equal(t, 9001, c.GetInt("servers.1.ports.1"))
+	equal(t, time.Date(2018, 5, 27, 7, 32, 0, 0, time.UTC), c.Get("released"))
+	equal(t, "2018-05-27T07:32:00Z", c.GetString("released"))
+
+	var s struct {
+		Released time.Time `cconf:"released"`
+		Servers  []struct {
+			Name  string `cconf:"name"`
+			IP    string `cconf:"ip"`
+			Ports []int  `cconf:"ports"`
+		} `cconf:"servers"`
+	}
+	if err := c.Populate(&s.Servers, "servers"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Populate(&s.Released, "released"); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, 2, len(s.Servers))
+	equal(t, "10.0.0.2", s.Servers[1].IP)
+	equal(t, []int{9000, 9001}, s.Servers[1].Ports)
+	equal(t, 2018, s.Released.Year())
+
+	if err := c.LoadReader("toml", strings.NewReader(`ext.email = "reader@example.com"`)); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, "reader@example.com", c.GetString("ext.email"))
+}
+
+func TestParseTOML(t *testing.T) {
+	odt := time.Date(1979, 5, 27, 0, 32, 0, 999999000, time.FixedZone("", -7*3600))
+	for doc, want := range map[string]interface{}{
+		"":              map[string]interface{}{},
+		"# comment\r\n": map[string]interface{}{},
+		`a.b."c.d" = 1`: map[string]interface{}{"a": map[string]interface{}{"b": map[string]interface{}{"c.d": 1}}},
+		"[a.b]\nc = 1\n[a]\nd = 2": map[string]interface{}{"a": map[string]interface{}{
+			"b": map[string]interface{}{"c": 1}, "d": 2,
+		}},
+		"[[a]]\nx = 1\n[a.b]\ny = 2\n[[a]]\nx = 3": map[string]interface{}{"a": []interface{}{
+			map[string]interface{}{"x": 1, "b": map[string]interface{}{"y": 2}},
+			map[string]interface{}{"x": 3},
+		}},
+		"[[a.b]]\n[[a.b]]\nc = true": map[string]interface{}{"a": map[string]interface{}{"b": []interface{}{
+			map[string]interface{}{}, map[string]interface{}{"c": true},
+		}}},
+		"t = {x = 1, y.z = 'w'} # inline": map[string]interface{}{"t": map[string]interface{}{
+			"x": 1, "y": map[string]interface{}{"z": "w"},
+		}},
+		"i = [1_000, +2, -0, 0xdead_BEEF, 0o17, 0b101]": map[string]interface{}{"i": []interface{}{1000, 2, 0, 0xdeadbeef, 15, 5}},
+		"f = [1.5, -1e-3, 6.626e+34, 1_0.0_1, -inf]":    map[string]interface{}{"f": []interface{}{1.5, -1e-3, 6.626e+34, 10.01, math.Inf(-1)}},
+		`s = ["a\tb\u00e9\U0001F600", 'C:\x', "say \"hi\""]`: map[string]interface{}{
+			"s": []interface{}{"a\tbé\U0001F600", `C:\x`, `say "hi"`},
+		},
+		"s = \"\"\"\nline 1\nline \\\n    2\"\"\"\"": map[string]interface{}{"s": "line 1\nline 2\""},
+		"s = '''\r\nraw \\n\r\n'''":                  map[string]interface{}{"s": "raw \\n\n"},
+		"d = [1979-05-27T00:32:00.999999-07:00, 1979-05-27 07:32:00, 1979-05-27, 07:32:00.5]": map[string]interface{}{
+			"d": []interface{}{odt, "1979-05-27T07:32:00", "1979-05-27", "07:32:00.5"},
+		},
+		"\"\" = 1\n'a b' = 2": map[string]interface{}{"": 1, "a b": 2},
+	} {
+		var v interface{}
+		if err := parseTOML(doc, &v); err != nil {
+			t.Errorf("%q: %v", doc, err)
+			continue
+		}
+		if !reflect.DeepEqual(want, v) {
+			t.Errorf("%q: expected %#v, got %#v", doc, want, v)
+		}
+	}
+}
+
+func TestParseTOMLErrors(t *testing.T) {
+	for doc, want := range map[string]string{
+		"a = 1\na = 2":              `line 2, column 1: key "a" is already defined`,
+		"[a]\n[a]":                  `line 2, column 1: table "a" is already defined`,
+		"a = 1\n[a]":                `line 2, column 1: cannot define "a" as a table, it is already defined`,
+		"a = [1]\n[[a]]":            `line 2, column 1: cannot define "a" as an array of tables, it is already defined`,
+		"a = {b = 1}\na.c = 2":      `line 2, column 1: cannot extend "a", it is already defined`,
+		"[a.b]\n[a]\nb.c = 1":       `line 3, column 1: cannot extend "b", it is already defined`,
+		"a = 1 b = 2":               `line 1, column 7: expected the end of the line, found 'b'`,
+		"a = ":                      "line 1, column 5: expected a value",
+		"a = 01":                    `line 1, column 5: invalid value "01"`,
+		"a = 1__0":                  `line 1, column 5: invalid value "1__0"`,
+		"a = 1979-13-01":            `line 1, column 5: invalid value "1979-13-01"`,
+		"a = \"x\ny\"":              "line 1, column 5: unterminated string",
+		`a = "\q"`:                  `line 1, column 6: invalid escape sequence \q`,
+		"a = [1, 2":                 "line 1, column 5: unterminated array",
+		"a = [1 2]":                 "line 1, column 8: expected ',' or ']'",
+		"a = {b = 1 c = 2}":         "line 1, column 12: expected ',' or '}'",
+		"[a":                        `line 1, column 3: expected "]"`,
+		"= 1":                       `line 1, column 1: expected a key, found '='`,
+		"a 1":                       "line 1, column 3: expected '=' after the key",
+		"a = 1 # \x01":              "line 1, column 9: control character U+0001 in a comment",
+		"[x]\n  é = 1":              "line 2, column 3: expected a key, found 'é'",
+		"a = 1\nb = tru":            `line 2, column 5: invalid value "tru"`,
+		"a = 9223372036854775808":   `line 1, column 5: invalid value "9223372036854775808"`,
+		"a = '''x":                  "line 1, column 5: unterminated string",
+		"a = 1.":                    `line 1, column 5: invalid value "1."`,
+		"[[a]]\n[a]":                `line 2, column 1: cannot define "a" as a table, it is already defined`,
+		"a.b = 1\n[a.b]":            `line 2, column 1: cannot define "a.b" as a table, it is already defined`,
+		"a = [{b = 1}]\n[[a]]\nc=1": `line 2, column 1: cannot define "a" as an array of tables, it is already defined`,
+	} {
+		var v interface{}
+		err := parseTOML(doc, &v)
+		var se *SyntaxError
+		if !errors.As(err, &se) {
+			t.Errorf("%q: expected a *SyntaxError, got %v", doc, err)
+			continue
+		}
+		equal(t, want, err.Error())
+	}
+}
+
+func TestTOMLRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	if err := encodeTOML(&buf, tricky); err != nil {
+		t.Fatal(err)
+	}
+	var v interface{}
+	if err := parseTOML(buf.String(), &v); err != nil {
+		t.Fatal(err)
+	}
+	m := v.(map[string]interface{})
+	for _, k := range []string{"answer", "zip", "flag", "empty", "none", "text", "path", "ratio", "debug"} {
+		equal(t, tricky[k], m[k])
+	}
+	equal(t, 8080, m["port"])
+
+	var again bytes.Buffer
+	if err := encodeTOML(&again, v); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, buf.String(), again.String())
+}
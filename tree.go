@@ -0,0 +1,224 @@
+package cconf
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// The configuration store is a tree of map[string]interface{}, []interface{} and scalar values.
+// A published tree is never modified: changes copy the containers on their path and share the rest.
+
+// normalize returns a copy of the value converted to the representation of the store:
+// maps become map[string]interface{} with the keys formatted as strings,
+// arrays and slices (except []byte) become []interface{}, and other values are kept as is.
+func normalize(v interface{}) interface{} {
+	switch d := v.(type) {
+	case nil:
+		return nil
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(d))
+		for k, e := range d {
+			m[k] = normalize(e)
+		}
+		return m
+	case []interface{}:
+		s := make([]interface{}, len(d))
+		for i, e := range d {
+			s[i] = normalize(e)
+		}
+		return s
+	case string, bool, float64, int, int64, []byte:
+		return v
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Map:
+		m := make(map[string]interface{}, rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			m[fmt.Sprint(iter.Key().Interface())] = normalize(iter.Value().Interface())
+		}
+		return m
+	case reflect.Slice, reflect.Array:
+		if rv.Kind() == reflect.Slice && rv.IsNil() {
+			return nil
+		}
+		s := make([]interface{}, rv.Len())
+		for i := range s {
+			s[i] = normalize(rv.Index(i).Interface())
+		}
+		return s
+	}
+	return v
+}
+
+// merge returns the result of merging v2 into v1. If both are maps, the key-value pairs of v2
+// are added to v1 and maps found under the same key are merged recursively; a nil value in v2
+// removes the key. Otherwise v2 replaces v1.
+func merge(v1, v2 interface{}) interface{} {
+	m1, ok1 := v1.(map[string]interface{})
+	m2, ok2 := v2.(map[string]interface{})
+	if !ok1 || !ok2 {
+		return v2
+	}
+
+	m := make(map[string]interface{}, len(m1)+len(m2))
+	for k, e := range m1 {
+		m[k] = e
+	}
+	for k, e2 := range m2 {
+		if e2 == nil {
+			delete(m, k)
+			continue
+		}
+		m[k] = merge(m[k], e2)
+	}
+	return m
+}
+
+// without returns v1 without the values that merging v2 into it replaces or removes.
+func without(v1, v2 interface{}) interface{} {
+	m1, ok1 := v1.(map[string]interface{})
+	m2, ok2 := v2.(map[string]interface{})
+	if !ok1 || !ok2 {
+		return nil
+	}
+	m := make(map[string]interface{}, len(m1))
+	for k, e := range m1 {
+		if e2, ok := m2[k]; ok {
+			if e = without(e, e2); e == nil {
+				continue
+			}
+		}
+		m[k] = e
+	}
+	return m
+}
+
+// nestedMap returns the map at the path of maps below m, creating the missing maps, for
+// decoders building configuration data in place.
+func nestedMap(m map[string]interface{}, path []string) (map[string]interface{}, error) {
+	for i, k := range path {
+		switch v := m[k].(type) {
+		case nil:
+			sub := make(map[string]interface{})
+			m[k] = sub
+			m = sub
+		case map[string]interface{}:
+			m = v
+		default:
+			return nil, fmt.Errorf("%q is a value", strings.Join(path[:i+1], "."))
+		}
+	}
+	return m, nil
+}
+
+// remove returns a copy of data without the value at the path segs of maps, and without
+// the maps left empty by the removal, and whether there was such a value.
+func remove(data interface{}, segs []string) (interface{}, bool) {
+	m, ok := data.(map[string]interface{})
+	if !ok {
+		return data, false
+	}
+	e, ok := m[segs[0]]
+	if !ok {
+		return data, false
+	}
+	if len(segs) > 1 {
+		if e, ok = remove(e, segs[1:]); !ok {
+			return data, false
+		}
+	}
+	r := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		r[k] = v
+	}
+	if em, isMap := e.(map[string]interface{}); len(segs) == 1 || isMap && len(em) == 0 {
+		delete(r, segs[0])
+	} else {
+		r[segs[0]] = e
+	}
+	return r, true
+}
+
+// mapStrings replaces the strings below the node at the key of normalized data in place with
+// the results of fn. Errors are reported as ConfigValueErrors with the key of the string.
+func (c *Conf) mapStrings(node interface{}, key string, fn func(key, s string) (string, error)) (interface{}, error) {
+	switch d := node.(type) {
+	case string:
+		s, err := fn(key, d)
+		if err != nil {
+			if _, ok := err.(*ConfigValueError); !ok {
+				err = &ConfigValueError{Key: key, Message: err.Error()}
+			}
+			return nil, err
+		}
+		return s, nil
+	case map[string]interface{}:
+		for k, v := range d {
+			v, err := c.mapStrings(v, c.join(key, k), fn)
+			if err != nil {
+				return nil, err
+			}
+			d[k] = v
+		}
+	case []interface{}:
+		for i, v := range d {
+			v, err := c.mapStrings(v, c.join(key, strconv.Itoa(i)), fn)
+			if err != nil {
+				return nil, err
+			}
+			d[i] = v
+		}
+	}
+	return node, nil
+}
+
+// getElement returns the element value of a map or slice at the specified index.
+// Nil elements are reported as missing.
+func getElement(v interface{}, seg string) (interface{}, bool) {
+	switch d := v.(type) {
+	case map[string]interface{}:
+		e := d[seg]
+		return e, e != nil
+	case []interface{}:
+		if i, err := strconv.Atoi(seg); err == nil && i >= 0 && i < len(d) {
+			return d[i], d[i] != nil
+		}
+	}
+	return nil, false
+}
+
+// setElement returns a copy of the map or slice with the element at the specified index set to v.
+// A slice can be extended by setting the element right after its end.
+func setElement(data interface{}, seg string, v interface{}) (interface{}, error) {
+	switch d := data.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(d)+1)
+		for k, e := range d {
+			m[k] = e
+		}
+		m[seg] = v
+		return m, nil
+	case []interface{}:
+		i, err := strconv.Atoi(seg)
+		if err != nil || i < 0 {
+			return nil, fmt.Errorf("%v is not a valid array or slice index", seg)
+		}
+		if i > len(d) {
+			return nil, fmt.Errorf("%v is out of the slice index bound", seg)
+		}
+		s := make([]interface{}, len(d), len(d)+1)
+		copy(s, d)
+		if i == len(d) {
+			s = append(s, v)
+		} else {
+			s[i] = v
+		}
+		return s, nil
+	}
+	return nil, &wrapError{fmt.Sprintf("got %v instead of a map, array, or slice", reflect.ValueOf(data).Kind()), ErrTypeMismatch}
+}
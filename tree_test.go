@@ -0,0 +1,116 @@
+package cconf
+
+import (
+	"testing"
+)
+
+func TestMerge(t *testing.T) {
+	v1 := map[string]interface{}{
+		"a": 1,
+		"b": map[string]interface{}{"c": 2, "d": 3},
+		"e": []interface{}{1, 2},
+		"f": "removed",
+	}
+	v2 := map[string]interface{}{
+		"b": map[string]interface{}{"c": 20, "x": "y"},
+		"e": []interface{}{3},
+		"f": nil,
+	}
+	equal(t, map[string]interface{}{
+		"a": 1,
+		"b": map[string]interface{}{"c": 20, "d": 3, "x": "y"},
+		"e": []interface{}{3},
+	}, merge(v1, v2))
+	// the merged values are left untouched.
+	equal(t, map[string]interface{}{"c": 2, "d": 3}, v1["b"])
+	equal(t, "removed", v1["f"])
+
+	equal(t, "scalar", merge(v1, "scalar"))
+	equal(t, v2, merge(nil, v2))
+}
+
+func TestNormalize(t *testing.T) {
+	equal(t, map[string]interface{}{
+		"m": map[string]interface{}{"1": "one"},
+		"s": []interface{}{"a", "b"},
+		"a": []interface{}{1, 2},
+		"b": []byte("raw"),
+	}, normalize(map[interface{}]interface{}{
+		"m": map[int]string{1: "one"},
+		"s": []string{"a", "b"},
+		"a": [2]int{1, 2},
+		"b": []byte("raw"),
+	}))
+}
+
+func TestSetPaths(t *testing.T) {
+	c := New()
+	c.SetStore(map[string]interface{}{
+		"list": []interface{}{"a", "b", "c"},
+		"name": "app",
+	})
+	store := c.GetStore()
+
+	if err := c.Set("list.1", "B"); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, []interface{}{"a", "B", "c"}, c.Get("list"))
+	if err := c.Set("list.3", "d"); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, []interface{}{"a", "B", "c", "d"}, c.Get("list"))
+	if err := c.Set("list.9", "x"); err == nil {
+		t.Error("Expected an error for an out of bound index")
+	}
+	if err := c.Set("list.x", "x"); err == nil {
+		t.Error("Expected an error for an invalid index")
+	}
+	if err := c.Set("list.0.name", "x"); err == nil {
+		t.Error("Expected an error for setting through a scalar")
+	} else {
+		equal(t, "list.0.name", err.(*ConfigKeyError).Key)
+	}
+	if err := c.Set("a.b.c", 1); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, map[string]interface{}{"b": map[string]interface{}{"c": 1}}, c.Get("a"))
+	if err := c.Set("map", map[string]int{"x": 1}); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, 1, c.Get("map.x"))
+
+	// the previous store is never modified.
+	equal(t, map[string]interface{}{
+		"list": []interface{}{"a", "b", "c"},
+		"name": "app",
+	}, store)
+}
+
+func BenchmarkSet(b *testing.B) {
+	c := New()
+	if err := c.Load("./testdata/app.json"); err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c.Set("ext.email", "new@example.com")
+	}
+}
+
+func TestRemove(t *testing.T) {
+	data := map[string]interface{}{
+		"a": map[string]interface{}{"b": map[string]interface{}{"c": 1}, "d": 2},
+		"e": map[string]interface{}{},
+	}
+	r, ok := remove(data, []string{"a", "b", "c"})
+	equal(t, true, ok)
+	equal(t, map[string]interface{}{"a": map[string]interface{}{"d": 2}, "e": map[string]interface{}{}}, r)
+	equal(t, 1, data["a"].(map[string]interface{})["b"].(map[string]interface{})["c"])
+
+	_, ok = remove(data, []string{"e", "x"})
+	equal(t, false, ok)
+	r, ok = remove(data, []string{"e"})
+	equal(t, true, ok)
+	equal(t, 1, len(r.(map[string]interface{})))
+}
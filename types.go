@@ -0,0 +1,103 @@
+package cconf
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// DeclareTypes declares the types of configuration values by example, like
+//
+//	c.DeclareTypes(map[string]interface{}{"port": 0, "timeout": time.Duration(0), "hosts": []string{}})
+//
+// Declared values are converted once, whenever they are loaded or set, so that the typed getters
+// only need a type assertion and misconfigurations are reported by Load and Set with their key.
+// See convertValue for the conversion rules; the converted value replaces the loaded one in the store.
+// Declared slices and maps are stored as []interface{} and map[string]interface{} holding the
+// converted elements, so that "hosts.0" still addresses the first element of "hosts".
+//
+// The declared types are applied to the current store immediately; if a value cannot be
+// converted, an error is returned and neither the declarations nor the store are changed.
+func (c *Conf) DeclareTypes(types map[string]interface{}) error {
+	c.mu.Lock()
+	defer c.unlock()
+	if err := c.errFrozen(); err != nil {
+		return err
+	}
+	declared := make(map[string]reflect.Type, len(c.declared)+len(types))
+	for k, t := range c.declared {
+		declared[k] = t
+	}
+	for k, v := range types {
+		declared[k] = reflect.TypeOf(v)
+	}
+
+	old := c.declared
+	c.declared = declared
+	store, err := c.applyTypes(c.snap.Load().store, "")
+	if err != nil {
+		c.declared = old
+		return err
+	}
+	c.reset(store)
+	return nil
+}
+
+// applyTypesLenient is like applyTypes, but keeps values that cannot be converted as they are.
+// The caller must hold c.mu.
+func (c *Conf) applyTypesLenient(store interface{}) interface{} {
+	for k := range c.declared {
+		if s, err := c.convertTypes(store, k); err == nil {
+			store = s
+		}
+	}
+	return store
+}
+
+// applyTypes converts the values of the store to their declared types and checks them against
+// their expected types, see ExpectTypes. If prefix is not empty, only the keys at or below the
+// prefix are converted and checked. The caller must hold c.mu.
+func (c *Conf) applyTypes(store interface{}, prefix string) (interface{}, error) {
+	store, err := c.convertTypes(store, prefix)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.checkTypes(store, prefix); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+// convertTypes converts the values of the store to their declared types.
+// If prefix is not empty, only the declared keys at, below or above the prefix, like a declared
+// slice holding the element at the prefix, are converted. The caller must hold c.mu.
+func (c *Conf) convertTypes(store interface{}, prefix string) (interface{}, error) {
+	keys := make([]string, 0, len(c.declared))
+	for k := range c.declared {
+		if prefix == "" || c.related(k, prefix) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		val, ok := walk(store, k, c.Separator)
+		if !ok {
+			continue
+		}
+		t := c.declared[k]
+		if reflect.TypeOf(val) == t {
+			continue
+		}
+		cv, err := convertValue(val, t)
+		if err != nil {
+			return nil, &ConfigValueError{Key: k, Message: err.Error(), Err: ErrTypeMismatch, Expected: t.String(), Actual: val}
+		}
+		// slices and maps are stored with their converted elements like loaded ones, so that
+		// the elements can be looked up and set by their keys.
+		if store, err = c.set(store, strings.Split(k, c.Separator), 0, normalize(cv)); err != nil {
+			return nil, err
+		}
+	}
+	return store, nil
+}
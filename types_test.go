@@ -0,0 +1,114 @@
+package cconf
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDeclareTypes(t *testing.T) {
+	c := New()
+	err := c.DeclareTypes(map[string]interface{}{
+		"http.port":    0,
+		"http.timeout": time.Duration(0),
+		"http.hosts":   []string{},
+		"http.ratio":   float64(0),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.LoadReader("json", strings.NewReader(`{"http": {"port": 8080, "timeout": "1m30s", "hosts": ["a", "b"], "ratio": 1}}`)); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, 8080, c.Get("http.port"))
+	equal(t, 90*time.Second, c.Get("http.timeout"))
+	equal(t, []interface{}{"a", "b"}, c.Get("http.hosts"))
+	equal(t, 1.0, c.Get("http.ratio"))
+
+	// defaults only apply to missing values.
+	equal(t, 8080, c.GetInt("http.port", 80))
+	equal(t, time.Second, c.Get("http.missing", time.Second))
+	equal(t, 90*time.Second, c.Get("http.timeout", time.Second))
+
+	if err := c.Set("http.timeout", "2s"); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, 2*time.Second, c.Get("http.timeout"))
+	if err := c.Set("http", map[string]interface{}{"port": 9090.0}); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, 9090, c.Get("http.port"))
+
+	if err := c.Set("http.timeout", 5*time.Second); err != nil {
+		t.Fatal(err)
+	}
+	var d time.Duration
+	if err := c.Populate(&d, "http.timeout"); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, 5*time.Second, d)
+}
+
+func TestDeclareTypesFailures(t *testing.T) {
+	c := New()
+	c.DeclareTypes(map[string]interface{}{"http.port": 0})
+	before := c.GetStore()
+
+	err := c.LoadReader("json", strings.NewReader(`{"http": {"port": "8080"}}`))
+	if err == nil {
+		t.Fatal("Expected a type error")
+	}
+	equal(t, "http.port", err.(*ConfigValueError).Key)
+	equal(t, before, c.GetStore())
+
+	if err := c.LoadReader("json", strings.NewReader(`{"http": {"port": 80.5}}`)); err == nil {
+		t.Error("Expected an error for a fractional port")
+	}
+	if err := c.Set("http.port", true); err == nil {
+		t.Error("Expected a type error")
+	}
+	equal(t, nil, c.Get("http.port"))
+
+	c.SetStore(map[string]interface{}{"timeout": "soon"})
+	if err := c.DeclareTypes(map[string]interface{}{"timeout": time.Duration(0)}); err == nil {
+		t.Error("Expected an error for an invalid duration")
+	}
+	equal(t, "soon", c.Get("timeout"))
+}
+
+func TestDeclareTypesContainers(t *testing.T) {
+	c := New()
+	err := c.DeclareTypes(map[string]interface{}{
+		"hosts":    []string{},
+		"limits":   map[string]int{},
+		"timeouts": []time.Duration{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.LoadReader("json", strings.NewReader(`{"hosts": ["a", "b"], "limits": {"x": 1}, "timeouts": ["1s"]}`)); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, "b", c.Get("hosts.1"))
+	equal(t, 1, c.Get("limits.x"))
+	equal(t, time.Second, c.Get("timeouts.0"))
+
+	if err := c.Set("hosts.2", "c"); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, []interface{}{"a", "b", "c"}, c.Get("hosts"))
+	if err := c.Set("limits.y", 2.0); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, map[string]interface{}{"x": 1, "y": 2}, c.Get("limits"))
+
+	// Walk sees the same values as Get.
+	c.Walk(func(key string, v interface{}) bool {
+		equal(t, c.Get(key), v)
+		return true
+	})
+	if err := c.Set("timeouts.0", "soon"); err == nil {
+		t.Error("Expected a type error")
+	}
+}
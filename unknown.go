@@ -0,0 +1,76 @@
+package cconf
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// CheckUnknownKeys checks the keys of the store (see Flatten) against the allowed keys, to
+// catch misspelled keys like "http.prot", which are otherwise silently ignored. An allowed key
+// may be a pattern: "*" matches any single key segment, and a trailing "*" matches the rest
+// of the key, so that "plugins.*" allows every key below plugins, like a section of dynamic
+// keys, and "feature_*" allows "feature_a". It returns a ConfigKeyError for the first key that
+// is not allowed, or a *MultiError of one for every such key in CollectAll mode (see
+// ValidationMode), sorted by key, or nil. The errors ask whether a close allowed
+// key was meant, like `did you mean "http.port"?`, or else name the nearest allowed key.
+func (c *Conf) CheckUnknownKeys(allowed []string) error {
+	return c.checkUnknownKeys(c.snap.Load().store, allowed)
+}
+
+// SetAllowedKeys sets the allowed keys checked by CheckUnknownKeys on every reload, which
+// fails like the reload validator (see SetReloadValidator) if the reloaded configuration has
+// unknown keys. Nil disables the check.
+func (c *Conf) SetAllowedKeys(allowed []string) {
+	c.mu.Lock()
+	defer c.unlock()
+	c.allowedKeys = append([]string(nil), allowed...)
+}
+
+// checkUnknownKeys checks the keys of the store against the allowed keys, see CheckUnknownKeys.
+func (c *Conf) checkUnknownKeys(store interface{}, allowed []string) error {
+	var errs []error
+	keys := make([]string, 0)
+	for k := range Flatten(store, c.Separator) {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if c.allowed(k, allowed) {
+			continue
+		}
+		msg := "unknown key"
+		if s := c.suggest(k, allowed); s != "" {
+			msg += s
+		} else if near, _ := nearest(k, allowed); near != "" {
+			msg = fmt.Sprintf("unknown key, the nearest allowed key is %q", near)
+		}
+		errs = append(errs, &ConfigKeyError{Key: k, Message: msg})
+		if c.failFast() {
+			break
+		}
+	}
+	return c.aggregate(errs)
+}
+
+// allowed reports whether the key matches any of the allowed keys, see CheckUnknownKeys.
+func (c *Conf) allowed(key string, allowed []string) bool {
+	segs := strings.Split(key, c.Separator)
+	for _, pattern := range allowed {
+		psegs := strings.Split(pattern, c.Separator)
+		last := len(psegs) - 1
+		if len(segs) < len(psegs) || !matchSegments(psegs[:last], segs) {
+			continue
+		}
+		p, seg := psegs[last], segs[last]
+		switch {
+		case strings.HasSuffix(p, "*"):
+			if strings.HasPrefix(seg, strings.TrimSuffix(p, "*")) {
+				return true
+			}
+		case len(segs) == len(psegs) && p == seg:
+			return true
+		}
+	}
+	return false
+}
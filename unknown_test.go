@@ -0,0 +1,65 @@
+package cconf
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckUnknownKeys(t *testing.T) {
+	c := New()
+	c.ValidationMode = CollectAll
+	err := c.LoadReader("json", strings.NewReader(`{
+		"http": {"prot": 8080, "host": "localhost"},
+		"plugins": {"auth": {"enabled": true}, "cache": {"size": 10}},
+		"servers": [{"name": "a"}, {"name": "b"}],
+		"feature_x": true
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	allowed := []string{"http.port", "http.host", "plugins.*", "servers.*.name", "feature_*"}
+	err = c.CheckUnknownKeys(allowed)
+	var me *MultiError
+	if !errors.As(err, &me) || len(me.Errors) != 1 {
+		t.Fatalf("Expected one unknown key, got %v", err)
+	}
+	var ke *ConfigKeyError
+	if !errors.As(me.Errors[0], &ke) || ke.Key != "http.prot" {
+		t.Fatalf("Expected http.prot to be reported, got %v", me.Errors[0])
+	}
+	equal(t, `unknown key, did you mean "http.port"?`, ke.Message)
+
+	if err := c.CheckUnknownKeys(append(allowed, "http.prot")); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSetAllowedKeys(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "app.json")
+	if err := os.WriteFile(file, []byte(`{"port": 8080}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	c := New()
+	if err := c.Load(file); err != nil {
+		t.Fatal(err)
+	}
+	c.SetAllowedKeys([]string{"port"})
+	if err := os.WriteFile(file, []byte(`{"port": 8080, "prot": 80}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	err := c.Reload()
+	var re *ReloadError
+	if !errors.As(err, &re) || re.Source != file {
+		t.Fatalf("Expected a reload error noting the source, got %v", err)
+	}
+	equal(t, nil, c.Get("prot"))
+
+	c.SetAllowedKeys(nil)
+	if err := c.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, 80, c.GetInt("prot"))
+}
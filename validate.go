@@ -0,0 +1,432 @@
+package cconf
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ValidationMode tells how validations report problems, see Conf.ValidationMode.
+type ValidationMode int
+
+// Validation modes.
+const (
+	// FailFast stops at the first problem and reports it alone, like a reload gate.
+	FailFast ValidationMode = iota
+	// CollectAll reports every problem, like CI-style validation: Validate, ValidateStruct,
+	// CheckUnknownKeys, ExpectTypes and Populate aggregate them in a *MultiError, while
+	// ValidateSchema and RequireKeys list them in their errors.
+	CollectAll
+)
+
+// failFast reports whether validations stop at their first problem, see ValidationMode.
+func (c *Conf) failFast() bool {
+	return c.ValidationMode == FailFast
+}
+
+// aggregate returns the errors of a validation as a *MultiError, or the first error alone
+// in FailFast mode, or nil without errors.
+func (c *Conf) aggregate(errs []error) error {
+	switch {
+	case len(errs) == 0:
+		return nil
+	case c.failFast():
+		return errs[0]
+	}
+	return &MultiError{Errors: errs}
+}
+
+// MultiError aggregates several errors, like the problems found by ValidateStruct.
+// errors.Is and errors.As look into each of them.
+type MultiError struct {
+	Errors []error
+}
+
+// Error returns the error message represented by MultiError
+func (e *MultiError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		msgs[i] = err.Error()
+	}
+	if len(msgs) == 1 {
+		return msgs[0]
+	}
+	return fmt.Sprintf("%d errors: %s", len(msgs), strings.Join(msgs, "; "))
+}
+
+// Unwrap returns the aggregated errors.
+func (e *MultiError) Unwrap() []error {
+	return e.Errors
+}
+
+// ValidateStruct validates the configuration at the optional key against the type of the
+// prototype, a struct or pointer to struct, without populating anything: it reports what
+// Populate would fail on, the values that cannot configure their fields, the keys without
+// fields and the missing required fields (see Populate for the tags), and also the other
+// problems, including the violations of the constraints of the tags. It returns the first
+// problem, or a *MultiError of ConfigKeyErrors and ConfigValueErrors sorted by key in
+// CollectAll mode (see ValidationMode), or nil.
+// Use it in the reload validator to reject configurations the program could not populate:
+//
+//	c.SetReloadValidator(func(candidate *cconf.Conf) error {
+//		return candidate.ValidateStruct(Config{})
+//	})
+//
+// Interface fields are checked against the types registered with Register, whose providers
+// are not called: the fields of types provided as interfaces are not checked.
+func (c *Conf) ValidateStruct(prototype interface{}, key ...string) error {
+	t := reflect.TypeOf(prototype)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return fmt.Errorf("ValidateStruct requires a struct, got %T", prototype)
+	}
+	v := &structValidator{populator: populator{c: c}}
+	config, f, err := v.lookup(key...)
+	if err != nil {
+		return c.aggregate([]error{err})
+	}
+	v.validate(t, config, f)
+	sort.SliceStable(v.errs, func(i, j int) bool { return v.errs[i].Key < v.errs[j].Key })
+	errs := make([]error, len(v.errs))
+	for i, err := range v.errs {
+		c.annotate(err)
+		errs[i] = err
+	}
+	return c.aggregate(errs)
+}
+
+// structValidator collects the problems of populating a type, see ValidateStruct.
+type structValidator struct {
+	populator
+	errs []*ConfigValueError
+}
+
+// fail records the problem at the key.
+func (v *structValidator) fail(key, msg string) {
+	v.record(&ConfigValueError{Key: key, Message: msg})
+}
+
+// record records the error.
+func (v *structValidator) record(err *ConfigValueError) {
+	err.Key = strings.Trim(err.Key, ".")
+	v.errs = append(v.errs, err)
+}
+
+// done reports whether the validation stops, at its first problem in FailFast mode.
+func (v *structValidator) done() bool {
+	return len(v.errs) > 0 && v.c.failFast()
+}
+
+// check records the violations of the constraints of the tag by the value at the key.
+func (v *structValidator) check(tag fieldTag, val reflect.Value, key string) {
+	for _, err := range checkConstraints(tag, val, key) {
+		v.record(err)
+	}
+}
+
+// validate validates the configuration at the key against the type, like populate.
+func (v *structValidator) validate(t reflect.Type, config reflect.Value, key string) {
+	if v.done() {
+		return
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	for config.Kind() == reflect.Interface || config.Kind() == reflect.Ptr {
+		config = config.Elem()
+	}
+	if !config.IsValid() {
+		return
+	}
+
+	switch config.Kind() {
+	case reflect.Array, reflect.Slice:
+		if t.Kind() == reflect.Interface && t.NumMethod() == 0 {
+			return
+		}
+		if t.Kind() != reflect.Array && t.Kind() != reflect.Slice {
+			v.record(mismatch(key, config, t))
+			return
+		}
+		n := config.Len()
+		if t.Kind() == reflect.Array && n > t.Len() {
+			// the extra elements are dropped.
+			n = t.Len()
+		}
+		for i := 0; i < n; i++ {
+			v.validate(t.Elem(), config.Index(i), key+"."+strconv.Itoa(i))
+		}
+	case reflect.Map:
+		switch t.Kind() {
+		case reflect.Interface:
+			v.validateInterface(t, config, key)
+		case reflect.Struct:
+			v.validateStruct(t, config, key)
+		case reflect.Map:
+			for _, k := range config.MapKeys() {
+				v.validate(t.Elem(), mapIndex(config, k), key+"."+k.String())
+			}
+		default:
+			v.record(mismatch(key, config, t))
+		}
+	default:
+		if err := v.populateScalar(reflect.New(t).Elem(), config, key); err != nil {
+			v.record(err.(*ConfigValueError))
+		}
+	}
+}
+
+// validateStruct validates the configuration at the key against the struct type, like populateStruct.
+func (v *structValidator) validateStruct(t reflect.Type, config reflect.Value, key string) {
+	set := make(map[string]bool)
+	for _, k := range config.MapKeys() {
+		if k.String() == typeKey.String() {
+			continue
+		}
+		if v.done() {
+			return
+		}
+		fkey := key + "." + k.String()
+		f, ok := fieldByKey(t, k.String())
+		if !ok {
+			if name, ok := v.secretFile(t, config, k); ok {
+				set[name] = true
+				continue
+			}
+			v.fail(fkey, v.c.fieldNotFound(k.String(), t))
+			continue
+		}
+		if f.PkgPath != "" {
+			v.fail(fkey, fmt.Sprintf("field %v cannot be set", k.String()))
+			continue
+		}
+		if mapIndex(config, k).IsValid() {
+			set[k.String()] = true
+		}
+		n := len(v.errs)
+		v.validate(f.Type, mapIndex(config, k), fkey)
+		if len(v.errs) == n && len(f.tag.constraints) > 0 {
+			// the constraints apply to the converted value.
+			fv := reflect.New(f.Type).Elem()
+			if err := v.populate(fv, mapIndex(config, k), fkey); err == nil {
+				v.check(f.tag, fv, fkey)
+			}
+		}
+	}
+
+	for _, f := range structFields(t) {
+		if f.Anonymous || f.PkgPath != "" || set[f.tag.name] || v.done() {
+			continue
+		}
+		fkey := key + "." + f.tag.name
+		switch {
+		case f.tag.hasDef:
+			fv := indirect(reflect.New(f.Type).Elem())
+			if err := parseEnvValue(f.tag.def, fv); err != nil {
+				v.fail(fkey, fmt.Sprintf("invalid default %q: %v", f.tag.def, err))
+			} else {
+				v.check(f.tag, fv, fkey)
+			}
+		case f.tag.required:
+			v.record(&ConfigValueError{Key: fkey, Message: "the required value is missing", Err: ErrKeyNotFound, Expected: f.Type.String()})
+		}
+	}
+}
+
+// secretFile returns the name of the field of the struct type the secret file at the key k
+// configures, like populateSecretFile, without reading the file.
+func (v *structValidator) secretFile(t reflect.Type, config, k reflect.Value) (string, bool) {
+	name := strings.TrimSuffix(k.String(), v.s.fileSuffix)
+	if v.s.fileSuffix == "" || name == k.String() || mapIndex(config, reflect.ValueOf(name)).IsValid() {
+		return "", false
+	}
+	f, ok := fieldByKey(t, name)
+	return name, ok && f.PkgPath == ""
+}
+
+// validateInterface validates the configuration at the key against the interface type, like populateInterface.
+func (v *structValidator) validateInterface(t reflect.Type, config reflect.Value, key string) {
+	if t.NumMethod() == 0 {
+		return
+	}
+	tk := mapIndex(config, typeKey)
+	if !tk.IsValid() {
+		v.fail(key, "missing the type element")
+		return
+	}
+	if tk.Kind() != reflect.String {
+		v.fail(key, "type must be a string")
+		return
+	}
+
+	v.c.typesMu.RLock()
+	builder, ok := v.c.types[tk.String()]
+	v.c.typesMu.RUnlock()
+	if !ok {
+		v.record(&ConfigValueError{Key: key, Message: fmt.Sprintf("type %q is unknown", tk.String()), Err: ErrUnknownType, Expected: t.String(), Actual: tk.String()})
+		return
+	}
+	st := builder.Type().Out(0)
+	for st.Kind() == reflect.Ptr {
+		st = st.Elem()
+	}
+	if st.Kind() == reflect.Interface {
+		return
+	}
+	if !reflect.PointerTo(st).Implements(t) {
+		v.record(&ConfigValueError{Key: key, Message: fmt.Sprintf("%v does not implement %v", st, t), Err: ErrTypeMismatch, Expected: t.String(), Actual: config.Interface()})
+		return
+	}
+	if st.Kind() != reflect.Struct {
+		v.record(mismatch(key, config, st))
+		return
+	}
+	v.validateStruct(st, config, key)
+}
+
+// StructValidationError describes a populated struct rejected by a struct validator, see AddStructValidator.
+type StructValidationError struct {
+	Key string // the key of the configuration of the struct
+	Err error  // the error of the validator
+}
+
+// Error returns the error message represented by StructValidationError
+func (e *StructValidationError) Error() string {
+	return fmt.Sprintf("%q points to an invalid configuration: %v", e.Key, e.Err)
+}
+
+// Unwrap returns the error of the validator.
+func (e *StructValidationError) Unwrap() error {
+	return e.Err
+}
+
+// AddStructValidator registers a function validating the structs of the type of the prototype,
+// a struct or pointer to struct, for constraints across fields like "MinConns must not exceed
+// MaxConns". Populate calls the function with every struct of the type it populates, nested
+// ones and elements of slices and maps included, after the fields are populated and the
+// constraints of their tags checked, and reports a *StructValidationError, with the key of
+// the struct, for every error, like the other problems of Populate. For example:
+//
+//	c.AddStructValidator(Pool{}, func(v interface{}) error {
+//		if p := v.(Pool); p.MinConns > p.MaxConns {
+//			return errors.New("MinConns must not exceed MaxConns")
+//		}
+//		return nil
+//	})
+//
+// The function gets the struct as a value, not a pointer. ValidateStruct does not call it.
+func (c *Conf) AddStructValidator(prototype interface{}, fn func(v interface{}) error) error {
+	t := reflect.TypeOf(prototype)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return fmt.Errorf("AddStructValidator requires a struct, got %T", prototype)
+	}
+	c.typesMu.Lock()
+	defer c.typesMu.Unlock()
+	if c.structValidators == nil {
+		c.structValidators = make(map[reflect.Type][]func(v interface{}) error)
+	}
+	validators := c.structValidators[t]
+	c.structValidators[t] = append(validators[:len(validators):len(validators)], fn)
+	return nil
+}
+
+// keyValidator is a validator of the values at the keys matching a pattern, see AddValidator.
+type keyValidator struct {
+	pattern string
+	fn      func(v interface{}) error
+}
+
+// AddValidator registers a function validating the value at the key, which runs on Validate
+// and on every reload, which it fails like the reload validator (see SetReloadValidator).
+// The key may be a pattern like those of MarkSecret: "db.*" validates every value directly
+// below db. The function gets the effective values, after merging and resolving references,
+// which it must not modify, and runs only for keys with values: see UnmatchedValidators.
+func (c *Conf) AddValidator(key string, fn func(v interface{}) error) {
+	c.mu.Lock()
+	defer c.unlock()
+	c.validators = append(c.validators[:len(c.validators):len(c.validators)], keyValidator{key, fn})
+}
+
+// Validate runs the validators registered with AddValidator on the store and returns a
+// ConfigValueError naming the key of the first failure, or a *MultiError of one for every
+// failure in CollectAll mode (see ValidationMode), or nil.
+func (c *Conf) Validate() error {
+	c.mu.Lock()
+	store, validators := c.snap.Load().store, c.validators
+	c.mu.Unlock()
+	return c.validate(store, validators)
+}
+
+// UnmatchedValidators returns the keys of the validators registered with AddValidator that
+// match no value of the store, like misspelled keys, in the order of registration.
+func (c *Conf) UnmatchedValidators() []string {
+	c.mu.Lock()
+	store, validators := c.snap.Load().store, c.validators
+	c.mu.Unlock()
+	matched := make([]bool, len(validators))
+	c.visit(store, "", func(key string, _ interface{}) {
+		for i, v := range validators {
+			matched[i] = matched[i] || c.isSecret(key, []string{v.pattern})
+		}
+	})
+	var keys []string
+	for i, v := range validators {
+		if !matched[i] {
+			keys = append(keys, v.pattern)
+		}
+	}
+	return keys
+}
+
+// validate runs the validators on the store, see Validate.
+func (c *Conf) validate(store interface{}, validators []keyValidator) error {
+	if len(validators) == 0 {
+		return nil
+	}
+	var errs []*ConfigValueError
+	c.visit(store, "", func(key string, val interface{}) {
+		for _, v := range validators {
+			if !c.isSecret(key, []string{v.pattern}) || len(errs) > 0 && c.failFast() {
+				continue
+			}
+			if err := v.fn(val); err != nil {
+				errs = append(errs, &ConfigValueError{Key: key, Message: err.Error()})
+			}
+		}
+	})
+	sort.SliceStable(errs, func(i, j int) bool { return errs[i].Key < errs[j].Key })
+	all := make([]error, len(errs))
+	for i, err := range errs {
+		all[i] = err
+	}
+	return c.aggregate(all)
+}
+
+// visit calls fn with every value below the node at the key, maps and lists included.
+func (c *Conf) visit(node interface{}, key string, fn func(key string, val interface{})) {
+	switch n := node.(type) {
+	case map[string]interface{}:
+		for k, v := range n {
+			if v != nil {
+				fk := c.join(key, k)
+				fn(fk, v)
+				c.visit(v, fk, fn)
+			}
+		}
+	case []interface{}:
+		for i, v := range n {
+			if v != nil {
+				fk := c.join(key, strconv.Itoa(i))
+				fn(fk, v)
+				c.visit(v, fk, fn)
+			}
+		}
+	}
+}
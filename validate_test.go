@@ -0,0 +1,327 @@
+package cconf
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type validatedConfig struct {
+	Server  serverConfig
+	Plugins []plugin
+	Weights map[string]float64
+	Level   string `cconf:"level,default=info"`
+	Tags    [2]string
+	Token   string `cconf:"token,required"`
+}
+
+func validationErrors(t *testing.T, err error) map[string]string {
+	t.Helper()
+	var me *MultiError
+	if !errors.As(err, &me) {
+		t.Fatalf("Expected a *MultiError, got %v", err)
+	}
+	msgs := make(map[string]string)
+	for _, err := range me.Errors {
+		var ve *ConfigValueError
+		if !errors.As(err, &ve) {
+			t.Fatalf("Expected a *ConfigValueError, got %v", err)
+		}
+		msgs[ve.Key] = ve.Message
+	}
+	return msgs
+}
+
+func TestValidateStruct(t *testing.T) {
+	c := New()
+	c.ValidationMode = CollectAll
+	c.Register("echo", func() *echoPlugin { return &echoPlugin{} })
+	c.SetStore(map[string]interface{}{
+		"Server":  map[string]interface{}{"Host": "localhost", "Port": 8080, "Limits": map[string]interface{}{"Conns": 10}},
+		"Plugins": []interface{}{map[string]interface{}{"type": "echo", "Message": "hello"}},
+		"Weights": map[string]interface{}{"a": 0.5},
+		"Tags":    []interface{}{"x", "y", "dropped"},
+		"token":   "secret",
+	})
+	if err := c.ValidateStruct(validatedConfig{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.ValidateStruct(&serverConfig{}, "Server"); err != nil {
+		t.Fatal(err)
+	}
+
+	c.SetStore(map[string]interface{}{
+		"Server": map[string]interface{}{"Host": "localhost", "Port": "8080", "Debug": true},
+		"Plugins": []interface{}{
+			map[string]interface{}{"type": "echo", "Message": "hello"},
+			map[string]interface{}{"type": "missing"},
+			map[string]interface{}{"Message": "untyped"},
+		},
+		"Weights": map[string]interface{}{"a": 0.5, "b": []interface{}{1}},
+		"Tags":    "x",
+	})
+	msgs := validationErrors(t, c.ValidateStruct(validatedConfig{}))
+	equal(t, map[string]string{
+		"Server.Port":  "string cannot be used to configure int",
+		"Server.Debug": "field Debug not found in struct cconf.serverConfig",
+		"Plugins.1":    `type "missing" is unknown`,
+		"Plugins.2":    "missing the type element",
+		"Weights.b":    "[]interface {} cannot be used to configure float64",
+		"Tags":         "string cannot be used to configure [2]string",
+		"token":        "the required value is missing",
+	}, msgs)
+	// the errors are sorted by key.
+	var me *MultiError
+	errors.As(c.ValidateStruct(validatedConfig{}), &me)
+	if !strings.HasPrefix(me.Errors[0].Error(), `"Plugins.1"`) {
+		t.Errorf("Expected the errors sorted by key, got %v", me)
+	}
+
+	// every problem Populate reports is found by the validation.
+	var s validatedConfig
+	err := c.Populate(&s)
+	var ve *ConfigValueError
+	if !errors.As(err, &ve) || msgs[strings.Trim(ve.Key, ".")] != ve.Message {
+		t.Errorf("Expected the Populate error %v among the validation errors", err)
+	}
+
+	if err := c.ValidateStruct(serverConfig{}, "missing"); err == nil {
+		t.Error("Expected an error for a missing key")
+	}
+	if err := c.ValidateStruct(42); err == nil {
+		t.Error("Expected an error for a non-struct prototype")
+	}
+}
+
+func TestValidateStructDefaults(t *testing.T) {
+	type defaults struct {
+		Port int `cconf:"port,default=http"`
+	}
+	c := New()
+	c.ValidationMode = CollectAll
+	c.SetStore(map[string]interface{}{})
+	msgs := validationErrors(t, c.ValidateStruct(defaults{}))
+	if !strings.HasPrefix(msgs["port"], `invalid default "http"`) {
+		t.Errorf("Expected an error for the invalid default, got %v", msgs)
+	}
+}
+
+func TestValidateStructReload(t *testing.T) {
+	c := New()
+	if err := c.Load("./testdata/server.json"); err != nil {
+		t.Fatal(err)
+	}
+	c.SetReloadValidator(func(candidate *Conf) error {
+		return candidate.ValidateStruct(serverConfig{}, "server")
+	})
+	err := c.Reload()
+	var re *ReloadError
+	if !errors.As(err, &re) || re.Source != "./testdata/server.json" {
+		t.Fatalf("Expected a reload error noting the source, got %v", err)
+	}
+	var ve *ConfigValueError
+	if !errors.As(err, &ve) || ve.Key != "server.Debug" {
+		t.Errorf("Expected the unknown key server.Debug, got %v", err)
+	}
+}
+
+func TestAddValidator(t *testing.T) {
+	c := New()
+	c.ValidationMode = CollectAll
+	c.Interpolate = true
+	err := c.LoadReader("json", strings.NewReader(`{
+		"http": {"port": 8080, "host": "${db.host}"},
+		"db": {"host": "localhost", "user": "", "pass": ""}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := func(v interface{}) error {
+		if n, ok := toFloat(v); !ok || n < 1 || n > 65535 {
+			return fmt.Errorf("invalid port %v", v)
+		}
+		return nil
+	}
+	nonEmpty := func(v interface{}) error {
+		if s, _ := v.(string); s == "" {
+			return errors.New("must not be empty")
+		}
+		return nil
+	}
+	var hosts []interface{}
+	c.AddValidator("http.port", port)
+	c.AddValidator("http.host", func(v interface{}) error {
+		hosts = append(hosts, v)
+		return nil
+	})
+	if err := c.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	// the validators get the effective values.
+	equal(t, []interface{}{"localhost"}, hosts)
+
+	c.AddValidator("db.*", nonEmpty)
+	c.AddValidator("cache.port", port)
+	c.Set("http.port", 0)
+	msgs := validationErrors(t, c.Validate())
+	equal(t, map[string]string{
+		"http.port": "invalid port 0",
+		"db.user":   "must not be empty",
+		"db.pass":   "must not be empty",
+	}, msgs)
+	equal(t, []string{"cache.port"}, c.UnmatchedValidators())
+}
+
+func TestAddValidatorReload(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "app.json")
+	if err := os.WriteFile(file, []byte(`{"port": 8080}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	c := New()
+	if err := c.Load(file); err != nil {
+		t.Fatal(err)
+	}
+	c.AddValidator("port", func(v interface{}) error {
+		if n, _ := toFloat(v); n < 1 {
+			return errors.New("the port must be positive")
+		}
+		return nil
+	})
+	if err := os.WriteFile(file, []byte(`{"port": -1}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	err := c.Reload()
+	var re *ReloadError
+	if !errors.As(err, &re) || re.Source != file {
+		t.Fatalf("Expected a reload error noting the source, got %v", err)
+	}
+	equal(t, 8080, c.GetInt("port"))
+}
+
+type poolConfig struct {
+	MinConns int
+	MaxConns int
+}
+
+type clusterConfig struct {
+	Default poolConfig
+	Pools   []poolConfig
+	ByName  map[string]*poolConfig
+}
+
+func TestAddStructValidator(t *testing.T) {
+	c := New()
+	c.ValidationMode = CollectAll
+	errInvalid := errors.New("MinConns must not exceed MaxConns")
+	var validated []poolConfig
+	err := c.AddStructValidator(&poolConfig{}, func(v interface{}) error {
+		p := v.(poolConfig)
+		validated = append(validated, p)
+		if p.MinConns > p.MaxConns {
+			return errInvalid
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.SetStore(map[string]interface{}{
+		"Default": map[string]interface{}{"MinConns": 1, "MaxConns": 10},
+		"Pools": []interface{}{
+			map[string]interface{}{"MinConns": 1, "MaxConns": 5},
+			map[string]interface{}{"MinConns": 8, "MaxConns": 5},
+		},
+		"ByName": map[string]interface{}{"main": map[string]interface{}{"MinConns": 3, "MaxConns": 2}},
+	})
+	var s clusterConfig
+	err = c.Populate(&s)
+	equal(t, 4, len(validated))
+	var me *MultiError
+	if !errors.As(err, &me) || len(me.Errors) != 2 {
+		t.Fatalf("Expected two errors, got %v", err)
+	}
+	keys := make(map[string]bool)
+	for _, err := range me.Errors {
+		var se *StructValidationError
+		if !errors.As(err, &se) || !errors.Is(err, errInvalid) {
+			t.Fatalf("Expected a *StructValidationError, got %v", err)
+		}
+		keys[se.Key] = true
+	}
+	equal(t, map[string]bool{"Pools.1": true, "ByName.main": true}, keys)
+
+	if err := c.Populate(&s.Pools[0], "Pools.0"); err != nil {
+		t.Error(err)
+	}
+	if err := c.AddStructValidator(42, nil); err == nil {
+		t.Error("Expected an error for a non-struct prototype")
+	}
+}
+
+func TestValidationMode(t *testing.T) {
+	type server struct {
+		Host string `cconf:"host,regexp=^[a-z]+$"`
+		Port int    `cconf:"port,max=65535"`
+		TLS  bool   `cconf:"tls"`
+	}
+	// three problems: an invalid host, a port out of range and a string for a bool.
+	store := map[string]interface{}{"host": "Local Host", "port": 70000, "tls": "yes"}
+	schema := []byte(`{"properties": {
+		"host": {"pattern": "^[a-z]+$"},
+		"port": {"maximum": 65535},
+		"tls": {"type": "boolean"}
+	}}`)
+	reject := func(v interface{}) error {
+		return errors.New("invalid")
+	}
+
+	count := func(err error) int {
+		if err == nil {
+			return 0
+		}
+		switch e := err.(type) {
+		case *MultiError:
+			return len(e.Errors)
+		case *SchemaError:
+			return len(e.Violations)
+		case *MissingKeysError:
+			return len(e.Keys)
+		}
+		return 1
+	}
+	for _, test := range []struct {
+		mode ValidationMode
+		want int
+	}{{CollectAll, 3}, {FailFast, 1}} {
+		c := New()
+		c.ValidationMode = test.mode
+		c.SetStore(store)
+		c.AddValidator("*", reject)
+
+		var s server
+		checks := map[string]error{
+			"Populate":         c.Populate(&s),
+			"ValidateStruct":   c.ValidateStruct(s),
+			"ValidateSchema":   c.ValidateSchema(schema),
+			"RequireKeys":      c.RequireKeys("a", "b", "c"),
+			"Validate":         c.Validate(),
+			"CheckUnknownKeys": c.CheckUnknownKeys(nil),
+			"ExpectTypes":      c.ExpectTypes(map[string]string{"host": "int", "port": "string", "tls": "bool"}),
+		}
+		for name, err := range checks {
+			if n := count(err); n != test.want {
+				t.Errorf("%s in mode %v: expected %d problems, got %d: %v", name, test.mode, test.want, n, err)
+			}
+			// the individual errors are typed.
+			var ke *ConfigKeyError
+			var ve *ConfigValueError
+			if !errors.As(err, &ke) && !errors.As(err, &ve) {
+				t.Errorf("%s in mode %v: expected a typed error, got %v", name, test.mode, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package cconf
+
+import (
+	"reflect"
+	"sort"
+	"strconv"
+)
+
+// View is a read-only window into the configuration below a prefix. See Conf.View.
+type View struct {
+	c      *Conf
+	prefix string
+}
+
+// View returns a read-only accessor of the configuration below the prefix (e.g. "tenants.acme"),
+// so that View(p).Get(k) is the same as Get(p + Separator + k), secret files, the environment,
+// lazy providers and the parent of a child Conf included.
+//
+// A View copies nothing: it resolves every call against the live store of the Conf and thus
+// reflects changes immediately. Values of the store do not allocate: the node at the prefix is
+// looked up through the cache, and the key is resolved below it without building the joined
+// key. Only the other sources are looked up with the joined key.
+func (c *Conf) View(prefix string) View {
+	return View{c: c, prefix: prefix}
+}
+
+// Prefix returns the prefix of the view.
+func (v View) Prefix() string {
+	return v.prefix
+}
+
+// lookup returns the configuration value at the specified path below the prefix like the
+// lookup of the joined key, parsing strings from the environment into the type t, if not nil.
+func (v View) lookup(key string, t reflect.Type) (interface{}, bool) {
+	s := v.c.snap.Load()
+	if len(s.lazy) == 0 && s.parent == nil {
+		// values of the store take precedence, so that they need no joined key.
+		if node, ok := v.storeNode(s); ok {
+			if val, ok := walk(node, key, v.c.Separator); ok {
+				if _, str := val.(string); !str || t == nil || len(s.envKeys) == 0 {
+					return val, true
+				}
+			}
+		}
+	}
+	if v.prefix != "" {
+		key = v.prefix + v.c.Separator + key
+	}
+	if t == nil {
+		return s.lookup(key, v.c.Separator)
+	}
+	return s.lookupAs(key, v.c.Separator, t)
+}
+
+// storeNode returns the value of the store at the prefix.
+func (v View) storeNode(s *snapshot) (interface{}, bool) {
+	if v.prefix == "" {
+		return s.store, s.store != nil
+	}
+	return s.lookupStore(v.prefix, v.c.Separator)
+}
+
+// node returns the configuration value at the prefix.
+func (v View) node() (interface{}, bool) {
+	s := v.c.snap.Load()
+	if v.prefix == "" {
+		return s.store, s.store != nil
+	}
+	return s.lookup(v.prefix, v.c.Separator)
+}
+
+// Has reports whether there is a configuration value at the specified path.
+func (v View) Has(key string) bool {
+	_, ok := v.lookup(key, nil)
+	return ok
+}
+
+// Get returns the configuration value at the specified path, see Conf.Get.
+func (v View) Get(key string, def ...interface{}) interface{} {
+	if len(def) == 0 {
+		val, _ := v.lookup(key, nil)
+		return val
+	}
+	val, ok := v.lookup(key, reflect.TypeOf(def[0]))
+	if !ok {
+		return def[0]
+	}
+	return convert(val, def[0])
+}
+
+// GetString returns a string.
+func (v View) GetString(key string, def ...string) string {
+	if val, ok := v.lookup(key, nil); ok {
+		if s, ok := toString(val); ok {
+			return s
+		}
+	}
+	if len(def) > 0 {
+		return def[0]
+	}
+	return ""
+}
+
+// GetInt returns an int
+func (v View) GetInt(key string, def ...int) int {
+	if val, ok := v.lookup(key, intType); ok {
+		if i, ok := toInt(val); ok {
+			return i
+		}
+	}
+	if len(def) > 0 {
+		return def[0]
+	}
+	return 0
+}
+
+// GetBool returns a bool
+func (v View) GetBool(key string, def ...bool) bool {
+	if val, ok := v.lookup(key, boolType); ok {
+		if b, ok := toBool(val); ok {
+			return b
+		}
+	}
+	if len(def) > 0 {
+		return def[0]
+	}
+	return false
+}
+
+// Keys returns the sorted keys of the map at the prefix, or the indexes of the slice at the prefix.
+// Nil is returned for scalars and missing prefixes.
+func (v View) Keys() []string {
+	node, _ := v.node()
+	switch d := node.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(d))
+		for k := range d {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		return keys
+	case []interface{}:
+		keys := make([]string, len(d))
+		for i := range d {
+			keys[i] = strconv.Itoa(i)
+		}
+		return keys
+	}
+	return nil
+}
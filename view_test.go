@@ -0,0 +1,121 @@
+package cconf
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestView(t *testing.T) {
+	c := New()
+	c.SetStore(map[string]interface{}{
+		"tenants": map[string]interface{}{
+			"acme": map[string]interface{}{
+				"name":  "Acme",
+				"limit": 10,
+				"hosts": []interface{}{"a", "b"},
+			},
+		},
+	})
+
+	v := c.View("tenants.acme")
+	equal(t, "tenants.acme", v.Prefix())
+	equal(t, "Acme", v.GetString("name"))
+	equal(t, 10, v.GetInt("limit"))
+	equal(t, "b", v.Get("hosts.1"))
+	equal(t, "def", v.Get("missing", "def"))
+	equal(t, true, v.Has("hosts"))
+	equal(t, false, v.Has("missing"))
+	equal(t, []string{"hosts", "limit", "name"}, v.Keys())
+	equal(t, []string{"0", "1"}, c.View("tenants.acme.hosts").Keys())
+	equal(t, []string{"tenants"}, c.View("").Keys())
+	equal(t, []string(nil), c.View("tenants.other").Keys())
+
+	// changes of the parent are visible immediately.
+	if err := c.Set("tenants.acme.limit", 20); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, 20, v.GetInt("limit"))
+
+	allocs := testing.AllocsPerRun(100, func() {
+		v.GetString("name")
+		v.GetInt("limit")
+		v.Has("hosts.0")
+	})
+	equal(t, float64(0), allocs)
+}
+
+func TestViewLayers(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "password")
+	if err := os.WriteFile(file, []byte("s3cret\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("CCONFTEST_DB_HOST", "db.env")
+	t.Setenv("CCONFTEST_DB_USER", "env")
+	t.Setenv("CCONFTEST_DB_PORT", "5432")
+	t.Setenv("CCONFTEST_DB_DEBUG", "true")
+
+	c := New()
+	c.SetStore(map[string]interface{}{
+		"db": map[string]interface{}{"user": "root", "password_file": file},
+	})
+	c.AutomaticEnv("CCONFTEST")
+	c.BindEnv("db.debug", "CCONFTEST_DB_DEBUG")
+	v := c.View("db")
+	for _, key := range []string{"host", "user", "password", "port", "debug", "missing"} {
+		equal(t, c.Get("db."+key), v.Get(key))
+	}
+	equal(t, "db.env", v.GetString("host"))
+	equal(t, "root", v.GetString("user"))
+	equal(t, "s3cret", v.GetString("password"))
+	equal(t, 5432, v.GetInt("port"))
+	equal(t, 5432, v.Get("port", 0))
+	equal(t, true, v.GetBool("debug"))
+	equal(t, true, v.Has("host"))
+
+	// strings loaded from the environment are parsed like by Get.
+	l := New()
+	if err := l.LoadEnv("CCONFTEST_"); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, 5432, l.View("db").GetInt("port"))
+
+	// values of the parent of a child Conf.
+	child := c.NewChild()
+	equal(t, "root", child.View("db").GetString("user"))
+	equal(t, "s3cret", child.View("db").GetString("password"))
+}
+
+func benchmarkConf(b *testing.B) *Conf {
+	c := New()
+	tenants := make(map[string]interface{})
+	for _, id := range []string{"acme", "globex", "initech"} {
+		tenants[id] = map[string]interface{}{"name": id, "limit": 10, "enabled": true}
+	}
+	c.SetStore(map[string]interface{}{"tenants": tenants})
+	return c
+}
+
+func BenchmarkView(b *testing.B) {
+	c := benchmarkConf(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		v := c.View("tenants.acme")
+		v.GetString("name")
+		v.GetInt("limit")
+	}
+}
+
+// BenchmarkSubCopy copies the subtree into a new Conf for every request.
+func BenchmarkSubCopy(b *testing.B) {
+	c := benchmarkConf(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		sub := New()
+		sub.SetStore(c.Get("tenants.acme"))
+		sub.GetString("name")
+		sub.GetInt("limit")
+	}
+}
@@ -0,0 +1,102 @@
+package cconf
+
+import (
+	"sort"
+	"strconv"
+)
+
+// WalkOption modifies how Walk visits the configuration.
+type WalkOption int
+
+const (
+	// LeavesOnly visits only the values that are neither maps nor lists.
+	LeavesOnly WalkOption = iota + 1
+)
+
+// Walk calls fn for every value of the configuration, maps and lists included, with its full
+// key, like "servers.0.host", depth-first: a map or list comes before the values it holds, the
+// keys of maps are visited in sorted order and the elements of lists by index. Walking stops
+// when fn returns false. Nulls are skipped, like everywhere in the store.
+//
+// Walk visits a single version of the store and passes copies of its maps and lists, so that
+// fn cannot modify the store.
+func (c *Conf) Walk(fn func(key string, value interface{}) bool, opts ...WalkOption) {
+	leaves := false
+	for _, opt := range opts {
+		leaves = leaves || opt == LeavesOnly
+	}
+	c.walkNode(normalize(c.snap.Load().store), "", leaves, fn)
+}
+
+// walkNode walks the values below the node, see Walk. It returns false if the walk stopped.
+func (c *Conf) walkNode(node interface{}, key string, leaves bool, fn func(key string, value interface{}) bool) bool {
+	visit := func(k string, v interface{}) bool {
+		if v == nil {
+			return true
+		}
+		switch v.(type) {
+		case map[string]interface{}, []interface{}:
+			if !leaves && !fn(k, v) {
+				return false
+			}
+			return c.walkNode(v, k, leaves, fn)
+		}
+		return fn(k, v)
+	}
+	switch n := node.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(n))
+		for k := range n {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if !visit(c.join(key, k), n[k]) {
+				return false
+			}
+		}
+	case []interface{}:
+		for i, v := range n {
+			if !visit(c.join(key, strconv.Itoa(i)), v) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Each calls fn for every direct child of the map or list at the prefix, or at the root for an
+// empty prefix, with its short key, like "0" or "primary", and a copy of its value: the keys
+// of maps in sorted order and the elements of lists by index. Nulls are skipped. Without map
+// or list at the prefix, fn is not called.
+//
+//	c.Each("listeners", func(name string, v interface{}) { ... })
+func (c *Conf) Each(prefix string, fn func(childKey string, value interface{})) {
+	s := c.snap.Load()
+	node, ok := s.store, s.store != nil
+	if prefix != "" {
+		node, ok = s.lookup(prefix, c.Separator)
+	}
+	if !ok {
+		return
+	}
+	switch n := node.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(n))
+		for k := range n {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if v := n[k]; v != nil {
+				fn(k, normalize(v))
+			}
+		}
+	case []interface{}:
+		for i, v := range n {
+			if v != nil {
+				fn(strconv.Itoa(i), normalize(v))
+			}
+		}
+	}
+}
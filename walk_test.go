@@ -0,0 +1,85 @@
+package cconf
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWalk(t *testing.T) {
+	c := New()
+	if err := c.LoadReader("json", strings.NewReader(`{
+		"name": "app",
+		"servers": [{"host": "a", "port": 80}, {"host": "b"}],
+		"db": {"dsn": "x", "opts": null}
+	}`)); err != nil {
+		t.Fatal(err)
+	}
+	var keys []string
+	c.Walk(func(key string, value interface{}) bool {
+		keys = append(keys, key)
+		return true
+	})
+	equal(t, []string{"db", "db.dsn", "name", "servers", "servers.0", "servers.0.host", "servers.0.port", "servers.1", "servers.1.host"}, keys)
+
+	leaves := make(map[string]interface{})
+	c.Walk(func(key string, value interface{}) bool {
+		leaves[key] = value
+		return true
+	}, LeavesOnly)
+	equal(t, map[string]interface{}{"db.dsn": "x", "name": "app", "servers.0.host": "a", "servers.0.port": 80.0, "servers.1.host": "b"}, leaves)
+
+	// the walk stops early.
+	keys = nil
+	c.Walk(func(key string, value interface{}) bool {
+		keys = append(keys, key)
+		return key != "servers.0"
+	})
+	equal(t, []string{"db", "db.dsn", "name", "servers", "servers.0"}, keys)
+
+	// the values are copies.
+	c.Walk(func(key string, value interface{}) bool {
+		if m, ok := value.(map[string]interface{}); ok {
+			m["injected"] = true
+		}
+		if l, ok := value.([]interface{}); ok {
+			l[0] = "replaced"
+		}
+		return true
+	})
+	equal(t, "a", c.GetString("servers.0.host"))
+	equal(t, nil, c.Get("db.injected"))
+}
+
+func TestEach(t *testing.T) {
+	c := New()
+	if err := c.LoadReader("json", strings.NewReader(`{
+		"databases": {"replica": {"host": "b"}, "primary": {"host": "a"}, "old": null},
+		"listeners": [{"port": 80}, {"port": 443}],
+		"name": "app"
+	}`)); err != nil {
+		t.Fatal(err)
+	}
+	var keys []string
+	var values []interface{}
+	each := func(childKey string, value interface{}) {
+		keys = append(keys, childKey)
+		values = append(values, value)
+	}
+	c.Each("databases", each)
+	equal(t, []string{"primary", "replica"}, keys)
+	equal(t, []interface{}{map[string]interface{}{"host": "a"}, map[string]interface{}{"host": "b"}}, values)
+
+	keys, values = nil, nil
+	c.Each("listeners", each)
+	equal(t, []string{"0", "1"}, keys)
+	equal(t, []interface{}{map[string]interface{}{"port": 80.0}, map[string]interface{}{"port": 443.0}}, values)
+
+	keys = nil
+	c.Each("", each)
+	equal(t, []string{"databases", "listeners", "name"}, keys)
+
+	keys = nil
+	c.Each("name", each)
+	c.Each("missing", each)
+	equal(t, []string(nil), keys)
+}
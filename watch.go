@@ -0,0 +1,193 @@
+package cconf
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"time"
+)
+
+// DefaultWatchInterval is the default interval at which Watch polls the loaded files.
+var DefaultWatchInterval = time.Second
+
+// DefaultWatchDebounce is the default time Watch waits for a burst of changes to settle.
+var DefaultWatchDebounce = 100 * time.Millisecond
+
+// Watch watches the files loaded so far in the background and, when one of them changes,
+// reloads all tracked sources in their original order into a new store, which is swapped in
+// atomically. If the reload fails, the old configuration stays live; the outcome of every
+// reload is reported by an EventReload, with the error if any (see OnEvent).
+//
+// Editors and deployment tools often write a file in several steps, like truncate, write and
+// rename, so a burst of changes triggers a single reload once no change was seen for
+// WatchDebounce. If that reload fails, it is retried once after another WatchDebounce,
+// in case a file was caught half-written.
+//
+// Changes are detected with fsnotify when built with the fsnotify tag, and by polling the
+// contents of the files every WatchInterval otherwise. Either way, a reload needs the size or
+// the SHA-256 digest of the contents of a file to change, see Stats. Watching stops and its resources are released when
+// ctx is done or the Conf is closed.
+func (c *Conf) Watch(ctx context.Context) error {
+	c.mu.Lock()
+	var files []string
+	for _, src := range c.sources {
+		if src.file != "" {
+			files = append(files, src.file)
+		}
+	}
+	interval, debounce := c.WatchInterval, c.WatchDebounce
+	if len(files) == 0 {
+		c.mu.Unlock()
+		return errors.New("no files to watch")
+	}
+	ctx, done, err := c.background(ctx)
+	c.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	// start watching before returning, so that no change is missed.
+	var changed func() bool
+	changes, err := notify(ctx, files)
+	if err == nil {
+		// the events of fsnotify do not tell whether the contents changed.
+		changed = c.newFileDigests(files).update
+	} else {
+		changes = c.poll(ctx, files, interval)
+	}
+	go func() {
+		defer done()
+		c.debounce(changes, debounce, changed)
+	}()
+	return nil
+}
+
+// debounce reloads once no change was seen for the duration, until changes is closed.
+// If changed is not nil, it tells whether the files changed since the last reload, so that
+// rewriting a file with the same contents does not reload it.
+func (c *Conf) debounce(changes <-chan struct{}, d time.Duration, changed func() bool) {
+	timer := time.NewTimer(d)
+	timer.Stop()
+	defer timer.Stop()
+	retry, failed := false, false
+	for {
+		select {
+		case _, ok := <-changes:
+			if !ok {
+				return
+			}
+			retry = true
+			if !timer.Stop() {
+				select {
+				case <-timer.C:
+				default:
+				}
+			}
+			timer.Reset(d)
+		case <-timer.C:
+			if !failed && changed != nil && !changed() {
+				retry = false
+				continue
+			}
+			failed = false
+			if err := c.Reload(); err != nil && retry {
+				retry, failed = false, true
+				timer.Reset(d)
+				continue
+			}
+			retry = false
+		}
+	}
+}
+
+// fileDigests tracks the sizes and the SHA-256 digests of the contents of files, which detect
+// changes reliably on all file systems, unlike modification times. The digests are recorded
+// for Stats.
+type fileDigests struct {
+	c      *Conf
+	files  []string
+	states []fileState
+}
+
+// fileState is the state of a file tracked by fileDigests.
+type fileState struct {
+	size int64
+	sum  [sha256.Size]byte
+	err  bool
+}
+
+// newFileDigests returns the tracked states of the files as they are now.
+func (c *Conf) newFileDigests(files []string) *fileDigests {
+	d := &fileDigests{c: c, files: files, states: make([]fileState, len(files))}
+	for i, file := range files {
+		d.states[i] = d.read(file)
+		d.record(file, d.states[i])
+	}
+	return d
+}
+
+// read returns the state of the file.
+func (d *fileDigests) read(file string) fileState {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return fileState{err: true}
+	}
+	return fileState{size: int64(len(data)), sum: sha256.Sum256(data)}
+}
+
+// record records the digest of the file for Stats.
+func (d *fileDigests) record(file string, s fileState) {
+	d.c.mu.Lock()
+	defer d.c.mu.Unlock()
+	if d.c.digests == nil {
+		d.c.digests = make(map[string]string)
+	}
+	if s.err {
+		delete(d.c.digests, file)
+	} else {
+		d.c.digests[file] = hex.EncodeToString(s.sum[:])
+	}
+}
+
+// update reads the states of the files again and reports whether one of them changed.
+func (d *fileDigests) update() bool {
+	changed := false
+	for i, file := range d.files {
+		if s := d.read(file); s != d.states[i] {
+			d.states[i], changed = s, true
+			d.record(file, s)
+		}
+	}
+	return changed
+}
+
+// poll polls the files at the interval and signals their changes until ctx is done.
+func (c *Conf) poll(ctx context.Context, files []string, interval time.Duration) <-chan struct{} {
+	digests := c.newFileDigests(files)
+	changes := make(chan struct{}, 1)
+	go func() {
+		defer close(changes)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			if digests.update() {
+				signal(changes)
+			}
+		}
+	}()
+	return changes
+}
+
+// signal sends a change to the channel, unless one is already pending.
+func signal(changes chan struct{}) {
+	select {
+	case changes <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,269 @@
+package cconf
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatch(t *testing.T) {
+	dir := t.TempDir()
+	app := filepath.Join(dir, "app.json")
+	local := filepath.Join(dir, "local.json")
+	write := func(file, data string) {
+		if err := os.WriteFile(file, []byte(data), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(app, `{"name": "app", "port": 80}`)
+	write(local, `{"port": 8080}`)
+
+	c := New()
+	c.WatchInterval = 10 * time.Millisecond
+	c.WatchDebounce = 10 * time.Millisecond
+	if err := c.Watch(context.Background()); err == nil {
+		t.Fatal("expected an error without files")
+	}
+	if err := c.Load(app, local); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Set("debug", true); err != nil {
+		t.Fatal(err)
+	}
+	reloads := make(chan error, 10)
+	c.OnEvent(func(e Event) {
+		if e.Type == EventReload {
+			reloads <- e.Err
+		}
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := c.Watch(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// the files are reloaded in order, and set values are kept.
+	write(app, `{"name": "new app", "port": 81}`)
+	if err := waitReload(reloads); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, "new app", c.GetString("name"))
+	equal(t, 8080, c.GetInt("port"))
+	equal(t, true, c.GetBool("debug"))
+
+	// a broken file keeps the old configuration, the reload is retried once.
+	write(local, `{"port": `)
+	for i := 0; i < 2; i++ {
+		if err := waitReload(reloads); err == nil {
+			t.Fatal("expected an error")
+		}
+	}
+	equal(t, "new app", c.GetString("name"))
+	equal(t, 8080, c.GetInt("port"))
+
+	write(local, `{"port": 9090}`)
+	if err := waitReload(reloads); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, 9090, c.GetInt("port"))
+
+	// changes are ignored after the context is canceled.
+	cancel()
+	time.Sleep(50 * time.Millisecond)
+	write(local, `{"port": 7070}`)
+	time.Sleep(50 * time.Millisecond)
+	equal(t, 9090, c.GetInt("port"))
+}
+
+// waitReload waits for the next reload and returns its error.
+func waitReload(reloads chan error) error {
+	select {
+	case err := <-reloads:
+		return err
+	case <-time.After(5 * time.Second):
+		panic("no reload")
+	}
+}
+
+func TestWatchDebounce(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "app.json")
+	write := func(file, data string) {
+		if err := os.WriteFile(file, []byte(data), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(file, `{"port": 80}`)
+
+	c := New()
+	c.WatchInterval = 5 * time.Millisecond
+	c.WatchDebounce = 100 * time.Millisecond
+	if err := c.Load(file); err != nil {
+		t.Fatal(err)
+	}
+	reloads := make(chan error, 10)
+	c.OnEvent(func(e Event) {
+		if e.Type == EventReload {
+			reloads <- e.Err
+		}
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := c.Watch(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// a burst of changes is reloaded once.
+	write(file, "")
+	time.Sleep(20 * time.Millisecond)
+	write(file, `{"port": `)
+	time.Sleep(20 * time.Millisecond)
+	tmp := filepath.Join(dir, "app.json.tmp")
+	write(tmp, `{"port": 8080}`)
+	if err := os.Rename(tmp, file); err != nil {
+		t.Fatal(err)
+	}
+	if err := waitReload(reloads); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, 8080, c.GetInt("port"))
+	time.Sleep(200 * time.Millisecond)
+	equal(t, 0, len(reloads))
+
+	// a file caught half-written is read again.
+	write(file, `{"port": 1`)
+	if err := waitReload(reloads); err == nil {
+		t.Fatal("expected an error")
+	}
+	fi, err := os.Stat(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	write(file, `{"port":1}`)
+	if err := os.Chtimes(file, fi.ModTime(), fi.ModTime()); err != nil {
+		t.Fatal(err)
+	}
+	if err := waitReload(reloads); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, 1, c.GetInt("port"))
+}
+
+func TestWatchDigest(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "app.json")
+	write := func(data string) {
+		if err := os.WriteFile(file, []byte(data), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(`{"port": 80}`)
+	fi, err := os.Stat(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := New()
+	c.WatchInterval = 5 * time.Millisecond
+	c.WatchDebounce = 5 * time.Millisecond
+	if err := c.Load(file); err != nil {
+		t.Fatal(err)
+	}
+	reloads := make(chan error, 10)
+	c.OnEvent(func(e Event) {
+		if e.Type == EventReload {
+			reloads <- e.Err
+		}
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := c.Watch(ctx); err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256([]byte(`{"port": 80}`))
+	digest := c.Stats().Digests[file]
+	equal(t, hex.EncodeToString(sum[:]), digest)
+
+	// identical contents are not reloaded.
+	write(`{"port": 80}`)
+	time.Sleep(50 * time.Millisecond)
+	equal(t, 0, len(reloads))
+
+	// changed contents are reloaded, even with the same size and modification time.
+	write(`{"port": 81}`)
+	if err := os.Chtimes(file, fi.ModTime(), fi.ModTime()); err != nil {
+		t.Fatal(err)
+	}
+	if err := waitReload(reloads); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, 81, c.GetInt("port"))
+	if c.Stats().Digests[file] == digest {
+		t.Fatal("expected a new digest")
+	}
+}
+
+// TestDebounceDigests checks the reloads of change notifications, like those of fsnotify,
+// which do not tell whether the contents changed.
+func TestDebounceDigests(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "app.json")
+	write := func(data string) {
+		if err := os.WriteFile(file, []byte(data), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(`{"port": 80}`)
+	c := New()
+	if err := c.Load(file); err != nil {
+		t.Fatal(err)
+	}
+	reloads := make(chan error, 10)
+	c.OnEvent(func(e Event) {
+		if e.Type == EventReload {
+			reloads <- e.Err
+		}
+	})
+	digests := c.newFileDigests([]string{file})
+	changes := make(chan struct{}, 1)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		c.debounce(changes, 5*time.Millisecond, digests.update)
+	}()
+	defer func() {
+		close(changes)
+		<-done
+	}()
+	sum := sha256.Sum256([]byte(`{"port": 80}`))
+	equal(t, hex.EncodeToString(sum[:]), c.Stats().Digests[file])
+
+	// identical contents are not reloaded, even after a truncation.
+	write("")
+	write(`{"port": 80}`)
+	signal(changes)
+	time.Sleep(50 * time.Millisecond)
+	equal(t, 0, len(reloads))
+
+	write(`{"port": 81}`)
+	signal(changes)
+	if err := waitReload(reloads); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, 81, c.GetInt("port"))
+	sum = sha256.Sum256([]byte(`{"port": 81}`))
+	equal(t, hex.EncodeToString(sum[:]), c.Stats().Digests[file])
+
+	// a failed reload is retried once, although the contents did not change since.
+	write(`{"port":`)
+	signal(changes)
+	if err := waitReload(reloads); err == nil {
+		t.Fatal("expected an error")
+	}
+	if err := waitReload(reloads); err == nil {
+		t.Fatal("expected an error")
+	}
+	equal(t, 81, c.GetInt("port"))
+}
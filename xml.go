@@ -0,0 +1,128 @@
+package cconf
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// loadXML reads and parses an XML file.
+func loadXML(file string, data interface{}) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return decodeXML(f, data)
+}
+
+// decodeXML parses an XML document from the reader into data, which must be a *interface{}.
+// The root element, like <config>, stands for the store, and the elements inside it become
+// keys named after their local names:
+//
+//   - an element holding only text, like <host>localhost</host>, becomes the string with
+//     the white space around it trimmed;
+//   - an element holding elements becomes a map of them;
+//   - sibling elements of the same name become a list;
+//   - attributes become keys of the map of their element prefixed with "@", like "@id",
+//     and the text of an element with attributes or elements the key "#text".
+//
+// Malformed documents fail with a *SyntaxError.
+func decodeXML(r io.Reader, data interface{}) error {
+	dec := xml.NewDecoder(r)
+	var root interface{}
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return xmlError(dec, err)
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		if root != nil {
+			line, col := dec.InputPos()
+			return &SyntaxError{Line: line, Column: col, Err: fmt.Errorf("unexpected element <%s> after the root element", start.Name.Local)}
+		}
+		v, err := decodeXMLElement(dec, start)
+		if err != nil {
+			return err
+		}
+		switch v := v.(type) {
+		case map[string]interface{}:
+			root = v
+		case string:
+			if v != "" {
+				return fmt.Errorf("the root element <%s> holds text instead of elements", start.Name.Local)
+			}
+			root = make(map[string]interface{})
+		}
+	}
+	*data.(*interface{}) = root
+	return nil
+}
+
+// decodeXMLElement decodes the content of the element up to its end.
+func decodeXMLElement(dec *xml.Decoder, start xml.StartElement) (interface{}, error) {
+	m := make(map[string]interface{})
+	for _, attr := range start.Attr {
+		if attr.Name.Space == "xmlns" || attr.Name.Local == "xmlns" {
+			continue
+		}
+		m["@"+attr.Name.Local] = attr.Value
+	}
+	var text strings.Builder
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, xmlError(dec, err)
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			v, err := decodeXMLElement(dec, t)
+			if err != nil {
+				return nil, err
+			}
+			switch e := m[t.Name.Local].(type) {
+			case nil:
+				m[t.Name.Local] = v
+			case []interface{}:
+				m[t.Name.Local] = append(e, v)
+			default:
+				m[t.Name.Local] = []interface{}{e, v}
+			}
+		case xml.CharData:
+			text.Write(t)
+		case xml.EndElement:
+			s := strings.TrimSpace(text.String())
+			if len(m) == 0 {
+				return s, nil
+			}
+			if s != "" {
+				m["#text"] = s
+			}
+			return m, nil
+		}
+	}
+}
+
+// xmlError returns the error of the decoder as a *SyntaxError at its position.
+func xmlError(dec *xml.Decoder, err error) error {
+	var se *xml.SyntaxError
+	if errors.As(err, &se) {
+		err = errors.New(se.Msg)
+	} else if err != io.ErrUnexpectedEOF {
+		return err
+	}
+	line, col := dec.InputPos()
+	return &SyntaxError{Line: line, Column: col, Err: err}
+}
@@ -0,0 +1,81 @@
+package cconf
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestLoadXML(t *testing.T) {
+	c := New()
+	if err := c.Load("./testdata/app.json", "./testdata/app.xml"); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, "cconf-xml", c.GetString("name"))
+	equal(t, "syyong.x", c.GetString("ext.author"))
+	equal(t, "xml@example.com", c.GetString("ext.email"))
+	equal(t, "localhost", c.GetString("server.host"))
+	equal(t, "8080", c.Get("server.port"))
+	equal(t, "b", c.GetString("upstream.1.@id"))
+	equal(t, "10.0.0.2", c.GetString("upstream.1.#text"))
+	equal(t, "<Welcome> & enjoy", c.GetString("motd"))
+
+	var s struct {
+		Server struct {
+			Host string `cconf:"host"`
+			Port string `cconf:"port"`
+		} `cconf:"server"`
+		Upstreams []struct {
+			ID      string `cconf:"@id"`
+			Weight  string `cconf:"@weight"`
+			Address string `cconf:"#text"`
+		} `cconf:"upstream"`
+	}
+	if err := c.Populate(&s.Server, "server"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Populate(&s.Upstreams, "upstream"); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, "localhost", s.Server.Host)
+	equal(t, 2, len(s.Upstreams))
+	equal(t, "a", s.Upstreams[0].ID)
+	equal(t, "10.0.0.1", s.Upstreams[0].Address)
+	equal(t, "2", s.Upstreams[1].Weight)
+}
+
+func TestDecodeXML(t *testing.T) {
+	var v interface{}
+	doc := `<c a="1"><e/><l>x</l><l><y>1</y></l><l>z</l><m k="v">text<n>1</n></m></c>`
+	if err := decodeXML(strings.NewReader(doc), &v); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, map[string]interface{}{
+		"@a": "1",
+		"e":  "",
+		"l":  []interface{}{"x", map[string]interface{}{"y": "1"}, "z"},
+		"m":  map[string]interface{}{"@k": "v", "#text": "text", "n": "1"},
+	}, v)
+
+	if err := decodeXML(strings.NewReader("<c/>"), &v); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, map[string]interface{}{}, v)
+
+	for doc, want := range map[string]string{
+		"<c>\n  <a>1</b>\n</c>": "line 2, column 11: element <a> closed by </b>",
+		"<c><a>":                "line 1, column 7: unexpected EOF",
+		"<c/>\n<d/>":            "line 2, column 5: unexpected element <d> after the root element",
+	} {
+		err := decodeXML(strings.NewReader(doc), &v)
+		var se *SyntaxError
+		if !errors.As(err, &se) {
+			t.Errorf("%q: expected a *SyntaxError, got %v", doc, err)
+			continue
+		}
+		equal(t, want, err.Error())
+	}
+	if err := decodeXML(strings.NewReader("<c>text</c>"), &v); err == nil {
+		t.Error("expected an error for a root element holding text")
+	}
+}
@@ -0,0 +1,1059 @@
+package cconf
+
+import (
+	"encoding"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// dumpYAML writes the data to the file as YAML.
+func dumpYAML(file string, data interface{}) error {
+	return dumpFile(file, data, encodeYAML)
+}
+
+// encodeYAML writes the data to the writer as a YAML document in block style, with the keys
+// of maps sorted. Strings that a YAML parser would read as another type, like "yes", "08"
+// or "null", and strings with special characters are double-quoted.
+func encodeYAML(w io.Writer, data interface{}) error {
+	var b strings.Builder
+	writeYAML(&b, normalize(data), 0)
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeYAML writes the value as a block at the indentation, which ends with a newline.
+// Scalars and empty containers are written on a single line.
+func writeYAML(b *strings.Builder, v interface{}, indent int) {
+	pad := strings.Repeat("  ", indent)
+	switch d := v.(type) {
+	case map[string]interface{}:
+		if len(d) == 0 {
+			break
+		}
+		keys := make([]string, 0, len(d))
+		for k := range d {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			b.WriteString(pad + yamlString(k) + ":")
+			writeYAMLNested(b, d[k], indent+1, false)
+		}
+		return
+	case []interface{}:
+		if len(d) == 0 {
+			break
+		}
+		for _, e := range d {
+			b.WriteString(pad + "-")
+			writeYAMLNested(b, e, indent+1, true)
+		}
+		return
+	}
+	b.WriteString(pad + yamlScalar(v) + "\n")
+}
+
+// writeYAMLNested writes the value of a map key or a list item after its "key:" or "-".
+// A container in a list starts on the line of the item, like "- a: 1" or "- - a".
+func writeYAMLNested(b *strings.Builder, v interface{}, indent int, item bool) {
+	if !isContainer(v) {
+		b.WriteString(" " + yamlScalar(v) + "\n")
+		return
+	}
+	if !item {
+		b.WriteString("\n")
+		writeYAML(b, v, indent)
+		return
+	}
+	var nested strings.Builder
+	writeYAML(&nested, v, indent)
+	b.WriteString(" " + strings.TrimLeft(nested.String(), " "))
+}
+
+// isContainer reports whether the value is a non-empty map or list.
+func isContainer(v interface{}) bool {
+	switch d := v.(type) {
+	case map[string]interface{}:
+		return len(d) > 0
+	case []interface{}:
+		return len(d) > 0
+	}
+	return false
+}
+
+// yamlScalar formats a scalar or an empty container as a YAML flow value.
+func yamlScalar(v interface{}) string {
+	switch d := v.(type) {
+	case nil:
+		return "null"
+	case map[string]interface{}:
+		return "{}"
+	case []interface{}:
+		return "[]"
+	case string:
+		return yamlString(d)
+	case []byte:
+		return yamlString(string(d))
+	case encoding.TextMarshaler:
+		if text, err := d.MarshalText(); err == nil {
+			return yamlString(string(text))
+		}
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Bool:
+		return strconv.FormatBool(rv.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(rv.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(rv.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		f := rv.Float()
+		switch {
+		case math.IsNaN(f):
+			return ".nan"
+		case math.IsInf(f, 1):
+			return ".inf"
+		case math.IsInf(f, -1):
+			return "-.inf"
+		}
+		return formatFloat(f)
+	}
+	return yamlString(fmt.Sprint(v))
+}
+
+// formatFloat formats a finite float like encoding/json, so that all dumps format numbers
+// alike: integral values like integers, as JSON numbers are loaded as floats, and with an
+// exponent only for very small or large values.
+func formatFloat(f float64) string {
+	if abs := math.Abs(f); abs != 0 && (abs < 1e-6 || abs >= 1e21) {
+		s := strconv.FormatFloat(f, 'e', -1, 64)
+		// clean up e-09 to e-9, like encoding/json.
+		if n := len(s); n >= 4 && s[n-4] == 'e' && s[n-3] == '-' && s[n-2] == '0' {
+			s = s[:n-2] + s[n-1:]
+		}
+		return s
+	}
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// yamlReserved are the plain scalars that YAML 1.1 or 1.2 parsers read as booleans or null.
+var yamlReserved = map[string]bool{
+	"y": true, "yes": true, "n": true, "no": true, "true": true, "false": true,
+	"on": true, "off": true, "null": true, "~": true,
+}
+
+// yamlString formats the string as a plain scalar if a YAML parser reads it back as the same
+// string, and as a double-quoted scalar otherwise.
+func yamlString(s string) string {
+	if s == "" || yamlReserved[strings.ToLower(s)] || strings.HasSuffix(s, " ") {
+		return strconv.Quote(s)
+	}
+	for i, r := range s {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r == '_', r == '/':
+		case r >= '0' && r <= '9', r == '-', r == '.', r == ' ', r == '@':
+			// may not start a plain scalar, like numbers, lists and hidden files.
+			if i == 0 {
+				return strconv.Quote(s)
+			}
+		default:
+			return strconv.Quote(s)
+		}
+	}
+	return s
+}
+
+// loadYAML reads and parses a YAML file.
+func loadYAML(file string, data interface{}) error {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	return parseYAML(string(b), data)
+}
+
+// decodeYAML parses a YAML document from the reader into data, which must be a *interface{}.
+func decodeYAML(r io.Reader, data interface{}) error {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	return parseYAML(string(b), data)
+}
+
+// parseYAML parses a single YAML document into data, which must be a *interface{}.
+// Block and flow collections, quoted, plain and block scalars, anchors, aliases and "<<"
+// merge keys are supported; tags and complex keys are not. Scalars are resolved with the
+// YAML 1.2 core schema, so "yes" is a string and "08" an integer. The keys of maps are
+// always strings, like in JSON, so that Get and Populate can look them up.
+// Malformed documents fail with a *SyntaxError.
+func parseYAML(s string, data interface{}) error {
+	p := &yamlParser{anchors: make(map[string]interface{})}
+	v, err := p.parse(s)
+	if err != nil {
+		return err
+	}
+	*data.(*interface{}) = normalize(v)
+	return nil
+}
+
+// yamlLine is a line of a YAML document.
+type yamlLine struct {
+	num    int    // the line number, starting at 1
+	indent int    // the number of leading spaces
+	text   string // the line without the leading spaces
+}
+
+// blank reports whether the line is empty or a comment.
+func (l yamlLine) blank() bool {
+	t := strings.TrimLeft(l.text, " \t")
+	return t == "" || t[0] == '#'
+}
+
+// yamlParser parses the block structure of a YAML document line by line.
+type yamlParser struct {
+	lines   []yamlLine
+	i       int // the next line
+	anchors map[string]interface{}
+}
+
+// errorf returns a *SyntaxError at the offset of the text of the line.
+func (p *yamlParser) errorf(l yamlLine, off int, format string, args ...interface{}) error {
+	col := l.indent + utf8.RuneCountInString(l.text[:off]) + 1
+	return &SyntaxError{Line: l.num, Column: col, Err: fmt.Errorf(format, args...)}
+}
+
+// parse splits the document into lines and parses its root node.
+func (p *yamlParser) parse(s string) (interface{}, error) {
+	started, ended := false, false
+	for i, line := range strings.Split(strings.TrimPrefix(s, "\ufeff"), "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		text := strings.TrimLeft(line, " ")
+		l := yamlLine{num: i + 1, indent: len(line) - len(text), text: text}
+		if l.indent == 0 && !l.blank() {
+			switch {
+			case ended:
+				return nil, p.errorf(l, 0, "multiple documents are not supported")
+			case strings.HasPrefix(text, "%") && !started:
+				// directives.
+				continue
+			case text == "---" || strings.HasPrefix(text, "--- ") || strings.HasPrefix(text, "---\t"):
+				if started {
+					return nil, p.errorf(l, 0, "multiple documents are not supported")
+				}
+				rest := strings.TrimLeft(text[3:], " \t")
+				l = yamlLine{num: l.num, indent: len(text) - len(rest), text: rest}
+			case text == "...":
+				ended = true
+				continue
+			}
+		}
+		if ended {
+			continue
+		}
+		started = started || !l.blank()
+		p.lines = append(p.lines, l)
+	}
+
+	if !p.skip() {
+		return nil, nil
+	}
+	v, err := p.parseBlock(-1)
+	if err != nil {
+		return nil, err
+	}
+	if p.skip() {
+		return nil, p.errorf(p.lines[p.i], 0, "unexpected content at the end of the document")
+	}
+	return v, nil
+}
+
+// skip moves to the next line that is not blank and reports whether there is one.
+func (p *yamlParser) skip() bool {
+	for ; p.i < len(p.lines); p.i++ {
+		if !p.lines[p.i].blank() {
+			return true
+		}
+	}
+	return false
+}
+
+// parseBlock parses the node starting at the current line, which is indented more than
+// the parent node.
+func (p *yamlParser) parseBlock(parent int) (interface{}, error) {
+	l := p.lines[p.i]
+	switch {
+	case strings.HasPrefix(l.text, "\t"):
+		return nil, p.errorf(l, 0, "found a tab character in the indentation")
+	case isYAMLItem(l.text):
+		return p.parseSeq(l.indent)
+	}
+	if _, _, ok := splitYAMLKey(l.text); ok {
+		return p.parseMap(l.indent)
+	}
+	p.i++
+	return p.parseValue(l, 0, parent)
+}
+
+// parseMap parses the block mapping whose keys are at the indentation.
+func (p *yamlParser) parseMap(indent int) (interface{}, error) {
+	m := make(map[string]interface{})
+	type merge struct {
+		l yamlLine
+		v interface{}
+	}
+	var merges []merge
+	for p.skip() {
+		l := p.lines[p.i]
+		if l.indent < indent {
+			break
+		}
+		if l.indent > indent {
+			return nil, p.errorf(l, 0, "unexpected indentation")
+		}
+		if strings.HasPrefix(l.text, "\t") {
+			return nil, p.errorf(l, 0, "found a tab character in the indentation")
+		}
+		key, off, ok := splitYAMLKey(l.text)
+		if !ok {
+			return nil, p.errorf(l, 0, "expected a key followed by ':'")
+		}
+		if _, ok := m[key]; ok {
+			return nil, p.errorf(l, 0, "duplicate key %q", key)
+		}
+		p.i++
+		var v interface{}
+		var err error
+		if rest := strings.TrimLeft(l.text[off:], " \t"); (rest == "" || rest[0] == '#') &&
+			p.skip() && p.lines[p.i].indent == indent && isYAMLItem(p.lines[p.i].text) {
+			// the items of a list may be at the indentation of its key.
+			v, err = p.parseSeq(indent)
+		} else {
+			v, err = p.parseValue(l, off, indent)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if key == "<<" {
+			merges = append(merges, merge{l, v})
+			continue
+		}
+		m[key] = v
+	}
+
+	// the keys of merged maps never replace the keys of the map, and the first merged
+	// map wins.
+	for _, mg := range merges {
+		maps, ok := mg.v.([]interface{})
+		if !ok {
+			maps = []interface{}{mg.v}
+		}
+		for _, e := range maps {
+			em, ok := e.(map[string]interface{})
+			if !ok {
+				return nil, p.errorf(mg.l, 0, "the value of << must be a map or a list of maps")
+			}
+			for k, v := range em {
+				if _, ok := m[k]; !ok {
+					m[k] = v
+				}
+			}
+		}
+	}
+	return m, nil
+}
+
+// parseSeq parses the block sequence whose items are at the indentation.
+func (p *yamlParser) parseSeq(indent int) (interface{}, error) {
+	s := make([]interface{}, 0)
+	for p.skip() {
+		l := p.lines[p.i]
+		if l.indent < indent || l.indent == indent && !isYAMLItem(l.text) {
+			break
+		}
+		if l.indent > indent {
+			return nil, p.errorf(l, 0, "unexpected indentation")
+		}
+		off := len(l.text) - len(strings.TrimLeft(l.text[1:], " "))
+		rest := l.text[off:]
+		var v interface{}
+		var err error
+		if _, _, ok := splitYAMLKey(rest); ok || isYAMLItem(rest) {
+			// a compact collection starts on the line of the item: parse the rest of the
+			// line as if it were a line of its own.
+			p.lines[p.i] = yamlLine{num: l.num, indent: l.indent + off, text: rest}
+			v, err = p.parseBlock(indent)
+		} else {
+			p.i++
+			v, err = p.parseValue(l, off, indent)
+		}
+		if err != nil {
+			return nil, err
+		}
+		s = append(s, v)
+	}
+	return s, nil
+}
+
+// parseValue parses the value starting at the offset of the line, after a key or a list
+// item; the following lines belong to it if they are indented more than the parent node.
+// The current line is the one after l.
+func (p *yamlParser) parseValue(l yamlLine, off, parent int) (interface{}, error) {
+	off = len(l.text) - len(strings.TrimLeft(l.text[off:], " \t"))
+	s := l.text[off:]
+	if s == "" || s[0] == '#' {
+		if p.skip() && p.lines[p.i].indent > parent {
+			return p.parseBlock(parent)
+		}
+		return nil, nil
+	}
+	switch s[0] {
+	case '&':
+		end := off + yamlNameEnd(s[1:]) + 1
+		if end == off+1 {
+			return nil, p.errorf(l, off, "missing anchor name")
+		}
+		v, err := p.parseValue(l, end, parent)
+		if err != nil {
+			return nil, err
+		}
+		p.anchors[l.text[off+1:end]] = v
+		return v, nil
+	case '*':
+		end := off + yamlNameEnd(s[1:]) + 1
+		v, ok := p.anchors[l.text[off+1:end]]
+		if !ok {
+			return nil, p.errorf(l, off, "unknown anchor %q", l.text[off+1:end])
+		}
+		if rest := strings.TrimLeft(l.text[end:], " \t"); rest != "" && rest[0] != '#' {
+			return nil, p.errorf(l, len(l.text)-len(rest), "unexpected %q after an alias", rest)
+		}
+		// copy the value, so that the nodes of the tree are never shared.
+		return normalize(v), nil
+	case '!':
+		return nil, p.errorf(l, off, "tags are not supported")
+	case '|', '>':
+		return p.parseBlockScalar(l, off, parent)
+	case '[', '{', '"', '\'':
+		return p.parseFlow(l, off, parent)
+	case '@', '`', '%':
+		return nil, p.errorf(l, off, "a plain scalar cannot start with %q", s[0])
+	}
+	return p.parsePlain(l, off, parent)
+}
+
+// parsePlain parses a plain scalar, which continues on the following lines indented more
+// than the parent node, with the line breaks folded.
+func (p *yamlParser) parsePlain(l yamlLine, off, parent int) (interface{}, error) {
+	text, err := p.plainLine(l, off)
+	if err != nil {
+		return nil, err
+	}
+	lines := []string{text}
+	for j := p.i; j < len(p.lines); j++ {
+		next := p.lines[j]
+		t := strings.TrimLeft(next.text, " \t")
+		if t == "" {
+			lines = append(lines, "")
+			continue
+		}
+		if t[0] == '#' || next.indent <= parent {
+			break
+		}
+		if text, err = p.plainLine(next, len(next.text)-len(t)); err != nil {
+			return nil, err
+		}
+		lines = append(lines, text)
+		p.i = j + 1
+	}
+	// trailing empty lines are left to the following nodes.
+	for lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return resolveYAML(foldLines(lines)), nil
+}
+
+// plainLine returns the text of a plain scalar on the line without its comment.
+func (p *yamlParser) plainLine(l yamlLine, off int) (string, error) {
+	s := l.text[off:]
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '#':
+			if i > 0 && (s[i-1] == ' ' || s[i-1] == '\t') {
+				return strings.TrimRight(s[:i], " \t"), nil
+			}
+		case ':':
+			if i+1 == len(s) || s[i+1] == ' ' || s[i+1] == '\t' {
+				return "", p.errorf(l, off+i, "mapping values are not allowed in this context")
+			}
+		}
+	}
+	return strings.TrimRight(s, " \t"), nil
+}
+
+// parseBlockScalar parses a literal (|) or folded (>) block scalar, whose header is at the
+// offset of the line.
+func (p *yamlParser) parseBlockScalar(l yamlLine, off, parent int) (interface{}, error) {
+	folded := l.text[off] == '>'
+	chomp, indent := byte(0), 0
+	i := off + 1
+	for ; i < len(l.text); i++ {
+		if c := l.text[i]; (c == '-' || c == '+') && chomp == 0 {
+			chomp = c
+		} else if c >= '1' && c <= '9' && indent == 0 {
+			indent = max(parent, 0) + int(c-'0')
+		} else {
+			break
+		}
+	}
+	if rest := strings.TrimLeft(l.text[i:], " \t"); rest != "" && (rest[0] != '#' || rest == l.text[i:]) {
+		return nil, p.errorf(l, off, "invalid block scalar header")
+	}
+
+	var lines []string
+	for ; p.i < len(p.lines); p.i++ {
+		next := p.lines[p.i]
+		if strings.TrimLeft(next.text, " \t") == "" {
+			lines = append(lines, "")
+			continue
+		}
+		if indent == 0 {
+			// the first line sets the indentation of the content.
+			indent = next.indent
+		}
+		if next.indent < indent || next.indent <= parent {
+			break
+		}
+		lines = append(lines, strings.Repeat(" ", next.indent-indent)+next.text)
+	}
+	trailing := 0
+	for n := len(lines); trailing < n && lines[n-1-trailing] == ""; trailing++ {
+	}
+	lines = lines[:len(lines)-trailing]
+
+	var b strings.Builder
+	prev := "" // the last non-empty line
+	for i, line := range lines {
+		switch {
+		case !folded:
+			if i > 0 {
+				b.WriteByte('\n')
+			}
+		case line == "":
+			b.WriteByte('\n')
+		case prev == "":
+		case line[0] == ' ' || prev[0] == ' ':
+			// lines with more indentation keep their line breaks.
+			b.WriteByte('\n')
+		case lines[i-1] != "":
+			b.WriteByte(' ')
+		}
+		if line != "" {
+			prev = line
+		}
+		b.WriteString(line)
+	}
+	switch {
+	case chomp == '+':
+		b.WriteString(strings.Repeat("\n", trailing+1))
+	case chomp == 0 && len(lines) > 0:
+		b.WriteByte('\n')
+	}
+	if chomp == '+' && len(lines) == 0 {
+		return strings.Repeat("\n", trailing), nil
+	}
+	return b.String(), nil
+}
+
+// parseFlow parses a flow collection or a quoted scalar starting at the offset of the line,
+// which may continue on the following lines indented more than the parent node.
+func (p *yamlParser) parseFlow(l yamlLine, off, parent int) (interface{}, error) {
+	f := &yamlFlow{p: p, s: l.text[off:], lines: []yamlLine{l}, off: off}
+	for {
+		f.pos, f.eof = 0, false
+		v, err := f.node()
+		if err == nil {
+			if f.ws(); f.pos < len(f.s) {
+				return nil, f.errorf(f.pos, "unexpected %q after the value", f.s[f.pos:])
+			}
+			return v, nil
+		}
+		// the value is incomplete: retry with the next line.
+		if !f.eof || p.i == len(p.lines) {
+			return nil, err
+		}
+		next := p.lines[p.i]
+		// only the closing bracket may be at the indentation of the parent node.
+		if t := strings.TrimLeft(next.text, " \t"); t != "" && next.indent <= parent && t[0] != ']' && t[0] != '}' {
+			return nil, err
+		}
+		p.i++
+		f.s += "\n" + strings.Repeat(" ", next.indent) + next.text
+		f.lines = append(f.lines, next)
+	}
+}
+
+// yamlFlow parses flow collections and quoted scalars.
+type yamlFlow struct {
+	p     *yamlParser
+	s     string
+	pos   int
+	lines []yamlLine // the lines of s, the first one starting at the offset
+	off   int
+	eof   bool // whether s ended within the value
+}
+
+// errorf returns a *SyntaxError at the position of s.
+func (f *yamlFlow) errorf(pos int, format string, args ...interface{}) error {
+	err := fmt.Errorf(format, args...)
+	if len(f.lines) == 0 {
+		return err
+	}
+	n := strings.Count(f.s[:pos], "\n")
+	if n == 0 {
+		return f.p.errorf(f.lines[0], f.off+pos, "%v", err)
+	}
+	start := strings.LastIndex(f.s[:pos], "\n") + 1
+	return &SyntaxError{Line: f.lines[n].num, Column: utf8.RuneCountInString(f.s[start:pos]) + 1, Err: err}
+}
+
+// ws skips white space, line breaks and comments.
+func (f *yamlFlow) ws() {
+	for f.pos < len(f.s) {
+		switch f.s[f.pos] {
+		case ' ', '\t', '\n':
+			f.pos++
+		case '#':
+			if i := strings.IndexByte(f.s[f.pos:], '\n'); i >= 0 {
+				f.pos += i
+			} else {
+				f.pos = len(f.s)
+			}
+		default:
+			return
+		}
+	}
+}
+
+// end reports whether s ended, marking the value as incomplete.
+func (f *yamlFlow) end() bool {
+	if f.pos >= len(f.s) {
+		f.eof = true
+	}
+	return f.eof
+}
+
+// node parses a flow node.
+func (f *yamlFlow) node() (interface{}, error) {
+	f.ws()
+	if f.end() {
+		return nil, f.errorf(f.pos, "unexpected end of a flow collection")
+	}
+	switch c := f.s[f.pos]; c {
+	case '[':
+		return f.seq()
+	case '{':
+		return f.mapping()
+	case '"':
+		return f.doubleQuoted()
+	case '\'':
+		return f.singleQuoted()
+	case '&':
+		start := f.pos + 1
+		f.pos = start + yamlNameEnd(f.s[start:])
+		if f.pos == start {
+			return nil, f.errorf(start-1, "missing anchor name")
+		}
+		v, err := f.node()
+		if err != nil {
+			return nil, err
+		}
+		f.p.anchors[f.s[start:f.pos]] = v
+		return v, nil
+	case '*':
+		start := f.pos + 1
+		f.pos = start + yamlNameEnd(f.s[start:])
+		v, ok := f.p.anchors[f.s[start:f.pos]]
+		if !ok {
+			return nil, f.errorf(start-1, "unknown anchor %q", f.s[start:f.pos])
+		}
+		return normalize(v), nil
+	case '!':
+		return nil, f.errorf(f.pos, "tags are not supported")
+	case ',', ']', '}', ':':
+		return nil, f.errorf(f.pos, "unexpected %q", c)
+	}
+	return resolveYAML(f.plain()), nil
+}
+
+// seq parses a flow sequence like [a, b].
+func (f *yamlFlow) seq() (interface{}, error) {
+	start := f.pos
+	f.pos++
+	s := make([]interface{}, 0)
+	for {
+		if f.ws(); f.end() {
+			return nil, f.errorf(start, "unterminated flow sequence")
+		}
+		if f.s[f.pos] == ']' {
+			f.pos++
+			return s, nil
+		}
+		v, err := f.node()
+		if err != nil {
+			return nil, err
+		}
+		s = append(s, v)
+		if f.ws(); f.end() {
+			return nil, f.errorf(start, "unterminated flow sequence")
+		}
+		switch f.s[f.pos] {
+		case ',':
+			f.pos++
+		case ']':
+		default:
+			return nil, f.errorf(f.pos, "expected ',' or ']'")
+		}
+	}
+}
+
+// mapping parses a flow mapping like {a: 1, b: 2}.
+func (f *yamlFlow) mapping() (interface{}, error) {
+	start := f.pos
+	f.pos++
+	m := make(map[string]interface{})
+	for {
+		if f.ws(); f.end() {
+			return nil, f.errorf(start, "unterminated flow mapping")
+		}
+		if f.s[f.pos] == '}' {
+			f.pos++
+			return m, nil
+		}
+		at := f.pos
+		var key string
+		switch f.s[f.pos] {
+		case '"', '\'':
+			k, err := f.node()
+			if err != nil {
+				return nil, err
+			}
+			key = k.(string)
+		case '[', '{', '&', '*', '!', ',', ':':
+			return nil, f.errorf(f.pos, "expected a scalar key")
+		default:
+			key = f.plain()
+		}
+		if _, ok := m[key]; ok {
+			return nil, f.errorf(at, "duplicate key %q", key)
+		}
+		var v interface{}
+		if f.ws(); f.end() {
+			return nil, f.errorf(start, "unterminated flow mapping")
+		}
+		if f.s[f.pos] == ':' {
+			f.pos++
+			if f.ws(); f.end() {
+				return nil, f.errorf(start, "unterminated flow mapping")
+			}
+			if c := f.s[f.pos]; c != ',' && c != '}' {
+				var err error
+				if v, err = f.node(); err != nil {
+					return nil, err
+				}
+			}
+		}
+		m[key] = v
+		if f.ws(); f.end() {
+			return nil, f.errorf(start, "unterminated flow mapping")
+		}
+		switch f.s[f.pos] {
+		case ',':
+			f.pos++
+		case '}':
+		default:
+			return nil, f.errorf(f.pos, "expected ',' or '}'")
+		}
+	}
+}
+
+// plain returns the text of a plain scalar in a flow collection, with the line breaks folded.
+func (f *yamlFlow) plain() string {
+	start := f.pos
+loop:
+	for ; f.pos < len(f.s); f.pos++ {
+		switch c := f.s[f.pos]; c {
+		case ',', '[', ']', '{', '}':
+			break loop
+		case ':':
+			if f.pos+1 == len(f.s) || strings.IndexByte(" \t\n,[]{}", f.s[f.pos+1]) >= 0 {
+				break loop
+			}
+		case '#':
+			if p := f.s[f.pos-1]; p == ' ' || p == '\t' || p == '\n' {
+				break loop
+			}
+		}
+	}
+	lines := strings.Split(f.s[start:f.pos], "\n")
+	for i, line := range lines {
+		lines[i] = strings.Trim(line, " \t")
+	}
+	return foldLines(lines)
+}
+
+// doubleQuoted parses a double-quoted scalar with escape sequences.
+func (f *yamlFlow) doubleQuoted() (interface{}, error) {
+	start := f.pos
+	f.pos++
+	var b strings.Builder
+	for !f.end() {
+		switch c := f.s[f.pos]; c {
+		case '"':
+			f.pos++
+			return b.String(), nil
+		case '\n':
+			f.fold(&b)
+		case '\\':
+			if f.pos++; f.end() {
+				break
+			}
+			if f.s[f.pos] == '\n' {
+				// an escaped line break joins the lines.
+				f.pos++
+				for f.pos < len(f.s) && (f.s[f.pos] == ' ' || f.s[f.pos] == '\t') {
+					f.pos++
+				}
+				continue
+			}
+			if err := f.escape(&b); err != nil {
+				return nil, err
+			}
+		default:
+			b.WriteByte(c)
+			f.pos++
+		}
+	}
+	return nil, f.errorf(start, "unterminated quoted string")
+}
+
+// yamlEscapes are the escape sequences of double-quoted scalars with a single character.
+var yamlEscapes = map[byte]string{
+	'0': "\x00", 'a': "\a", 'b': "\b", 't': "\t", '\t': "\t", 'n': "\n", 'v': "\v", 'f': "\f",
+	'r': "\r", 'e': "\x1b", ' ': " ", '"': "\"", '/': "/", '\\': "\\", 'N': "\u0085",
+	'_': "\u00a0", 'L': "\u2028", 'P': "\u2029",
+}
+
+// escape writes the character of the escape sequence at the current position, after the
+// backslash.
+func (f *yamlFlow) escape(b *strings.Builder) error {
+	c := f.s[f.pos]
+	if s, ok := yamlEscapes[c]; ok {
+		b.WriteString(s)
+		f.pos++
+		return nil
+	}
+	n := map[byte]int{'x': 2, 'u': 4, 'U': 8}[c]
+	if n == 0 {
+		return f.errorf(f.pos-1, "invalid escape sequence \\%c", c)
+	}
+	if f.pos+n >= len(f.s) {
+		f.eof = true
+		return f.errorf(f.pos-1, "unterminated quoted string")
+	}
+	r, err := strconv.ParseUint(f.s[f.pos+1:f.pos+1+n], 16, 32)
+	if err != nil || !utf8.ValidRune(rune(r)) {
+		return f.errorf(f.pos-1, "invalid escape sequence \\%s", f.s[f.pos:f.pos+1+n])
+	}
+	b.WriteRune(rune(r))
+	f.pos += n + 1
+	return nil
+}
+
+// singleQuoted parses a single-quoted scalar, in which two quotes are a quote.
+func (f *yamlFlow) singleQuoted() (interface{}, error) {
+	start := f.pos
+	f.pos++
+	var b strings.Builder
+	for !f.end() {
+		switch c := f.s[f.pos]; {
+		case c == '\'' && strings.HasPrefix(f.s[f.pos:], "''"):
+			b.WriteByte('\'')
+			f.pos += 2
+		case c == '\'':
+			f.pos++
+			return b.String(), nil
+		case c == '\n':
+			f.fold(&b)
+		default:
+			b.WriteByte(c)
+			f.pos++
+		}
+	}
+	return nil, f.errorf(start, "unterminated quoted string")
+}
+
+// fold folds the line breaks at the current position of a quoted scalar: a single line
+// break becomes a space and each following one a line break, without the white space
+// around them.
+func (f *yamlFlow) fold(b *strings.Builder) {
+	s := strings.TrimRight(b.String(), " \t")
+	b.Reset()
+	b.WriteString(s)
+	breaks := 0
+	for ; f.pos < len(f.s) && strings.IndexByte(" \t\n", f.s[f.pos]) >= 0; f.pos++ {
+		if f.s[f.pos] == '\n' {
+			breaks++
+		}
+	}
+	if breaks == 1 {
+		b.WriteByte(' ')
+	} else {
+		b.WriteString(strings.Repeat("\n", breaks-1))
+	}
+}
+
+// foldLines joins the lines of a plain scalar: a line break between two lines becomes a
+// space, and each empty line a line break.
+func foldLines(lines []string) string {
+	var b strings.Builder
+	for i, line := range lines {
+		switch {
+		case i == 0:
+		case line == "":
+			b.WriteByte('\n')
+		case lines[i-1] != "":
+			b.WriteByte(' ')
+		}
+		b.WriteString(line)
+	}
+	return b.String()
+}
+
+// isYAMLItem reports whether the text starts a block sequence item.
+func isYAMLItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ") || strings.HasPrefix(text, "-\t")
+}
+
+// splitYAMLKey returns the key of the text of a line like "key: value" or `"key": value`
+// and the offset after the colon.
+func splitYAMLKey(text string) (string, int, bool) {
+	if text == "" || strings.IndexByte("[]{}&*!|>#%@`,?", text[0]) >= 0 || isYAMLItem(text) {
+		return "", 0, false
+	}
+	colon := func(i int) bool {
+		return i < len(text) && text[i] == ':' && (i+1 == len(text) || text[i+1] == ' ' || text[i+1] == '\t')
+	}
+	if text[0] == '"' || text[0] == '\'' {
+		f := &yamlFlow{s: text}
+		v, err := f.node()
+		if err != nil {
+			return "", 0, false
+		}
+		i := len(text) - len(strings.TrimLeft(text[f.pos:], " \t"))
+		if !colon(i) {
+			return "", 0, false
+		}
+		return v.(string), i + 1, true
+	}
+	for i := 0; i < len(text); i++ {
+		switch {
+		case text[i] == '#' && (text[i-1] == ' ' || text[i-1] == '\t'):
+			return "", 0, false
+		case colon(i):
+			return strings.TrimRight(text[:i], " \t"), i + 1, true
+		}
+	}
+	return "", 0, false
+}
+
+// yamlNameEnd returns the length of the anchor or alias name at the start of s.
+func yamlNameEnd(s string) int {
+	if i := strings.IndexAny(s, " \t\n,[]{}"); i >= 0 {
+		return i
+	}
+	return len(s)
+}
+
+// resolveYAML returns the value of a plain scalar with the YAML 1.2 core schema: null,
+// booleans, integers, floats and strings.
+func resolveYAML(s string) interface{} {
+	switch s {
+	case "", "~", "null", "Null", "NULL":
+		return nil
+	case "true", "True", "TRUE":
+		return true
+	case "false", "False", "FALSE":
+		return false
+	case ".inf", ".Inf", ".INF", "+.inf", "+.Inf", "+.INF":
+		return math.Inf(1)
+	case "-.inf", "-.Inf", "-.INF":
+		return math.Inf(-1)
+	case ".nan", ".NaN", ".NAN":
+		return math.NaN()
+	}
+	switch {
+	case strings.HasPrefix(s, "0x") && len(s) > 2:
+		if n, err := strconv.ParseUint(s[2:], 16, 63); err == nil {
+			return int(n)
+		}
+		return s
+	case strings.HasPrefix(s, "0o") && len(s) > 2:
+		if n, err := strconv.ParseUint(s[2:], 8, 63); err == nil {
+			return int(n)
+		}
+		return s
+	}
+	digits := strings.TrimLeft(s, "+-")
+	if len(s)-len(digits) > 1 || digits == "" {
+		return s
+	}
+	if strings.Trim(digits, "0123456789") == "" {
+		if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return int(n)
+		}
+	}
+	if isYAMLFloat(digits) {
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return f
+		}
+	}
+	return s
+}
+
+// isYAMLFloat reports whether the unsigned text is a number like "1.5", ".5" or "1e3".
+func isYAMLFloat(s string) bool {
+	i, digits := 0, 0
+	for ; i < len(s) && s[i] >= '0' && s[i] <= '9'; i++ {
+		digits++
+	}
+	if i < len(s) && s[i] == '.' {
+		for i++; i < len(s) && s[i] >= '0' && s[i] <= '9'; i++ {
+			digits++
+		}
+	}
+	if digits == 0 {
+		return false
+	}
+	if i < len(s) && (s[i] == 'e' || s[i] == 'E') {
+		i++
+		if i < len(s) && (s[i] == '+' || s[i] == '-') {
+			i++
+		}
+		exp := i
+		for ; i < len(s) && s[i] >= '0' && s[i] <= '9'; i++ {
+		}
+		if i == exp {
+			return false
+		}
+	}
+	return i == len(s)
+}
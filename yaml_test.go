@@ -0,0 +1,275 @@
+package cconf
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// tricky holds values that a naive encoder does not write back as the same values.
+var tricky = map[string]interface{}{
+	"answer": "yes",
+	"zip":    "08",
+	"flag":   "true",
+	"empty":  "",
+	"none":   "null",
+	"text":   "line 1\nline 2 \"quoted\"",
+	"path":   "/var/log",
+	"port":   8080.0,
+	"ratio":  0.5,
+	"debug":  true,
+	"nested": map[string]interface{}{
+		"servers": []interface{}{
+			map[string]interface{}{"host": "a", "port": 1},
+			map[string]interface{}{"host": "b", "tags": []interface{}{}},
+		},
+		"matrix": []interface{}{[]interface{}{1, 2}, []interface{}{"x: y"}},
+		"none":   map[string]interface{}{},
+	},
+}
+
+func TestEncodeYAML(t *testing.T) {
+	var buf bytes.Buffer
+	if err := encodeYAML(&buf, tricky); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, `answer: "yes"
+debug: true
+empty: ""
+flag: "true"
+nested:
+  matrix:
+    - - 1
+      - 2
+    - - "x: y"
+  none: {}
+  servers:
+    - host: a
+      port: 1
+    - host: b
+      tags: []
+none: "null"
+path: /var/log
+port: 8080
+ratio: 0.5
+text: "line 1\nline 2 \"quoted\""
+zip: "08"
+`, buf.String())
+
+	for v, want := range map[interface{}]string{
+		nil:            "null\n",
+		"Yes":          "\"Yes\"\n",
+		"-1":           "\"-1\"\n",
+		"a b":          "a b\n",
+		"a ":           "\"a \"\n",
+		"# x":          "\"# x\"\n",
+		math.Inf(-1):   "-.inf\n",
+		1e21:           "1e+21\n",
+		int64(-3):      "-3\n",
+		uint8(3):       "3\n",
+		"user@example": "user@example\n",
+	} {
+		buf.Reset()
+		if err := encodeYAML(&buf, v); err != nil {
+			t.Fatal(err)
+		}
+		equal(t, want, buf.String())
+	}
+}
+
+func TestSaveYAML(t *testing.T) {
+	c := New()
+	c.SetStore(tricky)
+	file := filepath.Join(t.TempDir(), "app.yml")
+	if err := c.Save(file); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if _, err := c.WriteTo(&buf, "yaml"); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, buf.String(), string(b))
+}
+
+func TestLoadYAML(t *testing.T) {
+	c := New()
+	if err := c.Load("./testdata/server.yml"); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, "localhost", c.GetString("http.host"))
+	equal(t, 8080, c.GetInt("http.port"))
+	equal(t, true, c.GetBool("http.tls.enabled"))
+	equal(t, "/etc/ssl/server.pem", c.GetString("http.tls.cert"))
+	equal(t, "http://127.0.0.1:9000", c.GetString("upstreams.0.url"))
+	equal(t, 3, c.GetInt("upstreams.0.retries"))
+	equal(t, 5, c.GetInt("upstreams.1.retries"))
+	equal(t, 30, c.GetInt("upstreams.1.timeout"))
+	equal(t, "Welcome!\nHave a nice day.\n", c.GetString("motd"))
+
+	var http struct {
+		Host string `cconf:"host"`
+		Port int    `cconf:"port"`
+		TLS  struct {
+			Enabled bool   `cconf:"enabled"`
+			Cert    string `cconf:"cert"`
+		} `cconf:"tls"`
+	}
+	if err := c.Populate(&http, "http"); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, 8080, http.Port)
+	equal(t, true, http.TLS.Enabled)
+	var upstreams []struct {
+		Name    string `cconf:"name"`
+		URL     string `cconf:"url"`
+		Timeout int    `cconf:"timeout"`
+		Retries int    `cconf:"retries"`
+	}
+	if err := c.Populate(&upstreams, "upstreams"); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, 2, len(upstreams))
+	equal(t, "auth", upstreams[1].Name)
+	equal(t, 5, upstreams[1].Retries)
+
+	// YAML merges on top of JSON.
+	c = New()
+	if err := c.Load("./testdata/app.json", "./testdata/app.yaml"); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, "cconf-yaml", c.GetString("name"))
+	equal(t, "syyong.x", c.GetString("ext.author"))
+	equal(t, "yaml@example.com", c.GetString("ext.email"))
+	equal(t, []interface{}{"config", "yaml"}, c.Get("ext.tags"))
+	equal(t, "./testdata/app.yaml", c.Source("ext.email"))
+
+	if err := c.LoadReader("yaml", strings.NewReader("ext: {email: reader@example.com}")); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, "reader@example.com", c.GetString("ext.email"))
+}
+
+func TestParseYAML(t *testing.T) {
+	for doc, want := range map[string]interface{}{
+		"":                          nil,
+		"# only comments":           nil,
+		"---\na: 1\n...\n":          map[string]interface{}{"a": 1},
+		"%YAML 1.2\n---\n- a\n- b":  []interface{}{"a", "b"},
+		"a:\n  b:\n    c: x":        map[string]interface{}{"a": map[string]interface{}{"b": map[string]interface{}{"c": "x"}}},
+		"a:\n- 1\n- 2\nb: 3":        map[string]interface{}{"a": []interface{}{1, 2}, "b": 3},
+		"- - 1\n  - 2\n- [3, 4]":    []interface{}{[]interface{}{1, 2}, []interface{}{3, 4}},
+		"- a: 1\n  b: 2\n-\n- c":    []interface{}{map[string]interface{}{"a": 1, "b": 2}, nil, "c"},
+		"a: b # comment\nc: d#e":    map[string]interface{}{"a": "b", "c": "d#e"},
+		"url: http://x:80/p":        map[string]interface{}{"url": "http://x:80/p"},
+		"a: long\n  text\n\n  here": map[string]interface{}{"a": "long text\nhere"},
+		"\"a b\": 1\n'c''d': 2":     map[string]interface{}{"a b": 1, "c'd": 2},
+		`a: "x\ty\u00e9\n"`:         map[string]interface{}{"a": "x\tyé\n"},
+		"a: \"one\n  two\"":         map[string]interface{}{"a": "one two"},
+		"a: 'it''s'":                map[string]interface{}{"a": "it's"},
+		"a: {b: 1, 'c': [x, y], d}": map[string]interface{}{"a": map[string]interface{}{"b": 1, "c": []interface{}{"x", "y"}, "d": nil}},
+		"a: [\n  1, # one\n  2,\n]": map[string]interface{}{"a": []interface{}{1, 2}},
+		"a: |\n  x\n\n    y\nb: 1":  map[string]interface{}{"a": "x\n\n  y\n", "b": 1},
+		"a: |-\n  x\n\n":            map[string]interface{}{"a": "x"},
+		"a: |+\n  x\n\nb: 1":        map[string]interface{}{"a": "x\n\n", "b": 1},
+		"a: >\n  x\n  y\n\n  z\n":   map[string]interface{}{"a": "x y\nz\n"},
+		"a: >2\n   x\n  y":          map[string]interface{}{"a": " x\ny\n"},
+		"- |\n  # text\n- b":        []interface{}{"# text\n", "b"},
+		"a: &x [1]\nb: *x":          map[string]interface{}{"a": []interface{}{1}, "b": []interface{}{1}},
+		"a: &x\n  k: 1\nb:\n  <<: *x\n  k: 2\n  l: 3": map[string]interface{}{
+			"a": map[string]interface{}{"k": 1},
+			"b": map[string]interface{}{"k": 2, "l": 3},
+		},
+		"b: &b {x: 1}\nc: &c {x: 2, y: 2}\nd:\n  <<: [*b, *c]": map[string]interface{}{
+			"b": map[string]interface{}{"x": 1},
+			"c": map[string]interface{}{"x": 2, "y": 2},
+			"d": map[string]interface{}{"x": 1, "y": 2},
+		},
+		"n: ~\nt: True\nf: false\ni: -12\nh: 0x1F\no: 0o17\nx: 1.5e3\ny: .5\ns: yes\nz: 08\nv: 1.2.3": map[string]interface{}{
+			"n": nil, "t": true, "f": false, "i": -12, "h": 31, "o": 15, "x": 1500.0, "y": 0.5,
+			"s": "yes", "z": 8, "v": "1.2.3",
+		},
+		"1: a\nnull: b": map[string]interface{}{"1": "a", "null": "b"},
+		"just text":     "just text",
+	} {
+		var v interface{}
+		if err := parseYAML(doc, &v); err != nil {
+			t.Errorf("%q: %v", doc, err)
+			continue
+		}
+		if !reflect.DeepEqual(want, v) {
+			t.Errorf("%q: expected %#v, got %#v", doc, want, v)
+		}
+	}
+
+	var v interface{}
+	if err := parseYAML("a: .inf\nb: .nan", &v); err != nil {
+		t.Fatal(err)
+	}
+	m := v.(map[string]interface{})
+	equal(t, math.Inf(1), m["a"])
+	equal(t, true, math.IsNaN(m["b"].(float64)))
+}
+
+func TestParseYAMLErrors(t *testing.T) {
+	for doc, want := range map[string]string{
+		"a: 1\n  b: 2":              "line 2, column 4: mapping values are not allowed in this context",
+		"a:\n    b: 1\n  c: 2":      "line 3, column 3: unexpected indentation",
+		"a: 1\na: 2":                `line 2, column 1: duplicate key "a"`,
+		"a: [1, 2":                  "line 1, column 4: unterminated flow sequence",
+		"a: {b: 1, c: 2 d: 3}":      "line 1, column 17: expected ',' or '}'",
+		"a: \"x":                    "line 1, column 4: unterminated quoted string",
+		"a: \"\\q\"":                `line 1, column 5: invalid escape sequence \q`,
+		"a: *nope":                  `line 1, column 4: unknown anchor "nope"`,
+		"a: !!str 1":                "line 1, column 4: tags are not supported",
+		"- a\nb: 1":                 "line 2, column 1: unexpected content at the end of the document",
+		"a: 1\n---\nb: 2":           "line 2, column 1: multiple documents are not supported",
+		"a:\n\tb: 1":                "line 2, column 1: found a tab character in the indentation",
+		"a: |x\n  b":                "line 1, column 4: invalid block scalar header",
+		"a:\n  - 1\n  b: 2":         "line 3, column 3: unexpected indentation",
+		"- 1\n- a: 1\n  - 2":        "line 3, column 3: expected a key followed by ':'",
+		"a: [1,\n  2]]":             "line 2, column 5: unexpected \"]\" after the value",
+		"a: 1\n<<: 2":               "line 2, column 1: the value of << must be a map or a list of maps",
+		"a:\n  b: \"x\n    y\" z\n": "line 3, column 8: unexpected \"z\" after the value",
+	} {
+		var v interface{}
+		err := parseYAML(doc, &v)
+		var se *SyntaxError
+		if !errors.As(err, &se) {
+			t.Errorf("%q: expected a *SyntaxError, got %v", doc, err)
+			continue
+		}
+		equal(t, want, err.Error())
+	}
+}
+
+func TestYAMLRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	if err := encodeYAML(&buf, tricky); err != nil {
+		t.Fatal(err)
+	}
+	var v interface{}
+	if err := parseYAML(buf.String(), &v); err != nil {
+		t.Fatal(err)
+	}
+	m := v.(map[string]interface{})
+	for _, k := range []string{"answer", "zip", "flag", "empty", "none", "text", "path"} {
+		equal(t, tricky[k], m[k])
+	}
+	equal(t, 8080, m["port"])
+	equal(t, tricky["nested"].(map[string]interface{})["matrix"].([]interface{})[1], m["nested"].(map[string]interface{})["matrix"].([]interface{})[1])
+
+	var again bytes.Buffer
+	if err := encodeYAML(&again, v); err != nil {
+		t.Fatal(err)
+	}
+	equal(t, buf.String(), again.String())
+}